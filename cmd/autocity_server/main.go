@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zekeriyyah/lujay-autocity/internal/config"
 	"github.com/zekeriyyah/lujay-autocity/internal/database"
 	"github.com/zekeriyyah/lujay-autocity/internal/routes"
+	"github.com/zekeriyyah/lujay-autocity/internal/scheduler"
 	"github.com/zekeriyyah/lujay-autocity/pkg"
 )
 
+// shutdownTimeout is how long requests in flight get to finish once the server is asked to stop
+const shutdownTimeout = 15 * time.Second
+
 
 func main() {
 
@@ -26,7 +37,16 @@ func main() {
 	}
 	
 
-	r = routes.SetupRouter(r, cfg)
+	r, jobs, err := routes.SetupRouter(r, cfg)
+	if err != nil {
+		pkg.Error(err, "failed to set up the server")
+		return
+	}
+
+	// background jobs and open event streams stop when the process is interrupted or terminated
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	scheduler.Start(ctx, jobs...)
 
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
@@ -35,8 +55,26 @@ func main() {
 
 	pkg.Info("Server starting on port " + port)
 
-	if err := r.Run(":" + port); err != nil {
+	server := &http.Server{
+		Addr:        ":" + port,
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			pkg.Error(err, "failed to shut the server down cleanly")
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		pkg.Error(err, "failed to start server")
 		return
 	}
+	<-stopped
+	pkg.Info("Server stopped")
 }
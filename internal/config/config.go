@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/zekeriyyah/lujay-autocity/pkg"
@@ -13,6 +15,40 @@ type Config struct {
     CloudinaryCloudName string
 	CloudinaryAPIKey    string
 	CloudinaryAPISecret string
+
+	StorageDir    string // Where generated files are kept when Cloudinary is not configured
+	PublicBaseURL string // Externally reachable address of this API, used to build links to locally stored files
+
+	CertificateSigningKey string // Base64 Ed25519 seed used to sign inspection certificates
+	PayoutEncryptionKey   string // Base64 AES-256 key sealing sellers' payout bank details
+
+	InspectionChangeCutoff time.Duration // How close to an inspection a booking can still be rescheduled or cancelled
+	InspectionReminderLead time.Duration // How long before an inspection the reminder emails go out
+
+	PrePurchaseInspectionFee float64 // Fee charged to a buyer for a pre-purchase inspection, in local currency
+	InvoiceTaxRate           float64 // VAT percentage included in the platform's fees, shown on receipts
+
+	Environment string // development or production; only development may use the mock payment provider
+
+	PaymentProvider      string // paystack or flutterwave, or mock in development
+	PaymentSecretKey     string // API secret key of the payment provider
+	PaymentWebhookSecret string // Secret webhooks are signed with (Flutterwave secret hash; Paystack signs with the secret key)
+	PaymentCurrency      string
+
+	EscrowPaymentWindow    time.Duration // How long a buyer has to pay before the sale is cancelled
+	EscrowHandoverWindow   time.Duration // How long a seller has to hand the vehicle over before the buyer is refunded
+	EscrowInspectionWindow time.Duration // How long a buyer has after handover to confirm or dispute before funds are released
+	EscrowDisputeWindow    time.Duration // How long admins have to resolve a dispute before the buyer is refunded
+
+	ReservationHold           time.Duration // How long a paid deposit keeps a listing reserved for its buyer
+	ReservationDepositPercent float64       // Share of the asking price a buyer pays to reserve a listing
+
+	ReviewSLA      time.Duration // How long a listing may wait in pending_review before it breaches the SLA
+	ReviewClaimTTL time.Duration // How long an admin's claim on a queued listing lasts without being renewed
+
+	OfferExpiry time.Duration // How long the party whose turn it is has to respond to an offer or counter-offer
+
+	TestDriveReminderLead time.Duration // How long before a test drive the reminder emails go out
 }
 
 func LoadConfig() (*Config, error) {
@@ -27,6 +63,40 @@ func LoadConfig() (*Config, error) {
         CloudinaryCloudName: getEnv("CLOUDINARY_CLOUD_NAME", ""),
 		CloudinaryAPIKey:    getEnv("CLOUDINARY_API_KEY", ""),    
 		CloudinaryAPISecret: getEnv("CLOUDINARY_API_SECRET", ""), 
+
+		StorageDir:    getEnv("STORAGE_DIR", "./storage"),
+		PublicBaseURL: getEnv("PUBLIC_BASE_URL", "http://localhost:8000"),
+
+		CertificateSigningKey: getEnv("CERTIFICATE_SIGNING_KEY", ""),
+		PayoutEncryptionKey:   getEnv("PAYOUT_ENCRYPTION_KEY", ""),
+
+		InspectionChangeCutoff: time.Duration(getEnvInt("INSPECTION_CHANGE_CUTOFF_HOURS", 24)) * time.Hour,
+		InspectionReminderLead: time.Duration(getEnvInt("INSPECTION_REMINDER_HOURS", 24)) * time.Hour,
+
+		PrePurchaseInspectionFee: getEnvFloat("PRE_PURCHASE_INSPECTION_FEE", 25000),
+		InvoiceTaxRate:           getEnvFloat("INVOICE_TAX_RATE", 7.5),
+
+		Environment: getEnv("APP_ENV", "production"),
+
+		PaymentProvider:      getEnv("PAYMENT_PROVIDER", ""),
+		PaymentSecretKey:     getEnv("PAYMENT_SECRET_KEY", ""),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PaymentCurrency:      getEnv("PAYMENT_CURRENCY", "NGN"),
+
+		EscrowPaymentWindow:    time.Duration(getEnvInt("ESCROW_PAYMENT_WINDOW_HOURS", 24)) * time.Hour,
+		EscrowHandoverWindow:   time.Duration(getEnvInt("ESCROW_HANDOVER_WINDOW_HOURS", 168)) * time.Hour,
+		EscrowInspectionWindow: time.Duration(getEnvInt("ESCROW_INSPECTION_WINDOW_HOURS", 72)) * time.Hour,
+		EscrowDisputeWindow:    time.Duration(getEnvInt("ESCROW_DISPUTE_WINDOW_HOURS", 336)) * time.Hour,
+
+		ReservationHold:           time.Duration(getEnvInt("RESERVATION_HOLD_HOURS", 72)) * time.Hour,
+		ReservationDepositPercent: getEnvFloat("RESERVATION_DEPOSIT_PERCENT", 10),
+
+		ReviewSLA:      time.Duration(getEnvInt("REVIEW_SLA_HOURS", 72)) * time.Hour,
+		ReviewClaimTTL: time.Duration(getEnvInt("REVIEW_CLAIM_MINUTES", 120)) * time.Minute,
+
+		OfferExpiry: time.Duration(getEnvInt("OFFER_EXPIRY_HOURS", 48)) * time.Hour,
+
+		TestDriveReminderLead: time.Duration(getEnvInt("TEST_DRIVE_REMINDER_HOURS", 24)) * time.Hour,
     }, nil
 }
 
@@ -36,4 +106,34 @@ func getEnv(key, defaultValue string) string {
         return value
     }
     return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvInt retrieves an integer environment variable or returns a default value when unset or invalid.
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		pkg.Info("Warning: " + key + " is not a valid integer, using default")
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat retrieves a decimal environment variable or returns a default value when unset or invalid.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		pkg.Info("Warning: " + key + " is not a valid number, using default")
+		return defaultValue
+	}
+	return parsed
+}
@@ -4,10 +4,15 @@ import (
 	"log"
 
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/gorm/clause"
 )
 
 
 func Run() {
+	dropSingleInspectionPerListingIndex()
+	dropSingleTransactionPerListingIndex()
+	dropSingleSalePerListingIndex()
+
 	err := DB.AutoMigrate(
 		&models.User{},
 		&models.Vehicle{},
@@ -15,10 +20,198 @@ func Run() {
 		&models.Listing{},
 		&models.Transaction{},
 		&models.Image{},
+		&models.ChecklistTemplate{},
+		&models.InspectionSlot{},
+		&models.InspectionBooking{},
+		&models.InspectionCertificate{},
+		&models.ListingStatusChange{},
+		&models.RejectionReason{},
+		&models.ListingResubmission{},
+		&models.InspectionRequest{},
+		&models.PaymentWebhookEvent{},
+		&models.Refund{},
+		&models.TransactionEvent{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.JournalLine{},
+		&models.FeeRule{},
+		&models.FeeWaiver{},
+		&models.TransactionFee{},
+		&models.PayoutAccount{},
+		&models.PayoutBatch{},
+		&models.Payout{},
+		&models.PayoutItem{},
+		&models.Invoice{},
+		&models.InvoiceSequence{},
+		&models.CancellationRequest{},
+		&models.Offer{},
+		&models.OfferEvent{},
+		&models.TestDrive{},
+		&models.TestDriveWindow{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.MessageAttachment{},
 	)
 
 	if err != nil {
 		log.Fatal("❌ database auto-migration failed:", err)
 	}
+
+	backfillListingVehicleSpecs()
+	backfillCurrentInspections()
+	backfillListingStatusChangedAt()
+	seedRejectionReasons()
+	backfillInspectionAssignedAt()
+	backfillTransactionEscrow()
+	protectJournal()
+
 	log.Println("✅ Migrations completed successfully!")
-}
\ No newline at end of file
+}
+
+// backfillListingVehicleSpecs snapshots the shared vehicle specs onto listings created before per-listing snapshots existed.
+// Mileage is left alone: the shared record only holds one reading, which says nothing about older listings.
+func backfillListingVehicleSpecs() {
+	err := DB.Exec(`
+		UPDATE listings SET
+			vehicle_make = vehicles.make,
+			vehicle_model = vehicles.model,
+			vehicle_year = vehicles.year,
+			vehicle_engine_size = vehicles.engine_size,
+			vehicle_fuel_type = vehicles.fuel_type,
+			vehicle_transmission = vehicles.transmission,
+			vehicle_body_type = vehicles.body_type,
+			vehicle_color = vehicles.color,
+			vehicle_condition = vehicles.condition
+		FROM vehicles
+		WHERE listings.vehicle_id = vehicles.id AND (listings.vehicle_make IS NULL OR listings.vehicle_make = '')
+	`).Error
+	if err != nil {
+		log.Fatal("❌ backfilling listing vehicle specs failed:", err)
+	}
+}
+
+// dropSingleInspectionPerListingIndex removes the unique listing_id index that allowed only one inspection per listing
+func dropSingleInspectionPerListingIndex() {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Inspection{}) || !migrator.HasIndex(&models.Inspection{}, "idx_inspections_listing_id") {
+		return
+	}
+
+	if err := migrator.DropIndex(&models.Inspection{}, "idx_inspections_listing_id"); err != nil {
+		log.Fatal("❌ dropping unique inspection listing index failed:", err)
+	}
+}
+
+// dropSingleTransactionPerListingIndex removes the unique listing_id index that allowed only one transaction per listing;
+// the one-sale rule now lives in a partial unique index so fee transactions can share the listing
+func dropSingleTransactionPerListingIndex() {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Transaction{}) || migrator.HasIndex(&models.Transaction{}, "idx_transactions_sale_listing") ||
+		migrator.HasIndex(&models.Transaction{}, "idx_transactions_open_sale_listing") ||
+		!migrator.HasIndex(&models.Transaction{}, "idx_transactions_listing_id") {
+		return
+	}
+
+	if err := migrator.DropIndex(&models.Transaction{}, "idx_transactions_listing_id"); err != nil {
+		log.Fatal("❌ dropping unique transaction listing index failed:", err)
+	}
+}
+
+// dropSingleSalePerListingIndex removes the partial index that allowed one sale per listing ever;
+// its replacement only covers pending and completed sales so a failed or cancelled purchase can be retried
+func dropSingleSalePerListingIndex() {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Transaction{}) || !migrator.HasIndex(&models.Transaction{}, "idx_transactions_sale_listing") {
+		return
+	}
+
+	if err := migrator.DropIndex(&models.Transaction{}, "idx_transactions_sale_listing"); err != nil {
+		log.Fatal("❌ dropping single sale transaction index failed:", err)
+	}
+}
+
+// backfillCurrentInspections points listings created before inspection history existed at their latest inspection
+func backfillCurrentInspections() {
+	err := DB.Exec(`
+		UPDATE listings SET current_inspection_id = latest.id
+		FROM (
+			SELECT DISTINCT ON (listing_id) id, listing_id
+			FROM inspections
+			ORDER BY listing_id, created_at DESC
+		) AS latest
+		WHERE listings.id = latest.listing_id AND listings.current_inspection_id IS NULL
+	`).Error
+	if err != nil {
+		log.Fatal("❌ backfilling current inspections failed:", err)
+	}
+}
+
+// backfillListingStatusChangedAt approximates time in state for listings created before it was tracked;
+// the last update is the closest record of when their status was set
+func backfillListingStatusChangedAt() {
+	err := DB.Exec(`UPDATE listings SET status_changed_at = COALESCE(updated_at, created_at) WHERE status_changed_at IS NULL`).Error
+	if err != nil {
+		log.Fatal("❌ backfilling listing status timestamps failed:", err)
+	}
+}
+
+// backfillTransactionEscrow puts sales recorded before escrow existed into the matching escrow state. Open sales get no
+// deadline, so no automatic action runs on money that was never taken through escrow.
+func backfillTransactionEscrow() {
+	err := DB.Exec(`
+		UPDATE transactions SET escrow_status = CASE status
+			WHEN 'pending' THEN 'awaiting_payment'
+			WHEN 'completed' THEN 'released'
+			ELSE 'cancelled'
+		END
+		WHERE type = 'sale' AND (escrow_status IS NULL OR escrow_status = '')
+	`).Error
+	if err != nil {
+		log.Fatal("❌ backfilling transaction escrow failed:", err)
+	}
+}
+
+// protectJournal makes journal entries and lines append-only in the database itself, so no code path or manual
+// query can rewrite the ledger; corrections are posted as reversing entries
+func protectJournal() {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION reject_journal_change() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'journal entries are immutable';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS journal_entries_immutable ON journal_entries`,
+		`CREATE TRIGGER journal_entries_immutable BEFORE UPDATE OR DELETE ON journal_entries
+			FOR EACH ROW EXECUTE FUNCTION reject_journal_change()`,
+		`DROP TRIGGER IF EXISTS journal_lines_immutable ON journal_lines`,
+		`CREATE TRIGGER journal_lines_immutable BEFORE UPDATE OR DELETE ON journal_lines
+			FOR EACH ROW EXECUTE FUNCTION reject_journal_change()`,
+	}
+
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatal("❌ protecting the journal failed:", err)
+		}
+	}
+}
+
+// backfillInspectionAssignedAt uses the creation time as the assignment time of inspections recorded before it was tracked
+func backfillInspectionAssignedAt() {
+	err := DB.Exec(`UPDATE inspections SET assigned_at = created_at WHERE assigned_at IS NULL`).Error
+	if err != nil {
+		log.Fatal("❌ backfilling inspection assignment timestamps failed:", err)
+	}
+}
+
+// seedRejectionReasons adds the default rejection reason codes; existing codes, including ones admins edited, are left alone
+func seedRejectionReasons() {
+	reasons := make([]models.RejectionReason, len(models.DefaultRejectionReasons))
+	copy(reasons, models.DefaultRejectionReasons)
+	for i := range reasons {
+		reasons[i].Active = true
+	}
+
+	if err := DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(&reasons).Error; err != nil {
+		log.Fatal("❌ seeding rejection reasons failed:", err)
+	}
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// Type names what happened; it is sent as the event name on the stream
+type Type string
+
+const (
+	TypeListingStatus Type = "listing.status"
+	TypeOffer         Type = "offer.updated"
+	TypeMessage       Type = "message.created"
+	TypeTransaction   Type = "transaction.updated"
+)
+
+const (
+	// subscriptionBuffer is how many events a connection can fall behind before it is dropped
+	subscriptionBuffer = 64
+	// MaxSubscriptionsPerUser caps the event streams one user can hold open, e.g. one per browser tab
+	MaxSubscriptionsPerUser = 5
+)
+
+// Event is one real-time update pushed to connected users. Events are not stored: a client that reconnects should
+// refetch what it shows rather than expect to be sent what it missed.
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      Type      `json:"type"`
+	Data      any       `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscription is one open event stream of a user. Events is closed when the subscription ends, either because the
+// client went away or because it fell too far behind.
+type Subscription struct {
+	Events <-chan Event
+
+	hub    *Hub
+	userID uuid.UUID
+	role   types.Role
+	ch     chan Event
+}
+
+// Close ends the subscription; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub fans out events published by the services to the users they concern, in process. Publishing never blocks:
+// a connection whose buffer is full is closed so its client reconnects and refetches.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[uuid.UUID]map[*Subscription]struct{}{}}
+}
+
+// Subscribe opens an event stream for a user
+func (h *Hub) Subscribe(userID uuid.UUID, role types.Role) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subscribers[userID]) >= MaxSubscriptionsPerUser {
+		return nil, fmt.Errorf("you can have at most %d event streams open", MaxSubscriptionsPerUser)
+	}
+
+	ch := make(chan Event, subscriptionBuffer)
+	subscription := &Subscription{Events: ch, hub: h, userID: userID, role: role, ch: ch}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = map[*Subscription]struct{}{}
+	}
+	h.subscribers[userID][subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Publish sends an event to every open stream of the given users; a user listed twice gets it once
+func (h *Hub) Publish(eventType Type, data any, userIDs ...uuid.UUID) {
+	event := newEvent(eventType, data)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	seen := map[uuid.UUID]bool{}
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		for subscription := range h.subscribers[userID] {
+			h.deliver(subscription, event)
+		}
+	}
+}
+
+// PublishToRole sends an event to every open stream of users with the given role, e.g. admins watching the review queue
+func (h *Hub) PublishToRole(role types.Role, eventType Type, data any) {
+	event := newEvent(eventType, data)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscriptions := range h.subscribers {
+		for subscription := range subscriptions {
+			if subscription.role == role {
+				h.deliver(subscription, event)
+			}
+		}
+	}
+}
+
+// deliver hands an event to a subscription without waiting; the caller holds the lock
+func (h *Hub) deliver(subscription *Subscription, event Event) {
+	select {
+	case subscription.ch <- event:
+	default:
+		h.removeLocked(subscription)
+	}
+}
+
+func (h *Hub) remove(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(subscription)
+}
+
+func (h *Hub) removeLocked(subscription *Subscription) {
+	subscriptions := h.subscribers[subscription.userID]
+	if _, ok := subscriptions[subscription]; !ok {
+		return
+	}
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(h.subscribers, subscription.userID)
+	}
+	close(subscription.ch)
+}
+
+func newEvent(eventType Type, data any) Event {
+	return Event{
+		ID:        uuid.New(),
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+)
+
+// ListingStatusChanged is sent to a listing's seller when the listing moves between statuses, and to admins when it
+// enters review
+type ListingStatusChanged struct {
+	ListingID  uuid.UUID            `json:"listing_id"`
+	Title      string               `json:"title"`
+	FromStatus models.ListingStatus `json:"from_status,omitempty"`
+	ToStatus   models.ListingStatus `json:"to_status"`
+	Reason     string               `json:"reason,omitempty"`
+}
+
+// OfferUpdated is sent to the buyer and seller when an offer is made, countered, accepted, declined, withdrawn or expires
+type OfferUpdated struct {
+	OfferID   uuid.UUID          `json:"offer_id"`
+	ListingID uuid.UUID          `json:"listing_id"`
+	Status    models.OfferStatus `json:"status"`
+	Amount    float64            `json:"amount"`
+}
+
+// MessageCreated is sent to both parties of a conversation when either of them sends a message
+type MessageCreated struct {
+	ConversationID uuid.UUID       `json:"conversation_id"`
+	ListingID      uuid.UUID       `json:"listing_id"`
+	Message        *models.Message `json:"message"`
+}
+
+// TransactionUpdated is sent to the buyer and seller whenever a sale or reservation changes, with the status of its
+// listing, which sales and reservations move between active, reserved and sold
+type TransactionUpdated struct {
+	TransactionID uuid.UUID                `json:"transaction_id"`
+	ListingID     uuid.UUID                `json:"listing_id"`
+	Type          models.TransactionType   `json:"type"`
+	Status        models.TransactionStatus `json:"status"`
+	EscrowStatus  models.EscrowStatus      `json:"escrow_status"`
+	ListingStatus models.ListingStatus     `json:"listing_status"`
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+// defaultAnalyticsPeriod is reported when no from date is given
+const defaultAnalyticsPeriod = 30 * 24 * time.Hour
+
+type AnalyticsHandler struct {
+	service *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(service *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetInspectorPerformance handles GET /analytics/inspectors?from=&to=&inspector_id=&format=json|csv (Admin only)
+func (h *AnalyticsHandler) GetInspectorPerformance(c *gin.Context) {
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		parsed, dateOnly, err := parseAnalyticsTime(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+		// a date-only upper bound includes the whole day
+		if dateOnly {
+			to = to.Add(24 * time.Hour)
+		}
+	}
+
+	from := to.Add(-defaultAnalyticsPeriod)
+	if raw := c.Query("from"); raw != "" {
+		parsed, _, err := parseAnalyticsTime(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+
+	inspectorID := uuid.Nil
+	if raw := c.Query("inspector_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inspector ID format"})
+			return
+		}
+		inspectorID = parsed
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format parameter, expected json or csv"})
+		return
+	}
+
+	report, err := h.service.GetInspectorPerformance(from, to, inspectorID)
+	if err != nil {
+		if err.Error() == "to must be after from" || strings.HasPrefix(err.Error(), "date range cannot exceed") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting inspector performance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspector performance"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.service.WriteInspectorPerformanceCSV(buf, report); err != nil {
+		log.Printf("Error writing inspector performance CSV: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export inspector performance"})
+		return
+	}
+
+	filename := fmt.Sprintf("inspector-performance-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// parseAnalyticsTime accepts an RFC3339 timestamp or a YYYY-MM-DD date, reporting which one was given
+func parseAnalyticsTime(raw string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed, false, nil
+	}
+	parsed, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return parsed, true, nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type CertificateHandler struct {
+	service *services.CertificateService
+}
+
+func NewCertificateHandler(service *services.CertificateService) *CertificateHandler {
+	return &CertificateHandler{
+		service: service,
+	}
+}
+
+// GetCertificate handles GET /certificates/{id} (Public)
+func (h *CertificateHandler) GetCertificate(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid certificate ID format"})
+		return
+	}
+
+	certificate, err := h.service.GetCertificate(idStr)
+	if err != nil {
+		log.Printf("Error getting certificate %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("certificate with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get certificate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, certificate)
+}
+
+// GetQRCode handles GET /certificates/{id}/qr (Public); the PNG links to the certificate's verification page
+func (h *CertificateHandler) GetQRCode(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid certificate ID format"})
+		return
+	}
+
+	image, err := h.service.GetQRCode(idStr)
+	if err != nil {
+		log.Printf("Error getting QR code of certificate %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("certificate with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// GetPublicKey handles GET /certificates/public-key (Public)
+func (h *CertificateHandler) GetPublicKey(c *gin.Context) {
+	publicKey, err := h.service.GetPublicKey()
+	if err != nil {
+		log.Printf("Error getting certificate public key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get public key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, publicKey)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"gorm.io/gorm"
+)
+
+type ChecklistTemplateHandler struct {
+	service   *services.ChecklistTemplateService
+	validator *validator.Validate
+}
+
+func NewChecklistTemplateHandler(service *services.ChecklistTemplateService) *ChecklistTemplateHandler {
+	return &ChecklistTemplateHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// CreateChecklistTemplateInput describes a new checklist template version
+type CreateChecklistTemplateInput struct {
+	Name     string                    `json:"name" validate:"required,max=100"`
+	Sections []models.ChecklistSection `json:"sections" validate:"required,min=1,dive"`
+}
+
+// CreateTemplate handles POST /checklist-templates (admin only)
+func (h *ChecklistTemplateHandler) CreateTemplate(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreateChecklistTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	template := &models.ChecklistTemplate{
+		Name:     input.Name,
+		Sections: input.Sections,
+	}
+
+	createdTemplate, err := h.service.CreateTemplate(template, adminID)
+	if err != nil {
+		log.Printf("Error creating checklist template: %v", err)
+		if strings.HasPrefix(err.Error(), "duplicate checklist") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create checklist template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdTemplate)
+}
+
+// GetTemplates handles GET /checklist-templates (admin only)
+func (h *ChecklistTemplateHandler) GetTemplates(c *gin.Context) {
+	templates, err := h.service.GetTemplates()
+	if err != nil {
+		log.Printf("Error getting checklist templates: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checklist templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetActiveTemplate handles GET /checklist-templates/active (admin only)
+func (h *ChecklistTemplateHandler) GetActiveTemplate(c *gin.Context) {
+	template, err := h.service.GetActiveTemplate()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no checklist template is currently in force"})
+			return
+		}
+		log.Printf("Error getting active checklist template: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active checklist template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// GetTemplateByID handles GET /checklist-templates/{id} (admin only)
+func (h *ChecklistTemplateHandler) GetTemplateByID(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist template ID format"})
+		return
+	}
+
+	template, err := h.service.GetTemplateByID(idStr)
+	if err != nil {
+		log.Printf("Error getting checklist template %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("checklist template with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checklist template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// ActivateTemplate handles POST /checklist-templates/{id}/activate (admin only)
+func (h *ChecklistTemplateHandler) ActivateTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist template ID format"})
+		return
+	}
+
+	template, err := h.service.ActivateTemplate(idStr)
+	if err != nil {
+		log.Printf("Error activating checklist template %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("failed to activate checklist template: checklist template with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("checklist template with id %s not found", idStr)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate checklist template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
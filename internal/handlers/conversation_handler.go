@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type ConversationHandler struct {
+	service   *services.ConversationService
+	validator *validator.Validate
+}
+
+func NewConversationHandler(service *services.ConversationService) *ConversationHandler {
+	return &ConversationHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// MessageInput is the text of a message; files are sent as multipart/form-data with the text in a body field
+type MessageInput struct {
+	Body string `json:"body" form:"body" validate:"max=5000"`
+}
+
+// ContactSeller handles POST /listings/{id}/conversations (Buyer only); starts or continues the buyer's conversation
+func (h *ConversationHandler) ContactSeller(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	body, uploads, ok := h.bindMessage(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.service.ContactSeller(idStr, buyerID, body, uploads)
+	if err != nil {
+		log.Printf("Error contacting seller of listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeConversationError(c, "", err)
+		return
+	}
+
+	conversation.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// GetMyConversations handles GET /conversations/my (Buyer or Seller)
+func (h *ConversationHandler) GetMyConversations(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	conversations, err := h.service.GetMyConversations(userID)
+	if err != nil {
+		log.Printf("Error getting conversations of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get conversations"})
+		return
+	}
+
+	for _, conversation := range conversations {
+		conversation.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetConversations handles GET /conversations (Admin only); optional ?listing_id= and ?user_id= filters
+func (h *ConversationHandler) GetConversations(c *gin.Context) {
+	var listingID, userID *uuid.UUID
+	if param := c.Query("listing_id"); param != "" {
+		parsed, err := uuid.Parse(param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing_id format"})
+			return
+		}
+		listingID = &parsed
+	}
+	if param := c.Query("user_id"); param != "" {
+		parsed, err := uuid.Parse(param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id format"})
+			return
+		}
+		userID = &parsed
+	}
+
+	conversations, err := h.service.GetConversations(listingID, userID)
+	if err != nil {
+		log.Printf("Error getting conversations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetConversation handles GET /conversations/{id} (Buyer or Seller of the conversation, or Admin)
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	idStr, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	conversation, err := h.service.GetConversation(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting conversation %s: %v", idStr, err)
+		h.writeConversationError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		conversation.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, conversation)
+}
+
+// SendMessage handles POST /conversations/{id}/messages (Buyer or Seller of the conversation)
+func (h *ConversationHandler) SendMessage(c *gin.Context) {
+	idStr, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	body, uploads, ok := h.bindMessage(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.service.SendMessage(idStr, userID, body, uploads)
+	if err != nil {
+		log.Printf("Error sending message in conversation %s: %v", idStr, err)
+		h.writeConversationError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// Block handles POST /conversations/{id}/block (Buyer or Seller of the conversation)
+func (h *ConversationHandler) Block(c *gin.Context) {
+	idStr, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	conversation, err := h.service.Block(idStr, userID)
+	if err != nil {
+		log.Printf("Error blocking conversation %s: %v", idStr, err)
+		h.writeConversationError(c, idStr, err)
+		return
+	}
+
+	conversation.HideSellerContactDetails()
+	c.JSON(http.StatusOK, conversation)
+}
+
+// Unblock handles DELETE /conversations/{id}/block (the party who blocked the conversation)
+func (h *ConversationHandler) Unblock(c *gin.Context) {
+	idStr, ok := conversationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	conversation, err := h.service.Unblock(idStr, userID)
+	if err != nil {
+		log.Printf("Error unblocking conversation %s: %v", idStr, err)
+		h.writeConversationError(c, idStr, err)
+		return
+	}
+
+	conversation.HideSellerContactDetails()
+	c.JSON(http.StatusOK, conversation)
+}
+
+// bindMessage reads a message sent as JSON, or as multipart/form-data with files in the attachments field
+func (h *ConversationHandler) bindMessage(c *gin.Context) (string, []services.AttachmentUpload, bool) {
+	var input MessageInput
+	uploads := []services.AttachmentUpload{}
+
+	if c.ContentType() == "multipart/form-data" {
+		// leave room for the text fields on top of the largest set of files allowed
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxMessageAttachments*services.MaxMessageAttachmentSize+1<<20)
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data", "details": err.Error()})
+			return "", nil, false
+		}
+		if values := form.Value["body"]; len(values) > 0 {
+			input.Body = values[0]
+		}
+
+		for _, header := range form.File["attachments"] {
+			file, err := header.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment", "details": err.Error()})
+				return "", nil, false
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment", "details": err.Error()})
+				return "", nil, false
+			}
+			uploads = append(uploads, services.AttachmentUpload{Filename: header.Filename, Data: data})
+		}
+	} else if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return "", nil, false
+	}
+	input.Body = strings.TrimSpace(input.Body)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return "", nil, false
+	}
+	return input.Body, uploads, true
+}
+
+func conversationIDParam(c *gin.Context) (string, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID format"})
+		return "", false
+	}
+	return idStr, true
+}
+
+func (h *ConversationHandler) writeConversationError(c *gin.Context, conversationID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("conversation with id %s not found", conversationID):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "you cannot message yourself about your own listing",
+		message == "message must have a body or an attachment",
+		strings.HasPrefix(message, "a message can have at most"),
+		strings.HasPrefix(message, "attachments can be at most"),
+		message == "attachments must be JPEG, PNG or WebP images or PDF documents":
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case message == "sellers can only be contacted about active listings",
+		message == "conversation is blocked",
+		message == "conversation is already blocked",
+		message == "conversation is not blocked":
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	case strings.HasPrefix(message, "failed to store attachment"):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to store attachment"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process conversation"})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+)
+
+// eventHeartbeat is how often an idle stream gets a comment line, so proxies and load balancers keep it open
+const eventHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	hub *events.Hub
+}
+
+func NewEventHandler(hub *events.Hub) *EventHandler {
+	return &EventHandler{hub: hub}
+}
+
+// Stream handles GET /events (any authenticated user); a Server-Sent Events stream of the user's real-time updates
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subscription, err := h.hub.Subscribe(userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// stop nginx buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	io.WriteString(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, open := <-subscription.Events:
+			// a closed subscription fell behind; the client reconnects and refetches
+			if !open {
+				return false
+			}
+			if err := writeEvent(w, event); err != nil {
+				log.Printf("Error writing event %s to user %s: %v", event.ID, userID, err)
+				return false
+			}
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
+
+// writeEvent writes one event in the text/event-stream format, named after its type so clients can listen per type
+func writeEvent(w io.Writer, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type FeeHandler struct {
+	service   *services.FeeService
+	validator *validator.Validate
+}
+
+func NewFeeHandler(service *services.FeeService) *FeeHandler {
+	return &FeeHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// FeeRuleInput describes a fee rule; updates replace the whole definition
+type FeeRuleInput struct {
+	Name       string           `json:"name" validate:"required,max=255"`
+	Kind       models.FeeKind   `json:"kind" validate:"required,oneof=sale_commission inspection_fee"`
+	Role       types.Role       `json:"role,omitempty" validate:"omitempty,oneof=buyer seller admin inspector"`
+	Method     models.FeeMethod `json:"method" validate:"required,oneof=percentage flat tiered"`
+	Percentage float64          `json:"percentage,omitempty" validate:"gte=0,lte=100"`
+	FlatAmount float64          `json:"flat_amount,omitempty" validate:"gte=0"`
+	Tiers      []models.FeeTier `json:"tiers,omitempty" validate:"max=20"`
+	MinAmount  float64          `json:"min_amount,omitempty" validate:"gte=0"`
+	MaxAmount  float64          `json:"max_amount,omitempty" validate:"gte=0"`
+	Active     *bool            `json:"active,omitempty"`
+}
+
+// CreateFeeWaiverInput describes a promotional waiver; without starts_at it starts straight away
+type CreateFeeWaiverInput struct {
+	Name       string         `json:"name" validate:"required,max=255"`
+	Kind       models.FeeKind `json:"kind" validate:"required,oneof=sale_commission inspection_fee"`
+	Role       types.Role     `json:"role,omitempty" validate:"omitempty,oneof=buyer seller admin inspector"`
+	UserID     *uuid.UUID     `json:"user_id,omitempty"`
+	Percentage float64        `json:"percentage" validate:"required,gt=0,lte=100"`
+	StartsAt   *time.Time     `json:"starts_at,omitempty"`
+	EndsAt     *time.Time     `json:"ends_at,omitempty"`
+}
+
+// UpdateFeeWaiverInput ends a waiver or changes when it ends; omitted fields are left as they are
+type UpdateFeeWaiverInput struct {
+	EndsAt *time.Time `json:"ends_at,omitempty"`
+	Active *bool      `json:"active,omitempty"`
+}
+
+// CreateRule handles POST /fee-rules (Admin only)
+func (h *FeeHandler) CreateRule(c *gin.Context) {
+	input, ok := h.bindRule(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.CreateRule(input)
+	if err != nil {
+		log.Printf("Error creating fee rule: %v", err)
+		h.writeFeeError(c, err, "Failed to create fee rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetRules handles GET /fee-rules (Admin only)
+func (h *FeeHandler) GetRules(c *gin.Context) {
+	kind, ok := feeKindQuery(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.service.GetRules(kind, c.Query("include_inactive") == "true")
+	if err != nil {
+		log.Printf("Error getting fee rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fee rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRule handles PUT /fee-rules/{id} (Admin only)
+func (h *FeeHandler) UpdateRule(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fee rule ID format"})
+		return
+	}
+
+	input, ok := h.bindRule(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.UpdateRule(idStr, input)
+	if err != nil {
+		log.Printf("Error updating fee rule %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("fee rule with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeFeeError(c, err, "Failed to update fee rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Quote handles GET /fee-rules/quote (Admin only): the fee the current schedule charges on an amount
+func (h *FeeHandler) Quote(c *gin.Context) {
+	kind, ok := feeKindQuery(c)
+	if !ok {
+		return
+	}
+	if kind == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind parameter is required"})
+		return
+	}
+
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount parameter, expected a non-negative number"})
+		return
+	}
+
+	role := types.Role(c.Query("role"))
+	payerID := uuid.Nil
+	if userStr := c.Query("user_id"); userStr != "" {
+		payerID, err = uuid.Parse(userStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+			return
+		}
+	}
+
+	fee, err := h.service.Quote(kind, amount, payerID, role)
+	if err != nil {
+		log.Printf("Error quoting %s fee: %v", kind, err)
+		h.writeFeeError(c, err, "Failed to quote fee")
+		return
+	}
+	if fee == nil {
+		c.JSON(http.StatusOK, gin.H{"kind": kind, "base_amount": amount, "amount": 0, "message": "no fee rule applies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fee)
+}
+
+// CreateWaiver handles POST /fee-waivers (Admin only)
+func (h *FeeHandler) CreateWaiver(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreateFeeWaiverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	waiver := &models.FeeWaiver{
+		Name:        input.Name,
+		Kind:        input.Kind,
+		Role:        input.Role,
+		UserID:      input.UserID,
+		Percentage:  input.Percentage,
+		EndsAt:      input.EndsAt,
+		CreatedByID: adminID,
+	}
+	if input.StartsAt != nil {
+		waiver.StartsAt = *input.StartsAt
+	}
+
+	created, err := h.service.CreateWaiver(waiver)
+	if err != nil {
+		log.Printf("Error creating fee waiver: %v", err)
+		h.writeFeeError(c, err, "Failed to create fee waiver")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetWaivers handles GET /fee-waivers (Admin only)
+func (h *FeeHandler) GetWaivers(c *gin.Context) {
+	kind, ok := feeKindQuery(c)
+	if !ok {
+		return
+	}
+
+	waivers, err := h.service.GetWaivers(kind, c.Query("include_inactive") == "true")
+	if err != nil {
+		log.Printf("Error getting fee waivers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fee waivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, waivers)
+}
+
+// UpdateWaiver handles PUT /fee-waivers/{id} (Admin only)
+func (h *FeeHandler) UpdateWaiver(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fee waiver ID format"})
+		return
+	}
+
+	var input UpdateFeeWaiverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	waiver, err := h.service.UpdateWaiver(idStr, input.EndsAt, input.Active)
+	if err != nil {
+		log.Printf("Error updating fee waiver %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("fee waiver with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeFeeError(c, err, "Failed to update fee waiver")
+		return
+	}
+
+	c.JSON(http.StatusOK, waiver)
+}
+
+func (h *FeeHandler) bindRule(c *gin.Context) (*models.FeeRule, bool) {
+	var input FeeRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return nil, false
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return nil, false
+	}
+
+	rule := &models.FeeRule{
+		Name:       input.Name,
+		Kind:       input.Kind,
+		Role:       input.Role,
+		Method:     input.Method,
+		Percentage: input.Percentage,
+		FlatAmount: input.FlatAmount,
+		Tiers:      input.Tiers,
+		MinAmount:  input.MinAmount,
+		MaxAmount:  input.MaxAmount,
+		Active:     input.Active == nil || *input.Active,
+	}
+	return rule, true
+}
+
+func feeKindQuery(c *gin.Context) (models.FeeKind, bool) {
+	kind := models.FeeKind(c.Query("kind"))
+	switch kind {
+	case "", models.FeeKindSaleCommission, models.FeeKindInspectionFee:
+		return kind, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kind parameter, expected sale_commission or inspection_fee"})
+	return "", false
+}
+
+func (h *FeeHandler) writeFeeError(c *gin.Context, err error, fallback string) {
+	if strings.HasPrefix(err.Error(), "invalid fee") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type InspectionRequestHandler struct {
+	service   *services.PrePurchaseInspectionService
+	validator *validator.Validate
+}
+
+func NewInspectionRequestHandler(service *services.PrePurchaseInspectionService) *InspectionRequestHandler {
+	return &InspectionRequestHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+type CreateInspectionRequestInput struct {
+	Note string `json:"note,omitempty" validate:"max=2000"`
+}
+
+type InspectionRequestResponseInput struct {
+	Consent *bool  `json:"consent" validate:"required"`
+	Reason  string `json:"reason,omitempty" validate:"max=500"`
+}
+
+type ScheduleInspectionRequestInput struct {
+	SlotID        uuid.UUID `json:"slot_id" validate:"required"`
+	PaymentMethod string    `json:"payment_method,omitempty" validate:"omitempty,oneof=card bank_transfer ussd"`
+}
+
+type CancelInspectionRequestInput struct {
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// CreateRequest handles POST /listings/{id}/inspection-requests (Buyer only)
+func (h *InspectionRequestHandler) CreateRequest(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreateInspectionRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	request, err := h.service.RequestInspection(idStr, buyerID, strings.TrimSpace(input.Note))
+	if err != nil {
+		log.Printf("Error requesting inspection of listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeRequestError(c, "", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetMyRequests handles GET /inspection-requests/my (Buyer only)
+func (h *InspectionRequestHandler) GetMyRequests(c *gin.Context) {
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	requests, err := h.service.GetBuyerRequests(buyerID)
+	if err != nil {
+		log.Printf("Error getting inspection requests of buyer %s: %v", buyerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspection requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetListingRequests handles GET /listings/{id}/inspection-requests (Seller of the listing or Admin)
+func (h *InspectionRequestHandler) GetListingRequests(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	requests, err := h.service.GetListingRequests(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting inspection requests of listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeRequestError(c, "", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetRequest handles GET /inspection-requests/{id} (Buyer or Seller party, or Admin)
+func (h *InspectionRequestHandler) GetRequest(c *gin.Context) {
+	idStr, userID, ok := h.requestParams(c)
+	if !ok {
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	request, err := h.service.GetRequest(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting inspection request %s: %v", idStr, err)
+		h.writeRequestError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// RespondToRequest handles POST /inspection-requests/{id}/response (Seller only)
+func (h *InspectionRequestHandler) RespondToRequest(c *gin.Context) {
+	idStr, sellerID, ok := h.requestParams(c)
+	if !ok {
+		return
+	}
+
+	var input InspectionRequestResponseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	request, err := h.service.RespondToRequest(idStr, sellerID, *input.Consent, strings.TrimSpace(input.Reason))
+	if err != nil {
+		log.Printf("Error responding to inspection request %s: %v", idStr, err)
+		h.writeRequestError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// ScheduleRequest handles POST /inspection-requests/{id}/schedule (Buyer only)
+func (h *InspectionRequestHandler) ScheduleRequest(c *gin.Context) {
+	idStr, buyerID, ok := h.requestParams(c)
+	if !ok {
+		return
+	}
+
+	var input ScheduleInspectionRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	request, err := h.service.ScheduleInspection(idStr, buyerID, input.SlotID, input.PaymentMethod)
+	if err != nil {
+		log.Printf("Error scheduling inspection request %s: %v", idStr, err)
+		h.writeRequestError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// CancelRequest handles POST /inspection-requests/{id}/cancel (Buyer or Seller party)
+func (h *InspectionRequestHandler) CancelRequest(c *gin.Context) {
+	idStr, userID, ok := h.requestParams(c)
+	if !ok {
+		return
+	}
+
+	var input CancelInspectionRequestInput
+	// the body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+		if err := h.validator.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+			return
+		}
+	}
+
+	if err := h.service.CancelRequest(idStr, userID, strings.TrimSpace(input.Reason)); err != nil {
+		log.Printf("Error cancelling inspection request %s: %v", idStr, err)
+		h.writeRequestError(c, idStr, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetReport handles GET /inspection-requests/{id}/report (requesting Buyer or Admin)
+func (h *InspectionRequestHandler) GetReport(c *gin.Context) {
+	idStr, userID, ok := h.requestParams(c)
+	if !ok {
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	report, err := h.service.GetReport(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting report of inspection request %s: %v", idStr, err)
+		h.writeRequestError(c, idStr, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="pre-purchase-inspection-%s.pdf"`, idStr))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", report)
+}
+
+// requestParams reads the inspection request ID and the authenticated user, writing the error response when either is missing
+func (h *InspectionRequestHandler) requestParams(c *gin.Context) (string, uuid.UUID, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inspection request ID format"})
+		return "", uuid.Nil, false
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", uuid.Nil, false
+	}
+	return idStr, userID, true
+}
+
+func (h *InspectionRequestHandler) writeRequestError(c *gin.Context, requestID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("inspection request with id %s not found", requestID),
+		strings.HasPrefix(message, "inspection slot with id"):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "you cannot request an inspection of your own listing":
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case message == "only active listings can be inspected before purchase",
+		message == "you already have an open inspection request for this listing",
+		message == "inspection request is not awaiting your consent",
+		message == "only requests the seller has consented to can be scheduled",
+		message == "inspection request can no longer be cancelled",
+		message == "inspection report is not available yet",
+		message == "inspection slot is no longer available",
+		message == "inspection slot has already started",
+		strings.HasPrefix(message, "inspection can no longer be changed within"):
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	case strings.HasPrefix(message, "payment provider"):
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process inspection request"})
+	}
+}
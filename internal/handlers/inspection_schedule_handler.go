@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+// defaultSlotSearchWindow bounds GET /inspection-slots when no end date is given
+const defaultSlotSearchWindow = 14 * 24 * time.Hour
+
+type InspectionScheduleHandler struct {
+	service   *services.InspectionScheduleService
+	validator *validator.Validate
+}
+
+func NewInspectionScheduleHandler(service *services.InspectionScheduleService) *InspectionScheduleHandler {
+	return &InspectionScheduleHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+type CreateSlotInput struct {
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required"`
+}
+
+type BookingInput struct {
+	SlotID uuid.UUID `json:"slot_id" validate:"required"`
+}
+
+// CreateSlot handles POST /inspector/slots (Inspector or Admin)
+func (h *InspectionScheduleHandler) CreateSlot(c *gin.Context) {
+	inspectorID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreateSlotInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	slot, err := h.service.CreateSlot(inspectorID, input.StartsAt, input.EndsAt)
+	if err != nil {
+		log.Printf("Error creating inspection slot: %v", err)
+
+		switch err.Error() {
+		case "slot must end after it starts", "slot must start in the future":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "slot overlaps an existing slot for this inspector":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inspection slot"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+// GetMySlots handles GET /inspector/slots (Inspector or Admin)
+func (h *InspectionScheduleHandler) GetMySlots(c *gin.Context) {
+	inspectorID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	slots, err := h.service.GetInspectorSlots(inspectorID)
+	if err != nil {
+		log.Printf("Error getting slots for inspector %s: %v", inspectorID.String(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspection slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// CancelSlot handles DELETE /inspector/slots/{id} (Inspector or Admin)
+func (h *InspectionScheduleHandler) CancelSlot(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot ID format"})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	if err := h.service.CancelSlot(idStr, userID, userRole); err != nil {
+		log.Printf("Error cancelling inspection slot %s: %v", idStr, err)
+
+		switch err.Error() {
+		case "unauthorized: you can only cancel your own slots":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case fmt.Sprintf("inspection slot with id %s not found", idStr):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "inspection slot is already cancelled":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel inspection slot"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAvailableSlots handles GET /inspection-slots?from=&to= (Seller)
+func (h *InspectionScheduleHandler) GetAvailableSlots(c *gin.Context) {
+	from := time.Now()
+	if fromParam := c.Query("from"); fromParam != "" {
+		parsed, err := time.Parse(time.RFC3339, fromParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	to := from.Add(defaultSlotSearchWindow)
+	if toParam := c.Query("to"); toParam != "" {
+		parsed, err := time.Parse(time.RFC3339, toParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	slots, err := h.service.GetAvailableSlots(from, to)
+	if err != nil {
+		log.Printf("Error getting available inspection slots: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspection slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// BookInspection handles POST /listings/{id}/inspection-booking (Seller only)
+func (h *InspectionScheduleHandler) BookInspection(c *gin.Context) {
+	h.changeBooking(c, http.StatusCreated, h.service.BookInspection)
+}
+
+// RescheduleInspection handles PUT /listings/{id}/inspection-booking (Seller only)
+func (h *InspectionScheduleHandler) RescheduleInspection(c *gin.Context) {
+	h.changeBooking(c, http.StatusOK, h.service.RescheduleInspection)
+}
+
+// changeBooking binds the slot choice shared by booking and rescheduling and maps their errors
+func (h *InspectionScheduleHandler) changeBooking(c *gin.Context, successStatus int, change func(listingID string, slotID uuid.UUID, sellerID uuid.UUID) (*models.InspectionBooking, error)) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input BookingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	booking, err := change(idStr, input.SlotID, sellerID)
+	if err != nil {
+		log.Printf("Error booking inspection for listing %s: %v", idStr, err)
+		h.writeBookingError(c, idStr, err)
+		return
+	}
+
+	c.JSON(successStatus, booking)
+}
+
+// CancelBooking handles DELETE /listings/{id}/inspection-booking (Seller only)
+func (h *InspectionScheduleHandler) CancelBooking(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.CancelBooking(idStr, sellerID); err != nil {
+		log.Printf("Error cancelling inspection booking for listing %s: %v", idStr, err)
+		h.writeBookingError(c, idStr, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetBooking handles GET /listings/{id}/inspection-booking (Seller or Admin)
+func (h *InspectionScheduleHandler) GetBooking(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	booking, err := h.service.GetBooking(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting inspection booking for listing %s: %v", idStr, err)
+		h.writeBookingError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+func (h *InspectionScheduleHandler) writeBookingError(c *gin.Context, listingID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("listing with id %s not found", listingID),
+		message == "no scheduled inspection for this listing",
+		strings.HasPrefix(message, "inspection slot with id"):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "only listings pending review can be booked for inspection",
+		message == "listing already has a scheduled inspection",
+		message == "inspection slot is no longer available",
+		message == "inspection slot has already started",
+		message == "inspection is already booked in this slot",
+		message == "inspection has already been rescheduled the maximum number of times",
+		strings.HasPrefix(message, "inspection can no longer be changed within"):
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process inspection booking"})
+	}
+}
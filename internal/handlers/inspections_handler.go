@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,10 +26,9 @@ type CreateInspectionInput struct {
 	ListingID       uuid.UUID           `json:"listing_id" validate:"required,uuid"`
 	InspectorID     uuid.UUID           `json:"inspector_id" validate:"required,uuid"`
 	InspectionDate  *time.Time          `json:"inspection_date,omitempty"` // Optional: Date of inspection
-	ConditionRating *int                `json:"condition_rating,omitempty" validate:"omitempty,min=1,max=10"`
 	Findings        map[string]any      `json:"findings,omitempty"` 
+	Checklist       []models.ChecklistFinding `json:"checklist,omitempty" validate:"omitempty,dive"` // Optional: condition rating is computed from it
 	ReportURL       string              `json:"report_url,omitempty" validate:"omitempty,url"` 
-	Status          models.InspectionStatus `json:"status" validate:"required,oneof=pending approved rejected"` 
 }
 
 
@@ -112,7 +112,9 @@ func (h *InspectionHandler) UpdateInspection(c *gin.Context) {
 	}
 
 	type UpdateInspectionInput struct {
-		Status models.InspectionStatus `json:"status" validate:"required,oneof=pending approved rejected"`
+		Status      models.InspectionStatus `json:"status" validate:"required,oneof=pending approved rejected"`
+		ReasonCodes []string                `json:"reason_codes,omitempty" validate:"max=20"`
+		Reason      string                  `json:"reason,omitempty" validate:"max=1000"`
 	}
 
 	// Parse JSON request body into input
@@ -129,14 +131,28 @@ func (h *InspectionHandler) UpdateInspection(c *gin.Context) {
 	}
 
 	
-	if err := h.service.UpdateInspectionStatus(idStr, input.Status, adminID); err != nil {
+	if err := h.service.UpdateInspectionStatus(idStr, input.Status, adminID, input.ReasonCodes, strings.TrimSpace(input.Reason)); err != nil {
 		log.Printf("Error updating inspection %s: %v", idStr, err)
+
+		if err.Error() == "at least one rejection reason is required" ||
+		   strings.HasPrefix(err.Error(), "unknown rejection reason code") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		
 		if err.Error() == fmt.Errorf("inspection with id %s not found", idStr).Error() ||
 		   err.Error() == fmt.Errorf("failed to get associated listing for inspection %s: %w", idStr, gorm.ErrRecordNotFound).Error() {
 			c.JSON(http.StatusNotFound, gin.H{"error": "inspection or associated listing not found"})
 			return
 		}
+		if err.Error() == fmt.Sprintf("checklist findings must be submitted before approving inspection %s", idStr) ||
+		   err.Error() == fmt.Sprintf("inspection %s has been superseded by a newer inspection of the listing", idStr) ||
+		   err.Error() == "listing is claimed by another admin" ||
+		   err.Error() == "only listings pending review can be decided" ||
+		   err.Error() == "pre-purchase inspections are completed by submitting findings, not approved or rejected" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Generic error for other failures (e.g., database transaction issues)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inspection"})
@@ -181,19 +197,15 @@ func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 		ListingID:       input.ListingID,
 		InspectorID:     input.InspectorID,
 		InspectionDate:  time.Time{},
-		ConditionRating: 0, 
 		Findings:        input.Findings,
+		Checklist:       input.Checklist,
 		ReportURL:       input.ReportURL,
-		Status:          input.Status, 
 	}
 
 	// Handle pointer fields from input
 	if input.InspectionDate != nil {
 		inspectionToCreate.InspectionDate = *input.InspectionDate
 	}
-	if input.ConditionRating != nil {
-		inspectionToCreate.ConditionRating = *input.ConditionRating
-	}
 
 
 	createdInspection, err := h.service.CreateInspection(inspectionToCreate, adminID)
@@ -205,6 +217,15 @@ func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Associated listing not found or invalid listing_id"})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "invalid checklist findings") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "no checklist template is currently in force" ||
+		   err.Error() == "failed to create inspection and potentially update associated listing: listing already has a pending inspection" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create inspection"})
 		return
@@ -212,4 +233,168 @@ func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 
 	
 	c.JSON(http.StatusCreated, createdInspection)
-}
\ No newline at end of file
+}
+
+
+// SubmitChecklist handles PUT /inspections/{id}/checklist (inspector or admin)
+func (h *InspectionHandler) SubmitChecklist(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inspection ID format"})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	var input struct {
+		Checklist []models.ChecklistFinding `json:"checklist" validate:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	inspection, err := h.service.SubmitChecklist(idStr, input.Checklist, userID, userRole)
+	if err != nil {
+		log.Printf("Error submitting checklist for inspection %s: %v", idStr, err)
+
+		if err.Error() == "unauthorized: inspection is assigned to another inspector" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		if strings.HasPrefix(err.Error(), "invalid checklist findings") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "no checklist template is currently in force" ||
+			err.Error() == "checklist can only be submitted for a pending inspection" ||
+			err.Error() == "the inspection fee has not been paid yet" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasSuffix(err.Error(), fmt.Sprintf("inspection with id %s not found", idStr)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "inspection not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit checklist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, inspection)
+}
+
+
+// GetListingInspections handles GET /listings/{id}/inspections (admin or owning seller)
+func (h *InspectionHandler) GetListingInspections(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	history, err := h.service.GetListingInspections(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting inspections of listing %s: %v", idStr, err)
+
+		if err.Error() == "unauthorized: you can only view inspections of your own listings" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get listing inspections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+
+// RequestReport handles POST /inspections/{id}/report (admin only); the report is generated in the background
+func (h *InspectionHandler) RequestReport(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inspection ID format"})
+		return
+	}
+
+	inspection, err := h.service.RequestReport(idStr)
+	if err != nil {
+		log.Printf("Error requesting report for inspection %s: %v", idStr, err)
+
+		if err.Error() == "report can only be generated for a finalised inspection" ||
+			err.Error() == "pre-purchase inspection reports are only delivered to the requesting buyer" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasSuffix(err.Error(), fmt.Sprintf("inspection with id %s not found", idStr)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "inspection not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request inspection report"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, inspection)
+}
+
+
+// GetListingReport handles GET /listings/{id}/inspection-report (public, active or sold listings)
+func (h *InspectionHandler) GetListingReport(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	report, err := h.service.GetListingReport(idStr)
+	if err != nil {
+		log.Printf("Error getting inspection report of listing %s: %v", idStr, err)
+
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) ||
+			err.Error() == "no inspection report is available for this listing" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspection report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type InvoiceHandler struct {
+	service *services.InvoiceService
+}
+
+func NewInvoiceHandler(service *services.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{service: service}
+}
+
+// GetReceipt handles GET /transactions/{id}/receipt (transaction Buyer, Seller or Admin)
+func (h *InvoiceHandler) GetReceipt(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID format"})
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format parameter, expected pdf or html"})
+		return
+	}
+
+	invoice, err := h.service.GetReceipt(idStr, userID, userRole)
+	if err != nil {
+		switch {
+		case err.Error() == "no receipt has been issued for this transaction":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "unauthorized:"):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error getting receipt of transaction %s: %v", idStr, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get receipt"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	if format == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(h.service.RenderHTML(invoice)))
+		return
+	}
+
+	document, err := h.service.RenderPDF(invoice)
+	if err != nil {
+		log.Printf("Error rendering receipt %s: %v", invoice.Number, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render receipt"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", document)
+}
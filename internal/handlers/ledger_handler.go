@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type LedgerHandler struct {
+	service *services.LedgerService
+}
+
+func NewLedgerHandler(service *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{service: service}
+}
+
+// GetTrialBalance handles GET /ledger/accounts (Admin only)
+func (h *LedgerHandler) GetTrialBalance(c *gin.Context) {
+	code := models.LedgerAccountCode(c.Query("code"))
+	switch code {
+	case "", models.LedgerAccountBuyerFunds, models.LedgerAccountEscrow, models.LedgerAccountPlatformRevenue, models.LedgerAccountSellerPayable, models.LedgerAccountPaidOut:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code parameter, expected buyer_funds, escrow, platform_revenue, seller_payable or paid_out"})
+		return
+	}
+
+	var ownerID *uuid.UUID
+	if ownerStr := c.Query("owner_id"); ownerStr != "" {
+		parsed, err := uuid.Parse(ownerStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner ID format"})
+			return
+		}
+		ownerID = &parsed
+	}
+
+	report, err := h.service.GetTrialBalance(code, ownerID)
+	if err != nil {
+		log.Printf("Error getting ledger balances: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ledger balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetEntries handles GET /ledger/entries (Admin only)
+func (h *LedgerHandler) GetEntries(c *gin.Context) {
+	var transactionID *uuid.UUID
+	if transactionStr := c.Query("transaction_id"); transactionStr != "" {
+		parsed, err := uuid.Parse(transactionStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID format"})
+			return
+		}
+		transactionID = &parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter, expected 1 to 500"})
+		return
+	}
+
+	entries, err := h.service.GetEntries(transactionID, limit)
+	if err != nil {
+		log.Printf("Error getting journal entries: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get journal entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -82,6 +83,10 @@ func (h *ListingHandler) CreateListing(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == fmt.Sprintf("vehicle with VIN %s is already listed by another seller", vehicle.VIN) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create listing"})
 		return
 	}
@@ -258,4 +263,205 @@ func (h *ListingHandler) DeleteListing(c *gin.Context) {
     }
 
     c.Status(http.StatusNoContent) 
-}
\ No newline at end of file
+}
+
+
+// UpdateListingVehicleInput carries the vehicle specs a seller wants to correct; empty fields are left unchanged
+type UpdateListingVehicleInput struct {
+	Make         string `json:"make"`
+	Model        string `json:"model"`
+	Year         int    `json:"year" validate:"omitempty,min=1886"`
+	Mileage      uint   `json:"mileage"`
+	EngineSize   string `json:"engine_size" validate:"omitempty,max=20"`
+	FuelType     string `json:"fuel_type" validate:"omitempty,max=50"`
+	Transmission string `json:"transmission" validate:"omitempty,max=50"`
+	BodyType     string `json:"body_type" validate:"omitempty,max=50"`
+	Color        string `json:"color" validate:"omitempty,max=50"`
+	Condition    string `json:"condition" validate:"omitempty,max=20"`
+}
+
+// UpdateListingVehicle handles PATCH /listings/{id}/vehicle (Seller only)
+func (h *ListingHandler) UpdateListingVehicle(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	authUserID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input UpdateListingVehicleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	specs := models.VehicleSpecs{
+		Make:         input.Make,
+		Model:        input.Model,
+		Year:         input.Year,
+		EngineSize:   input.EngineSize,
+		FuelType:     input.FuelType,
+		Transmission: input.Transmission,
+		BodyType:     input.BodyType,
+		Color:        input.Color,
+		Condition:    input.Condition,
+	}
+
+	listing, err := h.service.UpdateListingVehicle(idStr, specs, input.Mileage, authUserID)
+	if err != nil {
+		log.Printf("Error updating vehicle of listing %s: %v", idStr, err)
+
+		if err.Error() == "unauthorized: you can only update your own listings" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == fmt.Sprintf("vehicle specs cannot be changed on a %s listing", models.ListingStatusActive) ||
+			err.Error() == fmt.Sprintf("vehicle specs cannot be changed on a %s listing", models.ListingStatusReserved) ||
+			err.Error() == fmt.Sprintf("vehicle specs cannot be changed on a %s listing", models.ListingStatusSold) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// ResubmitListingInput carries the seller's explanation and any fixes to a rejected listing
+type ResubmitListingInput struct {
+	Note        string  `json:"note" validate:"required,max=2000"`
+	Title       string  `json:"title" validate:"omitempty,max=255"`
+	Description *string `json:"description"`
+	Price       float64 `json:"price" validate:"omitempty,gt=0"`
+	Location    string  `json:"location" validate:"omitempty,max=255"`
+	UpdateListingVehicleInput
+}
+
+// ResubmitListing handles POST /listings/{id}/resubmit (Seller only)
+func (h *ListingHandler) ResubmitListing(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	authUserID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input ResubmitListingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	input.Note = strings.TrimSpace(input.Note)
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	resubmission := services.ListingResubmissionInput{
+		Note:        input.Note,
+		Title:       input.Title,
+		Description: input.Description,
+		Price:       input.Price,
+		Location:    input.Location,
+		Mileage:     input.Mileage,
+		VehicleSpecs: models.VehicleSpecs{
+			Make:         input.Make,
+			Model:        input.Model,
+			Year:         input.Year,
+			EngineSize:   input.EngineSize,
+			FuelType:     input.FuelType,
+			Transmission: input.Transmission,
+			BodyType:     input.BodyType,
+			Color:        input.Color,
+			Condition:    input.Condition,
+		},
+	}
+
+	listing, err := h.service.ResubmitListing(idStr, resubmission, authUserID)
+	if err != nil {
+		log.Printf("Error resubmitting listing %s: %v", idStr, err)
+
+		if err.Error() == "unauthorized: you can only resubmit your own listings" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "only rejected listings can be resubmitted" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resubmit listing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// GetListingResubmissions handles GET /listings/{id}/resubmissions (Seller of the listing or Admin)
+func (h *ListingHandler) GetListingResubmissions(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	authUserID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	resubmissions, err := h.service.GetListingResubmissions(idStr, authUserID, userRole)
+	if err != nil {
+		log.Printf("Error getting resubmissions of listing %s: %v", idStr, err)
+
+		if err.Error() == "unauthorized: you can only view your own listings" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get listing resubmissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resubmissions)
+}
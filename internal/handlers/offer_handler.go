@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type OfferHandler struct {
+	service   *services.OfferService
+	validator *validator.Validate
+}
+
+func NewOfferHandler(service *services.OfferService) *OfferHandler {
+	return &OfferHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// OfferInput is an offer or counter-offer with an optional message to the other party
+type OfferInput struct {
+	Amount  float64 `json:"amount" validate:"required,gt=0"`
+	Message string  `json:"message,omitempty" validate:"max=1000"`
+}
+
+// AcceptOfferInput optionally picks how the buyer pays for the sale the accepted offer opens
+type AcceptOfferInput struct {
+	PaymentMethod string `json:"payment_method,omitempty" validate:"omitempty,oneof=card bank_transfer ussd"`
+}
+
+type DeclineOfferInput struct {
+	Message string `json:"message,omitempty" validate:"max=1000"`
+}
+
+// MakeOffer handles POST /listings/{id}/offers (Buyer only)
+func (h *OfferHandler) MakeOffer(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input OfferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Message = strings.TrimSpace(input.Message)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	offer, err := h.service.MakeOffer(idStr, buyerID, input.Amount, input.Message)
+	if err != nil {
+		log.Printf("Error making offer on listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeOfferError(c, "", err)
+		return
+	}
+
+	offer.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, offer)
+}
+
+// GetListingOffers handles GET /listings/{id}/offers (Seller of the listing or Admin)
+func (h *OfferHandler) GetListingOffers(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status, ok := offerStatusParam(c)
+	if !ok {
+		return
+	}
+
+	offers, err := h.service.GetListingOffers(idStr, userID, userRole, status)
+	if err != nil {
+		log.Printf("Error getting offers on listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeOfferError(c, "", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+// GetInbox handles GET /offers/inbox (Seller only); active offers unless ?status= is given
+func (h *OfferHandler) GetInbox(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status, ok := offerStatusParam(c)
+	if !ok {
+		return
+	}
+
+	offers, err := h.service.GetInbox(sellerID, status)
+	if err != nil {
+		log.Printf("Error getting offer inbox of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get offers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+// GetMyOffers handles GET /offers/my (Buyer only)
+func (h *OfferHandler) GetMyOffers(c *gin.Context) {
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	offers, err := h.service.GetMyOffers(buyerID)
+	if err != nil {
+		log.Printf("Error getting offers of buyer %s: %v", buyerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get offers"})
+		return
+	}
+
+	for _, offer := range offers {
+		offer.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, offers)
+}
+
+// GetOffer handles GET /offers/{id} (Buyer or Seller of the offer, or Admin)
+func (h *OfferHandler) GetOffer(c *gin.Context) {
+	idStr, ok := offerIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	offer, err := h.service.GetOffer(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting offer %s: %v", idStr, err)
+		h.writeOfferError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		offer.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, offer)
+}
+
+// Counter handles POST /offers/{id}/counter (Buyer or Seller whose turn it is)
+func (h *OfferHandler) Counter(c *gin.Context) {
+	idStr, ok := offerIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input OfferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Message = strings.TrimSpace(input.Message)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	offer, err := h.service.Counter(idStr, userID, input.Amount, input.Message)
+	if err != nil {
+		log.Printf("Error countering offer %s: %v", idStr, err)
+		h.writeOfferError(c, idStr, err)
+		return
+	}
+
+	offer.HideSellerContactDetails()
+	c.JSON(http.StatusOK, offer)
+}
+
+// Accept handles POST /offers/{id}/accept (Buyer or Seller whose turn it is); responds with the sale it opened
+func (h *OfferHandler) Accept(c *gin.Context) {
+	idStr, ok := offerIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input AcceptOfferInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.Accept(idStr, userID, strings.TrimSpace(input.PaymentMethod))
+	if err != nil {
+		log.Printf("Error accepting offer %s: %v", idStr, err)
+		h.writeOfferError(c, idStr, err)
+		return
+	}
+
+	transaction.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// Decline handles POST /offers/{id}/decline (Buyer or Seller whose turn it is)
+func (h *OfferHandler) Decline(c *gin.Context) {
+	idStr, ok := offerIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input DeclineOfferInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+	input.Message = strings.TrimSpace(input.Message)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	offer, err := h.service.Decline(idStr, userID, input.Message)
+	if err != nil {
+		log.Printf("Error declining offer %s: %v", idStr, err)
+		h.writeOfferError(c, idStr, err)
+		return
+	}
+
+	offer.HideSellerContactDetails()
+	c.JSON(http.StatusOK, offer)
+}
+
+// Withdraw handles POST /offers/{id}/withdraw (Buyer of the offer)
+func (h *OfferHandler) Withdraw(c *gin.Context) {
+	idStr, ok := offerIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	offer, err := h.service.Withdraw(idStr, userID)
+	if err != nil {
+		log.Printf("Error withdrawing offer %s: %v", idStr, err)
+		h.writeOfferError(c, idStr, err)
+		return
+	}
+
+	offer.HideSellerContactDetails()
+	c.JSON(http.StatusOK, offer)
+}
+
+func offerIDParam(c *gin.Context) (string, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID format"})
+		return "", false
+	}
+	return idStr, true
+}
+
+func offerStatusParam(c *gin.Context) (models.OfferStatus, bool) {
+	status := models.OfferStatus(c.Query("status"))
+	switch status {
+	case "", models.OfferStatusPending, models.OfferStatusCountered, models.OfferStatusAccepted,
+		models.OfferStatusDeclined, models.OfferStatusWithdrawn, models.OfferStatusExpired:
+		return status, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected pending, countered, accepted, declined, withdrawn or expired"})
+	return "", false
+}
+
+func (h *OfferHandler) writeOfferError(c *gin.Context, offerID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("offer with id %s not found", offerID):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "you cannot make an offer on your own listing",
+		message == "offer must be below the asking price, purchase the listing instead",
+		message == "counter-offer cannot be more than the asking price",
+		message == "counter-offer must change the amount, accept the offer instead":
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case message == "offers can only be made on active listings",
+		message == "you already have an active offer on this listing",
+		message == "offer is no longer open",
+		message == "offer has expired",
+		message == "offer is waiting on the other party",
+		message == "only active listings can be purchased",
+		message == "listing already has a purchase in progress",
+		message == "listing is reserved by another buyer",
+		message == "reservation deposit has not been paid yet",
+		message == "reservation deposit cannot cover the whole price":
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	case strings.HasPrefix(message, "payment provider"):
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process offer"})
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+)
+
+// maxWebhookBodyBytes bounds the size of a provider notification
+const maxWebhookBodyBytes = 1 << 20
+
+type PaymentWebhookHandler struct {
+	service *services.PaymentWebhookService
+}
+
+func NewPaymentWebhookHandler(service *services.PaymentWebhookService) *PaymentWebhookHandler {
+	return &PaymentWebhookHandler{service: service}
+}
+
+// Receive handles POST /webhooks/payments/{provider} (Public, signed by the provider)
+func (h *PaymentWebhookHandler) Receive(c *gin.Context) {
+	provider := c.Param("provider")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes)
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body"})
+		return
+	}
+
+	event, duplicate, err := h.service.Receive(provider, payload, c.Request.Header)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case err.Error() == fmt.Sprintf("unknown payment provider '%s'", provider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "invalid webhook payload"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			// the provider retries on errors, which is what we want when the event could not be stored
+			log.Printf("Error receiving %s payment webhook: %v", provider, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to receive webhook"})
+		}
+		return
+	}
+
+	// stored events are acknowledged even if applying them failed; failures are replayed from the inbox
+	c.JSON(http.StatusOK, gin.H{"id": event.ID, "status": event.Status, "duplicate": duplicate})
+}
+
+// GetEvents handles GET /payment-webhooks?status=&limit= (Admin only)
+func (h *PaymentWebhookHandler) GetEvents(c *gin.Context) {
+	status := models.PaymentWebhookStatus(c.Query("status"))
+	switch status {
+	case "", models.PaymentWebhookStatusReceived, models.PaymentWebhookStatusProcessed, models.PaymentWebhookStatusIgnored, models.PaymentWebhookStatusFailed, models.PaymentWebhookStatusRefunded:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected received, processed, ignored, failed or refunded"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter, expected 1 to 500"})
+		return
+	}
+
+	events, err := h.service.GetEvents(status, limit)
+	if err != nil {
+		log.Printf("Error getting payment webhook events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment webhook events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetEvent handles GET /payment-webhooks/{id} (Admin only)
+func (h *PaymentWebhookHandler) GetEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID format"})
+		return
+	}
+
+	event, err := h.service.GetEvent(idStr)
+	if err != nil {
+		if err.Error() == fmt.Sprintf("payment webhook event with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting payment webhook event %s: %v", idStr, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment webhook event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ReplayEvent handles POST /payment-webhooks/{id}/replay (Admin only)
+func (h *PaymentWebhookHandler) ReplayEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID format"})
+		return
+	}
+
+	event, err := h.service.Replay(idStr)
+	if err != nil {
+		switch err.Error() {
+		case fmt.Sprintf("payment webhook event with id %s not found", idStr):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "payment webhook event was already applied":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error replaying payment webhook event %s: %v", idStr, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay payment webhook event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ReplayFailed handles POST /payment-webhooks/replay (Admin only)
+func (h *PaymentWebhookHandler) ReplayFailed(c *gin.Context) {
+	events, err := h.service.ReplayFailed()
+	if err != nil {
+		log.Printf("Error replaying failed payment webhook events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay payment webhook events", "replayed": events})
+		return
+	}
+
+	stillFailed := 0
+	for _, event := range events {
+		if event.Status == models.PaymentWebhookStatusFailed {
+			stillFailed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replayed": len(events), "still_failed": stillFailed, "events": events})
+}
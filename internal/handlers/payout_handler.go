@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type PayoutHandler struct {
+	service   *services.PayoutService
+	validator *validator.Validate
+}
+
+func NewPayoutHandler(service *services.PayoutService) *PayoutHandler {
+	return &PayoutHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// PayoutAccountInput is the bank account a seller wants to be paid out to
+type PayoutAccountInput struct {
+	BankName      string `json:"bank_name" validate:"required,max=255"`
+	BankCode      string `json:"bank_code" validate:"required,max=10"`
+	AccountName   string `json:"account_name" validate:"required,max=255"`
+	AccountNumber string `json:"account_number" validate:"required,max=20"`
+}
+
+// FailedPayoutInput is a payout the bank rejected and why
+type FailedPayoutInput struct {
+	PayoutID uuid.UUID `json:"payout_id" validate:"required"`
+	Reason   string    `json:"reason" validate:"required,max=1000"`
+}
+
+// SettlePayoutBatchInput reports the outcome of a batch at the bank; payouts not listed as failed were paid
+type SettlePayoutBatchInput struct {
+	BankReference string              `json:"bank_reference,omitempty" validate:"max=255"`
+	Failed        []FailedPayoutInput `json:"failed,omitempty" validate:"dive"`
+}
+
+// SaveAccount handles PUT /payouts/account (Seller only)
+func (h *PayoutHandler) SaveAccount(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input PayoutAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	account, err := h.service.SaveAccount(sellerID, input.BankName, input.BankCode, input.AccountName, input.AccountNumber)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error saving payout account of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save payout account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetAccount handles GET /payouts/account (Seller only)
+func (h *PayoutHandler) GetAccount(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	account, err := h.service.GetAccount(sellerID)
+	if err != nil {
+		if err.Error() == "no payout account registered" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting payout account of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payout account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetBalance handles GET /payouts/balance (Seller only)
+func (h *PayoutHandler) GetBalance(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	balance, err := h.service.GetBalance(sellerID)
+	if err != nil {
+		log.Printf("Error getting payout balance of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payout balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// GetMyPayouts handles GET /payouts/my (Seller only)
+func (h *PayoutHandler) GetMyPayouts(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payouts, err := h.service.GetSellerPayouts(sellerID)
+	if err != nil {
+		log.Printf("Error getting payouts of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payouts)
+}
+
+// CreateBatch handles POST /payout-batches (Admin only)
+func (h *PayoutHandler) CreateBatch(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	batch, err := h.service.CreateBatch(adminID)
+	if err != nil {
+		if err.Error() == "nothing to pay out" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error creating payout batch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payout batch"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// GetBatches handles GET /payout-batches (Admin only)
+func (h *PayoutHandler) GetBatches(c *gin.Context) {
+	status := models.PayoutBatchStatus(c.Query("status"))
+	switch status {
+	case "", models.PayoutBatchStatusPendingApproval, models.PayoutBatchStatusApproved, models.PayoutBatchStatusSettled, models.PayoutBatchStatusCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected pending_approval, approved, settled or cancelled"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter, expected 1 to 500"})
+		return
+	}
+
+	batches, err := h.service.GetBatches(status, limit)
+	if err != nil {
+		log.Printf("Error getting payout batches: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payout batches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// GetBatch handles GET /payout-batches/{id} (Admin only)
+func (h *PayoutHandler) GetBatch(c *gin.Context) {
+	idStr, ok := batchIDParam(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.GetBatch(idStr)
+	if err != nil {
+		h.writeBatchError(c, idStr, err, "Failed to get payout batch")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// ApproveBatch handles POST /payout-batches/{id}/approve (Admin only)
+func (h *PayoutHandler) ApproveBatch(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	idStr, ok := batchIDParam(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.Approve(idStr, adminID)
+	if err != nil {
+		h.writeBatchError(c, idStr, err, "Failed to approve payout batch")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// ExportBatch handles GET /payout-batches/{id}/export (Admin only)
+func (h *PayoutHandler) ExportBatch(c *gin.Context) {
+	idStr, ok := batchIDParam(c)
+	if !ok {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.service.ExportCSV(idStr, buf); err != nil {
+		h.writeBatchError(c, idStr, err, "Failed to export payout batch")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("payout-batch-%s.csv", idStr)))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// SettleBatch handles POST /payout-batches/{id}/settle (Admin only)
+func (h *PayoutHandler) SettleBatch(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	idStr, ok := batchIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input SettlePayoutBatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	failures := map[uuid.UUID]string{}
+	for _, failed := range input.Failed {
+		failures[failed.PayoutID] = failed.Reason
+	}
+
+	batch, err := h.service.Settle(idStr, adminID, input.BankReference, failures)
+	if err != nil {
+		h.writeBatchError(c, idStr, err, "Failed to settle payout batch")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// CancelBatch handles POST /payout-batches/{id}/cancel (Admin only)
+func (h *PayoutHandler) CancelBatch(c *gin.Context) {
+	idStr, ok := batchIDParam(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.Cancel(idStr)
+	if err != nil {
+		h.writeBatchError(c, idStr, err, "Failed to cancel payout batch")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func batchIDParam(c *gin.Context) (string, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payout batch ID format"})
+		return "", false
+	}
+	return idStr, true
+}
+
+func (h *PayoutHandler) writeBatchError(c *gin.Context, idStr string, err error, fallback string) {
+	switch {
+	case err.Error() == fmt.Sprintf("payout batch with id %s not found", idStr):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "invalid "):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "payout batch is "):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error handling payout batch %s: %v", idStr, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type RefundHandler struct {
+	service *services.RefundService
+}
+
+func NewRefundHandler(service *services.RefundService) *RefundHandler {
+	return &RefundHandler{service: service}
+}
+
+// GetRefunds handles GET /refunds?status=&limit= (Admin only)
+func (h *RefundHandler) GetRefunds(c *gin.Context) {
+	status := models.RefundStatus(c.Query("status"))
+	switch status {
+	case "", models.RefundStatusPending, models.RefundStatusIssued, models.RefundStatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected pending, issued or failed"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter, expected 1 to 500"})
+		return
+	}
+
+	refunds, err := h.service.GetRefunds(status, limit)
+	if err != nil {
+		log.Printf("Error getting refunds: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get refunds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, refunds)
+}
+
+// GetRefund handles GET /refunds/{id} (Admin only)
+func (h *RefundHandler) GetRefund(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund ID format"})
+		return
+	}
+
+	refund, err := h.service.GetRefund(idStr)
+	if err != nil {
+		if err.Error() == fmt.Sprintf("refund with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting refund %s: %v", idStr, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get refund"})
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// RetryRefund handles POST /refunds/{id}/retry (Admin only)
+func (h *RefundHandler) RetryRefund(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund ID format"})
+		return
+	}
+
+	refund, err := h.service.Retry(idStr)
+	if err != nil {
+		switch err.Error() {
+		case fmt.Sprintf("refund with id %s not found", idStr):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "only failed refunds can be retried":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error retrying refund %s: %v", idStr, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry refund"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
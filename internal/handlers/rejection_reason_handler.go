@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type RejectionReasonHandler struct {
+	service   *services.RejectionReasonService
+	validator *validator.Validate
+}
+
+func NewRejectionReasonHandler(service *services.RejectionReasonService) *RejectionReasonHandler {
+	return &RejectionReasonHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// CreateRejectionReasonInput describes a new rejection reason code
+type CreateRejectionReasonInput struct {
+	Code        string `json:"code" validate:"required,max=64"`
+	Label       string `json:"label" validate:"required,max=255"`
+	Description string `json:"description,omitempty" validate:"max=2000"`
+}
+
+// UpdateRejectionReasonInput changes a rejection reason; omitted fields are left as they are
+type UpdateRejectionReasonInput struct {
+	Label       string  `json:"label,omitempty" validate:"max=255"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=2000"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+// CreateReason handles POST /rejection-reasons (admin only)
+func (h *RejectionReasonHandler) CreateReason(c *gin.Context) {
+	var input CreateRejectionReasonInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	reason := &models.RejectionReason{
+		Code:        input.Code,
+		Label:       input.Label,
+		Description: input.Description,
+	}
+
+	createdReason, err := h.service.CreateReason(reason)
+	if err != nil {
+		log.Printf("Error creating rejection reason: %v", err)
+		if strings.HasSuffix(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rejection reason"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdReason)
+}
+
+// GetReasons handles GET /rejection-reasons (authenticated users; admins may add ?include_inactive=true)
+func (h *RejectionReasonHandler) GetReasons(c *gin.Context) {
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User role not found in context"})
+		return
+	}
+
+	includeInactive := userRole == types.RoleAdmin && c.Query("include_inactive") == "true"
+
+	reasons, err := h.service.GetReasons(includeInactive)
+	if err != nil {
+		log.Printf("Error getting rejection reasons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rejection reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reasons)
+}
+
+// UpdateReason handles PUT /rejection-reasons/{code} (admin only)
+func (h *RejectionReasonHandler) UpdateReason(c *gin.Context) {
+	code := c.Param("code")
+
+	var input UpdateRejectionReasonInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	reason, err := h.service.UpdateReason(code, input.Label, input.Description, input.Active)
+	if err != nil {
+		log.Printf("Error updating rejection reason %s: %v", code, err)
+		if err.Error() == fmt.Sprintf("rejection reason '%s' not found", code) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rejection reason"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reason)
+}
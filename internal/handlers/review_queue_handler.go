@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+)
+
+type ReviewQueueHandler struct {
+	service   *services.ReviewQueueService
+	validator *validator.Validate
+}
+
+func NewReviewQueueHandler(service *services.ReviewQueueService) *ReviewQueueHandler {
+	return &ReviewQueueHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+type ReviewDecisionInput struct {
+	ListingIDs  []uuid.UUID             `json:"listing_ids" validate:"required,min=1,max=100"`
+	Decision    models.InspectionStatus `json:"decision" validate:"required,oneof=approved rejected"`
+	ReasonCodes []string                `json:"reason_codes,omitempty" validate:"max=20"`
+	Reason      string                  `json:"reason,omitempty" validate:"max=1000"`
+}
+
+// GetQueue handles GET /review-queue?sort=oldest|newest&breached=true&claimed=mine|unclaimed|others (Admin only)
+func (h *ReviewQueueHandler) GetQueue(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter := services.ReviewQueueFilter{
+		BreachedOnly: c.Query("breached") == "true",
+		Claimed:      c.Query("claimed"),
+	}
+
+	switch c.DefaultQuery("sort", "oldest") {
+	case "oldest":
+	case "newest":
+		filter.NewestFirst = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort parameter, expected oldest or newest"})
+		return
+	}
+
+	switch filter.Claimed {
+	case "", "mine", "unclaimed", "others":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claimed parameter, expected mine, unclaimed or others"})
+		return
+	}
+
+	queue, err := h.service.GetQueue(filter, adminID)
+	if err != nil {
+		log.Printf("Error getting review queue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get review queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+// ClaimListing handles POST /review-queue/{id}/claim (Admin only)
+func (h *ReviewQueueHandler) ClaimListing(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	listing, err := h.service.ClaimListing(idStr, adminID)
+	if err != nil {
+		log.Printf("Error claiming listing %s for review: %v", idStr, err)
+		h.writeReviewError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// UnclaimListing handles DELETE /review-queue/{id}/claim (Admin only)
+func (h *ReviewQueueHandler) UnclaimListing(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.UnclaimListing(idStr, adminID); err != nil {
+		log.Printf("Error releasing review claim on listing %s: %v", idStr, err)
+		h.writeReviewError(c, idStr, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DecideListings handles POST /review-queue/decisions (Admin only)
+func (h *ReviewQueueHandler) DecideListings(c *gin.Context) {
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input ReviewDecisionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	results, err := h.service.DecideListings(input.ListingIDs, input.Decision, input.ReasonCodes, strings.TrimSpace(input.Reason), adminID)
+	if err != nil {
+		log.Printf("Error applying bulk review decision: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Succeeded {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// GetStatusHistory handles GET /listings/{id}/status-history (Admin only)
+func (h *ReviewQueueHandler) GetStatusHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	history, err := h.service.GetStatusHistory(idStr)
+	if err != nil {
+		log.Printf("Error getting status history of listing %s: %v", idStr, err)
+		h.writeReviewError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *ReviewQueueHandler) writeReviewError(c *gin.Context, listingID string, err error) {
+	message := err.Error()
+
+	switch message {
+	case fmt.Sprintf("listing with id %s not found", listingID):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case "only listings pending review can be claimed",
+		"listing is already claimed by another admin",
+		"listing is not claimed by you":
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process review queue request"})
+	}
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type TestDriveHandler struct {
+	service   *services.TestDriveService
+	validator *validator.Validate
+}
+
+func NewTestDriveHandler(service *services.TestDriveService) *TestDriveHandler {
+	return &TestDriveHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// TestDriveWindowInput is a stretch of time the buyer is available for a test drive
+type TestDriveWindowInput struct {
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required"`
+}
+
+type TestDriveRequestInput struct {
+	Windows []TestDriveWindowInput `json:"windows" validate:"required,min=1,max=3,dive"`
+	Note    string                 `json:"note,omitempty" validate:"max=1000"`
+}
+
+// AcceptTestDriveInput is the time and place a seller confirms a request for; a buyer confirming the seller's
+// proposal sends no body
+type AcceptTestDriveInput struct {
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	Location string     `json:"location,omitempty" validate:"max=255"`
+}
+
+// RescheduleTestDriveInput is a seller's new starts_at (and optional location) or a buyer's new windows
+type RescheduleTestDriveInput struct {
+	Windows  []TestDriveWindowInput `json:"windows,omitempty" validate:"omitempty,max=3,dive"`
+	StartsAt *time.Time             `json:"starts_at,omitempty"`
+	Location string                 `json:"location,omitempty" validate:"max=255"`
+	Message  string                 `json:"message,omitempty" validate:"max=1000"`
+}
+
+type DeclineTestDriveInput struct {
+	Message string `json:"message,omitempty" validate:"max=1000"`
+}
+
+type CancelTestDriveInput struct {
+	Reason string `json:"reason,omitempty" validate:"max=1000"`
+}
+
+// TestDriveOutcomeInput reports how a test drive went; no_show means the other party did not turn up
+type TestDriveOutcomeInput struct {
+	Outcome string `json:"outcome" validate:"required,oneof=completed no_show"`
+}
+
+// RequestTestDrive handles POST /listings/{id}/test-drives (Buyer only)
+func (h *TestDriveHandler) RequestTestDrive(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input TestDriveRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Note = strings.TrimSpace(input.Note)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.RequestTestDrive(idStr, buyerID, testDriveWindows(input.Windows), input.Note)
+	if err != nil {
+		log.Printf("Error requesting test drive of listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeTestDriveError(c, "", err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, drive)
+}
+
+// GetInbox handles GET /test-drives/inbox (Seller only); open test drives unless ?status= is given
+func (h *TestDriveHandler) GetInbox(c *gin.Context) {
+	sellerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status := models.TestDriveStatus(c.Query("status"))
+	switch status {
+	case "", models.TestDriveStatusRequested, models.TestDriveStatusRescheduled, models.TestDriveStatusConfirmed,
+		models.TestDriveStatusCompleted, models.TestDriveStatusNoShow, models.TestDriveStatusDeclined,
+		models.TestDriveStatusCancelled, models.TestDriveStatusExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected requested, rescheduled, confirmed, completed, no_show, declined, cancelled or expired"})
+		return
+	}
+
+	drives, err := h.service.GetInbox(sellerID, status)
+	if err != nil {
+		log.Printf("Error getting test drive inbox of seller %s: %v", sellerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get test drives"})
+		return
+	}
+
+	c.JSON(http.StatusOK, drives)
+}
+
+// GetMyTestDrives handles GET /test-drives/my (Buyer only)
+func (h *TestDriveHandler) GetMyTestDrives(c *gin.Context) {
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	drives, err := h.service.GetMyTestDrives(buyerID)
+	if err != nil {
+		log.Printf("Error getting test drives of buyer %s: %v", buyerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get test drives"})
+		return
+	}
+
+	for _, drive := range drives {
+		drive.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, drives)
+}
+
+// GetTestDrive handles GET /test-drives/{id} (Buyer or Seller of the test drive, or Admin)
+func (h *TestDriveHandler) GetTestDrive(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	drive, err := h.service.GetTestDrive(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		drive.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, drive)
+}
+
+// Accept handles POST /test-drives/{id}/accept (Seller confirming a request, or Buyer confirming the seller's time)
+func (h *TestDriveHandler) Accept(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input AcceptTestDriveInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+	input.Location = strings.TrimSpace(input.Location)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.Accept(idStr, userID, input.StartsAt, input.Location)
+	if err != nil {
+		log.Printf("Error accepting test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusOK, drive)
+}
+
+// Reschedule handles POST /test-drives/{id}/reschedule (Buyer or Seller)
+func (h *TestDriveHandler) Reschedule(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input RescheduleTestDriveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Location = strings.TrimSpace(input.Location)
+	input.Message = strings.TrimSpace(input.Message)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.Reschedule(idStr, userID, testDriveWindows(input.Windows), input.StartsAt, input.Location, input.Message)
+	if err != nil {
+		log.Printf("Error rescheduling test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusOK, drive)
+}
+
+// Decline handles POST /test-drives/{id}/decline (Buyer or Seller whose turn it is)
+func (h *TestDriveHandler) Decline(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input DeclineTestDriveInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+	input.Message = strings.TrimSpace(input.Message)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.Decline(idStr, userID, input.Message)
+	if err != nil {
+		log.Printf("Error declining test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusOK, drive)
+}
+
+// Cancel handles POST /test-drives/{id}/cancel (Buyer or Seller)
+func (h *TestDriveHandler) Cancel(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input CancelTestDriveInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.Cancel(idStr, userID, input.Reason)
+	if err != nil {
+		log.Printf("Error cancelling test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusOK, drive)
+}
+
+// ReportOutcome handles POST /test-drives/{id}/outcome (Buyer or Seller)
+func (h *TestDriveHandler) ReportOutcome(c *gin.Context) {
+	idStr, ok := testDriveIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input TestDriveOutcomeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	drive, err := h.service.ReportOutcome(idStr, userID, models.TestDriveStatus(input.Outcome))
+	if err != nil {
+		log.Printf("Error reporting outcome of test drive %s: %v", idStr, err)
+		h.writeTestDriveError(c, idStr, err)
+		return
+	}
+
+	drive.HideSellerContactDetails()
+	c.JSON(http.StatusOK, drive)
+}
+
+func testDriveIDParam(c *gin.Context) (string, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid test drive ID format"})
+		return "", false
+	}
+	return idStr, true
+}
+
+func testDriveWindows(inputs []TestDriveWindowInput) []models.TestDriveWindow {
+	windows := make([]models.TestDriveWindow, 0, len(inputs))
+	for _, input := range inputs {
+		windows = append(windows, models.TestDriveWindow{StartsAt: input.StartsAt, EndsAt: input.EndsAt})
+	}
+	return windows
+}
+
+func (h *TestDriveHandler) writeTestDriveError(c *gin.Context, testDriveID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("test drive with id %s not found", testDriveID):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "you cannot request a test drive of your own listing",
+		message == "proposed windows must be in the future",
+		strings.HasPrefix(message, "each proposed window must leave room for"),
+		message == "starts_at is required to confirm a test drive request",
+		message == "starts_at is required to propose a new time",
+		message == "windows are required to propose new times",
+		message == "test drive time must fall within one of the buyer's proposed windows",
+		message == "test drive time must be in the future",
+		message == "outcome must be completed or no_show":
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case message == "test drives can only be requested on active listings",
+		message == "you already have an open test drive for this listing",
+		message == "listing is no longer available for test drives",
+		message == "test drive is no longer open",
+		message == "test drive times have passed",
+		message == "test drive is waiting on the other party",
+		message == "test drive has already been rescheduled the maximum number of times",
+		message == "seller already has a test drive booked at that time",
+		message == "buyer already has a test drive booked at that time",
+		message == "only confirmed test drives can be reported on",
+		message == "test drive has not started yet":
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process test drive"})
+	}
+}
@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+type TransactionHandler struct {
+	service   *services.TransactionService
+	validator *validator.Validate
+}
+
+func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
+	return &TransactionHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+type PurchaseInput struct {
+	PaymentMethod string `json:"payment_method,omitempty" validate:"omitempty,oneof=card bank_transfer ussd"`
+}
+
+type UpdateTransactionStatusInput struct {
+	Status models.TransactionStatus `json:"status" validate:"required,oneof=failed cancelled"`
+}
+
+type DisputeInput struct {
+	Reason string `json:"reason" validate:"required,max=2000"`
+}
+
+type ResolveDisputeInput struct {
+	Outcome      services.DisputeOutcome `json:"outcome" validate:"required,oneof=release refund partial_refund"`
+	RefundAmount float64                 `json:"refund_amount,omitempty" validate:"omitempty,gt=0"`
+	Note         string                  `json:"note,omitempty" validate:"max=2000"`
+}
+
+type CancellationInput struct {
+	Reason string `json:"reason" validate:"required,max=2000"`
+}
+
+// RespondToCancellationInput accepts or declines a cancellation request; only an admin may agree to a partial refund
+type RespondToCancellationInput struct {
+	Accept       *bool   `json:"accept" validate:"required"`
+	RefundAmount float64 `json:"refund_amount,omitempty" validate:"omitempty,gt=0"`
+	Note         string  `json:"note,omitempty" validate:"max=2000"`
+}
+
+// CancelReservationInput optionally says why a reservation was called off
+type CancelReservationInput struct {
+	Reason string `json:"reason,omitempty" validate:"max=2000"`
+}
+
+// RefundInput is an admin refund; without an amount the buyer is refunded in full
+type RefundInput struct {
+	Amount float64 `json:"amount,omitempty" validate:"omitempty,gt=0"`
+	Reason string  `json:"reason" validate:"required,max=2000"`
+}
+
+// Purchase handles POST /listings/{id}/purchase (Buyer only)
+func (h *TransactionHandler) Purchase(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input PurchaseInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.Purchase(idStr, buyerID, strings.TrimSpace(input.PaymentMethod))
+	if err != nil {
+		log.Printf("Error purchasing listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeTransactionError(c, "", err)
+		return
+	}
+
+	transaction.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// Reserve handles POST /listings/{id}/reservation (Buyer only); responds with the deposit to pay
+func (h *TransactionHandler) Reserve(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+
+	buyerID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input PurchaseInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	deposit, err := h.service.Reserve(idStr, buyerID, strings.TrimSpace(input.PaymentMethod))
+	if err != nil {
+		log.Printf("Error reserving listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeTransactionError(c, "", err)
+		return
+	}
+
+	deposit.HideSellerContactDetails()
+	c.JSON(http.StatusCreated, deposit)
+}
+
+// CancelReservation handles DELETE /listings/{id}/reservation (reserving Buyer, Seller of the listing or Admin)
+func (h *TransactionHandler) CancelReservation(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID format"})
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// the body is optional
+	var input CancelReservationInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+			return
+		}
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	deposit, err := h.service.CancelReservation(idStr, userID, userRole, input.Reason)
+	if err != nil {
+		log.Printf("Error cancelling reservation of listing %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("listing with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeTransactionError(c, "", err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		deposit.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, deposit)
+}
+
+// GetMyTransactions handles GET /transactions/my (Buyer or Seller)
+func (h *TransactionHandler) GetMyTransactions(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	transactions, err := h.service.GetUserTransactions(userID)
+	if err != nil {
+		log.Printf("Error getting transactions of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transactions"})
+		return
+	}
+
+	for _, transaction := range transactions {
+		transaction.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, transactions)
+}
+
+// GetTransaction handles GET /transactions/{id} (Buyer or Seller of the transaction, or Admin)
+func (h *TransactionHandler) GetTransaction(c *gin.Context) {
+	idStr, userID, userRole, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	transaction, err := h.service.GetTransaction(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		transaction.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, transaction)
+}
+
+// UpdateStatus handles PUT /transactions/{id}/status (Buyer or Seller of the transaction, or Admin)
+func (h *TransactionHandler) UpdateStatus(c *gin.Context) {
+	idStr, userID, userRole, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateTransactionStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.UpdateStatus(idStr, input.Status, userID, userRole)
+	if err != nil {
+		log.Printf("Error updating transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		transaction.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, transaction)
+}
+
+// GetTransactionEvents handles GET /transactions/{id}/events (Buyer or Seller of the transaction, or Admin)
+func (h *TransactionHandler) GetTransactionEvents(c *gin.Context) {
+	idStr, userID, userRole, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.service.GetTransactionEvents(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting events of transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// CapturePayment handles POST /transactions/{id}/capture (Buyer of the transaction or Admin)
+func (h *TransactionHandler) CapturePayment(c *gin.Context) {
+	h.escrowAction(c, "capturing payment of", h.service.CapturePayment)
+}
+
+// MarkHandedOver handles POST /transactions/{id}/handover (Seller of the transaction or Admin)
+func (h *TransactionHandler) MarkHandedOver(c *gin.Context) {
+	h.escrowAction(c, "handing over", h.service.MarkHandedOver)
+}
+
+// ConfirmReceipt handles POST /transactions/{id}/confirm (Buyer of the transaction or Admin)
+func (h *TransactionHandler) ConfirmReceipt(c *gin.Context) {
+	h.escrowAction(c, "confirming receipt of", h.service.ConfirmReceipt)
+}
+
+// OpenDispute handles POST /transactions/{id}/dispute (Buyer of the transaction)
+func (h *TransactionHandler) OpenDispute(c *gin.Context) {
+	idStr, userID, _, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	var input DisputeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.OpenDispute(idStr, userID, input.Reason)
+	if err != nil {
+		log.Printf("Error opening dispute on transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	transaction.HideSellerContactDetails()
+	c.JSON(http.StatusOK, transaction)
+}
+
+// ResolveDispute handles POST /transactions/{id}/resolve (Admin only)
+func (h *TransactionHandler) ResolveDispute(c *gin.Context) {
+	idStr, adminID, _, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	var input ResolveDisputeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.ResolveDispute(idStr, adminID, input.Outcome, input.RefundAmount, strings.TrimSpace(input.Note))
+	if err != nil {
+		log.Printf("Error resolving dispute on transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// RequestCancellation handles POST /transactions/{id}/cancellation (Buyer or Seller of the transaction)
+func (h *TransactionHandler) RequestCancellation(c *gin.Context) {
+	idStr, userID, _, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	var input CancellationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	request, err := h.service.RequestCancellation(idStr, userID, input.Reason)
+	if err != nil {
+		log.Printf("Error requesting cancellation of transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetCancellationRequests handles GET /transactions/{id}/cancellations (Buyer or Seller of the transaction, or Admin)
+func (h *TransactionHandler) GetCancellationRequests(c *gin.Context) {
+	idStr, userID, userRole, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	requests, err := h.service.GetCancellationRequests(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error getting cancellation requests of transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// RespondToCancellation handles POST /cancellation-requests/{id}/respond (other party of the transaction or Admin)
+func (h *TransactionHandler) RespondToCancellation(c *gin.Context) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation request ID format"})
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input RespondToCancellationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	request, err := h.service.RespondToCancellation(idStr, userID, userRole, *input.Accept, input.RefundAmount, strings.TrimSpace(input.Note))
+	if err != nil {
+		log.Printf("Error responding to cancellation request %s: %v", idStr, err)
+		if err.Error() == fmt.Sprintf("cancellation request with id %s not found", idStr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// GetCancellationQueue handles GET /cancellation-requests (Admin only)
+func (h *TransactionHandler) GetCancellationQueue(c *gin.Context) {
+	status := models.CancellationRequestStatus(c.DefaultQuery("status", string(models.CancellationRequestStatusPending)))
+	switch status {
+	case models.CancellationRequestStatusPending, models.CancellationRequestStatusAccepted, models.CancellationRequestStatusDeclined, models.CancellationRequestStatusClosed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected pending, accepted, declined or closed"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter, expected 1 to 500"})
+		return
+	}
+
+	requests, err := h.service.GetCancellationQueue(status, limit)
+	if err != nil {
+		log.Printf("Error getting cancellation requests: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cancellation requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// Refund handles POST /transactions/{id}/refund (Admin only)
+func (h *TransactionHandler) Refund(c *gin.Context) {
+	idStr, adminID, _, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	var input RefundInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	if err := h.validator.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Validation error: %v", err)})
+		return
+	}
+
+	transaction, err := h.service.Refund(idStr, adminID, input.Amount, input.Reason)
+	if err != nil {
+		log.Printf("Error refunding transaction %s: %v", idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// escrowAction runs an escrow step that takes no input beyond the caller
+func (h *TransactionHandler) escrowAction(c *gin.Context, action string, step func(string, uuid.UUID, types.Role) (*models.Transaction, error)) {
+	idStr, userID, userRole, ok := h.transactionParams(c)
+	if !ok {
+		return
+	}
+
+	transaction, err := step(idStr, userID, userRole)
+	if err != nil {
+		log.Printf("Error %s transaction %s: %v", action, idStr, err)
+		h.writeTransactionError(c, idStr, err)
+		return
+	}
+
+	if userRole != types.RoleAdmin {
+		transaction.HideSellerContactDetails()
+	}
+	c.JSON(http.StatusOK, transaction)
+}
+
+func (h *TransactionHandler) transactionParams(c *gin.Context) (string, uuid.UUID, types.Role, bool) {
+	idStr := c.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID format"})
+		return "", uuid.Nil, "", false
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", uuid.Nil, "", false
+	}
+
+	userRole, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", uuid.Nil, "", false
+	}
+
+	return idStr, userID, userRole, true
+}
+
+func (h *TransactionHandler) writeTransactionError(c *gin.Context, transactionID string, err error) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unauthorized:"):
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case message == fmt.Sprintf("transaction with id %s not found", transactionID):
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	case message == "you cannot purchase your own listing",
+		message == "you cannot reserve your own listing",
+		message == "partial refund must be more than 0 and less than the sale amount",
+		strings.HasPrefix(message, "invalid transaction status"),
+		strings.HasPrefix(message, "invalid dispute outcome"),
+		message == "refund cannot be more than the sale amount":
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case message == "only active listings can be purchased",
+		message == "listing already has a purchase in progress",
+		message == "only transactions awaiting payment can be cancelled or failed",
+		message == "transaction is not awaiting payment",
+		message == "transaction has no payment to capture",
+		message == "funds must be held in escrow before the vehicle is handed over",
+		message == "only handed over vehicles can be confirmed",
+		message == "disputes can only be opened after handover",
+		message == "the inspection window has ended",
+		message == "only disputed transactions can be resolved",
+		strings.HasPrefix(message, "escrow is no longer"),
+		message == "listing is no longer available for sale",
+		message == "payment has not been completed yet",
+		strings.HasPrefix(message, "only sale transactions can be"),
+		message == "transaction already has a pending cancellation request",
+		message == "completed or cancelled transactions can no longer be cancelled",
+		message == "cancellation request is no longer pending",
+		message == "only funds held in escrow can be refunded",
+		message == "only active listings can be reserved",
+		message == "listing is already reserved",
+		message == "listing is reserved by another buyer",
+		message == "listing is no longer reserved",
+		message == "listing is not reserved",
+		message == "reservations are not available",
+		message == "reservation deposit has not been paid yet",
+		message == "reservation deposit cannot cover the whole price",
+		message == "reservation is credited to a sale in progress":
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+	case strings.HasPrefix(message, "payment was declined"):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": message})
+	case strings.HasPrefix(message, "payment provider"):
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process transaction"})
+	}
+}
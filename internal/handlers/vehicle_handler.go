@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"gorm.io/gorm"
+)
+
+type VehicleHandler struct {
+	service *services.VehicleService
+}
+
+func NewVehicleHandler(service *services.VehicleService) *VehicleHandler {
+	return &VehicleHandler{
+		service: service,
+	}
+}
+
+// GetVehicleHistory handles GET /vehicles/{id}/history (Public)
+func (h *VehicleHandler) GetVehicleHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	_, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID format"})
+		return
+	}
+
+	history, err := h.service.GetVehicleHistory(idStr)
+	if err != nil {
+		log.Printf("Error getting history for vehicle %s: %v", idStr, err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vehicle history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InspectorPerformanceReport summarises how each inspector performed over a date range.
+type InspectorPerformanceReport struct {
+	From       time.Time              `json:"from"`
+	To         time.Time              `json:"to"`
+	Inspectors []*InspectorPerformance `json:"inspectors"`
+}
+
+// InspectorPerformance is one inspector's figures for a report period.
+// Assigned counts inspections assigned in the period; the other figures count inspections completed in it.
+type InspectorPerformance struct {
+	InspectorID    uuid.UUID `json:"inspector_id"`
+	InspectorName  string    `json:"inspector_name"`
+	InspectorEmail string    `json:"inspector_email"`
+
+	Assigned             int `json:"assigned"`
+	Completed            int `json:"completed"`
+	Approved             int `json:"approved"`
+	Rejected             int `json:"rejected"`
+	PrePurchaseCompleted int `json:"pre_purchase_completed"`
+
+	ApprovalRate       float64  `json:"approval_rate"`  // share of decided vetting inspections approved, 0-1
+	RejectionRate      float64  `json:"rejection_rate"` // share of decided vetting inspections rejected, 0-1
+	AvgTurnaroundHours *float64 `json:"avg_turnaround_hours"` // assignment to findings submission; null without submissions
+	AvgConditionRating *float64 `json:"avg_condition_rating"` // null when no rated inspections
+
+	// Reversals are decisions on the same inspection that were later changed; Overturned are decisions
+	// contradicted by a later inspection of the same listing (e.g. a rejection disputed and approved on re-inspection).
+	Reversals  int `json:"reversals"`
+	Overturned int `json:"overturned"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CancellationRequestStatus string
+
+const (
+	CancellationRequestStatusPending  CancellationRequestStatus = "pending"  // waiting for the other party or an admin
+	CancellationRequestStatusAccepted CancellationRequestStatus = "accepted" // the sale was cancelled and refunded as requested
+	CancellationRequestStatusDeclined CancellationRequestStatus = "declined"
+	CancellationRequestStatusClosed   CancellationRequestStatus = "closed" // the sale was settled some other way first
+)
+
+// CancellationRequest is a buyer's or seller's request to call off a sale. Whether it takes effect straight away or
+// needs the other party or an admin to agree depends on how far the sale has gone; see TransactionService.RequestCancellation.
+// A sale has at most one pending request.
+type CancellationRequest struct {
+	ID            uuid.UUID                 `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID uuid.UUID                 `json:"transaction_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_cancellation_requests_pending,where:status = 'pending'"`
+	RequestedByID uuid.UUID                 `json:"requested_by_id" gorm:"type:uuid;not null"`
+	RequesterRole string                    `json:"requester_role" gorm:"size:10;not null;comment:buyer or seller"`
+	Reason        string                    `json:"reason" gorm:"type:text;not null"`
+	EscrowStatus  EscrowStatus              `json:"escrow_status" gorm:"size:30;not null;comment:Escrow step the sale was in when cancellation was requested"`
+	Status        CancellationRequestStatus `json:"status" gorm:"size:20;not null;index"`
+	RefundAmount  float64                   `json:"refund_amount,omitempty" gorm:"default:0;not null"`
+	RespondedByID *uuid.UUID                `json:"responded_by_id,omitempty" gorm:"type:uuid"`
+	ResponseNote  string                    `json:"response_note,omitempty" gorm:"type:text"`
+	RespondedAt   *time.Time                `json:"responded_at,omitempty"`
+	CreatedAt     time.Time                 `json:"created_at"`
+	UpdatedAt     time.Time                 `json:"updated_at"`
+}
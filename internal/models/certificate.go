@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CertificateStatus string
+
+const (
+	CertificateStatusValid   CertificateStatus = "valid"
+	CertificateStatusRevoked CertificateStatus = "revoked"
+)
+
+// certificatePayloadVersion prefixes the signed payload so its layout can change without breaking older certificates
+const certificatePayloadVersion = "autocity-inspection-certificate/v1"
+
+// InspectionCertificate is the signed proof issued when an inspection approves a listing.
+// The signed fields are copied at issue time so the certificate stays verifiable whatever happens to the listing later.
+type InspectionCertificate struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	InspectionID     uuid.UUID  `json:"inspection_id" gorm:"type:uuid;index;not null"`
+	ListingID        uuid.UUID  `json:"listing_id" gorm:"type:uuid;index;not null"`
+	VIN              string     `json:"vin" gorm:"size:17;index;not null"`
+	VehicleMake      string     `json:"vehicle_make"`
+	VehicleModel     string     `json:"vehicle_model"`
+	VehicleYear      int        `json:"vehicle_year,omitempty"`
+	Mileage          uint       `json:"mileage,omitempty"`
+	InspectionDate   time.Time  `json:"inspection_date"`
+	ConditionRating  int        `json:"condition_rating"`
+	InspectorID      uuid.UUID  `json:"inspector_id" gorm:"type:uuid;not null"`
+	InspectorName    string     `json:"inspector_name"`
+	IssuedAt         time.Time  `json:"issued_at" gorm:"not null"`
+	KeyID            string     `json:"key_id" gorm:"size:32;not null;comment:Fingerprint of the public key that verifies the signature"`
+	Signature        string     `json:"signature" gorm:"type:text;not null"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty" gorm:"size:255"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func (c *InspectionCertificate) Status() CertificateStatus {
+	if c.RevokedAt != nil {
+		return CertificateStatusRevoked
+	}
+	return CertificateStatusValid
+}
+
+// SignedPayload is the exact byte sequence covered by the signature: one key=value pair per line in a fixed order.
+// Offline verifiers rebuild it from the certificate fields, or take it verbatim from the certificate endpoint.
+func (c *InspectionCertificate) SignedPayload() []byte {
+	lines := []string{
+		certificatePayloadVersion,
+		"id=" + c.ID.String(),
+		"inspection_id=" + c.InspectionID.String(),
+		"listing_id=" + c.ListingID.String(),
+		"vin=" + payloadValue(c.VIN),
+		"make=" + payloadValue(c.VehicleMake),
+		"model=" + payloadValue(c.VehicleModel),
+		fmt.Sprintf("year=%d", c.VehicleYear),
+		fmt.Sprintf("mileage=%d", c.Mileage),
+		"inspection_date=" + c.InspectionDate.UTC().Format("2006-01-02"),
+		fmt.Sprintf("condition_rating=%d", c.ConditionRating),
+		"inspector_id=" + c.InspectorID.String(),
+		"inspector_name=" + payloadValue(c.InspectorName),
+		"issued_at=" + c.IssuedAt.UTC().Format(time.RFC3339),
+		"key_id=" + c.KeyID,
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// payloadValue keeps a value on its own line of the signed payload
+func payloadValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(strings.TrimSpace(value))
+}
+
+// CertificateVerification is the public view of a certificate with the outcome of checking its signature.
+type CertificateVerification struct {
+	Certificate    *InspectionCertificate `json:"certificate"`
+	Status         CertificateStatus      `json:"status"`
+	SignatureValid bool                   `json:"signature_valid"`
+	Algorithm      string                 `json:"algorithm"`
+	SignedPayload  string                 `json:"signed_payload"`
+	PublicKeyURL   string                 `json:"public_key_url"`
+	QRCodeURL      string                 `json:"qr_code_url"`
+}
+
+// CertificatePublicKey publishes the key that verifies certificate signatures.
+type CertificatePublicKey struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"` // Raw 32-byte key, base64
+	PEM       string `json:"pem"`
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChecklistResult string
+
+const (
+	ChecklistResultPass ChecklistResult = "pass"
+	ChecklistResultFail ChecklistResult = "fail"
+	ChecklistResultNA   ChecklistResult = "na"
+)
+
+type ChecklistSeverity string
+
+const (
+	ChecklistSeverityMinor    ChecklistSeverity = "minor"
+	ChecklistSeverityMajor    ChecklistSeverity = "major"
+	ChecklistSeverityCritical ChecklistSeverity = "critical"
+)
+
+// severityCredit is the share of an item's weight still earned when it fails with the given severity
+var severityCredit = map[ChecklistSeverity]float64{
+	ChecklistSeverityMinor:    0.6,
+	ChecklistSeverityMajor:    0.3,
+	ChecklistSeverityCritical: 0,
+}
+
+// ChecklistTemplate is an admin-managed, versioned inspection checklist.
+// Templates are never edited in place; a change is published as a new version of the same name.
+type ChecklistTemplate struct {
+	ID          uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string             `json:"name" gorm:"size:100;not null;uniqueIndex:idx_checklist_template_version"`
+	Version     int                `json:"version" gorm:"not null;uniqueIndex:idx_checklist_template_version"`
+	Active      bool               `json:"active" gorm:"default:false;not null;comment:Only the active template is used to validate new findings"`
+	Sections    []ChecklistSection `json:"sections" gorm:"type:jsonb;serializer:json;not null"`
+	CreatedByID uuid.UUID          `json:"created_by_id" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// ChecklistSection groups related items, e.g. engine, body, interior or electrics.
+type ChecklistSection struct {
+	Key    string          `json:"key" validate:"required"`
+	Name   string          `json:"name" validate:"required"`
+	Weight float64         `json:"weight,omitempty" validate:"gte=0"`
+	Items  []ChecklistItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// ChecklistItem is a single check an inspector must assess.
+type ChecklistItem struct {
+	Key    string  `json:"key" validate:"required"`
+	Label  string  `json:"label" validate:"required"`
+	Weight float64 `json:"weight,omitempty" validate:"gte=0"`
+}
+
+// ChecklistFinding is an inspector's result for one template item.
+type ChecklistFinding struct {
+	Section  string            `json:"section" validate:"required"`
+	Item     string            `json:"item" validate:"required"`
+	Result   ChecklistResult   `json:"result" validate:"required,oneof=pass fail na"`
+	Severity ChecklistSeverity `json:"severity,omitempty" validate:"omitempty,oneof=minor major critical"`
+	Notes    string            `json:"notes,omitempty"`
+	Photos   []string          `json:"photos,omitempty" validate:"omitempty,dive,url"`
+}
+
+// ValidateStructure checks that section and item keys are unique within the template.
+func (t *ChecklistTemplate) ValidateStructure() error {
+	sectionKeys := map[string]bool{}
+	for _, section := range t.Sections {
+		if sectionKeys[section.Key] {
+			return fmt.Errorf("duplicate checklist section '%s'", section.Key)
+		}
+		sectionKeys[section.Key] = true
+
+		itemKeys := map[string]bool{}
+		for _, item := range section.Items {
+			if itemKeys[item.Key] {
+				return fmt.Errorf("duplicate checklist item '%s' in section '%s'", item.Key, section.Key)
+			}
+			itemKeys[item.Key] = true
+		}
+	}
+	return nil
+}
+
+// Evaluate validates findings against the template and returns the weighted condition rating (1-10).
+// Every item must be answered exactly once; failures must carry a severity.
+func (t *ChecklistTemplate) Evaluate(findings []ChecklistFinding) (int, error) {
+	findingsByItem := map[string]ChecklistFinding{}
+	for _, finding := range findings {
+		key := finding.Section + "." + finding.Item
+		if _, duplicate := findingsByItem[key]; duplicate {
+			return 0, fmt.Errorf("checklist item '%s' answered more than once", key)
+		}
+
+		switch finding.Result {
+		case ChecklistResultFail:
+			if _, ok := severityCredit[finding.Severity]; !ok {
+				return 0, fmt.Errorf("checklist item '%s' failed without a valid severity", key)
+			}
+		case ChecklistResultPass, ChecklistResultNA:
+			if finding.Severity != "" {
+				return 0, fmt.Errorf("checklist item '%s' has a severity but did not fail", key)
+			}
+		default:
+			return 0, fmt.Errorf("checklist item '%s' has invalid result '%s'", key, finding.Result)
+		}
+
+		findingsByItem[key] = finding
+	}
+
+	var earned, possible float64
+	answered := 0
+	for _, section := range t.Sections {
+		sectionWeight := weightOrDefault(section.Weight)
+
+		for _, item := range section.Items {
+			key := section.Key + "." + item.Key
+			finding, ok := findingsByItem[key]
+			if !ok {
+				return 0, fmt.Errorf("checklist item '%s' is missing from the findings", key)
+			}
+			answered++
+
+			if finding.Result == ChecklistResultNA {
+				continue
+			}
+
+			weight := sectionWeight * weightOrDefault(item.Weight)
+			possible += weight
+			if finding.Result == ChecklistResultPass {
+				earned += weight
+			} else {
+				earned += weight * severityCredit[finding.Severity]
+			}
+		}
+	}
+
+	if answered != len(findingsByItem) {
+		return 0, fmt.Errorf("findings contain items not in checklist template '%s' v%d", t.Name, t.Version)
+	}
+	if possible == 0 {
+		return 0, fmt.Errorf("at least one checklist item must be assessed")
+	}
+
+	return 1 + int(math.Round(9*earned/possible)), nil
+}
+
+func weightOrDefault(weight float64) float64 {
+	if weight == 0 {
+		return 1
+	}
+	return weight
+}
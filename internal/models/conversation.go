@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation is the message thread between a buyer and the seller about one listing. A buyer has one conversation
+// per listing. Either party can block the other, after which neither can send messages until the blocker unblocks.
+type Conversation struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID     uuid.UUID  `json:"listing_id" gorm:"type:uuid;not null;uniqueIndex:idx_conversations_listing_buyer"`
+	BuyerID       uuid.UUID  `json:"buyer_id" gorm:"type:uuid;not null;uniqueIndex:idx_conversations_listing_buyer;index"`
+	SellerID      uuid.UUID  `json:"seller_id" gorm:"type:uuid;not null;index"`
+	LastMessageAt time.Time  `json:"last_message_at" gorm:"not null;index"`
+	BlockedByID   *uuid.UUID `json:"blocked_by_id,omitempty" gorm:"type:uuid;comment:Party who blocked the other; nobody can send while set"`
+	BlockedAt     *time.Time `json:"blocked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// UnreadCount is how many messages from the other party the viewer has not read yet
+	UnreadCount int64 `json:"unread_count" gorm:"-"`
+
+	Listing  Listing   `json:"listing,omitempty" gorm:"foreignKey:ListingID"`
+	Buyer    User      `json:"buyer,omitempty" gorm:"foreignKey:BuyerID"`
+	Seller   User      `json:"seller,omitempty" gorm:"foreignKey:SellerID"`
+	Messages []Message `json:"messages,omitempty" gorm:"foreignKey:ConversationID"`
+}
+
+// HideSellerContactDetails blanks the seller's email and phone before a conversation is shown to one of its parties
+func (c *Conversation) HideSellerContactDetails() {
+	c.Seller.HideContactDetails()
+	c.Listing.Seller.HideContactDetails()
+}
+
+// Message is one message in a conversation; ReadAt is the read receipt, set when the recipient opens the thread
+type Message struct {
+	ID             uuid.UUID           `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ConversationID uuid.UUID           `json:"conversation_id" gorm:"type:uuid;not null;index"`
+	SenderID       uuid.UUID           `json:"sender_id" gorm:"type:uuid;not null"`
+	Body           string              `json:"body,omitempty" gorm:"type:text"`
+	ReadAt         *time.Time          `json:"read_at,omitempty" gorm:"index"`
+	CreatedAt      time.Time           `json:"created_at"`
+	Attachments    []MessageAttachment `json:"attachments,omitempty" gorm:"foreignKey:MessageID"`
+}
+
+// MessageAttachment is a file sent with a message, kept in file storage
+type MessageAttachment struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	MessageID   uuid.UUID `json:"message_id" gorm:"type:uuid;not null;index"`
+	Filename    string    `json:"filename" gorm:"size:255;not null"`
+	ContentType string    `json:"content_type" gorm:"size:100;not null"`
+	Size        int       `json:"size" gorm:"not null;comment:Bytes"`
+	URL         string    `json:"url" gorm:"size:500;not null"`
+}
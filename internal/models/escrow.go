@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EscrowStatus tracks the buyer's money through a sale:
+//
+//	awaiting_payment → funds_held → handed_over → released
+//	                                     ↘ disputed → released | refunded | partially_refunded
+//
+// An unpaid sale ends cancelled and a seller who never hands over has the funds refunded. A reservation deposit only
+// goes awaiting_payment → funds_held, then is released to the seller with the sale it was credited to, or refunded
+// when the sale or the reservation falls through.
+type EscrowStatus string
+
+const (
+	EscrowStatusAwaitingPayment   EscrowStatus = "awaiting_payment"
+	EscrowStatusFundsHeld         EscrowStatus = "funds_held"
+	EscrowStatusHandedOver        EscrowStatus = "handed_over"
+	EscrowStatusDisputed          EscrowStatus = "disputed"
+	EscrowStatusReleased          EscrowStatus = "released"
+	EscrowStatusRefunded          EscrowStatus = "refunded"
+	EscrowStatusPartiallyRefunded EscrowStatus = "partially_refunded"
+	EscrowStatusCancelled         EscrowStatus = "cancelled"
+)
+
+// IsFinal reports whether the escrow has ended
+func (s EscrowStatus) IsFinal() bool {
+	switch s {
+	case EscrowStatusReleased, EscrowStatusRefunded, EscrowStatusPartiallyRefunded, EscrowStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// TransactionEvent is the audit record of one change to a transaction's status or escrow
+type TransactionEvent struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID uuid.UUID         `json:"transaction_id" gorm:"type:uuid;index;not null"`
+	FromStatus    TransactionStatus `json:"from_status,omitempty" gorm:"size:30"`
+	ToStatus      TransactionStatus `json:"to_status" gorm:"size:30;not null"`
+	FromEscrow    EscrowStatus      `json:"from_escrow,omitempty" gorm:"size:30"`
+	ToEscrow      EscrowStatus      `json:"to_escrow,omitempty" gorm:"size:30"`
+	ActorID       *uuid.UUID        `json:"actor_id,omitempty" gorm:"type:uuid;comment:User who made the change; empty for automatic actions"`
+	Reason        string            `json:"reason,omitempty" gorm:"type:text"`
+	Amount        float64           `json:"amount,omitempty" gorm:"comment:Money moved by the change, e.g. a refund"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// FeeKind is what a fee is charged for, and so who pays it
+type FeeKind string
+
+const (
+	FeeKindSaleCommission FeeKind = "sale_commission" // charged to the seller when a sale completes
+	FeeKindInspectionFee  FeeKind = "inspection_fee"  // charged to the buyer for a pre-purchase inspection
+)
+
+type FeeMethod string
+
+const (
+	FeeMethodPercentage FeeMethod = "percentage" // a share of the amount
+	FeeMethodFlat       FeeMethod = "flat"       // a fixed amount
+	FeeMethodTiered     FeeMethod = "tiered"     // the percentage and flat amount of the price band the amount falls in
+)
+
+// FeeTier is one price band of a tiered fee, covering amounts up to UpTo; the last band may leave UpTo at 0 for no limit
+type FeeTier struct {
+	UpTo       float64 `json:"up_to"`
+	Percentage float64 `json:"percentage"`
+	FlatAmount float64 `json:"flat_amount"`
+}
+
+// FeeRule is one entry of the fee schedule. A rule for the payer's role overrides the rule for everyone (empty Role).
+type FeeRule struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name       string     `json:"name" gorm:"size:255;not null"`
+	Kind       FeeKind    `json:"kind" gorm:"size:30;not null;index"`
+	Role       types.Role `json:"role,omitempty" gorm:"size:20;comment:Payer role the rule applies to; empty for everyone"`
+	Method     FeeMethod  `json:"method" gorm:"size:20;not null"`
+	Percentage float64    `json:"percentage,omitempty"`
+	FlatAmount float64    `json:"flat_amount,omitempty"`
+	Tiers      []FeeTier  `json:"tiers,omitempty" gorm:"type:jsonb;serializer:json"`
+	MinAmount  float64    `json:"min_amount,omitempty" gorm:"comment:Lowest fee charged; 0 for no minimum"`
+	MaxAmount  float64    `json:"max_amount,omitempty" gorm:"comment:Highest fee charged; 0 for no cap"`
+	Active     bool       `json:"active" gorm:"default:true;not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// FeeWaiver is a promotion waiving a share of a fee for everyone, a role or a single user while it runs
+type FeeWaiver struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string     `json:"name" gorm:"size:255;not null"`
+	Kind        FeeKind    `json:"kind" gorm:"size:30;not null;index"`
+	Role        types.Role `json:"role,omitempty" gorm:"size:20;comment:Payer role the waiver applies to; empty for everyone"`
+	UserID      *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;comment:Single payer the waiver applies to"`
+	Percentage  float64    `json:"percentage" gorm:"not null;comment:Share of the fee waived, 0-100"`
+	StartsAt    time.Time  `json:"starts_at" gorm:"not null"`
+	EndsAt      *time.Time `json:"ends_at,omitempty" gorm:"comment:Empty for no end"`
+	Active      bool       `json:"active" gorm:"default:true;not null"`
+	CreatedByID uuid.UUID  `json:"created_by_id" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TransactionFee is a fee line item charged on a transaction. It keeps a copy of how it was worked out, so later
+// changes to the fee schedule do not rewrite what was charged.
+type TransactionFee struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID uuid.UUID  `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	Kind          FeeKind    `json:"kind" gorm:"size:30;not null"`
+	PayerID       uuid.UUID  `json:"payer_id" gorm:"type:uuid;not null"`
+	RuleID        *uuid.UUID `json:"rule_id,omitempty" gorm:"type:uuid"`
+	WaiverID      *uuid.UUID `json:"waiver_id,omitempty" gorm:"type:uuid"`
+	Description   string     `json:"description" gorm:"size:255;not null"`
+	BaseAmount    float64    `json:"base_amount" gorm:"not null;comment:Amount the fee was worked out on"`
+	GrossAmount   float64    `json:"gross_amount" gorm:"not null;comment:Fee before waivers"`
+	WaivedAmount  float64    `json:"waived_amount" gorm:"not null;default:0"`
+	Amount        float64    `json:"amount" gorm:"not null;comment:Fee charged"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -13,21 +13,44 @@ const (
 	InspectionStatusPending   InspectionStatus = "pending"
 	InspectionStatusApproved  InspectionStatus = "approved"
 	InspectionStatusRejected  InspectionStatus = "rejected"
+	InspectionStatusCompleted InspectionStatus = "completed" // pre-purchase inspections are delivered, not approved or rejected
+)
+
+type InspectionType string
+
+const (
+	InspectionTypeVetting     InspectionType = "vetting"      // admin review deciding whether a listing goes live
+	InspectionTypePrePurchase InspectionType = "pre_purchase" // ordered by a buyer; private to them and never changes the listing
 )
 
 // Inspection represents the vetting report for a listed vehicle.
 type Inspection struct {
 	ID 				uuid.UUID		   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	ListingID       uuid.UUID          `json:"listing_id" gorm:"type:uuid;uniqueIndex;not null"`
+	ListingID       uuid.UUID          `json:"listing_id" gorm:"type:uuid;index:idx_inspections_listing_history;not null"` // A listing keeps every inspection; Listing.CurrentInspectionID marks the one in force
 	InspectorID     uuid.UUID          `json:"inspector_id" gorm:"type:uuid;not null"`
+	AssignedAt      *time.Time         `json:"assigned_at,omitempty" gorm:"comment:When the current inspector was assigned"`
 	InspectionDate  time.Time          `json:"inspection_date,omitempty"`
 	ConditionRating int                `json:"condition_rating,omitempty" gorm:"comment:Overall condition score (e.g., 1-10)"`
 	Findings        map[string]any     `json:"findings,omitempty" gorm:"type:jsonb;comment:Detailed inspection notes (e.g., dents, scratches, mechanical issues)"`
 	ReportURL       string             `json:"report_url,omitempty" gorm:"size:500;comment:Link to the full inspection report PDF/image"`
+	ReportGeneratedAt *time.Time       `json:"report_generated_at,omitempty" gorm:"comment:When the PDF behind ReportURL was last generated"`
+	ReportPending   bool               `json:"report_pending,omitempty" gorm:"not null;default:false;index;comment:A report is queued for the background generator"`
+	ReportAttempts  int                `json:"-" gorm:"not null;default:0;comment:Failed generations of the queued report"`
 	Status          InspectionStatus   `json:"status" gorm:"default:pending;not null"`
+	Type            InspectionType     `json:"type" gorm:"size:20;default:vetting;not null;index"`
+	RequestedByID   *uuid.UUID         `json:"requested_by_id,omitempty" gorm:"type:uuid;comment:Buyer who ordered a pre-purchase inspection"`
 	CreatedAt       time.Time          `json:"created_at"`
 	UpdatedAt       time.Time          `json:"updated_at"`
 
+	// Structured checklist results and the template version they were validated against
+	ChecklistTemplateID *uuid.UUID         `json:"checklist_template_id,omitempty" gorm:"type:uuid"`
+	ChecklistVersion    int                `json:"checklist_version,omitempty"`
+	Checklist           []ChecklistFinding `json:"checklist,omitempty" gorm:"type:jsonb;serializer:json"`
+	SubmittedAt         *time.Time         `json:"submitted_at,omitempty" gorm:"comment:When the checklist findings were submitted"`
+
+	// Structured reasons given when the inspection rejected the listing
+	RejectionReasons []RejectionDetail `json:"rejection_reasons,omitempty" gorm:"type:jsonb;serializer:json"`
+
 	// Relationships
 	Listing   Listing `json:"listing" gorm:"foreignKey:ListingID"`
 	Inspector User    `json:"inspector,omitempty" gorm:"foreignKey:InspectorID"`
@@ -37,19 +60,31 @@ type Inspection struct {
 // Inspection represents the vetting report for a listed vehicle.
 type InspectionFetchInput struct {
 	ID              uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	ListingID       uuid.UUID          `json:"listing_id" gorm:"type:uuid;uniqueIndex;not null"`
+	ListingID       uuid.UUID          `json:"listing_id" gorm:"type:uuid;index:idx_inspections_listing_history;not null"`
 	InspectorID     uuid.UUID          `json:"inspector_id" gorm:"type:uuid;not null"`
+	AssignedAt      *time.Time         `json:"assigned_at,omitempty"`
 	InspectionDate  time.Time          `json:"inspection_date,omitempty"`
 	ConditionRating int                `json:"condition_rating,omitempty" gorm:"comment:Overall condition score (e.g., 1-10)"`
 
 	Findings        string             `json:"findings,omitempty" gorm:"comment:Detailed inspection notes (e.g., dents, scratches, mechanical issues)"` // GORM tag might not be needed for reading, but kept for clarity if used in other contexts
 
 	ReportURL       string             `json:"report_url,omitempty" gorm:"size:500;comment:Link to the full inspection report PDF/image"`
+	ReportGeneratedAt *time.Time       `json:"report_generated_at,omitempty"`
+	ReportPending   bool               `json:"report_pending,omitempty"`
+	ReportAttempts  int                `json:"-"`
 	Status          InspectionStatus   `json:"status" gorm:"default:pending;not null"`
+	Type            InspectionType     `json:"type"`
+	RequestedByID   *uuid.UUID         `json:"requested_by_id,omitempty" gorm:"type:uuid"`
 	CreatedAt       time.Time          `json:"created_at"`
 	UpdatedAt       time.Time          `json:"updated_at"`
 
-	
+	ChecklistTemplateID *uuid.UUID         `json:"checklist_template_id,omitempty" gorm:"type:uuid"`
+	ChecklistVersion    int                `json:"checklist_version,omitempty"`
+	Checklist           []ChecklistFinding `json:"checklist,omitempty" gorm:"type:jsonb;serializer:json"`
+	SubmittedAt         *time.Time         `json:"submitted_at,omitempty"`
+
+	RejectionReasons []RejectionDetail `json:"rejection_reasons,omitempty" gorm:"type:jsonb;serializer:json"`
+
 	Listing   Listing `json:"listing" gorm:"foreignKey:ListingID"`
 	Inspector User    `json:"inspector,omitempty" gorm:"foreignKey:InspectorID"`
 }
@@ -65,17 +100,44 @@ func (i *InspectionFetchInput) ParseInspectionInputToModel() ( *Inspection, erro
 		ID: i.ID,
 		ListingID: i.ListingID,
 		InspectorID: i.InspectorID,
+		AssignedAt: i.AssignedAt,
 		InspectionDate: i.InspectionDate,
 		ConditionRating: i.ConditionRating,
 
 		Findings: findings,
 		ReportURL: i.ReportURL,
+		ReportGeneratedAt: i.ReportGeneratedAt,
+		ReportPending: i.ReportPending,
+		ReportAttempts: i.ReportAttempts,
 		Status: i.Status,
+		Type: i.Type,
+		RequestedByID: i.RequestedByID,
 		CreatedAt: i.CreatedAt,
 		UpdatedAt: i.UpdatedAt,		
+		ChecklistTemplateID: i.ChecklistTemplateID,
+		ChecklistVersion: i.ChecklistVersion,
+		Checklist: i.Checklist,
+		SubmittedAt: i.SubmittedAt,
+		RejectionReasons: i.RejectionReasons,
 		Listing: i.Listing,
 		Inspector:	i.Inspector,
 	}
 
 	return inspection, nil
-}
\ No newline at end of file
+}
+
+// ListingInspectionHistory lists every inspection of a listing, newest first, with the one currently in force.
+type ListingInspectionHistory struct {
+	ListingID           uuid.UUID     `json:"listing_id"`
+	CurrentInspectionID *uuid.UUID    `json:"current_inspection_id,omitempty"`
+	Inspections         []*Inspection `json:"inspections"`
+}
+
+// InspectionReport points to the downloadable PDF report of the inspection in force for a listing.
+type InspectionReport struct {
+	ListingID         uuid.UUID  `json:"listing_id"`
+	InspectionID      uuid.UUID  `json:"inspection_id"`
+	ConditionRating   int        `json:"condition_rating,omitempty"`
+	ReportURL         string     `json:"report_url"`
+	ReportGeneratedAt *time.Time `json:"report_generated_at,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InspectionRequestStatus string
+
+const (
+	InspectionRequestStatusAwaitingConsent InspectionRequestStatus = "awaiting_consent" // waiting for the seller to allow access to the vehicle
+	InspectionRequestStatusDeclined        InspectionRequestStatus = "declined"
+	InspectionRequestStatusConsented       InspectionRequestStatus = "consented" // the buyer can now book a slot
+	InspectionRequestStatusScheduled       InspectionRequestStatus = "scheduled"
+	InspectionRequestStatusCompleted       InspectionRequestStatus = "completed"
+	InspectionRequestStatusCancelled       InspectionRequestStatus = "cancelled"
+)
+
+// InspectionRequest is a buyer's order for an independent pre-purchase inspection of an active listing.
+// Once scheduled, the inspection itself is an Inspection of type pre_purchase and the fee a Transaction.
+type InspectionRequest struct {
+	ID                uuid.UUID               `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID         uuid.UUID               `json:"listing_id" gorm:"type:uuid;not null;index"`
+	BuyerID           uuid.UUID               `json:"buyer_id" gorm:"type:uuid;not null;index"`
+	SellerID          uuid.UUID               `json:"seller_id" gorm:"type:uuid;not null"`
+	Status            InspectionRequestStatus `json:"status" gorm:"size:30;default:awaiting_consent;not null"`
+	Note              string                  `json:"note,omitempty" gorm:"type:text;comment:What the buyer wants checked"`
+	Fee               float64                 `json:"fee" gorm:"not null;comment:Inspection fee quoted when the request was made"`
+	SellerRespondedAt *time.Time              `json:"seller_responded_at,omitempty"`
+	DeclineReason     string                  `json:"decline_reason,omitempty" gorm:"size:500"`
+	SlotID            *uuid.UUID              `json:"slot_id,omitempty" gorm:"type:uuid"`
+	ScheduledFor      *time.Time              `json:"scheduled_for,omitempty"`
+	InspectionID      *uuid.UUID              `json:"inspection_id,omitempty" gorm:"type:uuid"`
+	TransactionID     *uuid.UUID              `json:"transaction_id,omitempty" gorm:"type:uuid;comment:Inspection fee transaction"`
+	CancelledAt       *time.Time              `json:"cancelled_at,omitempty"`
+	CompletedAt       *time.Time              `json:"completed_at,omitempty"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+
+	// FeeCheckoutURL is where the buyer pays the inspection fee while it is unpaid
+	FeeCheckoutURL string `json:"fee_checkout_url,omitempty" gorm:"-"`
+
+	// Relationships
+	Listing Listing `json:"listing,omitempty" gorm:"foreignKey:ListingID"`
+	Buyer   User    `json:"-" gorm:"foreignKey:BuyerID"`
+	Seller  User    `json:"-" gorm:"foreignKey:SellerID"`
+}
+
+// IsOpen reports whether the request still blocks the buyer from ordering another inspection of the listing.
+func (r *InspectionRequest) IsOpen() bool {
+	switch r.Status {
+	case InspectionRequestStatusAwaitingConsent, InspectionRequestStatusConsented, InspectionRequestStatusScheduled:
+		return true
+	}
+	return false
+}
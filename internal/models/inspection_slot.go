@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InspectionSlotStatus string
+
+const (
+	InspectionSlotStatusAvailable InspectionSlotStatus = "available"
+	InspectionSlotStatusBooked    InspectionSlotStatus = "booked"
+	InspectionSlotStatusCancelled InspectionSlotStatus = "cancelled"
+)
+
+// InspectionSlot is a window an inspector has published as available for an inspection.
+type InspectionSlot struct {
+	ID          uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	InspectorID uuid.UUID            `json:"inspector_id" gorm:"type:uuid;not null;index:idx_inspection_slot_inspector_time"`
+	StartsAt    time.Time            `json:"starts_at" gorm:"not null;index:idx_inspection_slot_inspector_time"`
+	EndsAt      time.Time            `json:"ends_at" gorm:"not null"`
+	Status      InspectionSlotStatus `json:"status" gorm:"default:available;not null"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+
+	// Relationships
+	Inspector User `json:"inspector,omitempty" gorm:"foreignKey:InspectorID"`
+}
+
+type InspectionBookingStatus string
+
+const (
+	InspectionBookingStatusScheduled InspectionBookingStatus = "scheduled"
+	InspectionBookingStatusCancelled InspectionBookingStatus = "cancelled"
+	InspectionBookingStatusCompleted InspectionBookingStatus = "completed"
+)
+
+// InspectionBooking is a seller's reservation of an inspector slot for their pending listing.
+type InspectionBooking struct {
+	ID              uuid.UUID               `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID       uuid.UUID               `json:"listing_id" gorm:"type:uuid;not null;index"`
+	SlotID          uuid.UUID               `json:"slot_id" gorm:"type:uuid;not null"`
+	InspectorID     uuid.UUID               `json:"inspector_id" gorm:"type:uuid;not null"`
+	SellerID        uuid.UUID               `json:"seller_id" gorm:"type:uuid;not null"`
+	ScheduledFor    time.Time               `json:"scheduled_for" gorm:"not null"`
+	Status          InspectionBookingStatus `json:"status" gorm:"default:scheduled;not null"`
+	RescheduleCount int                     `json:"reschedule_count" gorm:"default:0;not null"`
+	ReminderSentAt  *time.Time              `json:"reminder_sent_at,omitempty"`
+	CancelledAt     *time.Time              `json:"cancelled_at,omitempty"`
+	CancelReason    string                  `json:"cancel_reason,omitempty" gorm:"size:255"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+
+	// Relationships
+	Slot      InspectionSlot `json:"slot" gorm:"foreignKey:SlotID"`
+	Listing   Listing        `json:"-" gorm:"foreignKey:ListingID"`
+	Inspector User           `json:"inspector,omitempty" gorm:"foreignKey:InspectorID"`
+	Seller    User           `json:"-" gorm:"foreignKey:SellerID"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is the numbered receipt of a completed transaction. It is a snapshot: the parties, vehicle and amounts are
+// copied when it is issued, so later changes to users, listings or the fee schedule never alter it.
+type Invoice struct {
+	ID            uuid.UUID       `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Number        string          `json:"number" gorm:"size:30;not null;uniqueIndex"`
+	Year          int             `json:"year" gorm:"not null;uniqueIndex:idx_invoices_year_sequence"`
+	Sequence      int             `json:"sequence" gorm:"not null;uniqueIndex:idx_invoices_year_sequence"`
+	TransactionID uuid.UUID       `json:"transaction_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Type          TransactionType `json:"type" gorm:"size:30;not null"`
+	BuyerID       uuid.UUID       `json:"buyer_id" gorm:"type:uuid;not null;index"`
+	SellerID      uuid.UUID       `json:"seller_id" gorm:"type:uuid;not null;index"`
+	BuyerName     string          `json:"buyer_name" gorm:"size:255;not null"`
+	BuyerEmail    string          `json:"buyer_email" gorm:"size:255;not null"`
+	SellerName    string          `json:"seller_name" gorm:"size:255;not null;comment:AutoCity for inspection fees"`
+	SellerEmail   string          `json:"seller_email" gorm:"size:255"`
+	ListingID     uuid.UUID       `json:"listing_id" gorm:"type:uuid;not null"`
+	ListingTitle  string          `json:"listing_title" gorm:"size:255;not null"`
+	VIN           string          `json:"vin" gorm:"size:17"`
+	Currency      string          `json:"currency" gorm:"size:3;not null"`
+	Lines         []InvoiceLine   `json:"lines" gorm:"type:jsonb;serializer:json;not null"`
+	Total         float64         `json:"total" gorm:"not null;comment:Paid by the buyer, net of refunds"`
+	SellerFees    float64         `json:"seller_fees" gorm:"not null;default:0"`
+	SellerNet     float64         `json:"seller_net" gorm:"not null;default:0;comment:Owed to the seller after refunds and fees"`
+	TaxRate       float64         `json:"tax_rate" gorm:"not null;default:0"`
+	TaxAmount     float64         `json:"tax_amount" gorm:"not null;default:0;comment:VAT included in the platform's fees"`
+	IssuedAt      time.Time       `json:"issued_at" gorm:"not null"`
+	EmailedAt     *time.Time      `json:"emailed_at,omitempty" gorm:"index"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// InvoiceLine is one amount on an invoice and the party it was charged to
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	PaidBy      string  `json:"paid_by"` // buyer or seller
+	Amount      float64 `json:"amount"`  // negative for refunds
+}
+
+// InvoiceSequence is the last invoice number issued in a year. It is only advanced inside the database transaction
+// that issues the invoice, so a rolled back invoice gives its number back and the numbering stays gap-free.
+type InvoiceSequence struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerAccountType decides which side of an account its balance normally sits on:
+// assets grow with debits, liabilities and revenue with credits
+type LedgerAccountType string
+
+const (
+	LedgerAccountTypeAsset     LedgerAccountType = "asset"
+	LedgerAccountTypeLiability LedgerAccountType = "liability"
+	LedgerAccountTypeRevenue   LedgerAccountType = "revenue"
+)
+
+type LedgerAccountCode string
+
+const (
+	LedgerAccountBuyerFunds      LedgerAccountCode = "buyer_funds"      // asset: money collected from a buyer, held at the payment provider
+	LedgerAccountEscrow          LedgerAccountCode = "escrow"           // liability: buyer money held until a sale settles
+	LedgerAccountPlatformRevenue LedgerAccountCode = "platform_revenue" // revenue: what the platform earned
+	LedgerAccountSellerPayable   LedgerAccountCode = "seller_payable"   // liability: money owed to a seller
+	LedgerAccountPaidOut         LedgerAccountCode = "paid_out"         // asset (credit balance): money that left the platform to sellers' banks
+)
+
+// Type returns the account type every account with the code has
+func (c LedgerAccountCode) Type() LedgerAccountType {
+	switch c {
+	case LedgerAccountBuyerFunds, LedgerAccountPaidOut:
+		return LedgerAccountTypeAsset
+	case LedgerAccountPlatformRevenue:
+		return LedgerAccountTypeRevenue
+	}
+	return LedgerAccountTypeLiability
+}
+
+// LedgerAccount is one account of the platform's double-entry ledger.
+// Buyer funds and seller payables are kept per user; the platform's own accounts have no owner (uuid.Nil).
+type LedgerAccount struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Code      LedgerAccountCode `json:"code" gorm:"size:50;not null;uniqueIndex:idx_ledger_accounts_code_owner_currency"`
+	Type      LedgerAccountType `json:"type" gorm:"size:20;not null"`
+	OwnerID   uuid.UUID         `json:"owner_id" gorm:"type:uuid;not null;uniqueIndex:idx_ledger_accounts_code_owner_currency"`
+	Currency  string            `json:"currency" gorm:"size:3;not null;uniqueIndex:idx_ledger_accounts_code_owner_currency"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// JournalEntry is an immutable, balanced record of money moving between ledger accounts.
+// Entries are never changed or deleted; mistakes are corrected with a reversing entry.
+type JournalEntry struct {
+	ID                 uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID      *uuid.UUID    `json:"transaction_id,omitempty" gorm:"type:uuid;index;comment:Transaction whose change posted the entry"`
+	TransactionEventID *uuid.UUID    `json:"transaction_event_id,omitempty" gorm:"type:uuid;uniqueIndex;comment:Audited change that posted the entry"`
+	Description        string        `json:"description" gorm:"type:text;not null"`
+	Currency           string        `json:"currency" gorm:"size:3;not null"`
+	CreatedAt          time.Time     `json:"created_at"`
+	Lines              []JournalLine `json:"lines" gorm:"foreignKey:EntryID"`
+}
+
+// JournalLine debits or credits one account, in integer minor units (e.g. kobo); exactly one side is set
+type JournalLine struct {
+	ID        uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EntryID   uuid.UUID     `json:"entry_id" gorm:"type:uuid;not null;index"`
+	AccountID uuid.UUID     `json:"account_id" gorm:"type:uuid;not null;index"`
+	Debit     int64         `json:"debit" gorm:"not null;default:0"`
+	Credit    int64         `json:"credit" gorm:"not null;default:0"`
+	Account   LedgerAccount `json:"account" gorm:"foreignKey:AccountID"`
+}
+
+// LedgerAccountBalance is an account with the totals posted to it, in minor units.
+// Balance is on the account's normal side, so it is positive for money held, owed or earned.
+type LedgerAccountBalance struct {
+	LedgerAccount
+	Debits  int64 `json:"debits"`
+	Credits int64 `json:"credits"`
+	Balance int64 `json:"balance"`
+}
+
+// TrialBalance lists every account's balance; debits and credits across the ledger always match
+type TrialBalance struct {
+	Accounts     []*LedgerAccountBalance `json:"accounts"`
+	TotalDebits  int64                   `json:"total_debits"`
+	TotalCredits int64                   `json:"total_credits"`
+	Balanced     bool                    `json:"balanced"`
+}
@@ -13,6 +13,7 @@ const (
 	ListingStatusActive      ListingStatus = "active"
 	ListingStatusRejected    ListingStatus = "rejected"
 	ListingStatusSold        ListingStatus = "sold"
+	ListingStatusReserved    ListingStatus = "reserved" // held for one buyer by a deposit; see TransactionTypeDeposit
 )
 
 // Listing represents a specific listing of a vehicle on the platform.
@@ -22,16 +23,36 @@ type Listing struct {
 	Description string        `json:"description,omitempty" gorm:"type:text"`
 	Price       float64       `json:"price" gorm:"not null;comment:Asking price in local currency"`
 	Location    string        `json:"location" gorm:"size:255;not null"`
+	Mileage     uint          `json:"mileage" gorm:"comment:Odometer reading recorded when the listing was created"`
+	VehicleSpecs VehicleSpecs `json:"vehicle_specs" gorm:"embedded;embeddedPrefix:vehicle_"` // Specs as submitted for this listing; never rewritten by later listings
 	Status      ListingStatus `json:"status" gorm:"default:pending_review;not null"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty" gorm:"index;comment:When the listing entered its current status"`
 	CreatedAt   time.Time     `json:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at"`
 
 	// Foreign Keys
 	SellerID  uuid.UUID `json:"seller_id" gorm:"type:uuid;not null"`
 	VehicleID uuid.UUID `json:"vehicle_id" gorm:"type:uuid;not null"`
+	CurrentInspectionID *uuid.UUID `json:"current_inspection_id,omitempty" gorm:"type:uuid;comment:Inspection whose outcome drives the listing status"`
+	CertificateID       *uuid.UUID `json:"certificate_id,omitempty" gorm:"type:uuid;comment:Signed inspection certificate backing an approved listing"`
+
+	// Why the listing was last rejected, shown to the seller until they resubmit
+	RejectionReasons []RejectionDetail `json:"rejection_reasons,omitempty" gorm:"type:jsonb;serializer:json"`
+	RejectionNote    string            `json:"rejection_note,omitempty" gorm:"type:text"`
+
+	// Review claim: the admin currently working the listing in the review queue, until the claim expires
+	ReviewClaimedByID    *uuid.UUID `json:"review_claimed_by_id,omitempty" gorm:"type:uuid"`
+	ReviewClaimedAt      *time.Time `json:"review_claimed_at,omitempty"`
+	ReviewClaimExpiresAt *time.Time `json:"review_claim_expires_at,omitempty"`
 
 	// Relationships
 	Seller    User     `json:"seller,omitempty" gorm:"foreignKey:SellerID"`
 	Vehicle   Vehicle  `json:"vehicle" gorm:"foreignKey:VehicleID"`      
 	Images    []Image  `json:"images,omitempty" gorm:"foreignKey:ListingID"`
-}
\ No newline at end of file
+}
+
+// ReviewClaimedByOther reports whether another admin holds an unexpired review claim on the listing.
+func (l *Listing) ReviewClaimedByOther(adminID uuid.UUID, now time.Time) bool {
+	return l.ReviewClaimedByID != nil && *l.ReviewClaimedByID != adminID &&
+		l.ReviewClaimExpiresAt != nil && l.ReviewClaimExpiresAt.After(now)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OfferStatus tracks a negotiation; an offer is active while pending or countered:
+//
+//	pending ⇄ countered → accepted
+//	   ↘ declined, withdrawn, expired ↙
+type OfferStatus string
+
+const (
+	OfferStatusPending   OfferStatus = "pending"   // the seller's turn to respond
+	OfferStatusCountered OfferStatus = "countered" // the buyer's turn to respond to the seller's counter-offer
+	OfferStatusAccepted  OfferStatus = "accepted"  // a pending sale was opened at the agreed amount
+	OfferStatusDeclined  OfferStatus = "declined"
+	OfferStatusWithdrawn OfferStatus = "withdrawn" // the buyer pulled out
+	OfferStatusExpired   OfferStatus = "expired"   // nobody responded in time
+)
+
+// Offer is a buyer's price negotiation with the seller of a listing. A buyer has at most one active offer per listing.
+type Offer struct {
+	ID            uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID     uuid.UUID   `json:"listing_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_offers_active_buyer_listing,where:status = 'pending' OR status = 'countered'"`
+	BuyerID       uuid.UUID   `json:"buyer_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_offers_active_buyer_listing,where:status = 'pending' OR status = 'countered'"`
+	SellerID      uuid.UUID   `json:"seller_id" gorm:"type:uuid;not null;index"`
+	Amount        float64     `json:"amount" gorm:"not null;comment:Amount currently on the table"`
+	Status        OfferStatus `json:"status" gorm:"size:20;not null;index"`
+	ExpiresAt     time.Time   `json:"expires_at" gorm:"not null;index;comment:When the party whose turn it is loses the chance to respond"`
+	TransactionID *uuid.UUID  `json:"transaction_id,omitempty" gorm:"type:uuid;comment:Sale opened when the offer was accepted"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	Listing Listing      `json:"listing,omitempty" gorm:"foreignKey:ListingID"`
+	Buyer   User         `json:"buyer,omitempty" gorm:"foreignKey:BuyerID"`
+	Events  []OfferEvent `json:"events,omitempty" gorm:"foreignKey:OfferID"`
+}
+
+// HideSellerContactDetails blanks the seller's email and phone before an offer is shown to one of its parties
+func (o *Offer) HideSellerContactDetails() {
+	o.Listing.Seller.HideContactDetails()
+}
+
+// OfferAction is a step in a negotiation
+type OfferAction string
+
+const (
+	OfferActionOffered   OfferAction = "offered"
+	OfferActionCountered OfferAction = "countered"
+	OfferActionAccepted  OfferAction = "accepted"
+	OfferActionDeclined  OfferAction = "declined"
+	OfferActionWithdrawn OfferAction = "withdrawn"
+	OfferActionExpired   OfferAction = "expired"
+)
+
+// OfferEvent records who did what in a negotiation and at which amount
+type OfferEvent struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OfferID   uuid.UUID   `json:"offer_id" gorm:"type:uuid;not null;index"`
+	ActorID   *uuid.UUID  `json:"actor_id,omitempty" gorm:"type:uuid;comment:Null for automatic actions"`
+	Action    OfferAction `json:"action" gorm:"size:20;not null"`
+	Amount    float64     `json:"amount" gorm:"not null"`
+	Message   string      `json:"message,omitempty" gorm:"type:text"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentWebhookStatus string
+
+const (
+	PaymentWebhookStatusReceived  PaymentWebhookStatus = "received"
+	PaymentWebhookStatusProcessed PaymentWebhookStatus = "processed"
+	PaymentWebhookStatusIgnored   PaymentWebhookStatus = "ignored"  // verified, but nothing to apply
+	PaymentWebhookStatusFailed    PaymentWebhookStatus = "failed"   // could not be applied; kept for replay
+	PaymentWebhookStatusRefunded  PaymentWebhookStatus = "refunded" // a succeeded payment that can never be applied; its money is sent back
+)
+
+// PaymentWebhookEvent is a verified payment provider notification kept in the inbox exactly as received.
+// Events are unique per provider and event ID, so redelivered notifications are stored and applied once.
+type PaymentWebhookEvent struct {
+	ID                uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Provider          string               `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_payment_webhook_events_provider_event"`
+	EventID           string               `json:"event_id" gorm:"size:191;not null;uniqueIndex:idx_payment_webhook_events_provider_event"`
+	EventType         string               `json:"event_type" gorm:"size:100"`
+	ProviderReference string               `json:"provider_reference" gorm:"size:191;index"`
+	PaymentStatus     string               `json:"payment_status" gorm:"size:30;comment:Provider-neutral payment status the event reports"`
+	Amount            int64                `json:"amount" gorm:"comment:Amount the event reports, in minor units"`
+	Currency          string               `json:"currency" gorm:"size:3;comment:Currency the event reports Amount in"`
+	Payload           string               `json:"payload" gorm:"type:text;not null"`
+	Status            PaymentWebhookStatus `json:"status" gorm:"size:20;default:received;not null;index"`
+	Error             string               `json:"error,omitempty" gorm:"type:text"`
+	Attempts          int                  `json:"attempts" gorm:"default:0;not null"`
+	TransactionID     *uuid.UUID           `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+	ProcessedAt       *time.Time           `json:"processed_at,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayoutAccount is the bank account a seller is paid out to. The account number is only stored encrypted;
+// the last four digits are kept in the clear so it can be recognised.
+type PayoutAccount struct {
+	ID                     uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	SellerID               uuid.UUID `json:"seller_id" gorm:"type:uuid;not null;uniqueIndex"`
+	BankName               string    `json:"bank_name" gorm:"size:255;not null"`
+	BankCode               string    `json:"bank_code" gorm:"size:10;not null"`
+	AccountName            string    `json:"account_name" gorm:"size:255;not null"`
+	AccountNumberEncrypted string    `json:"-" gorm:"type:text;not null"`
+	AccountNumberLast4     string    `json:"account_number_last4" gorm:"size:4;not null"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// PayoutBatchStatus tracks a batch from creation to the bank:
+//
+//	pending_approval → approved → settled
+//	       ↘ cancelled  ↙
+type PayoutBatchStatus string
+
+const (
+	PayoutBatchStatusPendingApproval PayoutBatchStatus = "pending_approval"
+	PayoutBatchStatusApproved        PayoutBatchStatus = "approved"
+	PayoutBatchStatusSettled         PayoutBatchStatus = "settled"
+	PayoutBatchStatusCancelled       PayoutBatchStatus = "cancelled"
+)
+
+// PayoutBatch groups the payouts an admin approves, exports to the bank and settles together
+type PayoutBatch struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Status        PayoutBatchStatus `json:"status" gorm:"size:30;not null;index"`
+	Currency      string            `json:"currency" gorm:"size:3;not null"`
+	TotalAmount   float64           `json:"total_amount" gorm:"not null"`
+	PayoutCount   int               `json:"payout_count" gorm:"not null"`
+	CreatedByID   uuid.UUID         `json:"created_by_id" gorm:"type:uuid;not null"`
+	ApprovedByID  *uuid.UUID        `json:"approved_by_id,omitempty" gorm:"type:uuid"`
+	ApprovedAt    *time.Time        `json:"approved_at,omitempty"`
+	SettledByID   *uuid.UUID        `json:"settled_by_id,omitempty" gorm:"type:uuid"`
+	SettledAt     *time.Time        `json:"settled_at,omitempty"`
+	BankReference string            `json:"bank_reference,omitempty" gorm:"size:255;comment:Reference the bank gave the upload"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+
+	Payouts []Payout `json:"payouts,omitempty" gorm:"foreignKey:BatchID"`
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending   PayoutStatus = "pending"   // in a batch awaiting approval or settlement
+	PayoutStatusSettled   PayoutStatus = "settled"   // the bank paid the seller
+	PayoutStatusFailed    PayoutStatus = "failed"    // the bank rejected it; its sales can be paid out again
+	PayoutStatusCancelled PayoutStatus = "cancelled" // its batch was cancelled; its sales can be paid out again
+)
+
+// Payout is one transfer to a seller, funded by the completed sales in its items.
+// The bank details are copied from the seller's payout account when the batch is created.
+type Payout struct {
+	ID                     uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BatchID                uuid.UUID    `json:"batch_id" gorm:"type:uuid;not null;index"`
+	SellerID               uuid.UUID    `json:"seller_id" gorm:"type:uuid;not null;index"`
+	Reference              string       `json:"reference" gorm:"size:50;not null;uniqueIndex;comment:Narration reference sent to the bank"`
+	Amount                 float64      `json:"amount" gorm:"not null"`
+	Status                 PayoutStatus `json:"status" gorm:"size:20;not null;index"`
+	BankName               string       `json:"bank_name" gorm:"size:255;not null"`
+	BankCode               string       `json:"bank_code" gorm:"size:10;not null"`
+	AccountName            string       `json:"account_name" gorm:"size:255;not null"`
+	AccountNumberEncrypted string       `json:"-" gorm:"type:text;not null"`
+	AccountNumberLast4     string       `json:"account_number_last4" gorm:"size:4;not null"`
+	FailureReason          string       `json:"failure_reason,omitempty" gorm:"type:text"`
+	SettledAt              *time.Time   `json:"settled_at,omitempty"`
+	CreatedAt              time.Time    `json:"created_at"`
+	UpdatedAt              time.Time    `json:"updated_at"`
+
+	Seller User         `json:"seller,omitempty" gorm:"foreignKey:SellerID"`
+	Items  []PayoutItem `json:"items,omitempty" gorm:"foreignKey:PayoutID"`
+}
+
+// PayoutItem is the share of a payout one completed sale funded. A sale is in at most one open or settled payout;
+// items of failed or cancelled payouts are released so the sale can be paid out again.
+type PayoutItem struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PayoutID      uuid.UUID `json:"payout_id" gorm:"type:uuid;not null;index"`
+	TransactionID uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;uniqueIndex:idx_payout_items_open_transaction,where:released = false"`
+	Amount        float64   `json:"amount" gorm:"not null;comment:Sale amount less refunds and commission"`
+	Released      bool      `json:"released" gorm:"default:false;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SellerBalance is what a seller has earned and not yet been paid
+type SellerBalance struct {
+	SellerID      uuid.UUID      `json:"seller_id"`
+	Currency      string         `json:"currency"`
+	LedgerBalance float64        `json:"ledger_balance"` // seller_payable balance in the ledger
+	PendingPayout float64        `json:"pending_payout"` // in batches not yet settled
+	Available     float64        `json:"available"`      // completed sales not in any payout yet
+	Unpaid        []*PayableSale `json:"unpaid_sales"`
+}
+
+// PayableSale is a completed sale's amount owed to its seller. The reservation deposit credited to a sale is paid
+// out as an item of its own, with the sale's commission charged on the sale.
+type PayableSale struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	Type          TransactionType `json:"type"`
+	ListingID     uuid.UUID       `json:"listing_id"`
+	CompletedAt   time.Time       `json:"completed_at"`
+	SaleAmount    float64         `json:"sale_amount"`
+	Refunded      float64         `json:"refunded"`
+	Fees          float64         `json:"fees"`
+	Amount        float64         `json:"amount"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefundStatus tracks a refund from the decision to return money to the provider accepting it:
+//
+//	pending → issued
+//	   ↘ failed (declined, or out of attempts; an admin retries it)
+type RefundStatus string
+
+const (
+	RefundStatusPending RefundStatus = "pending" // decided and recorded; the provider has not accepted it yet
+	RefundStatusIssued  RefundStatus = "issued"  // the provider accepted it under our idempotency key
+	RefundStatusFailed  RefundStatus = "failed"  // declined or still unconfirmed after every attempt
+)
+
+// Refund is money owed back to a buyer through the payment provider. It is recorded in the same database
+// transaction as the decision to refund and only sent to the provider after that commits, under IdempotencyKey,
+// so a retried request can never refund twice.
+type Refund struct {
+	ID                uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID     uuid.UUID    `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	WebhookEventID    *uuid.UUID   `json:"webhook_event_id,omitempty" gorm:"type:uuid;index;comment:Payment webhook whose payment could not be applied"`
+	Provider          string       `json:"provider" gorm:"size:50;not null"`
+	ProviderReference string       `json:"provider_reference" gorm:"size:191;not null"`
+	Amount            int64        `json:"amount" gorm:"not null;comment:Amount to refund, in minor units"`
+	Reason            string       `json:"reason" gorm:"type:text"`
+	IdempotencyKey    string       `json:"idempotency_key" gorm:"size:100;not null;uniqueIndex"`
+	Status            RefundStatus `json:"status" gorm:"size:20;default:pending;not null;index"`
+	RefundReference   string       `json:"refund_reference,omitempty" gorm:"size:191;comment:The provider's id of the refund"`
+	Error             string       `json:"error,omitempty" gorm:"type:text"`
+	Attempts          int          `json:"attempts" gorm:"default:0;not null"`
+	IssuedAt          *time.Time   `json:"issued_at,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RejectionReason is an admin-managed code explaining why a listing failed review.
+// Codes are never deleted, only deactivated, so past rejections keep pointing at a known reason.
+type RejectionReason struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Code        string    `json:"code" gorm:"size:64;uniqueIndex;not null"`
+	Label       string    `json:"label" gorm:"size:255;not null;comment:Seller-facing explanation"`
+	Description string    `json:"description,omitempty" gorm:"type:text;comment:Guidance on what the seller should fix"`
+	Active      bool      `json:"active" gorm:"default:true;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RejectionDetail is a rejection reason as recorded on an inspection or listing at the time of rejection.
+type RejectionDetail struct {
+	Code        string `json:"code"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListingResubmission records a seller sending a rejected listing back for review and what they changed.
+type ListingResubmission struct {
+	ID               uuid.UUID                     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID        uuid.UUID                     `json:"listing_id" gorm:"type:uuid;index;not null"`
+	SellerID         uuid.UUID                     `json:"seller_id" gorm:"type:uuid;not null"`
+	Note             string                        `json:"note" gorm:"type:text;not null;comment:Seller's explanation of what was fixed"`
+	Changes          map[string]ListingFieldChange `json:"changes" gorm:"type:jsonb;serializer:json"`
+	RejectionReasons []RejectionDetail             `json:"rejection_reasons,omitempty" gorm:"type:jsonb;serializer:json;comment:Reasons the listing was rejected with, as addressed by this resubmission"`
+	RejectionNote    string                        `json:"rejection_note,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time                     `json:"created_at"`
+}
+
+// ListingFieldChange is the before and after value of one field changed on resubmission.
+type ListingFieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// DefaultRejectionReasons seeds the code list on first migration; admins manage it afterwards.
+var DefaultRejectionReasons = []RejectionReason{
+	{Code: "insufficient_photos", Label: "Photos are missing, blurry or do not show the whole vehicle", Description: "Upload clear photos of the exterior from all sides, the interior, the dashboard with the odometer and the engine bay."},
+	{Code: "vin_mismatch", Label: "The VIN does not match the vehicle or its documents", Description: "Check the VIN on the chassis and registration documents and correct the listing."},
+	{Code: "mileage_discrepancy", Label: "The mileage does not match the odometer or vehicle history", Description: "Update the mileage to the current odometer reading."},
+	{Code: "specs_incorrect", Label: "Vehicle details (make, model, year, engine) are incorrect", Description: "Correct the vehicle specifications to match the vehicle."},
+	{Code: "missing_documents", Label: "Ownership or registration documents are missing", Description: "Make the vehicle's ownership and registration documents available for inspection."},
+	{Code: "misleading_description", Label: "The description or price is misleading", Description: "Describe the vehicle's condition accurately, including known defects."},
+	{Code: "safety_defect", Label: "The vehicle has a safety defect that must be repaired", Description: "Repair the defects noted by the inspector and resubmit for a new inspection."},
+	{Code: "vehicle_unavailable", Label: "The vehicle was not available at the inspection appointment", Description: "Book a new inspection slot when the vehicle is available."},
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListingStatusChange is an audit record of a listing moving between statuses.
+type ListingStatusChange struct {
+	ID           uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID    uuid.UUID     `json:"listing_id" gorm:"type:uuid;index;not null"`
+	FromStatus   ListingStatus `json:"from_status"`
+	ToStatus     ListingStatus `json:"to_status" gorm:"not null"`
+	InspectionID *uuid.UUID    `json:"inspection_id,omitempty" gorm:"type:uuid;comment:Inspection whose decision caused the change, if any"`
+	ChangedByID  uuid.UUID     `json:"changed_by_id" gorm:"type:uuid;not null"`
+	Reason       string        `json:"reason,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// ReviewQueue is the admin view of listings waiting for a review decision, oldest first by default.
+type ReviewQueue struct {
+	SLAHours      int                `json:"sla_hours"`
+	Total         int                `json:"total"`
+	BreachedCount int                `json:"breached_count"`
+	Items         []*ReviewQueueItem `json:"items"`
+}
+
+// ReviewQueueItem is one pending listing with its time in state and SLA standing.
+type ReviewQueueItem struct {
+	ListingID         uuid.UUID         `json:"listing_id"`
+	Title             string            `json:"title"`
+	SellerID          uuid.UUID         `json:"seller_id"`
+	SellerName        string            `json:"seller_name"`
+	Status            ListingStatus     `json:"status"`
+	PendingSince      time.Time         `json:"pending_since"`
+	HoursInState      float64           `json:"hours_in_state"`
+	SLADeadline       time.Time         `json:"sla_deadline"`
+	SLABreached       bool              `json:"sla_breached"`
+	ClaimedByID       *uuid.UUID        `json:"claimed_by_id,omitempty"`
+	ClaimExpiresAt    *time.Time        `json:"claim_expires_at,omitempty"`
+	CurrentInspection *ReviewInspection `json:"current_inspection,omitempty"`
+}
+
+// ReviewInspection summarises the current inspection of a queued listing.
+type ReviewInspection struct {
+	ID                 uuid.UUID        `json:"id"`
+	Status             InspectionStatus `json:"status"`
+	InspectorID        uuid.UUID        `json:"inspector_id"`
+	ConditionRating    int              `json:"condition_rating,omitempty"`
+	ChecklistSubmitted bool             `json:"checklist_submitted"`
+	CreatedAt          time.Time        `json:"created_at"`
+	HoursInState       float64          `json:"hours_in_state"`
+}
+
+// ReviewDecisionResult reports the outcome of one listing in a bulk review decision.
+type ReviewDecisionResult struct {
+	ListingID    uuid.UUID        `json:"listing_id"`
+	InspectionID *uuid.UUID       `json:"inspection_id,omitempty"`
+	Status       InspectionStatus `json:"status,omitempty"`
+	Succeeded    bool             `json:"succeeded"`
+	Error        string           `json:"error,omitempty"`
+}
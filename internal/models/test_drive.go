@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestDriveStatus tracks a test-drive appointment; a test drive is open while requested, rescheduled or confirmed:
+//
+//	requested ⇄ rescheduled → confirmed → completed | no_show
+//	     ↘ declined, cancelled, expired ↙
+//
+// A confirmed test drive can be rescheduled by either party, which sends it back to the other party to agree.
+type TestDriveStatus string
+
+const (
+	TestDriveStatusRequested   TestDriveStatus = "requested"   // the seller's turn to pick a time in the buyer's windows or propose another
+	TestDriveStatusRescheduled TestDriveStatus = "rescheduled" // the buyer's turn to confirm the time the seller proposed
+	TestDriveStatusConfirmed   TestDriveStatus = "confirmed"
+	TestDriveStatusCompleted   TestDriveStatus = "completed"
+	TestDriveStatusNoShow      TestDriveStatus = "no_show" // a party did not turn up; see NoShowUserID
+	TestDriveStatusDeclined    TestDriveStatus = "declined"
+	TestDriveStatusCancelled   TestDriveStatus = "cancelled"
+	TestDriveStatusExpired     TestDriveStatus = "expired" // no time was agreed before the proposed ones passed
+)
+
+// TestDrive is a buyer's appointment to drive a listed vehicle with its seller. A buyer has at most one open test
+// drive per listing.
+type TestDrive struct {
+	ID              uuid.UUID       `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListingID       uuid.UUID       `json:"listing_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_test_drives_open_buyer_listing,where:status = 'requested' OR status = 'rescheduled' OR status = 'confirmed'"`
+	BuyerID         uuid.UUID       `json:"buyer_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_test_drives_open_buyer_listing,where:status = 'requested' OR status = 'rescheduled' OR status = 'confirmed'"`
+	SellerID        uuid.UUID       `json:"seller_id" gorm:"type:uuid;not null;index"`
+	Status          TestDriveStatus `json:"status" gorm:"size:20;not null;index"`
+	Note            string          `json:"note,omitempty" gorm:"type:text;comment:Buyer's note with the request"`
+	ScheduledFor    *time.Time      `json:"scheduled_for,omitempty" gorm:"index;comment:Agreed start, or the start the seller proposed while rescheduled"`
+	Location        string          `json:"location,omitempty" gorm:"size:255;comment:Where the buyer and seller meet"`
+	ExpiresAt       time.Time       `json:"expires_at" gorm:"not null;index;comment:When an unconfirmed request lapses because its proposed times passed"`
+	RescheduleCount int             `json:"reschedule_count" gorm:"default:0;not null"`
+	Sequence        int             `json:"-" gorm:"default:0;not null;comment:Revision of the calendar invite, raised whenever the confirmed time changes"`
+	ReminderSentAt  *time.Time      `json:"reminder_sent_at,omitempty"`
+	NoShowUserID    *uuid.UUID      `json:"no_show_user_id,omitempty" gorm:"type:uuid;index;comment:Party who did not turn up"`
+	ReportedByID    *uuid.UUID      `json:"reported_by_id,omitempty" gorm:"type:uuid;comment:Party who reported how the test drive went"`
+	CloseReason     string          `json:"close_reason,omitempty" gorm:"type:text"`
+	ClosedAt        *time.Time      `json:"closed_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+
+	// BuyerNoShows is how many confirmed test drives the buyer missed, shown to the seller deciding on a request
+	BuyerNoShows int64 `json:"buyer_no_shows" gorm:"-"`
+
+	Listing Listing           `json:"listing,omitempty" gorm:"foreignKey:ListingID"`
+	Buyer   User              `json:"buyer,omitempty" gorm:"foreignKey:BuyerID"`
+	Windows []TestDriveWindow `json:"windows,omitempty" gorm:"foreignKey:TestDriveID"`
+}
+
+// HideSellerContactDetails blanks the seller's email and phone before a test drive is shown to one of its parties
+func (d *TestDrive) HideSellerContactDetails() {
+	d.Listing.Seller.HideContactDetails()
+}
+
+// TestDriveWindow is a stretch of time the buyer proposed for a test drive
+type TestDriveWindow struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TestDriveID uuid.UUID `json:"test_drive_id" gorm:"type:uuid;not null;index"`
+	StartsAt    time.Time `json:"starts_at" gorm:"not null"`
+	EndsAt      time.Time `json:"ends_at" gorm:"not null"`
+}
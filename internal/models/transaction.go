@@ -15,16 +15,41 @@ const (
 	TransactionStatusCancelled  TransactionStatus = "cancelled"
 )
 
-// Transaction represents a completed sale/purchase of a vehicle.
+type TransactionType string
+
+const (
+	TransactionTypeSale          TransactionType = "sale"
+	TransactionTypeInspectionFee TransactionType = "inspection_fee" // paid by a buyer for a pre-purchase inspection
+	TransactionTypeDeposit       TransactionType = "deposit"        // paid into escrow by a buyer to reserve a listing
+)
+
+// Transaction represents a sale/purchase of a vehicle or a fee charged around it.
+// A listing has at most one pending or completed sale at a time, but may carry any number of fee transactions;
+// a failed or cancelled sale frees the listing for another purchase. A reserved listing has one pending deposit.
 type Transaction struct {
 	ID 				uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	ListingID       uuid.UUID `json:"listing_id" gorm:"type:uuid;uniqueIndex;not null"`
+	ListingID       uuid.UUID `json:"listing_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_transactions_open_sale_listing,where:type = 'sale' AND status <> 'failed' AND status <> 'cancelled'"`
+	Type            TransactionType `json:"type" gorm:"size:30;default:sale;not null"`
+	InspectionID    *uuid.UUID `json:"inspection_id,omitempty" gorm:"type:uuid;comment:Pre-purchase inspection an inspection fee pays for"`
 	BuyerID         uuid.UUID `json:"buyer_id" gorm:"type:uuid;not null"`
 	SellerID        uuid.UUID `json:"seller_id" gorm:"type:uuid;not null"` // Denormalized for easy querying
 	Amount          float64   `json:"amount" gorm:"not null;comment:Final sale price"`
-	PaymentMethod   string    `json:"payment_method,omitempty" gorm:"size:100"`
+	PaymentMethod   string    `json:"payment_method,omitempty" gorm:"size:100;comment:Channel requested by the buyer, then the one the provider captured with"`
+	PaymentProvider   string  `json:"payment_provider,omitempty" gorm:"size:50"`
+	ProviderReference string  `json:"provider_reference,omitempty" gorm:"size:191;index;comment:Payment reference at the provider"`
+	CheckoutURL       string  `json:"checkout_url,omitempty" gorm:"type:text;comment:Where the buyer pays"`
 	Status          TransactionStatus `json:"status" gorm:"default:pending;not null"`
 	TransactionDate time.Time `json:"transaction_date,omitempty"`
+
+	// Escrow, for sales, deposits and inspection fees that are charged; see EscrowStatus
+	EscrowStatus   EscrowStatus `json:"escrow_status,omitempty" gorm:"size:30;index"`
+	EscrowDeadline *time.Time   `json:"escrow_deadline,omitempty" gorm:"index;comment:When the current escrow step expires and its automatic action runs"`
+	FundsHeldAt    *time.Time   `json:"funds_held_at,omitempty"`
+	HandedOverAt   *time.Time   `json:"handed_over_at,omitempty"`
+	DisputedAt     *time.Time   `json:"disputed_at,omitempty"`
+	DisputeReason  string       `json:"dispute_reason,omitempty" gorm:"type:text"`
+	ReleasedAt     *time.Time   `json:"released_at,omitempty"`
+	RefundedAmount float64      `json:"refunded_amount,omitempty" gorm:"default:0;not null"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
@@ -32,4 +57,13 @@ type Transaction struct {
 	Listing Listing `json:"listing" gorm:"foreignKey:ListingID"`
 	Buyer   User    `json:"buyer" gorm:"foreignKey:BuyerID"`
 	Seller  User    `json:"seller" gorm:"foreignKey:SellerID"` // Denormalized for easy querying
-}
\ No newline at end of file
+	Fees    []TransactionFee `json:"fees,omitempty" gorm:"foreignKey:TransactionID"`
+}
+// HideSellerContactDetails blanks the seller's email and phone until the buyer's money is held in escrow; until then
+// the parties talk through conversations, and a fee transaction never shows them
+func (t *Transaction) HideSellerContactDetails() {
+	if t.FundsHeldAt == nil {
+		t.Seller.HideContactDetails()
+	}
+	t.Listing.Seller.HideContactDetails()
+}
@@ -11,9 +11,9 @@ import (
 // User represents the user profile in the system.
 type User struct {
 	ID 		  uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
+	Email     string    `json:"email,omitempty" gorm:"uniqueIndex;not null"`
 	Name      string    `json:"name" gorm:"size:255;not null"`
-	Phone     string    `json:"phone" gorm:"size:20"`
+	Phone     string    `json:"phone,omitempty" gorm:"size:20"`
 	Password  string    `json:"-" gorm:"size:255;not null"`
 	Role      types.Role      `json:"role" gorm:"default:buyer;not null"`
 	CreatedAt time.Time `json:"created_at"`
@@ -35,6 +35,12 @@ func (u *User) SetPassword(password string) error {
 	return nil
 }
 
+// HideContactDetails blanks the email and phone of a user shown publicly; buyers reach sellers through conversations
+func (u *User) HideContactDetails() {
+	u.Email = ""
+	u.Phone = ""
+}
+
 func (u *User) VerifyPassword(password string) bool {
 	return pkg.CheckPassword(u.Password, password)
 }
\ No newline at end of file
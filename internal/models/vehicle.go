@@ -23,6 +23,91 @@ type Vehicle struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Seller responsible for the canonical record; only they may correct its specs
+	OwnerID *uuid.UUID `json:"owner_id,omitempty" gorm:"type:uuid;index"`
+
 	// Relationships
 	Listings []Listing `json:"-" gorm:"foreignKey:VehicleID"` // Omit from JSON
+}
+
+// VehicleSpecs is the snapshot of a vehicle's specs as submitted for a single listing.
+// Mileage is kept on the listing itself.
+type VehicleSpecs struct {
+	Make         string `json:"make,omitempty" gorm:"size:100"`
+	Model        string `json:"model,omitempty" gorm:"size:100"`
+	Year         int    `json:"year,omitempty"`
+	EngineSize   string `json:"engine_size,omitempty" gorm:"size:20"`
+	FuelType     string `json:"fuel_type,omitempty" gorm:"size:50"`
+	Transmission string `json:"transmission,omitempty" gorm:"size:50"`
+	BodyType     string `json:"body_type,omitempty" gorm:"size:50"`
+	Color        string `json:"color,omitempty" gorm:"size:50"`
+	Condition    string `json:"condition,omitempty" gorm:"size:20"`
+}
+
+// Specs returns the current specs of the vehicle record.
+func (v *Vehicle) Specs() VehicleSpecs {
+	return VehicleSpecs{
+		Make:         v.Make,
+		Model:        v.Model,
+		Year:         v.Year,
+		EngineSize:   v.EngineSize,
+		FuelType:     v.FuelType,
+		Transmission: v.Transmission,
+		BodyType:     v.BodyType,
+		Color:        v.Color,
+		Condition:    v.Condition,
+	}
+}
+
+// ApplySpecs overwrites the vehicle's specs with every non-empty value in specs.
+func (v *Vehicle) ApplySpecs(specs VehicleSpecs) {
+	v.Make = mergeSpec(v.Make, specs.Make)
+	v.Model = mergeSpec(v.Model, specs.Model)
+	if specs.Year != 0 {
+		v.Year = specs.Year
+	}
+	v.EngineSize = mergeSpec(v.EngineSize, specs.EngineSize)
+	v.FuelType = mergeSpec(v.FuelType, specs.FuelType)
+	v.Transmission = mergeSpec(v.Transmission, specs.Transmission)
+	v.BodyType = mergeSpec(v.BodyType, specs.BodyType)
+	v.Color = mergeSpec(v.Color, specs.Color)
+	v.Condition = mergeSpec(v.Condition, specs.Condition)
+}
+
+// Merge returns a copy of s with every non-empty value in patch applied.
+func (s VehicleSpecs) Merge(patch VehicleSpecs) VehicleSpecs {
+	vehicle := &Vehicle{}
+	vehicle.ApplySpecs(s)
+	vehicle.ApplySpecs(patch)
+	return vehicle.Specs()
+}
+
+// Changes lists the specs that patch would change, keyed by their JSON name; empty patch fields change nothing.
+func (s VehicleSpecs) Changes(patch VehicleSpecs) map[string]ListingFieldChange {
+	changes := map[string]ListingFieldChange{}
+	addString := func(name, from, to string) {
+		if to != "" && to != from {
+			changes[name] = ListingFieldChange{From: from, To: to}
+		}
+	}
+
+	addString("make", s.Make, patch.Make)
+	addString("model", s.Model, patch.Model)
+	if patch.Year != 0 && patch.Year != s.Year {
+		changes["year"] = ListingFieldChange{From: s.Year, To: patch.Year}
+	}
+	addString("engine_size", s.EngineSize, patch.EngineSize)
+	addString("fuel_type", s.FuelType, patch.FuelType)
+	addString("transmission", s.Transmission, patch.Transmission)
+	addString("body_type", s.BodyType, patch.BodyType)
+	addString("color", s.Color, patch.Color)
+	addString("condition", s.Condition, patch.Condition)
+	return changes
+}
+
+func mergeSpec(current, update string) string {
+	if update == "" {
+		return current
+	}
+	return update
 }
\ No newline at end of file
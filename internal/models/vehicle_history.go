@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VehicleHistory is the timeline of every listing recorded against a single vehicle.
+type VehicleHistory struct {
+	Vehicle          Vehicle               `json:"vehicle"`
+	Entries          []VehicleHistoryEntry `json:"entries"`
+	RollbackDetected bool                  `json:"odometer_rollback_detected"`
+}
+
+// VehicleHistoryEntry summarises one listing of the vehicle, oldest first.
+type VehicleHistoryEntry struct {
+	ListingID        uuid.UUID                 `json:"listing_id"`
+	Title            string                    `json:"title"`
+	Price            float64                   `json:"price"`
+	Mileage          uint                      `json:"mileage"`
+	VehicleSpecs     VehicleSpecs              `json:"vehicle_specs"`
+	Status           ListingStatus             `json:"status"`
+	ListedAt         time.Time                 `json:"listed_at"`
+	Inspection       *VehicleHistoryInspection `json:"inspection,omitempty"`
+	Sale             *VehicleHistorySale       `json:"sale,omitempty"`
+	OdometerRollback bool                      `json:"odometer_rollback"`
+}
+
+// VehicleHistoryInspection is the inspection outcome recorded for a listing.
+type VehicleHistoryInspection struct {
+	Status          InspectionStatus `json:"status"`
+	ConditionRating int              `json:"condition_rating,omitempty"`
+	InspectionDate  time.Time        `json:"inspection_date,omitempty"`
+}
+
+// VehicleHistorySale is the completed sale recorded for a listing.
+type VehicleHistorySale struct {
+	Amount          float64   `json:"amount"`
+	TransactionDate time.Time `json:"transaction_date,omitempty"`
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+)
+
+type CertificateRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, certificate *models.InspectionCertificate) error
+	GetByID(id string) (*models.InspectionCertificate, error)
+	GetValidByInspectionID(inspectionID uuid.UUID) (*models.InspectionCertificate, error)
+	GetValidByInspectionIDWithTx(tx *gorm.DB, inspectionID uuid.UUID) (*models.InspectionCertificate, error)
+	RevokeByListingIDWithTx(tx *gorm.DB, listingID uuid.UUID, keepInspectionID uuid.UUID, reason string) error
+}
+
+type CertificateRepository struct {
+	DB *gorm.DB
+}
+
+func NewCertificateRepository(db *gorm.DB) *CertificateRepository {
+	return &CertificateRepository{DB: db}
+}
+
+// CreateWithTx stores a newly signed certificate within an ongoing transaction
+func (r *CertificateRepository) CreateWithTx(tx *gorm.DB, certificate *models.InspectionCertificate) error {
+	if err := tx.Create(certificate).Error; err != nil {
+		return fmt.Errorf("failed to create inspection certificate: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a certificate, valid or revoked
+func (r *CertificateRepository) GetByID(id string) (*models.InspectionCertificate, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := &models.InspectionCertificate{}
+	if err := r.DB.First(certificate, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("certificate with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get inspection certificate: %w", err)
+	}
+	return certificate, nil
+}
+
+// GetValidByInspectionID retrieves the unrevoked certificate of an inspection; gorm.ErrRecordNotFound is returned when there is none
+func (r *CertificateRepository) GetValidByInspectionID(inspectionID uuid.UUID) (*models.InspectionCertificate, error) {
+	return r.GetValidByInspectionIDWithTx(r.DB, inspectionID)
+}
+
+// GetValidByInspectionIDWithTx is GetValidByInspectionID within an ongoing transaction
+func (r *CertificateRepository) GetValidByInspectionIDWithTx(tx *gorm.DB, inspectionID uuid.UUID) (*models.InspectionCertificate, error) {
+	certificate := &models.InspectionCertificate{}
+
+	err := tx.Where("inspection_id = ? AND revoked_at IS NULL", inspectionID).Order("issued_at DESC").First(certificate).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection certificate: %w", err)
+	}
+	return certificate, nil
+}
+
+// RevokeByListingIDWithTx revokes every unrevoked certificate of a listing except those of keepInspectionID
+func (r *CertificateRepository) RevokeByListingIDWithTx(tx *gorm.DB, listingID uuid.UUID, keepInspectionID uuid.UUID, reason string) error {
+	err := tx.Model(&models.InspectionCertificate{}).
+		Where("listing_id = ? AND inspection_id <> ? AND revoked_at IS NULL", listingID, keepInspectionID).
+		Updates(map[string]any{"revoked_at": time.Now(), "revocation_reason": reason}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke inspection certificates: %w", err)
+	}
+	return nil
+}
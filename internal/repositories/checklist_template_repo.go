@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+)
+
+type ChecklistTemplateRepositoryInterface interface {
+	Create(template *models.ChecklistTemplate) error
+	GetByID(id string) (*models.ChecklistTemplate, error)
+	GetAll() ([]*models.ChecklistTemplate, error)
+	GetActive() (*models.ChecklistTemplate, error)
+	Activate(id string) error
+}
+
+type ChecklistTemplateRepository struct {
+	DB *gorm.DB
+}
+
+func NewChecklistTemplateRepository(db *gorm.DB) *ChecklistTemplateRepository {
+	return &ChecklistTemplateRepository{DB: db}
+}
+
+// Create stores a template as the next version of its name
+func (r *ChecklistTemplateRepository) Create(template *models.ChecklistTemplate) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var latestVersion int
+		if err := tx.Model(&models.ChecklistTemplate{}).Where("name = ?", template.Name).Select("COALESCE(MAX(version), 0)").Scan(&latestVersion).Error; err != nil {
+			return fmt.Errorf("failed to get latest checklist template version: %w", err)
+		}
+
+		template.Version = latestVersion + 1
+		template.Active = false
+
+		if err := tx.Create(template).Error; err != nil {
+			return fmt.Errorf("failed to create checklist template: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByID retrieves a template version by its ID
+func (r *ChecklistTemplateRepository) GetByID(id string) (*models.ChecklistTemplate, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.ChecklistTemplate{}
+	if err := r.DB.First(template, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("checklist template with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get checklist template: %w", err)
+	}
+	return template, nil
+}
+
+// GetAll retrieves every template version, newest first
+func (r *ChecklistTemplateRepository) GetAll() ([]*models.ChecklistTemplate, error) {
+	templates := []*models.ChecklistTemplate{}
+
+	if err := r.DB.Order("name ASC, version DESC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get checklist templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetActive retrieves the template version in force; gorm.ErrRecordNotFound is returned when none is active
+func (r *ChecklistTemplateRepository) GetActive() (*models.ChecklistTemplate, error) {
+	template := &models.ChecklistTemplate{}
+
+	if err := r.DB.Where("active = ?", true).First(template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get active checklist template: %w", err)
+	}
+	return template, nil
+}
+
+// Activate puts a template version in force, retiring whichever was active before
+func (r *ChecklistTemplateRepository) Activate(id string) error {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ChecklistTemplate{}).Where("active = ?", true).Update("active", false).Error; err != nil {
+			return fmt.Errorf("failed to retire active checklist template: %w", err)
+		}
+
+		result := tx.Model(&models.ChecklistTemplate{}).Where("id = ?", parsedID).Update("active", true)
+		if result.Error != nil {
+			return fmt.Errorf("failed to activate checklist template: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("checklist template with id %s not found", id)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ConversationRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, conversation *models.Conversation) error
+	GetByListingAndBuyerForUpdateWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (*models.Conversation, error)
+	GetByID(id string) (*models.Conversation, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Conversation, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	GetByUserID(userID uuid.UUID) ([]*models.Conversation, error)
+	GetAll(listingID, userID *uuid.UUID) ([]*models.Conversation, error)
+	CreateMessageWithTx(tx *gorm.DB, message *models.Message) error
+	GetMessages(conversationID uuid.UUID) ([]models.Message, error)
+	MarkRead(conversationID, readerID uuid.UUID, readAt time.Time) error
+	CountUnreadWithTx(tx *gorm.DB, conversationID, readerID uuid.UUID) (int64, error)
+	CountUnreadByConversation(conversationIDs []uuid.UUID, readerID uuid.UUID) (map[uuid.UUID]int64, error)
+}
+
+type ConversationRepository struct {
+	DB *gorm.DB
+}
+
+func NewConversationRepository(db *gorm.DB) *ConversationRepository {
+	return &ConversationRepository{DB: db}
+}
+
+func (r *ConversationRepository) CreateWithTx(tx *gorm.DB, conversation *models.Conversation) error {
+	if err := tx.Create(conversation).Error; err != nil {
+		return fmt.Errorf("failed to create conversation: %w", err)
+	}
+	return nil
+}
+
+// GetByListingAndBuyerForUpdateWithTx retrieves and locks a buyer's conversation about a listing, or returns nil when
+// they have not started one
+func (r *ConversationRepository) GetByListingAndBuyerForUpdateWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (*models.Conversation, error) {
+	conversation := &models.Conversation{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND buyer_id = ?", listingID, buyerID).First(conversation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// GetByID retrieves a conversation with its listing and both parties
+func (r *ConversationRepository) GetByID(id string) (*models.Conversation, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := &models.Conversation{}
+	err = r.DB.Preload("Listing").Preload("Buyer").Preload("Seller").First(conversation, "id = ?", parsedID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("conversation with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// GetByIDForUpdateWithTx retrieves a conversation and locks its row until the database transaction ends
+func (r *ConversationRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Conversation, error) {
+	conversation := &models.Conversation{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(conversation, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("conversation with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// UpdateFieldsWithTx updates the given columns of a conversation, including ones being reset to NULL
+func (r *ConversationRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.Conversation{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update conversation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("conversation with id %s not found", id.String())
+	}
+	return nil
+}
+
+// GetByUserID retrieves the conversations a user takes part in as buyer or seller, most recently active first
+func (r *ConversationRepository) GetByUserID(userID uuid.UUID) ([]*models.Conversation, error) {
+	conversations := []*models.Conversation{}
+	err := r.DB.Preload("Listing").Preload("Buyer").Preload("Seller").
+		Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("last_message_at DESC").Find(&conversations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// GetAll retrieves conversations for admins, most recently active first, optionally about one listing or with one user
+func (r *ConversationRepository) GetAll(listingID, userID *uuid.UUID) ([]*models.Conversation, error) {
+	conversations := []*models.Conversation{}
+
+	query := r.DB.Preload("Listing").Preload("Buyer").Preload("Seller")
+	if listingID != nil {
+		query = query.Where("listing_id = ?", *listingID)
+	}
+	if userID != nil {
+		query = query.Where("buyer_id = ? OR seller_id = ?", *userID, *userID)
+	}
+	if err := query.Order("last_message_at DESC").Find(&conversations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// CreateMessageWithTx adds a message together with its attachments
+func (r *ConversationRepository) CreateMessageWithTx(tx *gorm.DB, message *models.Message) error {
+	if err := tx.Create(message).Error; err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+	return nil
+}
+
+// GetMessages retrieves the messages of a conversation with their attachments, oldest first
+func (r *ConversationRepository) GetMessages(conversationID uuid.UUID) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.DB.Preload("Attachments").Where("conversation_id = ?", conversationID).
+		Order("created_at ASC").Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkRead sets the read receipt on every message the reader received in a conversation and had not read yet
+func (r *ConversationRepository) MarkRead(conversationID, readerID uuid.UUID, readAt time.Time) error {
+	err := r.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conversationID, readerID).
+		Update("read_at", readAt).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	return nil
+}
+
+// CountUnreadWithTx counts the messages the reader received in a conversation and has not read yet
+func (r *ConversationRepository) CountUnreadWithTx(tx *gorm.DB, conversationID, readerID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conversationID, readerID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	return count, nil
+}
+
+// CountUnreadByConversation counts the unread messages the reader received in each of the given conversations
+func (r *ConversationRepository) CountUnreadByConversation(conversationIDs []uuid.UUID, readerID uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := map[uuid.UUID]int64{}
+	if len(conversationIDs) == 0 {
+		return counts, nil
+	}
+
+	rows := []struct {
+		ConversationID uuid.UUID
+		Count          int64
+	}{}
+	err := r.DB.Model(&models.Message{}).Select("conversation_id, COUNT(*) AS count").
+		Where("conversation_id IN ? AND sender_id <> ? AND read_at IS NULL", conversationIDs, readerID).
+		Group("conversation_id").Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+)
+
+type FeeRepositoryInterface interface {
+	CreateRule(rule *models.FeeRule) error
+	GetRules(kind models.FeeKind, includeInactive bool) ([]*models.FeeRule, error)
+	GetRuleByID(id string) (*models.FeeRule, error)
+	UpdateRule(rule *models.FeeRule) error
+	CreateWaiver(waiver *models.FeeWaiver) error
+	GetWaivers(kind models.FeeKind, includeInactive bool) ([]*models.FeeWaiver, error)
+	GetWaiverByID(id string) (*models.FeeWaiver, error)
+	UpdateWaiver(waiver *models.FeeWaiver) error
+	GetActiveRulesWithTx(tx *gorm.DB, kind models.FeeKind) ([]*models.FeeRule, error)
+	GetActiveWaiversWithTx(tx *gorm.DB, kind models.FeeKind, at time.Time) ([]*models.FeeWaiver, error)
+	CreateFeesWithTx(tx *gorm.DB, fees []*models.TransactionFee) error
+	CountFeesWithTx(tx *gorm.DB, transactionID uuid.UUID, kind models.FeeKind) (int64, error)
+}
+
+type FeeRepository struct {
+	DB *gorm.DB
+}
+
+func NewFeeRepository(db *gorm.DB) *FeeRepository {
+	return &FeeRepository{DB: db}
+}
+
+func (r *FeeRepository) CreateRule(rule *models.FeeRule) error {
+	if err := r.DB.Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create fee rule: %w", err)
+	}
+	return nil
+}
+
+// GetRules retrieves the fee schedule, optionally for one kind of fee; deactivated rules are only listed on request
+func (r *FeeRepository) GetRules(kind models.FeeKind, includeInactive bool) ([]*models.FeeRule, error) {
+	rules := []*models.FeeRule{}
+
+	query := r.DB.Order("kind ASC, created_at ASC")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fee rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *FeeRepository) GetRuleByID(id string) (*models.FeeRule, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &models.FeeRule{}
+	if err := r.DB.First(rule, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("fee rule with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get fee rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *FeeRepository) UpdateRule(rule *models.FeeRule) error {
+	if err := r.DB.Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to update fee rule: %w", err)
+	}
+	return nil
+}
+
+func (r *FeeRepository) CreateWaiver(waiver *models.FeeWaiver) error {
+	if err := r.DB.Create(waiver).Error; err != nil {
+		return fmt.Errorf("failed to create fee waiver: %w", err)
+	}
+	return nil
+}
+
+// GetWaivers retrieves the promotional waivers, newest first, optionally for one kind of fee
+func (r *FeeRepository) GetWaivers(kind models.FeeKind, includeInactive bool) ([]*models.FeeWaiver, error) {
+	waivers := []*models.FeeWaiver{}
+
+	query := r.DB.Order("created_at DESC")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&waivers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fee waivers: %w", err)
+	}
+	return waivers, nil
+}
+
+func (r *FeeRepository) GetWaiverByID(id string) (*models.FeeWaiver, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	waiver := &models.FeeWaiver{}
+	if err := r.DB.First(waiver, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("fee waiver with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get fee waiver: %w", err)
+	}
+	return waiver, nil
+}
+
+func (r *FeeRepository) UpdateWaiver(waiver *models.FeeWaiver) error {
+	if err := r.DB.Save(waiver).Error; err != nil {
+		return fmt.Errorf("failed to update fee waiver: %w", err)
+	}
+	return nil
+}
+
+// GetActiveRulesWithTx retrieves the active rules for a kind of fee, newest first
+func (r *FeeRepository) GetActiveRulesWithTx(tx *gorm.DB, kind models.FeeKind) ([]*models.FeeRule, error) {
+	rules := []*models.FeeRule{}
+	if err := tx.Where("kind = ? AND active = ?", kind, true).Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fee rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetActiveWaiversWithTx retrieves the active waivers for a kind of fee running at the given time
+func (r *FeeRepository) GetActiveWaiversWithTx(tx *gorm.DB, kind models.FeeKind, at time.Time) ([]*models.FeeWaiver, error) {
+	waivers := []*models.FeeWaiver{}
+
+	err := tx.Where("kind = ? AND active = ? AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", kind, true, at, at).
+		Order("created_at DESC").Find(&waivers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee waivers: %w", err)
+	}
+	return waivers, nil
+}
+
+// CreateFeesWithTx records the fee line items charged on a transaction
+func (r *FeeRepository) CreateFeesWithTx(tx *gorm.DB, fees []*models.TransactionFee) error {
+	if len(fees) == 0 {
+		return nil
+	}
+	if err := tx.Create(fees).Error; err != nil {
+		return fmt.Errorf("failed to record transaction fees: %w", err)
+	}
+	return nil
+}
+
+// CountFeesWithTx counts the line items of a kind already charged on a transaction
+func (r *FeeRepository) CountFeesWithTx(tx *gorm.DB, transactionID uuid.UUID, kind models.FeeKind) (int64, error) {
+	var count int64
+	if err := tx.Model(&models.TransactionFee{}).Where("transaction_id = ? AND kind = ?", transactionID, kind).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count transaction fees: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InspectionBookingRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, booking *models.InspectionBooking) error
+	GetScheduledByListingID(listingID uuid.UUID) (*models.InspectionBooking, error)
+	GetScheduledByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) (*models.InspectionBooking, error)
+	GetScheduledBySlotIDWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionBooking, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	GetDueForReminder(before time.Time) ([]*models.InspectionBooking, error)
+	MarkReminderSent(id uuid.UUID, sentAt time.Time) error
+}
+
+type InspectionBookingRepository struct {
+	DB *gorm.DB
+}
+
+func NewInspectionBookingRepository(db *gorm.DB) *InspectionBookingRepository {
+	return &InspectionBookingRepository{DB: db}
+}
+
+// CreateWithTx adds a new booking within an ongoing transaction
+func (r *InspectionBookingRepository) CreateWithTx(tx *gorm.DB, booking *models.InspectionBooking) error {
+	if err := tx.Create(booking).Error; err != nil {
+		return fmt.Errorf("failed to create inspection booking: %w", err)
+	}
+	return nil
+}
+
+// GetScheduledByListingID retrieves the scheduled booking of a listing; gorm.ErrRecordNotFound is returned when there is none
+func (r *InspectionBookingRepository) GetScheduledByListingID(listingID uuid.UUID) (*models.InspectionBooking, error) {
+	booking := &models.InspectionBooking{}
+
+	err := r.DB.Preload("Slot").Preload("Inspector").
+		Where("listing_id = ? AND status = ?", listingID, models.InspectionBookingStatusScheduled).
+		First(booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection booking: %w", err)
+	}
+	return booking, nil
+}
+
+// GetScheduledByListingIDForUpdateWithTx retrieves and locks the scheduled booking of a listing; gorm.ErrRecordNotFound is returned when there is none
+func (r *InspectionBookingRepository) GetScheduledByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) (*models.InspectionBooking, error) {
+	booking := &models.InspectionBooking{}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND status = ?", listingID, models.InspectionBookingStatusScheduled).
+		First(booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection booking: %w", err)
+	}
+	return booking, nil
+}
+
+// GetScheduledBySlotIDWithTx retrieves the scheduled booking holding a slot; gorm.ErrRecordNotFound is returned when there is none
+func (r *InspectionBookingRepository) GetScheduledBySlotIDWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionBooking, error) {
+	booking := &models.InspectionBooking{}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Listing").Preload("Seller").
+		Where("slot_id = ? AND status = ?", slotID, models.InspectionBookingStatusScheduled).
+		First(booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection booking by slot: %w", err)
+	}
+	return booking, nil
+}
+
+// UpdateFieldsWithTx updates the given columns of a booking, including ones being reset to NULL or zero
+func (r *InspectionBookingRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.InspectionBooking{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inspection booking: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection booking with id %s not found", id.String())
+	}
+	return nil
+}
+
+// GetDueForReminder retrieves scheduled bookings starting before the given time that have not been reminded yet
+func (r *InspectionBookingRepository) GetDueForReminder(before time.Time) ([]*models.InspectionBooking, error) {
+	bookings := []*models.InspectionBooking{}
+
+	err := r.DB.Preload("Listing").Preload("Seller").Preload("Inspector").
+		Where("status = ? AND reminder_sent_at IS NULL AND scheduled_for > ? AND scheduled_for <= ?", models.InspectionBookingStatusScheduled, time.Now(), before).
+		Find(&bookings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspection bookings due for reminder: %w", err)
+	}
+	return bookings, nil
+}
+
+// MarkReminderSent records that reminder emails went out for a booking
+func (r *InspectionBookingRepository) MarkReminderSent(id uuid.UUID, sentAt time.Time) error {
+	if err := r.DB.Model(&models.InspectionBooking{}).Where("id = ?", id).Update("reminder_sent_at", sentAt).Error; err != nil {
+		return fmt.Errorf("failed to mark inspection reminder sent: %w", err)
+	}
+	return nil
+}
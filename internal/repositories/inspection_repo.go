@@ -3,7 +3,9 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
 	"github.com/zekeriyyah/lujay-autocity/pkg"
 	"gorm.io/gorm"
@@ -15,9 +17,15 @@ type InspectionRepositoryInterface interface {
 	GetByID(id string) (*models.Inspection, error) 
 	GetByStatus(status models.InspectionStatus) ([]*models.Inspection, error)
 	GetByListingID(listingID string) (*models.Inspection, error) 
+	GetAllByListingID(listingID string) ([]*models.Inspection, error)
 	Update(inspection *models.Inspection) error
 	UpdateWithTx(tx *gorm.DB, inspection *models.Inspection) error
+	UpdateDecisionWithTx(tx *gorm.DB, id uuid.UUID, status models.InspectionStatus, rejectionReasons []models.RejectionDetail) error
 	Delete(id string) error 
+	DeleteWithTx(tx *gorm.DB, id uuid.UUID) error
+	GetActiveBetween(from, to time.Time) ([]*models.InspectionFetchInput, error)
+	QueueReportWithTx(tx *gorm.DB, id uuid.UUID) error
+	GetPendingReportIDs(maxAttempts int, limit int) ([]uuid.UUID, error)
 }
 
 // InspectionRepository implements the InspectionRepositoryInterface.
@@ -48,7 +56,7 @@ func (r *InspectionRepository) CreateWithTx(tx *gorm.DB, inspection *models.Insp
 func (r *InspectionRepository) GetByStatus(status models.InspectionStatus) ([]*models.InspectionFetchInput, error) {
 	inspectionsInput := []*models.InspectionFetchInput{}
 	// Preload related data (Listing, Inspector)
-	if err := r.DB.Table("inspections").Preload("Listing").Preload("Inspector").Where("status = ?", status).Find(&inspectionsInput).Error; err != nil {
+	if err := r.DB.Table("inspections").Preload("Listing").Preload("Inspector").Where("status = ?", status).Order("created_at ASC").Find(&inspectionsInput).Error; err != nil {
 		return nil, fmt.Errorf("failed to get inspections by status: %w", err)
 	}
 	
@@ -64,7 +72,8 @@ func (i *InspectionRepository) GetByListingID(listingID string) (*models.Inspect
 
 	inspectionInput := &models.InspectionFetchInput{}
 
-	if err := i.DB.Table("inspections").Preload("Listing").Preload("Inspector").First(inspectionInput, "listing_id = ?", parsedListingID).Error; err != nil {
+	// a listing may have been inspected several times; the latest vetting inspection is returned
+	if err := i.DB.Table("inspections").Preload("Listing").Preload("Inspector").Order("created_at DESC").First(inspectionInput, "listing_id = ? AND type = ?", parsedListingID, models.InspectionTypeVetting).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("inspection for listing ID %s not found", listingID)
 		}
@@ -91,6 +100,49 @@ func(i *InspectionRepository) UpdateWithTx(tx *gorm.DB, inspection *models.Inspe
 	return nil
 }
 
+// UpdateDecisionWithTx sets the outcome of an inspection, replacing any rejection reasons of an earlier decision
+func (i *InspectionRepository) UpdateDecisionWithTx(tx *gorm.DB, id uuid.UUID, status models.InspectionStatus, rejectionReasons []models.RejectionDetail) error {
+	result := tx.Model(&models.Inspection{ID: id}).Select("status", "rejection_reasons").
+		Updates(&models.Inspection{Status: status, RejectionReasons: rejectionReasons})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inspection: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection with id %s not found", id.String())
+	}
+	return nil
+}
+
+// QueueReportWithTx marks an inspection for the background report generator, starting its attempts afresh
+func (i *InspectionRepository) QueueReportWithTx(tx *gorm.DB, id uuid.UUID) error {
+	return i.UpdateFieldsWithTx(tx, id, map[string]any{"report_pending": true, "report_attempts": 0})
+}
+
+// UpdateFieldsWithTx updates the given columns of an inspection
+func (i *InspectionRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.Inspection{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inspection: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection with id %s not found", id.String())
+	}
+	return nil
+}
+
+// GetPendingReportIDs returns the inspections queued for a report that have failed fewer than maxAttempts times, oldest first
+func (r *InspectionRepository) GetPendingReportIDs(maxAttempts int, limit int) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+
+	err := r.DB.Model(&models.Inspection{}).
+		Where("report_pending = ? AND report_attempts < ?", true, maxAttempts).
+		Order("updated_at ASC").Limit(limit).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspections awaiting a report: %w", err)
+	}
+	return ids, nil
+}
+
 // Delete removes an inspection by its UUID ID (passed as a string).
 func (i *InspectionRepository) Delete(id string) error {
 	parsedID, err := pkg.StringToUUID(id)
@@ -108,6 +160,18 @@ func (i *InspectionRepository) Delete(id string) error {
 	return nil
 }
 
+// DeleteWithTx removes an inspection within an ongoing transaction
+func (i *InspectionRepository) DeleteWithTx(tx *gorm.DB, id uuid.UUID) error {
+	result := tx.Delete(&models.Inspection{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete inspection: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection with id %s not found", id.String())
+	}
+	return nil
+}
+
 func (r *InspectionRepository) GetByID(id string) (*models.InspectionFetchInput, error) {
 	parsedID, err := pkg.StringToUUID(id)
 	if err != nil {
@@ -123,4 +187,65 @@ func (r *InspectionRepository) GetByID(id string) (*models.InspectionFetchInput,
 		return nil, fmt.Errorf("failed to get inspection: %w", err)
 	}
 	return rawInspection, nil
-}
\ No newline at end of file
+}
+
+// GetByListingIDs retrieves the vetting inspections recorded for any of the given listings, oldest first.
+// Pre-purchase inspections are private to the buyer who ordered them and never included.
+func (r *InspectionRepository) GetByListingIDs(listingIDs []uuid.UUID) ([]*models.InspectionFetchInput, error) {
+	inspectionsInput := []*models.InspectionFetchInput{}
+	if len(listingIDs) == 0 {
+		return inspectionsInput, nil
+	}
+
+	if err := r.DB.Table("inspections").Where("listing_id IN ? AND type = ?", listingIDs, models.InspectionTypeVetting).Order("created_at ASC").Find(&inspectionsInput).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspections by listing IDs: %w", err)
+	}
+	return inspectionsInput, nil
+}
+
+// GetPendingIDByListingIDWithTx returns the ID of the listing's pending vetting inspection, or uuid.Nil when there is none
+func (r *InspectionRepository) GetPendingIDByListingIDWithTx(tx *gorm.DB, listingID uuid.UUID) (uuid.UUID, error) {
+	ids := []uuid.UUID{}
+
+	err := tx.Model(&models.Inspection{}).
+		Where("listing_id = ? AND status = ? AND type = ?", listingID, models.InspectionStatusPending, models.InspectionTypeVetting).
+		Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get pending inspection for listing: %w", err)
+	}
+	if len(ids) == 0 {
+		return uuid.Nil, nil
+	}
+	return ids[0], nil
+}
+
+// GetAllByListingID retrieves every vetting inspection of a listing, newest first
+func (r *InspectionRepository) GetAllByListingID(listingID string) ([]*models.InspectionFetchInput, error) {
+	parsedListingID, err := pkg.StringToUUID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	inspectionsInput := []*models.InspectionFetchInput{}
+	if err := r.DB.Table("inspections").Preload("Inspector").Where("listing_id = ? AND type = ?", parsedListingID, models.InspectionTypeVetting).Order("created_at DESC").Find(&inspectionsInput).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspections by listing ID: %w", err)
+	}
+	return inspectionsInput, nil
+}
+
+// GetActiveBetween retrieves the inspections assigned or completed within [from, to), with their inspector.
+// Inspections from before assignment was tracked count as assigned when created, and as completed when last updated
+// if their findings were never submitted through a checklist.
+func (r *InspectionRepository) GetActiveBetween(from, to time.Time) ([]*models.InspectionFetchInput, error) {
+	inspectionsInput := []*models.InspectionFetchInput{}
+
+	err := r.DB.Table("inspections").Preload("Inspector").
+		Where("(COALESCE(assigned_at, created_at) >= ? AND COALESCE(assigned_at, created_at) < ?) OR "+
+			"(status <> ? AND COALESCE(submitted_at, updated_at) >= ? AND COALESCE(submitted_at, updated_at) < ?)",
+			from, to, models.InspectionStatusPending, from, to).
+		Order("created_at ASC").Find(&inspectionsInput).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspections for period: %w", err)
+	}
+	return inspectionsInput, nil
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InspectionRequestRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, request *models.InspectionRequest) error
+	GetByID(id string) (*models.InspectionRequest, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.InspectionRequest, error)
+	GetByInspectionIDWithTx(tx *gorm.DB, inspectionID uuid.UUID) (*models.InspectionRequest, error)
+	GetScheduledBySlotIDWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionRequest, error)
+	CountOpenByListingAndBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error)
+	GetByBuyerID(buyerID uuid.UUID) ([]*models.InspectionRequest, error)
+	GetByListingID(listingID uuid.UUID) ([]*models.InspectionRequest, error)
+	GetScheduledWithUnpaidFee(now time.Time, limit int) ([]*models.InspectionRequest, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+}
+
+type InspectionRequestRepository struct {
+	DB *gorm.DB
+}
+
+func NewInspectionRequestRepository(db *gorm.DB) *InspectionRequestRepository {
+	return &InspectionRequestRepository{DB: db}
+}
+
+// CreateWithTx adds a new pre-purchase inspection request within an ongoing transaction
+func (r *InspectionRequestRepository) CreateWithTx(tx *gorm.DB, request *models.InspectionRequest) error {
+	if err := tx.Create(request).Error; err != nil {
+		return fmt.Errorf("failed to create inspection request: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an inspection request with its listing and both parties
+func (r *InspectionRequestRepository) GetByID(id string) (*models.InspectionRequest, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.InspectionRequest{}
+	if err := r.DB.Preload("Listing").Preload("Buyer").Preload("Seller").First(request, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("inspection request with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get inspection request: %w", err)
+	}
+	return request, nil
+}
+
+// GetByIDForUpdateWithTx retrieves and locks an inspection request
+func (r *InspectionRequestRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.InspectionRequest, error) {
+	request := &models.InspectionRequest{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(request, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("inspection request with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get inspection request: %w", err)
+	}
+	return request, nil
+}
+
+// GetByInspectionIDWithTx retrieves and locks the request behind a pre-purchase inspection; gorm.ErrRecordNotFound is returned when there is none
+func (r *InspectionRequestRepository) GetByInspectionIDWithTx(tx *gorm.DB, inspectionID uuid.UUID) (*models.InspectionRequest, error) {
+	request := &models.InspectionRequest{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("inspection_id = ?", inspectionID).First(request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection request by inspection: %w", err)
+	}
+	return request, nil
+}
+
+// GetScheduledBySlotIDWithTx retrieves and locks the scheduled request holding a slot; gorm.ErrRecordNotFound is returned when there is none
+func (r *InspectionRequestRepository) GetScheduledBySlotIDWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionRequest, error) {
+	request := &models.InspectionRequest{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Listing").Preload("Buyer").
+		Where("slot_id = ? AND status = ?", slotID, models.InspectionRequestStatusScheduled).
+		First(request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get inspection request by slot: %w", err)
+	}
+	return request, nil
+}
+
+// CountOpenByListingAndBuyerWithTx counts a buyer's requests for a listing that are still in progress
+func (r *InspectionRequestRepository) CountOpenByListingAndBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.InspectionRequest{}).
+		Where("listing_id = ? AND buyer_id = ? AND status IN ?", listingID, buyerID, []models.InspectionRequestStatus{
+			models.InspectionRequestStatusAwaitingConsent,
+			models.InspectionRequestStatusConsented,
+			models.InspectionRequestStatusScheduled,
+		}).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count open inspection requests: %w", err)
+	}
+	return count, nil
+}
+
+// GetByBuyerID retrieves a buyer's inspection requests, newest first
+func (r *InspectionRequestRepository) GetByBuyerID(buyerID uuid.UUID) ([]*models.InspectionRequest, error) {
+	requests := []*models.InspectionRequest{}
+	if err := r.DB.Preload("Listing").Where("buyer_id = ?", buyerID).Order("created_at DESC").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspection requests by buyer: %w", err)
+	}
+	return requests, nil
+}
+
+// GetByListingID retrieves the inspection requests made for a listing, newest first
+func (r *InspectionRequestRepository) GetByListingID(listingID uuid.UUID) ([]*models.InspectionRequest, error) {
+	requests := []*models.InspectionRequest{}
+	if err := r.DB.Where("listing_id = ?", listingID).Order("created_at DESC").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspection requests by listing: %w", err)
+	}
+	return requests, nil
+}
+
+// GetScheduledWithUnpaidFee retrieves scheduled requests whose inspection fee was not paid by its deadline or can no
+// longer be paid because it failed or was cancelled, oldest first
+func (r *InspectionRequestRepository) GetScheduledWithUnpaidFee(now time.Time, limit int) ([]*models.InspectionRequest, error) {
+	requests := []*models.InspectionRequest{}
+	err := r.DB.Joins("JOIN transactions ON transactions.id = inspection_requests.transaction_id").
+		Where("inspection_requests.status = ?", models.InspectionRequestStatusScheduled).
+		Where("(transactions.escrow_status = ? AND transactions.escrow_deadline <= ?) OR transactions.status IN ?",
+			models.EscrowStatusAwaitingPayment, now,
+			[]models.TransactionStatus{models.TransactionStatusFailed, models.TransactionStatusCancelled}).
+		Order("inspection_requests.created_at ASC").Limit(limit).Find(&requests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspection requests with unpaid fees: %w", err)
+	}
+	return requests, nil
+}
+
+// UpdateFieldsWithTx updates the given columns of a request, including ones being reset to NULL or zero
+func (r *InspectionRequestRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.InspectionRequest{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inspection request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection request with id %s not found", id.String())
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InspectionSlotRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, slot *models.InspectionSlot) error
+	LockInspectorWithTx(tx *gorm.DB, inspectorID uuid.UUID) error
+	CountOverlappingWithTx(tx *gorm.DB, inspectorID uuid.UUID, startsAt, endsAt time.Time) (int64, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.InspectionSlot, error)
+	GetAvailable(from, to time.Time) ([]*models.InspectionSlot, error)
+	GetByInspectorID(inspectorID string, from time.Time) ([]*models.InspectionSlot, error)
+	UpdateStatusWithTx(tx *gorm.DB, id uuid.UUID, status models.InspectionSlotStatus) error
+}
+
+type InspectionSlotRepository struct {
+	DB *gorm.DB
+}
+
+func NewInspectionSlotRepository(db *gorm.DB) *InspectionSlotRepository {
+	return &InspectionSlotRepository{DB: db}
+}
+
+// CreateWithTx adds a new availability slot within an ongoing transaction
+func (r *InspectionSlotRepository) CreateWithTx(tx *gorm.DB, slot *models.InspectionSlot) error {
+	if err := tx.Create(slot).Error; err != nil {
+		return fmt.Errorf("failed to create inspection slot: %w", err)
+	}
+	return nil
+}
+
+// LockInspectorWithTx serialises slot changes of one inspector by locking their user row
+func (r *InspectionSlotRepository) LockInspectorWithTx(tx *gorm.DB, inspectorID uuid.UUID) error {
+	inspector := &models.User{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(inspector, "id = ?", inspectorID).Error; err != nil {
+		return fmt.Errorf("failed to lock inspector schedule: %w", err)
+	}
+	return nil
+}
+
+// CountOverlappingWithTx counts the inspector's non-cancelled slots overlapping the given window
+func (r *InspectionSlotRepository) CountOverlappingWithTx(tx *gorm.DB, inspectorID uuid.UUID, startsAt, endsAt time.Time) (int64, error) {
+	var count int64
+
+	err := tx.Model(&models.InspectionSlot{}).
+		Where("inspector_id = ? AND status <> ? AND starts_at < ? AND ends_at > ?", inspectorID, models.InspectionSlotStatusCancelled, endsAt, startsAt).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to check overlapping inspection slots: %w", err)
+	}
+	return count, nil
+}
+
+// GetByIDForUpdateWithTx retrieves a slot and locks it until the transaction ends
+func (r *InspectionSlotRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.InspectionSlot, error) {
+	slot := &models.InspectionSlot{}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(slot, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("inspection slot with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get inspection slot: %w", err)
+	}
+	return slot, nil
+}
+
+// GetAvailable retrieves open slots starting within the given window, earliest first
+func (r *InspectionSlotRepository) GetAvailable(from, to time.Time) ([]*models.InspectionSlot, error) {
+	slots := []*models.InspectionSlot{}
+
+	err := r.DB.Preload("Inspector").
+		Where("status = ? AND starts_at >= ? AND starts_at < ?", models.InspectionSlotStatusAvailable, from, to).
+		Order("starts_at ASC").
+		Find(&slots).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available inspection slots: %w", err)
+	}
+	return slots, nil
+}
+
+// GetByInspectorID retrieves an inspector's slots ending after from, earliest first
+func (r *InspectionSlotRepository) GetByInspectorID(inspectorID string, from time.Time) ([]*models.InspectionSlot, error) {
+	parsedInspectorID, err := pkg.StringToUUID(inspectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []*models.InspectionSlot{}
+	if err := r.DB.Where("inspector_id = ? AND ends_at > ?", parsedInspectorID, from).Order("starts_at ASC").Find(&slots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspection slots by inspector: %w", err)
+	}
+	return slots, nil
+}
+
+// UpdateStatusWithTx changes the status of a slot within an ongoing transaction
+func (r *InspectionSlotRepository) UpdateStatusWithTx(tx *gorm.DB, id uuid.UUID, status models.InspectionSlotStatus) error {
+	result := tx.Model(&models.InspectionSlot{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inspection slot status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("inspection slot with id %s not found", id.String())
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepositoryInterface interface {
+	NextNumberWithTx(tx *gorm.DB, year int) (int, error)
+	CreateWithTx(tx *gorm.DB, invoice *models.Invoice) error
+	ExistsForTransactionWithTx(tx *gorm.DB, transactionID uuid.UUID) (bool, error)
+	GetTransactionWithTx(tx *gorm.DB, transactionID uuid.UUID) (*models.Transaction, error)
+	GetByTransactionID(transactionID uuid.UUID) (*models.Invoice, error)
+	GetUnemailed(limit int) ([]*models.Invoice, error)
+	MarkEmailed(id uuid.UUID, at time.Time) error
+}
+
+type InvoiceRepository struct {
+	DB *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{DB: db}
+}
+
+// NextNumberWithTx advances the year's invoice counter and returns the new number. The counter row stays locked until
+// the database transaction ends, so concurrent invoices queue up and a rollback hands the number back.
+func (r *InvoiceRepository) NextNumberWithTx(tx *gorm.DB, year int) (int, error) {
+	var next int
+	err := tx.Raw(`INSERT INTO invoice_sequences (year, last_number) VALUES (?, 1)
+ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+RETURNING last_number`, year).Scan(&next).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate invoice number: %w", err)
+	}
+	return next, nil
+}
+
+func (r *InvoiceRepository) CreateWithTx(tx *gorm.DB, invoice *models.Invoice) error {
+	if err := tx.Create(invoice).Error; err != nil {
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+	return nil
+}
+
+func (r *InvoiceRepository) ExistsForTransactionWithTx(tx *gorm.DB, transactionID uuid.UUID) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Invoice{}).Where("transaction_id = ?", transactionID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check invoice: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetTransactionWithTx retrieves a transaction with everything its invoice shows: both parties, the listing with its
+// vehicle and the fee line items
+func (r *InvoiceRepository) GetTransactionWithTx(tx *gorm.DB, transactionID uuid.UUID) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+	err := tx.Preload("Listing.Vehicle").Preload("Buyer").Preload("Seller").Preload("Fees").
+		First(transaction, "id = ?", transactionID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("transaction with id %s not found", transactionID.String())
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+func (r *InvoiceRepository) GetByTransactionID(transactionID uuid.UUID) (*models.Invoice, error) {
+	invoice := &models.Invoice{}
+	if err := r.DB.First(invoice, "transaction_id = ?", transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no receipt has been issued for this transaction")
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return invoice, nil
+}
+
+// GetUnemailed retrieves the oldest invoices not sent to their parties yet
+func (r *InvoiceRepository) GetUnemailed(limit int) ([]*models.Invoice, error) {
+	invoices := []*models.Invoice{}
+	if err := r.DB.Where("emailed_at IS NULL").Order("issued_at ASC").Limit(limit).Find(&invoices).Error; err != nil {
+		return nil, fmt.Errorf("failed to get unsent invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+func (r *InvoiceRepository) MarkEmailed(id uuid.UUID, at time.Time) error {
+	if err := r.DB.Model(&models.Invoice{}).Where("id = ?", id).Update("emailed_at", at).Error; err != nil {
+		return fmt.Errorf("failed to mark invoice emailed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testYear is far enough out that the tests never touch real invoice numbers
+const testYear = 2991
+
+// errRollback rolls back a database transaction a test only opened to look inside
+var errRollback = errors.New("rollback")
+
+// testInvoiceRepository connects to the Postgres database in TEST_DATABASE_URL, skipping the test without one
+func testInvoiceRepository(t *testing.T) *InvoiceRepository {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.InvoiceSequence{}); err != nil {
+		t.Fatalf("failed to migrate invoice sequences: %v", err)
+	}
+
+	cleanup := func() { db.Where("year IN ?", []int{testYear, testYear + 1}).Delete(&models.InvoiceSequence{}) }
+	cleanup()
+	t.Cleanup(cleanup)
+	return NewInvoiceRepository(db)
+}
+
+func TestNextNumberWithTx(t *testing.T) {
+	repo := testInvoiceRepository(t)
+
+	// an allocation of a year, rolled back if undo is set, and the number it should get
+	type allocation struct {
+		year int
+		undo bool
+		want int
+	}
+	tests := []struct {
+		name        string
+		allocations []allocation
+	}{
+		{"first number of a year", []allocation{{testYear, false, 1}}},
+		{"numbers run on", []allocation{{testYear, false, 1}, {testYear, false, 2}, {testYear, false, 3}}},
+		{"years are numbered separately", []allocation{{testYear, false, 1}, {testYear + 1, false, 1}, {testYear, false, 2}}},
+		{"a rolled back invoice gives its number back", []allocation{{testYear, false, 1}, {testYear, true, 2}, {testYear, false, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// everything is rolled back at the end, so every case starts from an empty sequence
+			err := repo.DB.Transaction(func(tx *gorm.DB) error {
+				for i, a := range tt.allocations {
+					var got int
+					err := tx.Transaction(func(nested *gorm.DB) error {
+						var err error
+						if got, err = repo.NextNumberWithTx(nested, a.year); err != nil {
+							return err
+						}
+						if a.undo {
+							return errRollback
+						}
+						return nil
+					})
+					if err != nil && !errors.Is(err, errRollback) {
+						t.Fatalf("allocation %d: %v", i, err)
+					}
+					if got != a.want {
+						t.Errorf("allocation %d of %d = %d, want %d", i, a.year, got, a.want)
+					}
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatalf("transaction: %v", err)
+			}
+		})
+	}
+}
+
+func TestNextNumberWithTxConcurrent(t *testing.T) {
+	repo := testInvoiceRepository(t)
+
+	const workers, perWorker = 4, 10
+	numbers := make(chan int, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := repo.DB.Transaction(func(tx *gorm.DB) error {
+					next, err := repo.NextNumberWithTx(tx, testYear)
+					numbers <- next
+					return err
+				})
+				if err != nil {
+					t.Errorf("allocation failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+
+	got := []int{}
+	for n := range numbers {
+		got = append(got, n)
+	}
+	sort.Ints(got)
+	for i, n := range got {
+		if n != i+1 {
+			t.Fatalf("allocated numbers = %v, want 1 to %d without gaps or repeats", got, workers*perWorker)
+		}
+	}
+}
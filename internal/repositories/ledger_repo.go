@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LedgerRepositoryInterface interface {
+	GetOrCreateAccountWithTx(tx *gorm.DB, code models.LedgerAccountCode, ownerID uuid.UUID, currency string) (*models.LedgerAccount, error)
+	CreateEntryWithTx(tx *gorm.DB, entry *models.JournalEntry) error
+	GetEntries(transactionID *uuid.UUID, limit int) ([]*models.JournalEntry, error)
+	GetBalances(code models.LedgerAccountCode, ownerID *uuid.UUID) ([]*models.LedgerAccountBalance, error)
+}
+
+// LedgerRepository only ever adds journal entries: nothing here updates or deletes them
+type LedgerRepository struct {
+	DB *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{DB: db}
+}
+
+// GetOrCreateAccountWithTx retrieves the account with the code, owner and currency, opening it on first use
+func (r *LedgerRepository) GetOrCreateAccountWithTx(tx *gorm.DB, code models.LedgerAccountCode, ownerID uuid.UUID, currency string) (*models.LedgerAccount, error) {
+	account := &models.LedgerAccount{Code: code, Type: code.Type(), OwnerID: ownerID, Currency: currency}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}, {Name: "owner_id"}, {Name: "currency"}},
+		DoNothing: true,
+	}).Create(account).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger account: %w", err)
+	}
+
+	if err := tx.First(account, "code = ? AND owner_id = ? AND currency = ?", code, ownerID, currency).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ledger account: %w", err)
+	}
+	return account, nil
+}
+
+// CreateEntryWithTx records a journal entry together with its lines
+func (r *LedgerRepository) CreateEntryWithTx(tx *gorm.DB, entry *models.JournalEntry) error {
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record journal entry: %w", err)
+	}
+	return nil
+}
+
+// GetEntries retrieves the newest journal entries with their lines, optionally only those of one transaction
+func (r *LedgerRepository) GetEntries(transactionID *uuid.UUID, limit int) ([]*models.JournalEntry, error) {
+	entries := []*models.JournalEntry{}
+
+	query := r.DB.Preload("Lines.Account").Order("created_at DESC").Limit(limit)
+	if transactionID != nil {
+		query = query.Where("transaction_id = ?", *transactionID)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+	return entries, nil
+}
+
+// GetBalances totals the debits and credits posted to each account, optionally only accounts with a code or owner
+func (r *LedgerRepository) GetBalances(code models.LedgerAccountCode, ownerID *uuid.UUID) ([]*models.LedgerAccountBalance, error) {
+	balances := []*models.LedgerAccountBalance{}
+
+	query := r.DB.Model(&models.LedgerAccount{}).
+		Select("ledger_accounts.*, COALESCE(SUM(journal_lines.debit), 0) AS debits, COALESCE(SUM(journal_lines.credit), 0) AS credits").
+		Joins("LEFT JOIN journal_lines ON journal_lines.account_id = ledger_accounts.id").
+		Group("ledger_accounts.id").
+		Order("ledger_accounts.code ASC, ledger_accounts.created_at ASC")
+	if code != "" {
+		query = query.Where("ledger_accounts.code = ?", code)
+	}
+	if ownerID != nil {
+		query = query.Where("ledger_accounts.owner_id = ?", *ownerID)
+	}
+	if err := query.Scan(&balances).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ledger balances: %w", err)
+	}
+	return balances, nil
+}
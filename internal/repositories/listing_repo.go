@@ -3,10 +3,13 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
 	"github.com/zekeriyyah/lujay-autocity/pkg"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ListingRepositoryInterface interface {
@@ -20,6 +23,19 @@ type ListingRepositoryInterface interface {
 	
 	GetAll() ([]*models.Listing, error)
 	GetByStatus(status models.ListingStatus) ([]*models.Listing, error)
+	GetByVehicleID(vehicleID string) ([]*models.Listing, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Listing, error)
+	CountOpenByVehicleIDWithTx(tx *gorm.DB, vehicleID uuid.UUID, excludeSellerID uuid.UUID) (int64, error)
+	SetCertificateWithTx(tx *gorm.DB, id uuid.UUID, certificateID *uuid.UUID) error
+	ChangeStatusWithTx(tx *gorm.DB, change *models.ListingStatusChange) error
+	GetStatusChanges(listingID string) ([]*models.ListingStatusChange, error)
+	GetReviewQueue() ([]*models.Listing, error)
+	ClaimForReview(id uuid.UUID, adminID uuid.UUID, expiresAt time.Time) (bool, error)
+	ReleaseReviewClaim(id uuid.UUID, adminID uuid.UUID) (bool, error)
+	SetRejectionWithTx(tx *gorm.DB, id uuid.UUID, reasons []models.RejectionDetail, note string) error
+	CreateResubmissionWithTx(tx *gorm.DB, resubmission *models.ListingResubmission) error
+	GetResubmissions(listingID string) ([]*models.ListingResubmission, error)
+	GetStatusChangesByInspectionIDs(inspectionIDs []uuid.UUID) ([]*models.ListingStatusChange, error)
 }
 
 type ListingRepository struct {
@@ -135,4 +151,189 @@ func (r *ListingRepository) GetByStatus(status models.ListingStatus) ([]*models.
 		return nil, fmt.Errorf("failed to get %s listings: %w", status, err) 
 	}
 	return listings, nil
-}
\ No newline at end of file
+}
+
+// GetByVehicleID retrieves every listing of a vehicle, oldest first
+func (r *ListingRepository) GetByVehicleID(vehicleID string) ([]*models.Listing, error) {
+	parsedVehicleID, err := pkg.StringToUUID(vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vehicle id to uuid: %w", err)
+	}
+
+	listings := []*models.Listing{}
+
+	if err := r.DB.Where("vehicle_id = ?", parsedVehicleID).Order("created_at ASC").Find(&listings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get listings by vehicle ID: %w", err)
+	}
+	return listings, nil
+}
+
+
+// CountOpenByVehicleIDWithTx counts pending, active or reserved listings of a vehicle held by sellers other than excludeSellerID
+func (r *ListingRepository) CountOpenByVehicleIDWithTx(tx *gorm.DB, vehicleID uuid.UUID, excludeSellerID uuid.UUID) (int64, error) {
+	var count int64
+
+	err := tx.Model(&models.Listing{}).
+		Where("vehicle_id = ? AND seller_id <> ? AND status IN ?", vehicleID, excludeSellerID, []models.ListingStatus{models.ListingStatusPending, models.ListingStatusActive, models.ListingStatusReserved}).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count open listings for vehicle: %w", err)
+	}
+	return count, nil
+}
+
+// GetByIDForUpdateWithTx retrieves a listing and locks its row until the transaction ends
+func (r *ListingRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Listing, error) {
+	listing := &models.Listing{}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(listing, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("listing with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get listing: %w", err)
+	}
+	return listing, nil
+}
+
+// SetCertificateWithTx points a listing at its current certificate, or clears the pointer when certificateID is nil
+func (r *ListingRepository) SetCertificateWithTx(tx *gorm.DB, id uuid.UUID, certificateID *uuid.UUID) error {
+	result := tx.Model(&models.Listing{}).Where("id = ?", id).Update("certificate_id", certificateID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update listing certificate: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("listing with id %s not found", id.String())
+	}
+	return nil
+}
+
+// ChangeStatusWithTx moves a listing to change.ToStatus, restarts its time in state, drops any review claim and records the change
+func (r *ListingRepository) ChangeStatusWithTx(tx *gorm.DB, change *models.ListingStatusChange) error {
+	result := tx.Model(&models.Listing{}).Where("id = ?", change.ListingID).Updates(map[string]any{
+		"status":                  change.ToStatus,
+		"status_changed_at":       time.Now(),
+		"review_claimed_by_id":    nil,
+		"review_claimed_at":       nil,
+		"review_claim_expires_at": nil,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update listing status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("listing with id %s not found", change.ListingID.String())
+	}
+
+	if err := tx.Create(change).Error; err != nil {
+		return fmt.Errorf("failed to record listing status change: %w", err)
+	}
+	return nil
+}
+
+// GetStatusChanges retrieves the status history of a listing, oldest first
+func (r *ListingRepository) GetStatusChanges(listingID string) ([]*models.ListingStatusChange, error) {
+	parsedListingID, err := pkg.StringToUUID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []*models.ListingStatusChange{}
+	if err := r.DB.Where("listing_id = ?", parsedListingID).Order("created_at ASC").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get listing status history: %w", err)
+	}
+	return changes, nil
+}
+
+// GetStatusChangesByInspectionIDs retrieves the status changes made by any of the given inspections, oldest first
+func (r *ListingRepository) GetStatusChangesByInspectionIDs(inspectionIDs []uuid.UUID) ([]*models.ListingStatusChange, error) {
+	changes := []*models.ListingStatusChange{}
+	if len(inspectionIDs) == 0 {
+		return changes, nil
+	}
+
+	if err := r.DB.Where("inspection_id IN ?", inspectionIDs).Order("created_at ASC").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get status changes by inspection IDs: %w", err)
+	}
+	return changes, nil
+}
+
+// GetReviewQueue retrieves listings pending review, longest waiting first
+func (r *ListingRepository) GetReviewQueue() ([]*models.Listing, error) {
+	listings := []*models.Listing{}
+
+	err := r.DB.Preload("Seller").
+		Where("status = ?", models.ListingStatusPending).
+		Order("COALESCE(status_changed_at, created_at) ASC").
+		Find(&listings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review queue: %w", err)
+	}
+	return listings, nil
+}
+
+// ClaimForReview gives adminID the review claim on a pending listing unless another admin holds an unexpired one.
+// It reports whether the claim was taken; renewing one's own claim counts as taking it.
+func (r *ListingRepository) ClaimForReview(id uuid.UUID, adminID uuid.UUID, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	result := r.DB.Model(&models.Listing{}).
+		Where("id = ? AND status = ?", id, models.ListingStatusPending).
+		Where("review_claimed_by_id IS NULL OR review_claimed_by_id = ? OR review_claim_expires_at <= ?", adminID, now).
+		Updates(map[string]any{
+			"review_claimed_by_id":    adminID,
+			"review_claimed_at":       now,
+			"review_claim_expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim listing for review: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseReviewClaim drops adminID's review claim on a listing and reports whether there was one
+func (r *ListingRepository) ReleaseReviewClaim(id uuid.UUID, adminID uuid.UUID) (bool, error) {
+	result := r.DB.Model(&models.Listing{}).
+		Where("id = ? AND review_claimed_by_id = ?", id, adminID).
+		Updates(map[string]any{
+			"review_claimed_by_id":    nil,
+			"review_claimed_at":       nil,
+			"review_claim_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to release review claim: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetRejectionWithTx records why a listing was rejected; nil reasons and an empty note clear it
+func (r *ListingRepository) SetRejectionWithTx(tx *gorm.DB, id uuid.UUID, reasons []models.RejectionDetail, note string) error {
+	result := tx.Model(&models.Listing{ID: id}).Select("rejection_reasons", "rejection_note").
+		Updates(&models.Listing{RejectionReasons: reasons, RejectionNote: note})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update listing rejection reasons: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("listing with id %s not found", id.String())
+	}
+	return nil
+}
+
+// CreateResubmissionWithTx records a seller resubmitting a rejected listing
+func (r *ListingRepository) CreateResubmissionWithTx(tx *gorm.DB, resubmission *models.ListingResubmission) error {
+	if err := tx.Create(resubmission).Error; err != nil {
+		return fmt.Errorf("failed to record listing resubmission: %w", err)
+	}
+	return nil
+}
+
+// GetResubmissions retrieves the resubmissions of a listing, newest first
+func (r *ListingRepository) GetResubmissions(listingID string) ([]*models.ListingResubmission, error) {
+	parsedListingID, err := pkg.StringToUUID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	resubmissions := []*models.ListingResubmission{}
+	if err := r.DB.Where("listing_id = ?", parsedListingID).Order("created_at DESC").Find(&resubmissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get listing resubmissions: %w", err)
+	}
+	return resubmissions, nil
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OfferRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, offer *models.Offer) error
+	CountActiveByBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error)
+	GetByID(id string) (*models.Offer, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Offer, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	CreateEventWithTx(tx *gorm.DB, event *models.OfferEvent) error
+	GetActiveByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) ([]*models.Offer, error)
+	GetByListingID(listingID uuid.UUID, status models.OfferStatus) ([]*models.Offer, error)
+	GetBySellerID(sellerID uuid.UUID, status models.OfferStatus) ([]*models.Offer, error)
+	GetByBuyerID(buyerID uuid.UUID) ([]*models.Offer, error)
+	GetExpired(now time.Time, limit int) ([]*models.Offer, error)
+}
+
+type OfferRepository struct {
+	DB *gorm.DB
+}
+
+func NewOfferRepository(db *gorm.DB) *OfferRepository {
+	return &OfferRepository{DB: db}
+}
+
+// activeOfferStatuses are the statuses of an offer still under negotiation
+var activeOfferStatuses = []models.OfferStatus{models.OfferStatusPending, models.OfferStatusCountered}
+
+func (r *OfferRepository) CreateWithTx(tx *gorm.DB, offer *models.Offer) error {
+	if err := tx.Create(offer).Error; err != nil {
+		return fmt.Errorf("failed to create offer: %w", err)
+	}
+	return nil
+}
+
+func (r *OfferRepository) CountActiveByBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Offer{}).
+		Where("listing_id = ? AND buyer_id = ? AND status IN ?", listingID, buyerID, activeOfferStatuses).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active offers: %w", err)
+	}
+	return count, nil
+}
+
+// GetByID retrieves an offer with its listing, buyer and negotiation history
+func (r *OfferRepository) GetByID(id string) (*models.Offer, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	offer := &models.Offer{}
+	err = r.DB.Preload("Listing.Seller").Preload("Buyer").
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(offer, "id = ?", parsedID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("offer with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get offer: %w", err)
+	}
+	return offer, nil
+}
+
+// GetByIDForUpdateWithTx retrieves an offer and locks its row until the database transaction ends
+func (r *OfferRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Offer, error) {
+	offer := &models.Offer{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(offer, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("offer with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get offer: %w", err)
+	}
+	return offer, nil
+}
+
+func (r *OfferRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.Offer{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update offer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("offer with id %s not found", id.String())
+	}
+	return nil
+}
+
+// CreateEventWithTx records a step of a negotiation
+func (r *OfferRepository) CreateEventWithTx(tx *gorm.DB, event *models.OfferEvent) error {
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record offer event: %w", err)
+	}
+	return nil
+}
+
+// GetActiveByListingIDForUpdateWithTx retrieves and locks the offers on a listing still under negotiation
+func (r *OfferRepository) GetActiveByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) ([]*models.Offer, error) {
+	offers := []*models.Offer{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND status IN ?", listingID, activeOfferStatuses).Find(&offers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active offers: %w", err)
+	}
+	return offers, nil
+}
+
+// GetByListingID retrieves the offers on a listing with their buyers, newest first, optionally only those in one status
+func (r *OfferRepository) GetByListingID(listingID uuid.UUID, status models.OfferStatus) ([]*models.Offer, error) {
+	offers := []*models.Offer{}
+
+	query := r.DB.Preload("Buyer").Where("listing_id = ?", listingID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("updated_at DESC").Find(&offers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+	return offers, nil
+}
+
+// GetBySellerID retrieves the offers on a seller's listings, newest first; without a status only active ones
+func (r *OfferRepository) GetBySellerID(sellerID uuid.UUID, status models.OfferStatus) ([]*models.Offer, error) {
+	offers := []*models.Offer{}
+
+	query := r.DB.Preload("Listing").Preload("Buyer").Where("seller_id = ?", sellerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", activeOfferStatuses)
+	}
+	if err := query.Order("updated_at DESC").Find(&offers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+	return offers, nil
+}
+
+// GetByBuyerID retrieves a buyer's offers with their listings, newest first
+func (r *OfferRepository) GetByBuyerID(buyerID uuid.UUID) ([]*models.Offer, error) {
+	offers := []*models.Offer{}
+	if err := r.DB.Preload("Listing").Where("buyer_id = ?", buyerID).Order("updated_at DESC").Find(&offers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+	return offers, nil
+}
+
+// GetExpired retrieves active offers nobody responded to in time, oldest first
+func (r *OfferRepository) GetExpired(now time.Time, limit int) ([]*models.Offer, error) {
+	offers := []*models.Offer{}
+	err := r.DB.Where("status IN ? AND expires_at <= ?", activeOfferStatuses, now).
+		Order("expires_at ASC").Limit(limit).Find(&offers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired offers: %w", err)
+	}
+	return offers, nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentWebhookRepositoryInterface interface {
+	CreateIfNew(event *models.PaymentWebhookEvent) (bool, error)
+	GetByID(id string) (*models.PaymentWebhookEvent, error)
+	GetByProviderEventID(provider, eventID string) (*models.PaymentWebhookEvent, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.PaymentWebhookEvent, error)
+	GetAll(status models.PaymentWebhookStatus, limit int) ([]*models.PaymentWebhookEvent, error)
+	GetRetryable(maxAttempts int, limit int) ([]*models.PaymentWebhookEvent, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+}
+
+type PaymentWebhookRepository struct {
+	DB *gorm.DB
+}
+
+func NewPaymentWebhookRepository(db *gorm.DB) *PaymentWebhookRepository {
+	return &PaymentWebhookRepository{DB: db}
+}
+
+// CreateIfNew stores an event unless the provider already delivered one with the same event ID; it reports whether it was stored
+func (r *PaymentWebhookRepository) CreateIfNew(event *models.PaymentWebhookEvent) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to store payment webhook event: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *PaymentWebhookRepository) GetByID(id string) (*models.PaymentWebhookEvent, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &models.PaymentWebhookEvent{}
+	if err := r.DB.First(event, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("payment webhook event with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get payment webhook event: %w", err)
+	}
+	return event, nil
+}
+
+// GetByProviderEventID retrieves the stored copy of a provider's event
+func (r *PaymentWebhookRepository) GetByProviderEventID(provider, eventID string) (*models.PaymentWebhookEvent, error) {
+	event := &models.PaymentWebhookEvent{}
+	if err := r.DB.First(event, "provider = ? AND event_id = ?", provider, eventID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payment webhook event: %w", err)
+	}
+	return event, nil
+}
+
+// GetByIDForUpdateWithTx retrieves an event and locks its row so it is never applied twice concurrently
+func (r *PaymentWebhookRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.PaymentWebhookEvent, error) {
+	event := &models.PaymentWebhookEvent{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("payment webhook event with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get payment webhook event: %w", err)
+	}
+	return event, nil
+}
+
+// GetAll retrieves the newest events, optionally only those in one status
+func (r *PaymentWebhookRepository) GetAll(status models.PaymentWebhookStatus, limit int) ([]*models.PaymentWebhookEvent, error) {
+	events := []*models.PaymentWebhookEvent{}
+
+	query := r.DB.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payment webhook events: %w", err)
+	}
+	return events, nil
+}
+
+// GetRetryable retrieves failed events, and received ones whose processing never finished, oldest first.
+// A positive maxAttempts leaves out events that have been tried that many times.
+func (r *PaymentWebhookRepository) GetRetryable(maxAttempts int, limit int) ([]*models.PaymentWebhookEvent, error) {
+	events := []*models.PaymentWebhookEvent{}
+
+	query := r.DB.Where("status IN ?", []models.PaymentWebhookStatus{models.PaymentWebhookStatusFailed, models.PaymentWebhookStatusReceived})
+	if maxAttempts > 0 {
+		query = query.Where("attempts < ?", maxAttempts)
+	}
+	if err := query.Order("created_at ASC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get retryable payment webhook events: %w", err)
+	}
+	return events, nil
+}
+
+// UpdateFieldsWithTx updates the given columns of an event
+func (r *PaymentWebhookRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.PaymentWebhookEvent{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update payment webhook event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("payment webhook event with id %s not found", id.String())
+	}
+	return nil
+}
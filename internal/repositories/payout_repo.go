@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PayoutRepositoryInterface interface {
+	SaveAccount(account *models.PayoutAccount) error
+	GetAccountBySellerID(sellerID uuid.UUID) (*models.PayoutAccount, error)
+	GetAccountsBySellerIDsWithTx(tx *gorm.DB, sellerIDs []uuid.UUID) (map[uuid.UUID]*models.PayoutAccount, error)
+	GetPayableSalesWithTx(tx *gorm.DB, sellerID *uuid.UUID) ([]*models.Transaction, error)
+	SumPendingBySellerID(sellerID uuid.UUID) (float64, error)
+	CreateBatchWithTx(tx *gorm.DB, batch *models.PayoutBatch) error
+	GetBatches(status models.PayoutBatchStatus, limit int) ([]*models.PayoutBatch, error)
+	GetBatchByID(id string) (*models.PayoutBatch, error)
+	GetBatchByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.PayoutBatch, error)
+	UpdateBatchFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	UpdatePayoutFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	ReleaseItemsWithTx(tx *gorm.DB, payoutID uuid.UUID) error
+	GetPayoutsBySellerID(sellerID uuid.UUID) ([]*models.Payout, error)
+}
+
+type PayoutRepository struct {
+	DB *gorm.DB
+}
+
+func NewPayoutRepository(db *gorm.DB) *PayoutRepository {
+	return &PayoutRepository{DB: db}
+}
+
+// SaveAccount registers a seller's payout account, replacing the one they had
+func (r *PayoutRepository) SaveAccount(account *models.PayoutAccount) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "seller_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bank_name", "bank_code", "account_name", "account_number_encrypted", "account_number_last4", "updated_at"}),
+	}).Create(account).Error
+	if err != nil {
+		return fmt.Errorf("failed to save payout account: %w", err)
+	}
+	return nil
+}
+
+func (r *PayoutRepository) GetAccountBySellerID(sellerID uuid.UUID) (*models.PayoutAccount, error) {
+	account := &models.PayoutAccount{}
+	if err := r.DB.First(account, "seller_id = ?", sellerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no payout account registered")
+		}
+		return nil, fmt.Errorf("failed to get payout account: %w", err)
+	}
+	return account, nil
+}
+
+// GetAccountsBySellerIDsWithTx retrieves the payout accounts of the sellers that registered one, keyed by seller
+func (r *PayoutRepository) GetAccountsBySellerIDsWithTx(tx *gorm.DB, sellerIDs []uuid.UUID) (map[uuid.UUID]*models.PayoutAccount, error) {
+	accounts := []*models.PayoutAccount{}
+	bySeller := map[uuid.UUID]*models.PayoutAccount{}
+	if len(sellerIDs) == 0 {
+		return bySeller, nil
+	}
+
+	if err := tx.Where("seller_id IN ?", sellerIDs).Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payout accounts: %w", err)
+	}
+	for _, account := range accounts {
+		bySeller[account.SellerID] = account
+	}
+	return bySeller, nil
+}
+
+// GetPayableSalesWithTx retrieves completed sales, and the reservation deposits credited to them, whose money was
+// released to the seller and is not in an open or settled payout yet, optionally of one seller, oldest first
+func (r *PayoutRepository) GetPayableSalesWithTx(tx *gorm.DB, sellerID *uuid.UUID) ([]*models.Transaction, error) {
+	transactions := []*models.Transaction{}
+
+	query := tx.Preload("Fees").
+		Where("type IN ? AND escrow_status IN ?", []models.TransactionType{models.TransactionTypeSale, models.TransactionTypeDeposit},
+			[]models.EscrowStatus{models.EscrowStatusReleased, models.EscrowStatusPartiallyRefunded}).
+		Where("NOT EXISTS (SELECT 1 FROM payout_items WHERE payout_items.transaction_id = transactions.id AND payout_items.released = false)")
+	if sellerID != nil {
+		query = query.Where("seller_id = ?", *sellerID)
+	}
+	if err := query.Order("transaction_date ASC").Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payable sales: %w", err)
+	}
+	return transactions, nil
+}
+
+// SumPendingBySellerID totals a seller's payouts that are in a batch but not settled yet
+func (r *PayoutRepository) SumPendingBySellerID(sellerID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.DB.Model(&models.Payout{}).Select("COALESCE(SUM(amount), 0)").
+		Where("seller_id = ? AND status = ?", sellerID, models.PayoutStatusPending).Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum pending payouts: %w", err)
+	}
+	return total, nil
+}
+
+// CreateBatchWithTx records a batch together with its payouts and their items
+func (r *PayoutRepository) CreateBatchWithTx(tx *gorm.DB, batch *models.PayoutBatch) error {
+	if err := tx.Create(batch).Error; err != nil {
+		return fmt.Errorf("failed to create payout batch: %w", err)
+	}
+	return nil
+}
+
+// GetBatches retrieves the newest batches, optionally only those in one status
+func (r *PayoutRepository) GetBatches(status models.PayoutBatchStatus, limit int) ([]*models.PayoutBatch, error) {
+	batches := []*models.PayoutBatch{}
+
+	query := r.DB.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payout batches: %w", err)
+	}
+	return batches, nil
+}
+
+// GetBatchByID retrieves a batch with its payouts, their sellers and the sales that funded them
+func (r *PayoutRepository) GetBatchByID(id string) (*models.PayoutBatch, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &models.PayoutBatch{}
+	err = r.DB.Preload("Payouts", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, reference ASC") }).
+		Preload("Payouts.Seller").Preload("Payouts.Items").
+		First(batch, "id = ?", parsedID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("payout batch with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	return batch, nil
+}
+
+// GetBatchByIDForUpdateWithTx retrieves a batch with its payouts and locks the batch row until the database transaction ends
+func (r *PayoutRepository) GetBatchByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(batch, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("payout batch with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	if err := tx.Where("batch_id = ?", id).Find(&batch.Payouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payouts of batch: %w", err)
+	}
+	return batch, nil
+}
+
+func (r *PayoutRepository) UpdateBatchFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.PayoutBatch{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update payout batch: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("payout batch with id %s not found", id.String())
+	}
+	return nil
+}
+
+func (r *PayoutRepository) UpdatePayoutFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.Payout{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update payout: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("payout with id %s not found", id.String())
+	}
+	return nil
+}
+
+// ReleaseItemsWithTx frees the sales of a failed or cancelled payout so they can be paid out again
+func (r *PayoutRepository) ReleaseItemsWithTx(tx *gorm.DB, payoutID uuid.UUID) error {
+	if err := tx.Model(&models.PayoutItem{}).Where("payout_id = ?", payoutID).Update("released", true).Error; err != nil {
+		return fmt.Errorf("failed to release payout items: %w", err)
+	}
+	return nil
+}
+
+// GetPayoutsBySellerID retrieves a seller's payouts with the sales that funded them, newest first
+func (r *PayoutRepository) GetPayoutsBySellerID(sellerID uuid.UUID) ([]*models.Payout, error) {
+	payouts := []*models.Payout{}
+	if err := r.DB.Preload("Items").Where("seller_id = ?", sellerID).Order("created_at DESC").Find(&payouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payouts: %w", err)
+	}
+	return payouts, nil
+}
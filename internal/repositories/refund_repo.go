@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+)
+
+type RefundRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, refund *models.Refund) error
+	GetByID(id string) (*models.Refund, error)
+	CountByWebhookEventIDWithTx(tx *gorm.DB, webhookEventID uuid.UUID) (int64, error)
+	AssignWebhookEventWithTx(tx *gorm.DB, transactionID uuid.UUID, amount int64, webhookEventID uuid.UUID) (bool, error)
+	GetAll(status models.RefundStatus, limit int) ([]*models.Refund, error)
+	GetRetryable(maxAttempts int, createdBefore time.Time, limit int) ([]*models.Refund, error)
+	UpdateIfStatus(id uuid.UUID, status models.RefundStatus, fields map[string]any) (bool, error)
+}
+
+type RefundRepository struct {
+	DB *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+	return &RefundRepository{DB: db}
+}
+
+// CreateWithTx records a refund in the database transaction that decided it
+func (r *RefundRepository) CreateWithTx(tx *gorm.DB, refund *models.Refund) error {
+	if refund.ID == uuid.Nil {
+		refund.ID = uuid.New()
+	}
+	if refund.IdempotencyKey == "" {
+		// the record's own id, so every attempt at the same refund reaches the provider under the same key
+		refund.IdempotencyKey = "refund-" + refund.ID.String()
+	}
+	if err := tx.Create(refund).Error; err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
+	}
+	return nil
+}
+
+func (r *RefundRepository) GetByID(id string) (*models.Refund, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{}
+	if err := r.DB.First(refund, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("refund with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return refund, nil
+}
+
+// CountByWebhookEventIDWithTx counts the refunds recorded for the payment a webhook event reported
+func (r *RefundRepository) CountByWebhookEventIDWithTx(tx *gorm.DB, webhookEventID uuid.UUID) (int64, error) {
+	var count int64
+	if err := tx.Model(&models.Refund{}).Where("webhook_event_id = ?", webhookEventID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count refunds: %w", err)
+	}
+	return count, nil
+}
+
+// AssignWebhookEventWithTx ties the oldest refund of amount minor units of a transaction that no webhook event accounts
+// for yet to webhookEventID, reporting whether there was one
+func (r *RefundRepository) AssignWebhookEventWithTx(tx *gorm.DB, transactionID uuid.UUID, amount int64, webhookEventID uuid.UUID) (bool, error) {
+	refund := &models.Refund{}
+	err := tx.Where("transaction_id = ? AND amount = ? AND webhook_event_id IS NULL", transactionID, amount).
+		Order("created_at ASC").First(refund).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get refund: %w", err)
+	}
+
+	if err := tx.Model(refund).Update("webhook_event_id", webhookEventID).Error; err != nil {
+		return false, fmt.Errorf("failed to update refund: %w", err)
+	}
+	return true, nil
+}
+
+// GetAll retrieves the newest refunds, optionally only those in one status
+func (r *RefundRepository) GetAll(status models.RefundStatus, limit int) ([]*models.Refund, error) {
+	refunds := []*models.Refund{}
+
+	query := r.DB.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// GetRetryable retrieves pending refunds recorded before createdBefore that have been tried fewer than maxAttempts
+// times, oldest first
+func (r *RefundRepository) GetRetryable(maxAttempts int, createdBefore time.Time, limit int) ([]*models.Refund, error) {
+	refunds := []*models.Refund{}
+
+	err := r.DB.Where("status = ? AND attempts < ? AND created_at < ?", models.RefundStatusPending, maxAttempts, createdBefore).
+		Order("created_at ASC").Limit(limit).Find(&refunds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retryable refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// UpdateIfStatus updates the given columns of a refund that is still in status, reporting whether it was
+func (r *RefundRepository) UpdateIfStatus(id uuid.UUID, status models.RefundStatus, fields map[string]any) (bool, error) {
+	result := r.DB.Model(&models.Refund{}).Where("id = ? AND status = ?", id, status).Updates(fields)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update refund: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"gorm.io/gorm"
+)
+
+type RejectionReasonRepositoryInterface interface {
+	Create(reason *models.RejectionReason) error
+	GetAll(includeInactive bool) ([]*models.RejectionReason, error)
+	GetByCode(code string) (*models.RejectionReason, error)
+	GetByCodes(codes []string) ([]*models.RejectionReason, error)
+	Update(reason *models.RejectionReason) error
+}
+
+type RejectionReasonRepository struct {
+	DB *gorm.DB
+}
+
+func NewRejectionReasonRepository(db *gorm.DB) *RejectionReasonRepository {
+	return &RejectionReasonRepository{DB: db}
+}
+
+// Create adds a new rejection reason code
+func (r *RejectionReasonRepository) Create(reason *models.RejectionReason) error {
+	if err := r.DB.Create(reason).Error; err != nil {
+		return fmt.Errorf("failed to create rejection reason: %w", err)
+	}
+	return nil
+}
+
+// GetAll retrieves the rejection reasons ordered by code, optionally including deactivated ones
+func (r *RejectionReasonRepository) GetAll(includeInactive bool) ([]*models.RejectionReason, error) {
+	reasons := []*models.RejectionReason{}
+
+	query := r.DB.Order("code ASC")
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&reasons).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rejection reasons: %w", err)
+	}
+	return reasons, nil
+}
+
+// GetByCode retrieves a rejection reason by its code
+func (r *RejectionReasonRepository) GetByCode(code string) (*models.RejectionReason, error) {
+	reason := &models.RejectionReason{}
+
+	if err := r.DB.First(reason, "code = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("rejection reason '%s' not found", code)
+		}
+		return nil, fmt.Errorf("failed to get rejection reason: %w", err)
+	}
+	return reason, nil
+}
+
+// GetByCodes retrieves the rejection reasons with the given codes, active or not
+func (r *RejectionReasonRepository) GetByCodes(codes []string) ([]*models.RejectionReason, error) {
+	reasons := []*models.RejectionReason{}
+
+	if err := r.DB.Where("code IN ?", codes).Find(&reasons).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rejection reasons: %w", err)
+	}
+	return reasons, nil
+}
+
+// Update saves the label, description and active flag of a rejection reason
+func (r *RejectionReasonRepository) Update(reason *models.RejectionReason) error {
+	result := r.DB.Model(reason).Select("label", "description", "active").Updates(reason)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update rejection reason: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("rejection reason '%s' not found", reason.Code)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TestDriveRepositoryInterface interface {
+	CreateWithTx(tx *gorm.DB, drive *models.TestDrive) error
+	CountOpenByBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error)
+	GetByID(id string) (*models.TestDrive, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.TestDrive, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	ReplaceWindowsWithTx(tx *gorm.DB, id uuid.UUID, windows []models.TestDriveWindow) error
+	LockUsersWithTx(tx *gorm.DB, userIDs ...uuid.UUID) error
+	CountConfirmedStartingBetweenWithTx(tx *gorm.DB, userID, excludeID uuid.UUID, after, before time.Time) (int64, error)
+	GetBySellerID(sellerID uuid.UUID, status models.TestDriveStatus) ([]*models.TestDrive, error)
+	GetByBuyerID(buyerID uuid.UUID) ([]*models.TestDrive, error)
+	CountNoShowsByUserID(userID uuid.UUID) (int64, error)
+	GetExpired(now time.Time, limit int) ([]*models.TestDrive, error)
+	GetDueForReminder(before time.Time) ([]*models.TestDrive, error)
+	MarkReminderSent(id uuid.UUID, sentAt time.Time) error
+}
+
+type TestDriveRepository struct {
+	DB *gorm.DB
+}
+
+func NewTestDriveRepository(db *gorm.DB) *TestDriveRepository {
+	return &TestDriveRepository{DB: db}
+}
+
+// openTestDriveStatuses are the statuses of a test drive that has not ended yet
+var openTestDriveStatuses = []models.TestDriveStatus{models.TestDriveStatusRequested, models.TestDriveStatusRescheduled, models.TestDriveStatusConfirmed}
+
+// CreateWithTx adds a test drive together with its proposed windows
+func (r *TestDriveRepository) CreateWithTx(tx *gorm.DB, drive *models.TestDrive) error {
+	if err := tx.Create(drive).Error; err != nil {
+		return fmt.Errorf("failed to create test drive: %w", err)
+	}
+	return nil
+}
+
+func (r *TestDriveRepository) CountOpenByBuyerWithTx(tx *gorm.DB, listingID, buyerID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.TestDrive{}).
+		Where("listing_id = ? AND buyer_id = ? AND status IN ?", listingID, buyerID, openTestDriveStatuses).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count open test drives: %w", err)
+	}
+	return count, nil
+}
+
+// GetByID retrieves a test drive with its listing and seller, its buyer and the windows they proposed
+func (r *TestDriveRepository) GetByID(id string) (*models.TestDrive, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	drive := &models.TestDrive{}
+	err = r.DB.Preload("Listing.Seller").Preload("Buyer").
+		Preload("Windows", func(db *gorm.DB) *gorm.DB { return db.Order("starts_at ASC") }).
+		First(drive, "id = ?", parsedID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("test drive with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get test drive: %w", err)
+	}
+	return drive, nil
+}
+
+// GetByIDForUpdateWithTx retrieves a test drive with its windows and locks its row until the database transaction ends
+func (r *TestDriveRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.TestDrive, error) {
+	drive := &models.TestDrive{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(drive, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("test drive with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get test drive: %w", err)
+	}
+	if err := tx.Where("test_drive_id = ?", id).Order("starts_at ASC").Find(&drive.Windows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get test drive windows: %w", err)
+	}
+	return drive, nil
+}
+
+func (r *TestDriveRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.TestDrive{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update test drive: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("test drive with id %s not found", id.String())
+	}
+	return nil
+}
+
+// ReplaceWindowsWithTx swaps the windows a buyer proposed for new ones
+func (r *TestDriveRepository) ReplaceWindowsWithTx(tx *gorm.DB, id uuid.UUID, windows []models.TestDriveWindow) error {
+	if err := tx.Where("test_drive_id = ?", id).Delete(&models.TestDriveWindow{}).Error; err != nil {
+		return fmt.Errorf("failed to replace test drive windows: %w", err)
+	}
+	for i := range windows {
+		windows[i].TestDriveID = id
+	}
+	if err := tx.Create(&windows).Error; err != nil {
+		return fmt.Errorf("failed to replace test drive windows: %w", err)
+	}
+	return nil
+}
+
+// LockUsersWithTx serialises the bookings of the given users by locking their user rows, in id order so two bookings
+// sharing a user cannot deadlock
+func (r *TestDriveRepository) LockUsersWithTx(tx *gorm.DB, userIDs ...uuid.UUID) error {
+	users := []*models.User{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id IN ?", userIDs).Order("id").Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to lock test drive calendars: %w", err)
+	}
+	return nil
+}
+
+// CountConfirmedStartingBetweenWithTx counts the user's confirmed test drives, as buyer or seller and other than
+// excludeID, that start strictly between after and before
+func (r *TestDriveRepository) CountConfirmedStartingBetweenWithTx(tx *gorm.DB, userID, excludeID uuid.UUID, after, before time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&models.TestDrive{}).
+		Where("status = ? AND (buyer_id = ? OR seller_id = ?) AND id <> ? AND scheduled_for > ? AND scheduled_for < ?",
+			models.TestDriveStatusConfirmed, userID, userID, excludeID, after, before).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to check test drive conflicts: %w", err)
+	}
+	return count, nil
+}
+
+// GetBySellerID retrieves the test drives of a seller's listings, soonest first; without a status only open ones
+func (r *TestDriveRepository) GetBySellerID(sellerID uuid.UUID, status models.TestDriveStatus) ([]*models.TestDrive, error) {
+	drives := []*models.TestDrive{}
+
+	query := r.DB.Preload("Listing").Preload("Buyer").
+		Preload("Windows", func(db *gorm.DB) *gorm.DB { return db.Order("starts_at ASC") }).
+		Where("seller_id = ?", sellerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", openTestDriveStatuses)
+	}
+	if err := query.Order("expires_at ASC").Find(&drives).Error; err != nil {
+		return nil, fmt.Errorf("failed to get test drives: %w", err)
+	}
+	return drives, nil
+}
+
+// GetByBuyerID retrieves a buyer's test drives with their listings, newest first
+func (r *TestDriveRepository) GetByBuyerID(buyerID uuid.UUID) ([]*models.TestDrive, error) {
+	drives := []*models.TestDrive{}
+	err := r.DB.Preload("Listing").
+		Preload("Windows", func(db *gorm.DB) *gorm.DB { return db.Order("starts_at ASC") }).
+		Where("buyer_id = ?", buyerID).Order("created_at DESC").Find(&drives).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test drives: %w", err)
+	}
+	return drives, nil
+}
+
+// CountNoShowsByUserID counts the confirmed test drives a user did not turn up to
+func (r *TestDriveRepository) CountNoShowsByUserID(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.TestDrive{}).
+		Where("status = ? AND no_show_user_id = ?", models.TestDriveStatusNoShow, userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count test drive no-shows: %w", err)
+	}
+	return count, nil
+}
+
+// GetExpired retrieves requested or rescheduled test drives whose proposed times have passed, oldest first
+func (r *TestDriveRepository) GetExpired(now time.Time, limit int) ([]*models.TestDrive, error) {
+	drives := []*models.TestDrive{}
+	err := r.DB.Where("status IN ? AND expires_at <= ?", []models.TestDriveStatus{models.TestDriveStatusRequested, models.TestDriveStatusRescheduled}, now).
+		Order("expires_at ASC").Limit(limit).Find(&drives).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired test drives: %w", err)
+	}
+	return drives, nil
+}
+
+// GetDueForReminder retrieves confirmed test drives starting before the given time that have not been reminded yet
+func (r *TestDriveRepository) GetDueForReminder(before time.Time) ([]*models.TestDrive, error) {
+	drives := []*models.TestDrive{}
+
+	err := r.DB.Preload("Listing.Seller").Preload("Buyer").
+		Where("status = ? AND reminder_sent_at IS NULL AND scheduled_for > ? AND scheduled_for <= ?", models.TestDriveStatusConfirmed, time.Now(), before).
+		Find(&drives).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test drives due for reminder: %w", err)
+	}
+	return drives, nil
+}
+
+// MarkReminderSent records that reminder emails went out for a test drive
+func (r *TestDriveRepository) MarkReminderSent(id uuid.UUID, sentAt time.Time) error {
+	if err := r.DB.Model(&models.TestDrive{}).Where("id = ?", id).Update("reminder_sent_at", sentAt).Error; err != nil {
+		return fmt.Errorf("failed to mark test drive reminder sent: %w", err)
+	}
+	return nil
+}
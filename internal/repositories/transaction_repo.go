@@ -0,0 +1,309 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransactionRepositoryInterface interface {
+	GetByListingIDs(listingIDs []uuid.UUID, status models.TransactionStatus) ([]*models.Transaction, error)
+	CreateWithTx(tx *gorm.DB, transaction *models.Transaction) error
+	UpdateStatusWithTx(tx *gorm.DB, id uuid.UUID, status models.TransactionStatus) error
+	GetByID(id string) (*models.Transaction, error)
+	GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Transaction, error)
+	CountOpenSalesByListingIDWithTx(tx *gorm.DB, listingID uuid.UUID) (int64, error)
+	GetByUserID(userID uuid.UUID) ([]*models.Transaction, error)
+	UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	GetByProviderReferenceForUpdateWithTx(tx *gorm.DB, provider, reference string) (*models.Transaction, error)
+	CreateEventWithTx(tx *gorm.DB, event *models.TransactionEvent) error
+	GetEvents(transactionID uuid.UUID) ([]*models.TransactionEvent, error)
+	GetEscrowsPastDeadline(now time.Time, limit int) ([]*models.Transaction, error)
+	CreateCancellationRequestWithTx(tx *gorm.DB, request *models.CancellationRequest) error
+	CountPendingCancellationRequestsWithTx(tx *gorm.DB, transactionID uuid.UUID) (int64, error)
+	GetCancellationRequestByID(id string) (*models.CancellationRequest, error)
+	GetCancellationRequestForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.CancellationRequest, error)
+	GetCancellationRequestsByTransactionID(transactionID uuid.UUID) ([]*models.CancellationRequest, error)
+	GetCancellationRequests(status models.CancellationRequestStatus, limit int) ([]*models.CancellationRequest, error)
+	UpdateCancellationRequestFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error
+	ClosePendingCancellationRequestsWithTx(tx *gorm.DB, transactionID uuid.UUID) error
+	GetOpenDepositByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) (*models.Transaction, error)
+}
+
+type TransactionRepository struct {
+	DB *gorm.DB
+}
+
+func NewTransactionRepository(db *gorm.DB) *TransactionRepository {
+	return &TransactionRepository{DB: db}
+}
+
+// GetByListingIDs retrieves sale transactions of the given status for any of the listings
+func (r *TransactionRepository) GetByListingIDs(listingIDs []uuid.UUID, status models.TransactionStatus) ([]*models.Transaction, error) {
+	transactions := []*models.Transaction{}
+	if len(listingIDs) == 0 {
+		return transactions, nil
+	}
+
+	if err := r.DB.Where("listing_id IN ? AND status = ? AND type = ?", listingIDs, status, models.TransactionTypeSale).Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transactions by listing IDs: %w", err)
+	}
+	return transactions, nil
+}
+
+// CreateWithTx records a new transaction within an ongoing database transaction
+func (r *TransactionRepository) CreateWithTx(tx *gorm.DB, transaction *models.Transaction) error {
+	if err := tx.Create(transaction).Error; err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatusWithTx moves a transaction to a new status, stamping the transaction date when it completes
+func (r *TransactionRepository) UpdateStatusWithTx(tx *gorm.DB, id uuid.UUID, status models.TransactionStatus) error {
+	fields := map[string]any{"status": status}
+	if status == models.TransactionStatusCompleted {
+		fields["transaction_date"] = time.Now()
+	}
+
+	result := tx.Model(&models.Transaction{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("transaction with id %s not found", id.String())
+	}
+	return nil
+}
+
+// GetByID retrieves a transaction with its listing, both parties and fee line items
+func (r *TransactionRepository) GetByID(id string) (*models.Transaction, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &models.Transaction{}
+	if err := r.DB.Preload("Listing").Preload("Buyer").Preload("Seller").Preload("Fees").First(transaction, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("transaction with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// GetByIDForUpdateWithTx retrieves a transaction and locks its row until the database transaction ends
+func (r *TransactionRepository) GetByIDForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(transaction, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("transaction with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// CountOpenSalesByListingIDWithTx counts the pending or completed sales of a listing
+func (r *TransactionRepository) CountOpenSalesByListingIDWithTx(tx *gorm.DB, listingID uuid.UUID) (int64, error) {
+	var count int64
+
+	err := tx.Model(&models.Transaction{}).
+		Where("listing_id = ? AND type = ? AND status IN ?", listingID, models.TransactionTypeSale,
+			[]models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusCompleted}).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count open sales for listing: %w", err)
+	}
+	return count, nil
+}
+
+// GetByUserID retrieves every transaction a user is the buyer or seller of, newest first
+func (r *TransactionRepository) GetByUserID(userID uuid.UUID) ([]*models.Transaction, error) {
+	transactions := []*models.Transaction{}
+
+	if err := r.DB.Preload("Listing").Where("buyer_id = ? OR seller_id = ?", userID, userID).Order("created_at DESC").Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transactions by user: %w", err)
+	}
+	return transactions, nil
+}
+
+// UpdateFieldsWithTx updates the given columns of a transaction
+func (r *TransactionRepository) UpdateFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.Transaction{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("transaction with id %s not found", id.String())
+	}
+	return nil
+}
+
+// GetByProviderReferenceForUpdateWithTx retrieves and locks the transaction a provider payment belongs to
+func (r *TransactionRepository) GetByProviderReferenceForUpdateWithTx(tx *gorm.DB, provider, reference string) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(transaction, "payment_provider = ? AND provider_reference = ?", provider, reference).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no transaction has %s payment reference %s", provider, reference)
+		}
+		return nil, fmt.Errorf("failed to get transaction by payment reference: %w", err)
+	}
+	return transaction, nil
+}
+
+// CreateEventWithTx records a change to a transaction in its audit trail
+func (r *TransactionRepository) CreateEventWithTx(tx *gorm.DB, event *models.TransactionEvent) error {
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record transaction event: %w", err)
+	}
+	return nil
+}
+
+// GetEvents retrieves the audit trail of a transaction, oldest first
+func (r *TransactionRepository) GetEvents(transactionID uuid.UUID) ([]*models.TransactionEvent, error) {
+	events := []*models.TransactionEvent{}
+	if err := r.DB.Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transaction events: %w", err)
+	}
+	return events, nil
+}
+
+// GetEscrowsPastDeadline retrieves sales and deposits whose current escrow step expired, oldest deadline first.
+// A deposit whose listing has a sale in progress is left out, so it cannot crowd the batch while it waits: its hold
+// only lapses once that sale ends. Unpaid inspection fees are released along with their booking by the pre-purchase
+// inspection job instead.
+func (r *TransactionRepository) GetEscrowsPastDeadline(now time.Time, limit int) ([]*models.Transaction, error) {
+	transactions := []*models.Transaction{}
+
+	err := r.DB.Where("escrow_status IN ? AND escrow_deadline <= ?", []models.EscrowStatus{
+		models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, models.EscrowStatusHandedOver, models.EscrowStatusDisputed,
+	}, now).
+		Where("NOT (type = ? AND EXISTS (SELECT 1 FROM transactions AS sales WHERE sales.listing_id = transactions.listing_id AND sales.type = ? AND sales.status IN ?))",
+			models.TransactionTypeDeposit, models.TransactionTypeSale,
+			[]models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusCompleted}).
+		Where("type <> ?", models.TransactionTypeInspectionFee).
+		Order("escrow_deadline ASC").Limit(limit).Find(&transactions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired escrows: %w", err)
+	}
+	return transactions, nil
+}
+
+func (r *TransactionRepository) CreateCancellationRequestWithTx(tx *gorm.DB, request *models.CancellationRequest) error {
+	if err := tx.Create(request).Error; err != nil {
+		return fmt.Errorf("failed to create cancellation request: %w", err)
+	}
+	return nil
+}
+
+func (r *TransactionRepository) CountPendingCancellationRequestsWithTx(tx *gorm.DB, transactionID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.CancellationRequest{}).
+		Where("transaction_id = ? AND status = ?", transactionID, models.CancellationRequestStatusPending).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending cancellation requests: %w", err)
+	}
+	return count, nil
+}
+
+func (r *TransactionRepository) GetCancellationRequestByID(id string) (*models.CancellationRequest, error) {
+	parsedID, err := pkg.StringToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.CancellationRequest{}
+	if err := r.DB.First(request, "id = ?", parsedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("cancellation request with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get cancellation request: %w", err)
+	}
+	return request, nil
+}
+
+// GetCancellationRequestForUpdateWithTx retrieves a cancellation request and locks its row until the database transaction ends
+func (r *TransactionRepository) GetCancellationRequestForUpdateWithTx(tx *gorm.DB, id uuid.UUID) (*models.CancellationRequest, error) {
+	request := &models.CancellationRequest{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(request, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("cancellation request with id %s not found", id.String())
+		}
+		return nil, fmt.Errorf("failed to get cancellation request: %w", err)
+	}
+	return request, nil
+}
+
+// GetCancellationRequestsByTransactionID retrieves the cancellation requests of a sale, oldest first
+func (r *TransactionRepository) GetCancellationRequestsByTransactionID(transactionID uuid.UUID) ([]*models.CancellationRequest, error) {
+	requests := []*models.CancellationRequest{}
+	if err := r.DB.Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cancellation requests: %w", err)
+	}
+	return requests, nil
+}
+
+// GetCancellationRequests retrieves cancellation requests, optionally only those in one status, oldest first so
+// waiting requests are handled in order
+func (r *TransactionRepository) GetCancellationRequests(status models.CancellationRequestStatus, limit int) ([]*models.CancellationRequest, error) {
+	requests := []*models.CancellationRequest{}
+
+	query := r.DB.Order("created_at ASC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cancellation requests: %w", err)
+	}
+	return requests, nil
+}
+
+func (r *TransactionRepository) UpdateCancellationRequestFieldsWithTx(tx *gorm.DB, id uuid.UUID, fields map[string]any) error {
+	result := tx.Model(&models.CancellationRequest{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update cancellation request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("cancellation request with id %s not found", id.String())
+	}
+	return nil
+}
+
+// ClosePendingCancellationRequestsWithTx closes the pending cancellation request of a sale that was settled some other way
+func (r *TransactionRepository) ClosePendingCancellationRequestsWithTx(tx *gorm.DB, transactionID uuid.UUID) error {
+	err := tx.Model(&models.CancellationRequest{}).
+		Where("transaction_id = ? AND status = ?", transactionID, models.CancellationRequestStatusPending).
+		Update("status", models.CancellationRequestStatusClosed).Error
+	if err != nil {
+		return fmt.Errorf("failed to close pending cancellation requests: %w", err)
+	}
+	return nil
+}
+
+// GetOpenDepositByListingIDForUpdateWithTx retrieves and locks the pending reservation deposit of a listing, or nil if
+// the listing is not reserved
+func (r *TransactionRepository) GetOpenDepositByListingIDForUpdateWithTx(tx *gorm.DB, listingID uuid.UUID) (*models.Transaction, error) {
+	deposits := []*models.Transaction{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND type = ? AND status = ?", listingID, models.TransactionTypeDeposit, models.TransactionStatusPending).
+		Limit(1).Find(&deposits).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation deposit: %w", err)
+	}
+	if len(deposits) == 0 {
+		return nil, nil
+	}
+	return deposits[0], nil
+}
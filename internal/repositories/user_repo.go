@@ -6,6 +6,7 @@ import (
 
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
 	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
 	"gorm.io/gorm"
 )
 
@@ -14,6 +15,7 @@ type UserRepositoryInterface interface {
 	Create(user *models.User) error
 	GetByEmail(email string) (*models.User, error) 
 	GetByID(id string) (*models.User, error)     
+	GetByRole(role types.Role) ([]*models.User, error)
 }
 
 type UserRepository struct {
@@ -74,3 +76,11 @@ func (u *UserRepository) GetByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByRole retrieves every user with the given role, ordered by name
+func (u *UserRepository) GetByRole(role types.Role) ([]*models.User, error) {
+	users := []*models.User{}
+	if err := u.db.Where("role = ?", role).Order("name ASC").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users by role: %w", err)
+	}
+	return users, nil
+}
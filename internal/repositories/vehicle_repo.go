@@ -14,6 +14,7 @@ type VehicleRepositoryInterface interface {
 	CreateWithTx(tx *gorm.DB, vehicle *models.Vehicle)
 	GetByID(id string) (*models.Vehicle, error)
 	Update(vehicle *models.Vehicle) error
+	UpdateWithTx(tx *gorm.DB, vehicle *models.Vehicle) error
 	Delete(id string) error
 	GetByVIN(vin string) (*models.Vehicle, error)
 	GetByVINWithTx(tx *gorm.DB, vin string) (*models.Vehicle, error)
@@ -57,7 +58,12 @@ func(v *VehicleRepository) GetByID(id string)  (*models.Vehicle, error) {
 }
 
 func(v *VehicleRepository) Update(vehicle *models.Vehicle) error {
-	result := v.db.Updates(vehicle)
+	return v.UpdateWithTx(v.db, vehicle)
+}
+
+// UpdateWithTx updates a vehicle record within an ongoing transaction
+func(v *VehicleRepository) UpdateWithTx(tx *gorm.DB, vehicle *models.Vehicle) error {
+	result := tx.Updates(vehicle)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update vehicle: %w", result.Error)
 	}
@@ -1,34 +1,161 @@
 package routes
 
 import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/zekeriyyah/lujay-autocity/internal/config"
 	"github.com/zekeriyyah/lujay-autocity/internal/database"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
 	"github.com/zekeriyyah/lujay-autocity/internal/handlers"
 	"github.com/zekeriyyah/lujay-autocity/internal/middleware"
 	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/internal/scheduler"
 	"github.com/zekeriyyah/lujay-autocity/internal/services"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	"github.com/zekeriyyah/lujay-autocity/pkg/encryption"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/signing"
+	"github.com/zekeriyyah/lujay-autocity/pkg/storage"
 	"github.com/zekeriyyah/lujay-autocity/pkg/types"
 )
 
-func SetupRouter(r *gin.Engine, cfg *config.Config) *gin.Engine {
+// SetupRouter wires the repositories, services and handlers onto r. It returns the background jobs the services need
+// run without starting them; the caller decides when they run and stops them.
+func SetupRouter(r *gin.Engine, cfg *config.Config) (*gin.Engine, []scheduler.Job, error) {
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(database.DB)
 	listingRepo := repositories.NewListingRepository(database.DB)
 	vehicleRepo := repositories.NewVehicleRepository(database.DB)
 	inspectionRepo := repositories.NewInspectionRepository(database.DB)
+	transactionRepo := repositories.NewTransactionRepository(database.DB)
+	checklistTemplateRepo := repositories.NewChecklistTemplateRepository(database.DB)
+	inspectionSlotRepo := repositories.NewInspectionSlotRepository(database.DB)
+	inspectionBookingRepo := repositories.NewInspectionBookingRepository(database.DB)
+	certificateRepo := repositories.NewCertificateRepository(database.DB)
+	rejectionReasonRepo := repositories.NewRejectionReasonRepository(database.DB)
+	inspectionRequestRepo := repositories.NewInspectionRequestRepository(database.DB)
+	paymentWebhookRepo := repositories.NewPaymentWebhookRepository(database.DB)
+	refundRepo := repositories.NewRefundRepository(database.DB)
+	ledgerRepo := repositories.NewLedgerRepository(database.DB)
+	feeRepo := repositories.NewFeeRepository(database.DB)
+	payoutRepo := repositories.NewPayoutRepository(database.DB)
+	invoiceRepo := repositories.NewInvoiceRepository(database.DB)
+	offerRepo := repositories.NewOfferRepository(database.DB)
+	testDriveRepo := repositories.NewTestDriveRepository(database.DB)
+	conversationRepo := repositories.NewConversationRepository(database.DB)
+
+
+	// Generated files go to Cloudinary when configured, otherwise to local disk served under /files
+	var fileStorage storage.Storage
+	if cfg.CloudinaryCloudName != "" && cfg.CloudinaryAPIKey != "" && cfg.CloudinaryAPISecret != "" {
+		fileStorage = storage.NewCloudinaryStorage(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	} else {
+		fileStorage = storage.NewLocalStorage(cfg.StorageDir, cfg.PublicBaseURL+"/files")
+		r.Static("/files", cfg.StorageDir)
+	}
 
+	// Certificates are signed with a dedicated key, so holding the JWT secret is not enough to forge one
+	signingSeed, err := base64.StdEncoding.DecodeString(cfg.CertificateSigningKey)
+	if err != nil || len(signingSeed) == 0 {
+		return nil, nil, fmt.Errorf("CERTIFICATE_SIGNING_KEY must be a base64-encoded Ed25519 seed")
+	}
+	certificateSigner, err := signing.NewSigner(signingSeed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CERTIFICATE_SIGNING_KEY: %w", err)
+	}
+
+	// Sellers' bank account numbers are sealed with a dedicated key, so they never depend on, or break with, the JWT secret
+	payoutKey, err := base64.StdEncoding.DecodeString(cfg.PayoutEncryptionKey)
+	if err != nil || len(payoutKey) != encryption.KeySize {
+		return nil, nil, fmt.Errorf("PAYOUT_ENCRYPTION_KEY must be a base64-encoded %d-byte key", encryption.KeySize)
+	}
+	payoutCipher, err := encryption.NewCipher(payoutKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paymentProvider, err := newPaymentProvider(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Services publish real-time updates to the hub, which fans them out to the users' open event streams
+	eventHub := events.NewHub()
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo)
-	listingService := services.NewListingService(listingRepo, vehicleRepo, userRepo)
-	inspectionService := services.NewInspectionService(inspectionRepo, listingRepo)
+	listingService := services.NewListingService(listingRepo, vehicleRepo, userRepo, eventHub)
+	certificateService := services.NewCertificateService(certificateRepo, certificateSigner, cfg.PublicBaseURL)
+	rejectionReasonService := services.NewRejectionReasonService(rejectionReasonRepo)
+	ledgerService := services.NewLedgerService(ledgerRepo, cfg.PaymentCurrency)
+	feeService := services.NewFeeService(feeRepo, userRepo, ledgerService, cfg.PrePurchaseInspectionFee)
+	invoiceService := services.NewInvoiceService(invoiceRepo, cfg.PaymentCurrency, cfg.InvoiceTaxRate)
+	refundService := services.NewRefundService(refundRepo, paymentProvider)
+	transactionService := services.NewTransactionService(transactionRepo, listingRepo, ledgerService, feeService, invoiceService, paymentProvider, refundService, cfg.PaymentCurrency, cfg.PublicBaseURL, services.EscrowWindows{
+		Payment:    cfg.EscrowPaymentWindow,
+		Handover:   cfg.EscrowHandoverWindow,
+		Inspection: cfg.EscrowInspectionWindow,
+		Dispute:    cfg.EscrowDisputeWindow,
+
+		Reservation: cfg.ReservationHold,
+	}, cfg.ReservationDepositPercent, eventHub)
+	prePurchaseInspectionService := services.NewPrePurchaseInspectionService(inspectionRequestRepo, listingRepo, inspectionRepo, inspectionSlotRepo, transactionRepo, checklistTemplateRepo, transactionService, feeService, fileStorage, cfg.InspectionChangeCutoff)
+	inspectionService := services.NewInspectionService(inspectionRepo, listingRepo, checklistTemplateRepo, fileStorage, certificateService, rejectionReasonService, prePurchaseInspectionService, eventHub)
+	checklistTemplateService := services.NewChecklistTemplateService(checklistTemplateRepo)
+	inspectionScheduleService := services.NewInspectionScheduleService(inspectionSlotRepo, inspectionBookingRepo, listingRepo, inspectionRepo, prePurchaseInspectionService, cfg.InspectionChangeCutoff, cfg.InspectionReminderLead)
+	reviewQueueService := services.NewReviewQueueService(listingRepo, inspectionRepo, inspectionService, cfg.ReviewSLA, cfg.ReviewClaimTTL)
+	vehicleService := services.NewVehicleService(vehicleRepo, listingRepo, inspectionRepo, transactionRepo)
+	analyticsService := services.NewAnalyticsService(inspectionRepo, listingRepo, userRepo)
+	paymentWebhookService := services.NewPaymentWebhookService(paymentWebhookRepo, transactionService, paymentProvider)
+	payoutService := services.NewPayoutService(payoutRepo, ledgerService, payoutCipher, cfg.PaymentCurrency)
+	offerService := services.NewOfferService(offerRepo, listingRepo, transactionService, cfg.OfferExpiry, eventHub)
+	testDriveService := services.NewTestDriveService(testDriveRepo, listingRepo, cfg.TestDriveReminderLead)
+	conversationService := services.NewConversationService(conversationRepo, listingRepo, fileStorage, eventHub)
 
 	// Initialize Handler
 	authHandler := handlers.NewAuthHandler(authService)
 	listingHandler := handlers.NewListingHandler(listingService)
 	inspectionHandler := handlers.NewInspectionHandler(inspectionService)
+	vehicleHandler := handlers.NewVehicleHandler(vehicleService)
+	checklistTemplateHandler := handlers.NewChecklistTemplateHandler(checklistTemplateService)
+	inspectionScheduleHandler := handlers.NewInspectionScheduleHandler(inspectionScheduleService)
+	certificateHandler := handlers.NewCertificateHandler(certificateService)
+	reviewQueueHandler := handlers.NewReviewQueueHandler(reviewQueueService)
+	rejectionReasonHandler := handlers.NewRejectionReasonHandler(rejectionReasonService)
+	inspectionRequestHandler := handlers.NewInspectionRequestHandler(prePurchaseInspectionService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	paymentWebhookHandler := handlers.NewPaymentWebhookHandler(paymentWebhookService)
+	refundHandler := handlers.NewRefundHandler(refundService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	feeHandler := handlers.NewFeeHandler(feeService)
+	payoutHandler := handlers.NewPayoutHandler(payoutService)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService)
+	offerHandler := handlers.NewOfferHandler(offerService)
+	testDriveHandler := handlers.NewTestDriveHandler(testDriveService)
+	conversationHandler := handlers.NewConversationHandler(conversationService)
+	eventHandler := handlers.NewEventHandler(eventHub)
+
+	// Background jobs need a database connection
+	jobs := []scheduler.Job{}
+	if database.DB != nil {
+		jobs = append(jobs,
+			scheduler.Job{Name: "inspection-reminders", Interval: 15 * time.Minute, Run: inspectionScheduleService.SendDueReminders},
+			scheduler.Job{Name: "payment-webhook-retries", Interval: 10 * time.Minute, Run: paymentWebhookService.RetryFailed},
+			scheduler.Job{Name: "refund-retries", Interval: 5 * time.Minute, Run: refundService.RetryPending},
+			scheduler.Job{Name: "escrow-deadlines", Interval: 15 * time.Minute, Run: transactionService.ProcessExpiredEscrows},
+			scheduler.Job{Name: "invoice-emails", Interval: 5 * time.Minute, Run: invoiceService.SendPendingEmails},
+			scheduler.Job{Name: "offer-expiry", Interval: 15 * time.Minute, Run: offerService.ExpireOffers},
+			scheduler.Job{Name: "test-drive-reminders", Interval: 15 * time.Minute, Run: testDriveService.SendDueReminders},
+			scheduler.Job{Name: "test-drive-expiry", Interval: 15 * time.Minute, Run: testDriveService.ExpireTestDrives},
+			scheduler.Job{Name: "inspection-reports", Interval: time.Minute, Run: inspectionService.GeneratePendingReports},
+			scheduler.Job{Name: "inspection-fee-expiry", Interval: time.Minute, Run: prePurchaseInspectionService.ReleaseUnpaidInspections},
+		)
+	}
 
 
 
@@ -43,6 +170,12 @@ func SetupRouter(r *gin.Engine, cfg *config.Config) *gin.Engine {
 	r.POST("/auth/register", authHandler.Register) 
 	r.POST("/auth/login", authHandler.Login)
 	r.GET("/listings", listingHandler.GetAllActiveListings)
+	r.GET("/vehicles/:id/history", vehicleHandler.GetVehicleHistory)
+	r.GET("/listings/:id/inspection-report", inspectionHandler.GetListingReport)
+	r.GET("/certificates/public-key", certificateHandler.GetPublicKey)
+	r.GET("/certificates/:id", certificateHandler.GetCertificate)
+	r.GET("/certificates/:id/qr", certificateHandler.GetQRCode)
+	r.POST("/webhooks/payments/:provider", paymentWebhookHandler.Receive)
 
 	// Protected routes (require authentication)
 	protected := r.Group("/")
@@ -52,18 +185,53 @@ func SetupRouter(r *gin.Engine, cfg *config.Config) *gin.Engine {
 		// User profile route (accessible by any authenticated user)
 		protected.GET("/auth/profile", authHandler.GetProfile)
 
+		// Real-time updates for the authenticated user as Server-Sent Events
+		protected.GET("/events", eventHandler.Stream)
+
 		// Seller-specific routes 
 		sellerRoutes := protected.Group("/")
 		sellerRoutes.Use(middleware.RBAC(types.RoleSeller))
 		{
 			sellerRoutes.POST("/listings", listingHandler.CreateListing)
 			sellerRoutes.GET("/listings/my", listingHandler.GetListingsBySeller)
+			sellerRoutes.PATCH("/listings/:id/vehicle", listingHandler.UpdateListingVehicle)
+			sellerRoutes.POST("/listings/:id/resubmit", listingHandler.ResubmitListing)
+
+			sellerRoutes.POST("/listings/:id/inspection-booking", inspectionScheduleHandler.BookInspection)
+			sellerRoutes.PUT("/listings/:id/inspection-booking", inspectionScheduleHandler.RescheduleInspection)
+			sellerRoutes.DELETE("/listings/:id/inspection-booking", inspectionScheduleHandler.CancelBooking)
+
+			sellerRoutes.POST("/inspection-requests/:id/response", inspectionRequestHandler.RespondToRequest)
+
+			sellerRoutes.PUT("/payouts/account", payoutHandler.SaveAccount)
+			sellerRoutes.GET("/payouts/account", payoutHandler.GetAccount)
+			sellerRoutes.GET("/payouts/balance", payoutHandler.GetBalance)
+			sellerRoutes.GET("/payouts/my", payoutHandler.GetMyPayouts)
+
+			sellerRoutes.GET("/offers/inbox", offerHandler.GetInbox)
+			sellerRoutes.GET("/test-drives/inbox", testDriveHandler.GetInbox)
 
 		}
 
 		// Buyer-specific routes
 		buyerRoutes := protected.Group("/")
 		buyerRoutes.Use(middleware.RBAC(types.RoleBuyer))
+		{
+			buyerRoutes.POST("/listings/:id/inspection-requests", inspectionRequestHandler.CreateRequest)
+			buyerRoutes.GET("/inspection-requests/my", inspectionRequestHandler.GetMyRequests)
+			buyerRoutes.POST("/inspection-requests/:id/schedule", inspectionRequestHandler.ScheduleRequest)
+
+			buyerRoutes.POST("/listings/:id/purchase", transactionHandler.Purchase)
+			buyerRoutes.POST("/listings/:id/reservation", transactionHandler.Reserve)
+
+			buyerRoutes.POST("/listings/:id/offers", offerHandler.MakeOffer)
+			buyerRoutes.GET("/offers/my", offerHandler.GetMyOffers)
+
+			buyerRoutes.POST("/listings/:id/test-drives", testDriveHandler.RequestTestDrive)
+			buyerRoutes.GET("/test-drives/my", testDriveHandler.GetMyTestDrives)
+
+			buyerRoutes.POST("/listings/:id/conversations", conversationHandler.ContactSeller)
+		}
 
 		// Admin-specific routes
 		adminRoutes := protected.Group("/")
@@ -78,6 +246,58 @@ func SetupRouter(r *gin.Engine, cfg *config.Config) *gin.Engine {
 			adminRoutes.GET("/inspections/:id", inspectionHandler.GetInspectionByID) 
 			adminRoutes.PUT("/inspections/:id", inspectionHandler.UpdateInspection)
 			adminRoutes.POST("/inspections", inspectionHandler.CreateInspection)
+			adminRoutes.POST("/inspections/:id/report", inspectionHandler.RequestReport)
+
+			adminRoutes.GET("/review-queue", reviewQueueHandler.GetQueue)
+			adminRoutes.POST("/review-queue/decisions", reviewQueueHandler.DecideListings)
+			adminRoutes.POST("/review-queue/:id/claim", reviewQueueHandler.ClaimListing)
+			adminRoutes.DELETE("/review-queue/:id/claim", reviewQueueHandler.UnclaimListing)
+			adminRoutes.GET("/listings/:id/status-history", reviewQueueHandler.GetStatusHistory)
+
+			adminRoutes.POST("/rejection-reasons", rejectionReasonHandler.CreateReason)
+			adminRoutes.PUT("/rejection-reasons/:code", rejectionReasonHandler.UpdateReason)
+
+			adminRoutes.GET("/analytics/inspectors", analyticsHandler.GetInspectorPerformance)
+
+			adminRoutes.POST("/transactions/:id/resolve", transactionHandler.ResolveDispute)
+			adminRoutes.POST("/transactions/:id/refund", transactionHandler.Refund)
+			adminRoutes.GET("/cancellation-requests", transactionHandler.GetCancellationQueue)
+
+			adminRoutes.GET("/payment-webhooks", paymentWebhookHandler.GetEvents)
+			adminRoutes.POST("/payment-webhooks/replay", paymentWebhookHandler.ReplayFailed)
+			adminRoutes.GET("/payment-webhooks/:id", paymentWebhookHandler.GetEvent)
+			adminRoutes.POST("/payment-webhooks/:id/replay", paymentWebhookHandler.ReplayEvent)
+
+			adminRoutes.GET("/refunds", refundHandler.GetRefunds)
+			adminRoutes.GET("/refunds/:id", refundHandler.GetRefund)
+			adminRoutes.POST("/refunds/:id/retry", refundHandler.RetryRefund)
+
+			adminRoutes.GET("/ledger/accounts", ledgerHandler.GetTrialBalance)
+			adminRoutes.GET("/ledger/entries", ledgerHandler.GetEntries)
+
+			adminRoutes.POST("/fee-rules", feeHandler.CreateRule)
+			adminRoutes.GET("/fee-rules", feeHandler.GetRules)
+			adminRoutes.GET("/fee-rules/quote", feeHandler.Quote)
+			adminRoutes.PUT("/fee-rules/:id", feeHandler.UpdateRule)
+			adminRoutes.POST("/fee-waivers", feeHandler.CreateWaiver)
+			adminRoutes.GET("/fee-waivers", feeHandler.GetWaivers)
+			adminRoutes.PUT("/fee-waivers/:id", feeHandler.UpdateWaiver)
+
+			adminRoutes.POST("/payout-batches", payoutHandler.CreateBatch)
+			adminRoutes.GET("/payout-batches", payoutHandler.GetBatches)
+			adminRoutes.GET("/payout-batches/:id", payoutHandler.GetBatch)
+			adminRoutes.POST("/payout-batches/:id/approve", payoutHandler.ApproveBatch)
+			adminRoutes.GET("/payout-batches/:id/export", payoutHandler.ExportBatch)
+			adminRoutes.POST("/payout-batches/:id/settle", payoutHandler.SettleBatch)
+			adminRoutes.POST("/payout-batches/:id/cancel", payoutHandler.CancelBatch)
+
+			adminRoutes.POST("/checklist-templates", checklistTemplateHandler.CreateTemplate)
+			adminRoutes.GET("/checklist-templates", checklistTemplateHandler.GetTemplates)
+			adminRoutes.GET("/checklist-templates/active", checklistTemplateHandler.GetActiveTemplate)
+			adminRoutes.GET("/checklist-templates/:id", checklistTemplateHandler.GetTemplateByID)
+			adminRoutes.POST("/checklist-templates/:id/activate", checklistTemplateHandler.ActivateTemplate)
+
+			adminRoutes.GET("/conversations", conversationHandler.GetConversations)
 
 		}
 
@@ -86,8 +306,112 @@ func SetupRouter(r *gin.Engine, cfg *config.Config) *gin.Engine {
 		protectedSellerOrAdmin.Use(middleware.RBAC(types.RoleSeller, types.RoleAdmin))
 		{
 			protectedSellerOrAdmin.PUT("/listings/:id", listingHandler.UpdateListing)
+			protectedSellerOrAdmin.GET("/listings/:id/inspection-booking", inspectionScheduleHandler.GetBooking)
+			protectedSellerOrAdmin.GET("/listings/:id/inspections", inspectionHandler.GetListingInspections)
+			protectedSellerOrAdmin.GET("/listings/:id/resubmissions", listingHandler.GetListingResubmissions)
+			protectedSellerOrAdmin.GET("/rejection-reasons", rejectionReasonHandler.GetReasons)
+			protectedSellerOrAdmin.GET("/listings/:id/inspection-requests", inspectionRequestHandler.GetListingRequests)
+			protectedSellerOrAdmin.GET("/listings/:id/offers", offerHandler.GetListingOffers)
+		}
+
+		// Pre-purchase inspection parties: the buyer and seller of a request, or an admin
+		protectedInspectionRequestParties := protected.Group("/")
+		protectedInspectionRequestParties.Use(middleware.RBAC(types.RoleBuyer, types.RoleSeller, types.RoleAdmin))
+		{
+			protectedInspectionRequestParties.GET("/inspection-slots", inspectionScheduleHandler.GetAvailableSlots)
+			protectedInspectionRequestParties.GET("/inspection-requests/:id", inspectionRequestHandler.GetRequest)
+			protectedInspectionRequestParties.POST("/inspection-requests/:id/cancel", inspectionRequestHandler.CancelRequest)
+			protectedInspectionRequestParties.GET("/inspection-requests/:id/report", inspectionRequestHandler.GetReport)
+		}
+
+		// Transaction parties: the buyer and seller of a sale, or an admin
+		protectedTransactionParties := protected.Group("/")
+		protectedTransactionParties.Use(middleware.RBAC(types.RoleBuyer, types.RoleSeller, types.RoleAdmin))
+		{
+			protectedTransactionParties.GET("/transactions/my", transactionHandler.GetMyTransactions)
+			protectedTransactionParties.GET("/transactions/:id", transactionHandler.GetTransaction)
+			protectedTransactionParties.PUT("/transactions/:id/status", transactionHandler.UpdateStatus)
+			protectedTransactionParties.GET("/transactions/:id/events", transactionHandler.GetTransactionEvents)
+			protectedTransactionParties.GET("/transactions/:id/receipt", invoiceHandler.GetReceipt)
+			protectedTransactionParties.POST("/transactions/:id/capture", transactionHandler.CapturePayment)
+			protectedTransactionParties.POST("/transactions/:id/handover", transactionHandler.MarkHandedOver)
+			protectedTransactionParties.POST("/transactions/:id/confirm", transactionHandler.ConfirmReceipt)
+			protectedTransactionParties.POST("/transactions/:id/dispute", transactionHandler.OpenDispute)
+			protectedTransactionParties.POST("/transactions/:id/cancellation", transactionHandler.RequestCancellation)
+			protectedTransactionParties.GET("/transactions/:id/cancellations", transactionHandler.GetCancellationRequests)
+			protectedTransactionParties.POST("/cancellation-requests/:id/respond", transactionHandler.RespondToCancellation)
+			protectedTransactionParties.DELETE("/listings/:id/reservation", transactionHandler.CancelReservation)
+		}
+
+		// Offer parties: the buyer and seller negotiating, or an admin
+		protectedOfferParties := protected.Group("/")
+		protectedOfferParties.Use(middleware.RBAC(types.RoleBuyer, types.RoleSeller, types.RoleAdmin))
+		{
+			protectedOfferParties.GET("/offers/:id", offerHandler.GetOffer)
+			protectedOfferParties.POST("/offers/:id/counter", offerHandler.Counter)
+			protectedOfferParties.POST("/offers/:id/accept", offerHandler.Accept)
+			protectedOfferParties.POST("/offers/:id/decline", offerHandler.Decline)
+			protectedOfferParties.POST("/offers/:id/withdraw", offerHandler.Withdraw)
+		}
+
+		// Test drive parties: the buyer and seller arranging it, or an admin
+		protectedTestDriveParties := protected.Group("/")
+		protectedTestDriveParties.Use(middleware.RBAC(types.RoleBuyer, types.RoleSeller, types.RoleAdmin))
+		{
+			protectedTestDriveParties.GET("/test-drives/:id", testDriveHandler.GetTestDrive)
+			protectedTestDriveParties.POST("/test-drives/:id/accept", testDriveHandler.Accept)
+			protectedTestDriveParties.POST("/test-drives/:id/reschedule", testDriveHandler.Reschedule)
+			protectedTestDriveParties.POST("/test-drives/:id/decline", testDriveHandler.Decline)
+			protectedTestDriveParties.POST("/test-drives/:id/cancel", testDriveHandler.Cancel)
+			protectedTestDriveParties.POST("/test-drives/:id/outcome", testDriveHandler.ReportOutcome)
 		}
 
-	return r
+		// Conversation parties: the buyer and seller messaging about a listing; admins can read any conversation
+		protectedConversationParties := protected.Group("/")
+		protectedConversationParties.Use(middleware.RBAC(types.RoleBuyer, types.RoleSeller, types.RoleAdmin))
+		{
+			protectedConversationParties.GET("/conversations/my", conversationHandler.GetMyConversations)
+			protectedConversationParties.GET("/conversations/:id", conversationHandler.GetConversation)
+			protectedConversationParties.POST("/conversations/:id/messages", conversationHandler.SendMessage)
+			protectedConversationParties.POST("/conversations/:id/block", conversationHandler.Block)
+			protectedConversationParties.DELETE("/conversations/:id/block", conversationHandler.Unblock)
+		}
+
+		// For both inspector and admin
+		protectedInspectorOrAdmin := protected.Group("/")
+		protectedInspectorOrAdmin.Use(middleware.RBAC(types.RoleInspector, types.RoleAdmin))
+		{
+			protectedInspectorOrAdmin.POST("/inspector/slots", inspectionScheduleHandler.CreateSlot)
+			protectedInspectorOrAdmin.GET("/inspector/slots", inspectionScheduleHandler.GetMySlots)
+			protectedInspectorOrAdmin.DELETE("/inspector/slots/:id", inspectionScheduleHandler.CancelSlot)
+			protectedInspectorOrAdmin.PUT("/inspections/:id/checklist", inspectionHandler.SubmitChecklist)
+		}
+
+	return r, jobs, nil
+	}
+}
+
+// newPaymentProvider builds the configured payment provider. There is no default: the mock provider takes any payment
+// without collecting money, so it must be chosen explicitly and only in development.
+func newPaymentProvider(cfg *config.Config) (payment.Provider, error) {
+	switch cfg.PaymentProvider {
+	case "paystack", "flutterwave":
+		if cfg.PaymentSecretKey == "" {
+			return nil, fmt.Errorf("PAYMENT_SECRET_KEY is required for the %s payment provider", cfg.PaymentProvider)
+		}
+		if cfg.PaymentProvider == "paystack" {
+			return payment.NewPaystackProvider(cfg.PaymentSecretKey), nil
+		}
+		return payment.NewFlutterwaveProvider(cfg.PaymentSecretKey, cfg.PaymentWebhookSecret), nil
+	case "mock":
+		if cfg.Environment != "development" {
+			return nil, fmt.Errorf("the mock payment provider can only be used with APP_ENV=development")
+		}
+		pkg.Info("Warning: using the mock payment provider; no money is collected")
+		return payment.NewMockProvider(cfg.PaymentWebhookSecret, cfg.PaymentCurrency), nil
+	case "":
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is required: paystack, flutterwave, or mock in development")
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q: expected paystack, flutterwave, or mock in development", cfg.PaymentProvider)
 	}
-}
\ No newline at end of file
+}
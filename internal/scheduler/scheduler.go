@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+)
+
+// Job is a recurring background task run in-process.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// Start runs every job on its own ticker until ctx is cancelled; a run in progress is allowed to finish.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runOnce(job)
+		}
+	}
+}
+
+// runOnce executes a single tick, keeping a failing or panicking job from taking the server down
+func runOnce(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkg.Error(fmt.Errorf("%v", r), "background job "+job.Name+" panicked")
+		}
+	}()
+
+	if err := job.Run(); err != nil {
+		pkg.Error(err, "background job "+job.Name+" failed")
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// maxAnalyticsRange bounds report periods so a single request cannot scan the whole inspection history
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+type AnalyticsService struct {
+	inspectionRepo *repositories.InspectionRepository
+	listingRepo    *repositories.ListingRepository
+	userRepo       *repositories.UserRepository
+}
+
+func NewAnalyticsService(inspectionRepo *repositories.InspectionRepository, listingRepo *repositories.ListingRepository, userRepo *repositories.UserRepository) *AnalyticsService {
+	return &AnalyticsService{
+		inspectionRepo: inspectionRepo,
+		listingRepo:    listingRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// GetInspectorPerformance computes per-inspector figures for [from, to) from the inspections and the listing status
+// history; inspectorID narrows the report to one inspector when not uuid.Nil.
+func (s *AnalyticsService) GetInspectorPerformance(from, to time.Time, inspectorID uuid.UUID) (*models.InspectorPerformanceReport, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		return nil, fmt.Errorf("date range cannot exceed 366 days")
+	}
+
+	inspectionsInput, err := s.inspectionRepo.GetActiveBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// every inspector is listed, including those with nothing in the period
+	inspectors, err := s.userRepo.GetByRole(types.RoleInspector)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[uuid.UUID]*models.InspectorPerformance{}
+	order := []uuid.UUID{}
+	addRow := func(user models.User) *models.InspectorPerformance {
+		if row, ok := rows[user.ID]; ok {
+			return row
+		}
+		row := &models.InspectorPerformance{InspectorID: user.ID, InspectorName: user.Name, InspectorEmail: user.Email}
+		rows[user.ID] = row
+		order = append(order, user.ID)
+		return row
+	}
+	for _, inspector := range inspectors {
+		addRow(*inspector)
+	}
+
+	completed := []*models.InspectionFetchInput{}
+	listingIDs := []uuid.UUID{}
+	seenListings := map[uuid.UUID]bool{}
+	turnaroundTotals := map[uuid.UUID]float64{}
+	turnaroundCounts := map[uuid.UUID]int{}
+	ratingTotals := map[uuid.UUID]int{}
+	ratingCounts := map[uuid.UUID]int{}
+
+	for _, inspection := range inspectionsInput {
+		if inspectorID != uuid.Nil && inspection.InspectorID != inspectorID {
+			continue
+		}
+
+		inspector := inspection.Inspector
+		inspector.ID = inspection.InspectorID
+		row := addRow(inspector)
+
+		if inRange(inspectionAssignedAt(inspection), from, to) {
+			row.Assigned++
+		}
+
+		if inspection.Status == models.InspectionStatusPending || !inRange(inspectionCompletedAt(inspection), from, to) {
+			continue
+		}
+		row.Completed++
+		completed = append(completed, inspection)
+
+		switch inspection.Status {
+		case models.InspectionStatusApproved:
+			row.Approved++
+		case models.InspectionStatusRejected:
+			row.Rejected++
+		case models.InspectionStatusCompleted:
+			row.PrePurchaseCompleted++
+		}
+
+		if inspection.SubmittedAt != nil {
+			turnaroundTotals[row.InspectorID] += inspection.SubmittedAt.Sub(inspectionAssignedAt(inspection)).Hours()
+			turnaroundCounts[row.InspectorID]++
+		}
+		if inspection.ConditionRating > 0 {
+			ratingTotals[row.InspectorID] += inspection.ConditionRating
+			ratingCounts[row.InspectorID]++
+		}
+
+		if inspection.Type != models.InspectionTypePrePurchase && !seenListings[inspection.ListingID] {
+			seenListings[inspection.ListingID] = true
+			listingIDs = append(listingIDs, inspection.ListingID)
+		}
+	}
+
+	if err := s.countReversals(completed, rows); err != nil {
+		return nil, err
+	}
+	if err := s.countOverturned(completed, listingIDs, rows); err != nil {
+		return nil, err
+	}
+
+	report := &models.InspectorPerformanceReport{From: from, To: to, Inspectors: []*models.InspectorPerformance{}}
+	for _, id := range order {
+		if inspectorID != uuid.Nil && id != inspectorID {
+			continue
+		}
+		row := rows[id]
+
+		if decided := row.Approved + row.Rejected; decided > 0 {
+			row.ApprovalRate = roundTo(float64(row.Approved)/float64(decided), 4)
+			row.RejectionRate = roundTo(float64(row.Rejected)/float64(decided), 4)
+		}
+		if count := turnaroundCounts[id]; count > 0 {
+			average := roundTo(turnaroundTotals[id]/float64(count), 2)
+			row.AvgTurnaroundHours = &average
+		}
+		if count := ratingCounts[id]; count > 0 {
+			average := roundTo(float64(ratingTotals[id])/float64(count), 2)
+			row.AvgConditionRating = &average
+		}
+		report.Inspectors = append(report.Inspectors, row)
+	}
+
+	return report, nil
+}
+
+// countReversals counts decided vetting inspections whose outcome was changed after it was first set
+func (s *AnalyticsService) countReversals(completed []*models.InspectionFetchInput, rows map[uuid.UUID]*models.InspectorPerformance) error {
+	inspectorByInspection := map[uuid.UUID]uuid.UUID{}
+	inspectionIDs := []uuid.UUID{}
+	for _, inspection := range completed {
+		if inspection.Type == models.InspectionTypePrePurchase {
+			continue
+		}
+		inspectorByInspection[inspection.ID] = inspection.InspectorID
+		inspectionIDs = append(inspectionIDs, inspection.ID)
+	}
+
+	changes, err := s.listingRepo.GetStatusChangesByInspectionIDs(inspectionIDs)
+	if err != nil {
+		return err
+	}
+
+	firstOutcome := map[uuid.UUID]models.ListingStatus{}
+	reversed := map[uuid.UUID]bool{}
+	for _, change := range changes {
+		if change.InspectionID == nil || (change.ToStatus != models.ListingStatusActive && change.ToStatus != models.ListingStatusRejected) {
+			continue
+		}
+		id := *change.InspectionID
+		outcome, seen := firstOutcome[id]
+		if !seen {
+			firstOutcome[id] = change.ToStatus
+			continue
+		}
+		if outcome != change.ToStatus && !reversed[id] {
+			reversed[id] = true
+			rows[inspectorByInspection[id]].Reversals++
+		}
+	}
+	return nil
+}
+
+// countOverturned counts decisions contradicted by the next decided vetting inspection of the same listing
+func (s *AnalyticsService) countOverturned(completed []*models.InspectionFetchInput, listingIDs []uuid.UUID, rows map[uuid.UUID]*models.InspectorPerformance) error {
+	history, err := s.inspectionRepo.GetByListingIDs(listingIDs)
+	if err != nil {
+		return err
+	}
+
+	// oldest first per listing, as returned by the repository
+	byListing := map[uuid.UUID][]*models.InspectionFetchInput{}
+	for _, inspection := range history {
+		byListing[inspection.ListingID] = append(byListing[inspection.ListingID], inspection)
+	}
+
+	for _, inspection := range completed {
+		if inspection.Status != models.InspectionStatusApproved && inspection.Status != models.InspectionStatusRejected {
+			continue
+		}
+		for _, later := range byListing[inspection.ListingID] {
+			if !later.CreatedAt.After(inspection.CreatedAt) {
+				continue
+			}
+			if later.Status != models.InspectionStatusApproved && later.Status != models.InspectionStatusRejected {
+				continue
+			}
+			if later.Status != inspection.Status {
+				rows[inspection.InspectorID].Overturned++
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// WriteInspectorPerformanceCSV writes a report as CSV, one row per inspector
+func (s *AnalyticsService) WriteInspectorPerformanceCSV(w io.Writer, report *models.InspectorPerformanceReport) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"inspector_id", "inspector_name", "inspector_email", "assigned", "completed", "approved", "rejected",
+		"pre_purchase_completed", "approval_rate", "rejection_rate", "avg_turnaround_hours", "avg_condition_rating",
+		"reversals", "overturned", "from", "to",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range report.Inspectors {
+		record := []string{
+			row.InspectorID.String(),
+			row.InspectorName,
+			row.InspectorEmail,
+			strconv.Itoa(row.Assigned),
+			strconv.Itoa(row.Completed),
+			strconv.Itoa(row.Approved),
+			strconv.Itoa(row.Rejected),
+			strconv.Itoa(row.PrePurchaseCompleted),
+			strconv.FormatFloat(row.ApprovalRate, 'f', -1, 64),
+			strconv.FormatFloat(row.RejectionRate, 'f', -1, 64),
+			formatOptionalFloat(row.AvgTurnaroundHours),
+			formatOptionalFloat(row.AvgConditionRating),
+			strconv.Itoa(row.Reversals),
+			strconv.Itoa(row.Overturned),
+			report.From.Format(time.RFC3339),
+			report.To.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// inspectionAssignedAt falls back to creation for inspections recorded before assignment was tracked
+func inspectionAssignedAt(inspection *models.InspectionFetchInput) time.Time {
+	if inspection.AssignedAt != nil {
+		return *inspection.AssignedAt
+	}
+	return inspection.CreatedAt
+}
+
+// inspectionCompletedAt is when the findings were submitted, or the last update for inspections decided without a checklist
+func inspectionCompletedAt(inspection *models.InspectionFetchInput) time.Time {
+	if inspection.SubmittedAt != nil {
+		return *inspection.SubmittedAt
+	}
+	return inspection.UpdatedAt
+}
+
+func inRange(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+func roundTo(value float64, places int) float64 {
+	parsed, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', places, 64), 64)
+	return parsed
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// RequestCancellation asks to call off a sale. What happens depends on how far it has gone:
+//
+//	awaiting_payment           either party cancels straight away
+//	funds_held, by the seller  the buyer is refunded in full straight away
+//	funds_held, by the buyer   the seller or an admin must accept
+//	handed_over, disputed      the other party or an admin must accept
+//
+// Completed and cancelled sales can no longer be cancelled.
+func (s *TransactionService) RequestCancellation(id string, userID uuid.UUID, reason string) (*models.CancellationRequest, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Type != models.TransactionTypeSale {
+		return nil, fmt.Errorf("only sale transactions can be cancelled, fees follow their inspection request")
+	}
+
+	requesterRole := ""
+	switch userID {
+	case transaction.BuyerID:
+		requesterRole = "buyer"
+	case transaction.SellerID:
+		requesterRole = "seller"
+	default:
+		return nil, fmt.Errorf("unauthorized: only the buyer or seller can request a cancellation")
+	}
+
+	request := &models.CancellationRequest{
+		TransactionID: transaction.ID,
+		RequestedByID: userID,
+		RequesterRole: requesterRole,
+		Reason:        reason,
+	}
+	var refunds []*models.Refund
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		pending, err := s.repo.CountPendingCancellationRequestsWithTx(tx, locked.ID)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("transaction already has a pending cancellation request")
+		}
+
+		request.EscrowStatus = locked.EscrowStatus
+		now := time.Now()
+		switch {
+		case locked.EscrowStatus == models.EscrowStatusAwaitingPayment:
+			request.Status = models.CancellationRequestStatusAccepted
+			request.RespondedAt = &now
+			if err := s.repo.CreateCancellationRequestWithTx(tx, request); err != nil {
+				return err
+			}
+			return s.transitionWithTx(tx, locked, transactionChange{
+				Status:  models.TransactionStatusCancelled,
+				Escrow:  models.EscrowStatusCancelled,
+				ActorID: &userID,
+				Reason:  fmt.Sprintf("cancelled by the %s: %s", requesterRole, reason),
+			})
+
+		case locked.EscrowStatus == models.EscrowStatusFundsHeld && requesterRole == "seller":
+			// a seller backing out before handover owes the buyer everything back
+			request.Status = models.CancellationRequestStatusAccepted
+			request.RefundAmount = locked.Amount
+			request.RespondedAt = &now
+			if err := s.repo.CreateCancellationRequestWithTx(tx, request); err != nil {
+				return err
+			}
+			refunds, err = s.refundWithTx(tx, locked, locked.Amount, &userID, "cancelled by the seller: "+reason)
+			return err
+
+		case locked.EscrowStatus == models.EscrowStatusFundsHeld,
+			locked.EscrowStatus == models.EscrowStatusHandedOver,
+			locked.EscrowStatus == models.EscrowStatusDisputed:
+			request.Status = models.CancellationRequestStatusPending
+			return s.repo.CreateCancellationRequestWithTx(tx, request)
+		}
+		return fmt.Errorf("completed or cancelled transactions can no longer be cancelled")
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.refunds.issue(refunds...)
+
+	switch {
+	case request.Status == models.CancellationRequestStatusPending:
+		s.notifyCancellationRequested(transaction, request)
+	case request.RefundAmount > 0:
+		s.notifyRefund(transaction, request.RefundAmount, "cancelled by the seller: "+reason)
+	default:
+		s.notifyParties(transaction, models.TransactionStatusCancelled, userID)
+	}
+	return request, nil
+}
+
+// RespondToCancellation accepts or declines a pending cancellation request. The other party of the sale or an admin
+// can respond; accepting refunds the buyer in full, or by refundAmount when an admin agrees to a partial refund, in
+// which case the rest is released to the seller.
+func (s *TransactionService) RespondToCancellation(requestID string, userID uuid.UUID, userRole types.Role, accept bool, refundAmount float64, note string) (*models.CancellationRequest, error) {
+	request, err := s.repo.GetCancellationRequestByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := s.repo.GetByID(request.TransactionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin := userRole == types.RoleAdmin
+	if !isAdmin {
+		if transaction.BuyerID != userID && transaction.SellerID != userID {
+			return nil, fmt.Errorf("unauthorized: you can only respond to cancellations of your own transactions")
+		}
+		if request.RequestedByID == userID {
+			return nil, fmt.Errorf("unauthorized: the other party or an admin must respond to your cancellation request")
+		}
+		if refundAmount > 0 {
+			return nil, fmt.Errorf("unauthorized: only an admin can agree to a partial refund")
+		}
+	}
+
+	amount := transaction.Amount
+	if refundAmount > 0 {
+		if refundAmount > transaction.Amount {
+			return nil, fmt.Errorf("refund cannot be more than the sale amount")
+		}
+		amount = refundAmount
+	}
+	reason := fmt.Sprintf("cancellation requested by the %s: %s", request.RequesterRole, request.Reason)
+	var refunds []*models.Refund
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		lockedRequest, err := s.repo.GetCancellationRequestForUpdateWithTx(tx, request.ID)
+		if err != nil {
+			return err
+		}
+		if lockedRequest.Status != models.CancellationRequestStatusPending {
+			return fmt.Errorf("cancellation request is no longer pending")
+		}
+
+		fields := map[string]any{
+			"status":          models.CancellationRequestStatusDeclined,
+			"responded_by_id": userID,
+			"response_note":   note,
+			"responded_at":    time.Now(),
+		}
+		if !accept {
+			return s.repo.UpdateCancellationRequestFieldsWithTx(tx, request.ID, fields)
+		}
+
+		switch locked.EscrowStatus {
+		case models.EscrowStatusFundsHeld, models.EscrowStatusHandedOver, models.EscrowStatusDisputed:
+		default:
+			return fmt.Errorf("escrow is no longer %s", request.EscrowStatus)
+		}
+		fields["status"] = models.CancellationRequestStatusAccepted
+		fields["refund_amount"] = amount
+		if err := s.repo.UpdateCancellationRequestFieldsWithTx(tx, request.ID, fields); err != nil {
+			return err
+		}
+		refunds, err = s.refundWithTx(tx, locked, amount, &userID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.refunds.issue(refunds...)
+
+	if accept {
+		s.notifyRefund(transaction, amount, reason)
+	} else {
+		s.notifyCancellationDeclined(transaction, request, note)
+	}
+	return s.repo.GetCancellationRequestByID(requestID)
+}
+
+// Refund is an admin returning money held in escrow to the buyer. A full refund cancels the sale and puts the listing
+// back on the market; a partial refund completes it with the rest released to the seller.
+func (s *TransactionService) Refund(id string, adminID uuid.UUID, amount float64, reason string) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Type != models.TransactionTypeSale {
+		return nil, fmt.Errorf("only sale transactions can be refunded, fees follow their inspection request")
+	}
+	if amount > transaction.Amount {
+		return nil, fmt.Errorf("refund cannot be more than the sale amount")
+	}
+	if amount <= 0 {
+		amount = transaction.Amount
+	}
+
+	switch transaction.EscrowStatus {
+	case models.EscrowStatusFundsHeld, models.EscrowStatusHandedOver, models.EscrowStatusDisputed:
+	default:
+		return nil, fmt.Errorf("only funds held in escrow can be refunded")
+	}
+
+	if err := s.refund(transaction, amount, &adminID, "refunded by an admin: "+reason, transaction.EscrowStatus); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// GetCancellationRequests retrieves the cancellation requests of a sale for one of its parties or an admin
+func (s *TransactionService) GetCancellationRequests(id string, userID uuid.UUID, userRole types.Role) ([]*models.CancellationRequest, error) {
+	transaction, err := s.GetTransaction(id, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetCancellationRequestsByTransactionID(transaction.ID)
+}
+
+// GetCancellationQueue lists cancellation requests for admins, pending ones by default
+func (s *TransactionService) GetCancellationQueue(status models.CancellationRequestStatus, limit int) ([]*models.CancellationRequest, error) {
+	return s.repo.GetCancellationRequests(status, limit)
+}
+
+// notifyCancellationRequested asks the other party of a sale to respond to a cancellation request
+func (s *TransactionService) notifyCancellationRequested(transaction *models.Transaction, request *models.CancellationRequest) {
+	s.publish(transaction.ID)
+	recipient := transaction.Seller
+	if request.RequesterRole == "seller" {
+		recipient = transaction.Buyer
+	}
+	if err := email_helper.SendCancellationRequestedEmail(recipient.Email, transaction.Listing.Title, request.RequesterRole, request.Reason); err != nil {
+		fmt.Printf("Warning: Failed to send cancellation request email to %s: %v\n", recipient.Email, err)
+	}
+}
+
+// notifyCancellationDeclined tells the requester their cancellation request was declined
+func (s *TransactionService) notifyCancellationDeclined(transaction *models.Transaction, request *models.CancellationRequest, note string) {
+	s.publish(transaction.ID)
+	recipient := transaction.Buyer
+	if request.RequesterRole == "seller" {
+		recipient = transaction.Seller
+	}
+	if err := email_helper.SendCancellationDeclinedEmail(recipient.Email, transaction.Listing.Title, note); err != nil {
+		fmt.Printf("Warning: Failed to send cancellation declined email to %s: %v\n", recipient.Email, err)
+	}
+}
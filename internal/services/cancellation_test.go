@@ -0,0 +1,176 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+const refundTestPrice = 1500000
+
+func TestRefund(t *testing.T) {
+	s := newTestServices(t)
+	buyer, admin := s.user(t, types.RoleBuyer), s.user(t, types.RoleAdmin)
+
+	tests := []struct {
+		name        string
+		handOver    bool
+		pay         bool
+		amount      float64
+		wantErr     string
+		wantEscrow  models.EscrowStatus
+		wantStatus  models.TransactionStatus
+		wantListing models.ListingStatus
+		refunded    float64
+	}{
+		{"full refund before handover", false, true, 0, "", models.EscrowStatusRefunded, models.TransactionStatusCancelled, models.ListingStatusActive, refundTestPrice},
+		{"full refund asked for by amount", true, true, refundTestPrice, "", models.EscrowStatusRefunded, models.TransactionStatusCancelled, models.ListingStatusActive, refundTestPrice},
+		{"partial refund after handover", true, true, 400000.50, "", models.EscrowStatusPartiallyRefunded, models.TransactionStatusCompleted, models.ListingStatusSold, 400000.50},
+		{"more than the sale amount", false, true, refundTestPrice + 1, "refund cannot be more than the sale amount", models.EscrowStatusFundsHeld, models.TransactionStatusPending, models.ListingStatusSold, 0},
+		{"nothing paid yet", false, false, 0, "only funds held in escrow can be refunded", models.EscrowStatusAwaitingPayment, models.TransactionStatusPending, models.ListingStatusActive, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// every case has a seller of its own, so the ledger balance is only this sale's
+			seller := s.user(t, types.RoleSeller)
+			listing := s.listing(t, seller, refundTestPrice)
+			transaction := s.purchase(t, listing, buyer)
+			if tt.pay {
+				s.pay(t, transaction)
+			}
+			if tt.handOver {
+				if _, err := s.transactions.MarkHandedOver(transaction.ID.String(), seller.ID, types.RoleSeller); err != nil {
+					t.Fatalf("MarkHandedOver: %v", err)
+				}
+			}
+
+			_, err := s.transactions.Refund(transaction.ID.String(), admin.ID, tt.amount, "test")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Refund error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Refund: %v", err)
+			}
+
+			got := s.reload(t, transaction.ID)
+			if got.EscrowStatus != tt.wantEscrow || got.Status != tt.wantStatus {
+				t.Errorf("transaction is %s/%s, want %s/%s", got.Status, got.EscrowStatus, tt.wantStatus, tt.wantEscrow)
+			}
+			if payment.ToMinorUnits(got.RefundedAmount) != payment.ToMinorUnits(tt.refunded) {
+				t.Errorf("refunded amount = %v, want %v", got.RefundedAmount, tt.refunded)
+			}
+			if status := s.listingStatus(t, listing.ID); status != tt.wantListing {
+				t.Errorf("listing status = %s, want %s", status, tt.wantListing)
+			}
+			s.assertRefunded(t, transaction, tt.refunded)
+
+			// the seller is owed whatever part of a paid sale was not refunded, once it is released
+			owed := 0.0
+			if tt.wantStatus == models.TransactionStatusCompleted {
+				owed = refundTestPrice - tt.refunded
+			}
+			balance, err := s.transactions.ledger.SellerPayableBalance(seller.ID)
+			if err != nil {
+				t.Fatalf("SellerPayableBalance: %v", err)
+			}
+			if payment.ToMinorUnits(balance) != payment.ToMinorUnits(owed) {
+				t.Errorf("seller payable balance = %v, want %v owed", balance, owed)
+			}
+		})
+	}
+}
+
+func TestCancellationRefunds(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer, admin := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer), s.user(t, types.RoleAdmin)
+	users := map[string]*models.User{"buyer": buyer, "seller": seller, "admin": admin}
+
+	tests := []struct {
+		name         string
+		handOver     bool
+		requester    string
+		responder    string // empty when the request takes effect straight away
+		accept       bool
+		refundAmount float64
+		wantErr      string
+		wantRequest  models.CancellationRequestStatus
+		wantEscrow   models.EscrowStatus
+		refunded     float64
+	}{
+		{"seller backs out before handover", false, "seller", "", false, 0, "", models.CancellationRequestStatusAccepted, models.EscrowStatusRefunded, refundTestPrice},
+		{"seller accepts the buyer's request", false, "buyer", "seller", true, 0, "", models.CancellationRequestStatusAccepted, models.EscrowStatusRefunded, refundTestPrice},
+		{"buyer accepts the seller's request after handover", true, "seller", "buyer", true, 0, "", models.CancellationRequestStatusAccepted, models.EscrowStatusRefunded, refundTestPrice},
+		{"admin agrees to a partial refund", true, "buyer", "admin", true, 250000, "", models.CancellationRequestStatusAccepted, models.EscrowStatusPartiallyRefunded, 250000},
+		{"seller declines", true, "buyer", "seller", false, 0, "", models.CancellationRequestStatusDeclined, models.EscrowStatusHandedOver, 0},
+		{"only an admin agrees to a partial refund", true, "buyer", "seller", true, 250000, "unauthorized: only an admin can agree to a partial refund", models.CancellationRequestStatusPending, models.EscrowStatusHandedOver, 0},
+		{"requester cannot accept their own request", true, "buyer", "buyer", true, 0, "unauthorized: the other party or an admin must respond to your cancellation request", models.CancellationRequestStatusPending, models.EscrowStatusHandedOver, 0},
+		{"refund above the sale amount", true, "buyer", "admin", true, refundTestPrice + 1, "refund cannot be more than the sale amount", models.CancellationRequestStatusPending, models.EscrowStatusHandedOver, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction := s.pay(t, s.purchase(t, s.listing(t, seller, refundTestPrice), buyer))
+			if tt.handOver {
+				if _, err := s.transactions.MarkHandedOver(transaction.ID.String(), seller.ID, types.RoleSeller); err != nil {
+					t.Fatalf("MarkHandedOver: %v", err)
+				}
+			}
+
+			request, err := s.transactions.RequestCancellation(transaction.ID.String(), users[tt.requester].ID, "changed my mind")
+			if err != nil {
+				t.Fatalf("RequestCancellation: %v", err)
+			}
+			if tt.responder != "" {
+				responder := users[tt.responder]
+				_, err = s.transactions.RespondToCancellation(request.ID.String(), responder.ID, responder.Role, tt.accept, tt.refundAmount, "ok")
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("RespondToCancellation error = %v, want %q", err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("RespondToCancellation: %v", err)
+				}
+			}
+
+			requests, err := s.transactions.GetCancellationRequests(transaction.ID.String(), admin.ID, types.RoleAdmin)
+			if err != nil {
+				t.Fatalf("GetCancellationRequests: %v", err)
+			}
+			if len(requests) != 1 || requests[0].Status != tt.wantRequest {
+				t.Fatalf("requests = %+v, want one %s", requests, tt.wantRequest)
+			}
+			if got := s.reload(t, transaction.ID); got.EscrowStatus != tt.wantEscrow {
+				t.Errorf("escrow = %s, want %s", got.EscrowStatus, tt.wantEscrow)
+			}
+			s.assertRefunded(t, transaction, tt.refunded)
+
+			// a request still open blocks another one
+			if tt.wantRequest == models.CancellationRequestStatusPending {
+				_, err := s.transactions.RequestCancellation(transaction.ID.String(), users[tt.requester].ID, "again")
+				if err == nil || err.Error() != "transaction already has a pending cancellation request" {
+					t.Errorf("second RequestCancellation error = %v, want it refused", err)
+				}
+			}
+		})
+	}
+}
+
+// assertRefunded checks that the refunds issued for a transaction add up to amount
+func (s *testServices) assertRefunded(t *testing.T, transaction *models.Transaction, amount float64) {
+	t.Helper()
+
+	var refunded int64
+	for _, refund := range s.refunds(t, transaction.ID) {
+		if refund.Status != models.RefundStatusIssued {
+			t.Errorf("refund of %d is %s, want %s", refund.Amount, refund.Status, models.RefundStatusIssued)
+		}
+		refunded += refund.Amount
+	}
+	if refunded != payment.ToMinorUnits(amount) {
+		t.Errorf("refunded %d, want %d", refunded, payment.ToMinorUnits(amount))
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/qrcode"
+	"github.com/zekeriyyah/lujay-autocity/pkg/signing"
+	"gorm.io/gorm"
+)
+
+const certificateAlgorithm = "Ed25519"
+
+// certificateQRScale is the pixel size of one QR module in the PNG served to listing pages
+const certificateQRScale = 8
+
+type CertificateService struct {
+	repo          *repositories.CertificateRepository
+	signer        *signing.Signer
+	publicBaseURL string
+}
+
+func NewCertificateService(repo *repositories.CertificateRepository, signer *signing.Signer, publicBaseURL string) *CertificateService {
+	return &CertificateService{
+		repo:          repo,
+		signer:        signer,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// IssueWithTx signs a certificate for an approved inspection, reusing the one already issued for it
+func (s *CertificateService) IssueWithTx(tx *gorm.DB, inspection *models.Inspection, listing *models.Listing) (*models.InspectionCertificate, error) {
+	existing, err := s.repo.GetValidByInspectionIDWithTx(tx, inspection.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	inspectionDate := inspection.InspectionDate
+	if inspectionDate.IsZero() {
+		inspectionDate = time.Now()
+	}
+	specs := listing.Vehicle.Specs().Merge(listing.VehicleSpecs)
+
+	certificate := &models.InspectionCertificate{
+		ID:              uuid.New(),
+		InspectionID:    inspection.ID,
+		ListingID:       listing.ID,
+		VIN:             listing.Vehicle.VIN,
+		VehicleMake:     specs.Make,
+		VehicleModel:    specs.Model,
+		VehicleYear:     specs.Year,
+		Mileage:         listing.Mileage,
+		InspectionDate:  inspectionDate,
+		ConditionRating: inspection.ConditionRating,
+		InspectorID:     inspection.InspectorID,
+		InspectorName:   inspection.Inspector.Name,
+		// whole seconds, so the payload rebuilt from the stored row matches the one signed
+		IssuedAt: time.Now().UTC().Truncate(time.Second),
+		KeyID:    s.signer.KeyID,
+	}
+	certificate.Signature = s.signer.Sign(certificate.SignedPayload())
+
+	if err := s.repo.CreateWithTx(tx, certificate); err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// RevokeListingCertificatesWithTx revokes the listing's certificates other than those of keepInspectionID (uuid.Nil revokes all)
+func (s *CertificateService) RevokeListingCertificatesWithTx(tx *gorm.DB, listingID uuid.UUID, keepInspectionID uuid.UUID, reason string) error {
+	return s.repo.RevokeByListingIDWithTx(tx, listingID, keepInspectionID, reason)
+}
+
+// GetValidCertificateForInspection returns the certificate backing an inspection, or nil when it has none
+func (s *CertificateService) GetValidCertificateForInspection(inspectionID uuid.UUID) (*models.InspectionCertificate, error) {
+	certificate, err := s.repo.GetValidByInspectionID(inspectionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// GetCertificate returns a certificate with its status and the result of checking its signature
+func (s *CertificateService) GetCertificate(id string) (*models.CertificateVerification, error) {
+	certificate, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := certificate.SignedPayload()
+
+	return &models.CertificateVerification{
+		Certificate: certificate,
+		Status:      certificate.Status(),
+		// certificates signed with a rotated-out key cannot be vouched for by the current one
+		SignatureValid: certificate.KeyID == s.signer.KeyID && s.signer.Verify(payload, certificate.Signature),
+		Algorithm:      certificateAlgorithm,
+		SignedPayload:  string(payload),
+		PublicKeyURL:   s.publicBaseURL + "/certificates/public-key",
+		QRCodeURL:      s.publicBaseURL + "/certificates/" + certificate.ID.String() + "/qr",
+	}, nil
+}
+
+// GetQRCode renders a PNG QR code linking to the certificate's verification page
+func (s *CertificateService) GetQRCode(id string) ([]byte, error) {
+	certificate, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.Encode(s.VerificationURL(certificate.ID))
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(certificateQRScale)
+}
+
+// VerificationURL is the public address a certificate's QR code points to
+func (s *CertificateService) VerificationURL(id uuid.UUID) string {
+	return s.publicBaseURL + "/certificates/" + id.String()
+}
+
+// GetPublicKey publishes the key that verifies certificate signatures
+func (s *CertificateService) GetPublicKey() (*models.CertificatePublicKey, error) {
+	publicKeyPEM, err := s.signer.PublicKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CertificatePublicKey{
+		Algorithm: certificateAlgorithm,
+		KeyID:     s.signer.KeyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.signer.PublicKey()),
+		PEM:       publicKeyPEM,
+	}, nil
+}
+
+// revocationReasonFor explains why earlier certificates of a listing stop being valid
+func revocationReasonFor(inspectionID uuid.UUID, status models.InspectionStatus) string {
+	return fmt.Sprintf("listing outcome set by inspection %s (%s)", inspectionID.String(), status)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+)
+
+type ChecklistTemplateService struct {
+	repo *repositories.ChecklistTemplateRepository
+}
+
+func NewChecklistTemplateService(repo *repositories.ChecklistTemplateRepository) *ChecklistTemplateService {
+	return &ChecklistTemplateService{
+		repo: repo,
+	}
+}
+
+// CreateTemplate publishes a new version of a checklist template; it is not in force until activated
+func (s *ChecklistTemplateService) CreateTemplate(template *models.ChecklistTemplate, adminID uuid.UUID) (*models.ChecklistTemplate, error) {
+	if err := template.ValidateStructure(); err != nil {
+		return nil, err
+	}
+
+	template.CreatedByID = adminID
+	if err := s.repo.Create(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// GetTemplates retrieves every checklist template version
+func (s *ChecklistTemplateService) GetTemplates() ([]*models.ChecklistTemplate, error) {
+	return s.repo.GetAll()
+}
+
+// GetTemplateByID retrieves a specific checklist template version
+func (s *ChecklistTemplateService) GetTemplateByID(id string) (*models.ChecklistTemplate, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetActiveTemplate retrieves the checklist template version in force
+func (s *ChecklistTemplateService) GetActiveTemplate() (*models.ChecklistTemplate, error) {
+	return s.repo.GetActive()
+}
+
+// ActivateTemplate puts a checklist template version in force for all new findings
+func (s *ChecklistTemplateService) ActivateTemplate(id string) (*models.ChecklistTemplate, error) {
+	if err := s.repo.Activate(id); err != nil {
+		return nil, fmt.Errorf("failed to activate checklist template: %w", err)
+	}
+	return s.repo.GetByID(id)
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/storage"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxMessageAttachments caps how many files can be sent with one message
+	MaxMessageAttachments = 5
+	// MaxMessageAttachmentSize caps the size of each file sent with a message, in bytes
+	MaxMessageAttachmentSize = 5 << 20
+)
+
+// messageAttachmentTypes are the kinds of file that can be sent in a conversation, by their sniffed content type
+var messageAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// unsafeFilenameChars are replaced in attachment names before they become part of a storage key
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// AttachmentUpload is a file a user sends with a message
+type AttachmentUpload struct {
+	Filename string
+	Data     []byte
+}
+
+// ConversationService runs the message threads between buyers and sellers about listings, so buyers never need the
+// seller's contact details. Admins can read any conversation, for example to settle a dispute.
+type ConversationService struct {
+	repo        *repositories.ConversationRepository
+	listingRepo *repositories.ListingRepository
+	fileStorage storage.Storage
+	hub         *events.Hub
+}
+
+func NewConversationService(repo *repositories.ConversationRepository, listingRepo *repositories.ListingRepository, fileStorage storage.Storage, hub *events.Hub) *ConversationService {
+	return &ConversationService{
+		repo:        repo,
+		listingRepo: listingRepo,
+		fileStorage: fileStorage,
+		hub:         hub,
+	}
+}
+
+// ContactSeller sends a buyer's message to the seller of an active or reserved listing, starting their conversation
+// about it or adding to the one they already have
+func (s *ConversationService) ContactSeller(listingID string, buyerID uuid.UUID, body string, uploads []AttachmentUpload) (*models.Conversation, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot message yourself about your own listing")
+	}
+	message, err := s.prepareMessage(buyerID, body, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversation *models.Conversation
+	firstUnread := false
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		// the listing lock serialises a buyer's first messages so they share one conversation
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status != models.ListingStatusActive && lockedListing.Status != models.ListingStatusReserved {
+			return fmt.Errorf("sellers can only be contacted about active listings")
+		}
+
+		conversation, err = s.repo.GetByListingAndBuyerForUpdateWithTx(tx, listing.ID, buyerID)
+		if err != nil {
+			return err
+		}
+		if conversation == nil {
+			conversation = &models.Conversation{
+				ListingID:     listing.ID,
+				BuyerID:       buyerID,
+				SellerID:      listing.SellerID,
+				LastMessageAt: time.Now(),
+			}
+			if err := s.repo.CreateWithTx(tx, conversation); err != nil {
+				return err
+			}
+		}
+
+		firstUnread, err = s.postWithTx(tx, conversation, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if firstUnread {
+		s.notify(listing.Seller.Email, listing.Title, "buyer")
+	}
+	s.publish(conversation, message)
+
+	return s.GetConversation(conversation.ID.String(), buyerID, types.RoleBuyer)
+}
+
+// SendMessage adds a message from one party to a conversation that is not blocked
+func (s *ConversationService) SendMessage(id string, senderID uuid.UUID, body string, uploads []AttachmentUpload) (*models.Message, error) {
+	conversation, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.BuyerID != senderID && conversation.SellerID != senderID {
+		return nil, fmt.Errorf("unauthorized: you can only send messages in your own conversations")
+	}
+	message, err := s.prepareMessage(senderID, body, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	firstUnread := false
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, conversation.ID)
+		if err != nil {
+			return err
+		}
+		firstUnread, err = s.postWithTx(tx, locked, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if firstUnread {
+		if senderID == conversation.BuyerID {
+			s.notify(conversation.Seller.Email, conversation.Listing.Title, "buyer")
+		} else {
+			s.notify(conversation.Buyer.Email, conversation.Listing.Title, "seller")
+		}
+	}
+	s.publish(conversation, message)
+
+	return message, nil
+}
+
+// GetConversation retrieves a conversation with all its messages for one of its parties or an admin. A party opening
+// the conversation marks the messages they received as read; an admin reading it leaves the read receipts alone.
+func (s *ConversationService) GetConversation(id string, userID uuid.UUID, userRole types.Role) (*models.Conversation, error) {
+	conversation, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	isParty := conversation.BuyerID == userID || conversation.SellerID == userID
+	if userRole != types.RoleAdmin && !isParty {
+		return nil, fmt.Errorf("unauthorized: you can only view your own conversations")
+	}
+
+	if isParty {
+		if err := s.repo.MarkRead(conversation.ID, userID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	conversation.Messages, err = s.repo.GetMessages(conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// GetMyConversations retrieves a user's conversations as buyer or seller, with how many messages they have not read
+func (s *ConversationService) GetMyConversations(userID uuid.UUID) ([]*models.Conversation, error) {
+	conversations, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		ids = append(ids, conversation.ID)
+	}
+	unread, err := s.repo.CountUnreadByConversation(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, conversation := range conversations {
+		conversation.UnreadCount = unread[conversation.ID]
+	}
+	return conversations, nil
+}
+
+// GetConversations retrieves conversations for admins, optionally about one listing or with one user
+func (s *ConversationService) GetConversations(listingID, userID *uuid.UUID) ([]*models.Conversation, error) {
+	return s.repo.GetAll(listingID, userID)
+}
+
+// Block stops both parties from sending messages in a conversation until the party who blocked it unblocks it
+func (s *ConversationService) Block(id string, userID uuid.UUID) (*models.Conversation, error) {
+	conversation, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, conversation.ID)
+		if err != nil {
+			return err
+		}
+		if locked.BuyerID != userID && locked.SellerID != userID {
+			return fmt.Errorf("unauthorized: you can only block your own conversations")
+		}
+		if locked.BlockedByID != nil {
+			return fmt.Errorf("conversation is already blocked")
+		}
+		return s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"blocked_by_id": userID,
+			"blocked_at":    time.Now(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(id)
+}
+
+// Unblock lets messages flow again; only the party who blocked the conversation can unblock it
+func (s *ConversationService) Unblock(id string, userID uuid.UUID) (*models.Conversation, error) {
+	conversation, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, conversation.ID)
+		if err != nil {
+			return err
+		}
+		if locked.BlockedByID == nil {
+			return fmt.Errorf("conversation is not blocked")
+		}
+		if *locked.BlockedByID != userID {
+			return fmt.Errorf("unauthorized: only the party who blocked the conversation can unblock it")
+		}
+		return s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"blocked_by_id": nil,
+			"blocked_at":    nil,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(id)
+}
+
+// prepareMessage checks a message and uploads its attachments to file storage under the message's id
+func (s *ConversationService) prepareMessage(senderID uuid.UUID, body string, uploads []AttachmentUpload) (*models.Message, error) {
+	if body == "" && len(uploads) == 0 {
+		return nil, fmt.Errorf("message must have a body or an attachment")
+	}
+	if len(uploads) > MaxMessageAttachments {
+		return nil, fmt.Errorf("a message can have at most %d attachments", MaxMessageAttachments)
+	}
+
+	message := &models.Message{
+		ID:       uuid.New(),
+		SenderID: senderID,
+		Body:     body,
+	}
+	// the content type is sniffed from the file itself rather than trusted from the upload
+	contentTypes := make([]string, len(uploads))
+	for i, upload := range uploads {
+		if len(upload.Data) > MaxMessageAttachmentSize {
+			return nil, fmt.Errorf("attachments can be at most %d MB", MaxMessageAttachmentSize>>20)
+		}
+		contentTypes[i] = http.DetectContentType(upload.Data)
+		if !messageAttachmentTypes[contentTypes[i]] {
+			return nil, fmt.Errorf("attachments must be JPEG, PNG or WebP images or PDF documents")
+		}
+	}
+
+	for i, upload := range uploads {
+		filename := unsafeFilenameChars.ReplaceAllString(filepath.Base(upload.Filename), "_")
+		contentType := contentTypes[i]
+		url, err := s.fileStorage.Upload(fmt.Sprintf("messages/%s/%d-%s", message.ID, i+1, filename), contentType, upload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store attachment: %w", err)
+		}
+		message.Attachments = append(message.Attachments, models.MessageAttachment{
+			MessageID:   message.ID,
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        len(upload.Data),
+			URL:         url,
+		})
+	}
+	return message, nil
+}
+
+// postWithTx adds a prepared message to a locked conversation and reports whether it is the only message the
+// recipient has not read, which is when they get an email about it
+func (s *ConversationService) postWithTx(tx *gorm.DB, locked *models.Conversation, message *models.Message) (bool, error) {
+	if locked.BlockedByID != nil {
+		return false, fmt.Errorf("conversation is blocked")
+	}
+
+	recipientID := locked.SellerID
+	if message.SenderID == locked.SellerID {
+		recipientID = locked.BuyerID
+	}
+	unread, err := s.repo.CountUnreadWithTx(tx, locked.ID, recipientID)
+	if err != nil {
+		return false, err
+	}
+
+	message.ConversationID = locked.ID
+	if err := s.repo.CreateMessageWithTx(tx, message); err != nil {
+		return false, err
+	}
+	if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{"last_message_at": message.CreatedAt}); err != nil {
+		return false, err
+	}
+	return unread == 0, nil
+}
+
+// publish pushes a new message to both parties, so the sender's other devices see it too
+func (s *ConversationService) publish(conversation *models.Conversation, message *models.Message) {
+	s.hub.Publish(events.TypeMessage, events.MessageCreated{
+		ConversationID: conversation.ID,
+		ListingID:      conversation.ListingID,
+		Message:        message,
+	}, conversation.BuyerID, conversation.SellerID)
+}
+
+func (s *ConversationService) notify(recipient, listingTitle, senderParty string) {
+	if err := email_helper.SendNewMessageEmail(recipient, listingTitle, senderParty); err != nil {
+		fmt.Printf("Warning: Failed to send new message email to %s: %v\n", recipient, err)
+	}
+}
@@ -0,0 +1,684 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// DisputeOutcome is an admin's decision on a disputed sale
+type DisputeOutcome string
+
+const (
+	DisputeOutcomeRelease       DisputeOutcome = "release"
+	DisputeOutcomeRefund        DisputeOutcome = "refund"
+	DisputeOutcomePartialRefund DisputeOutcome = "partial_refund"
+)
+
+// expiredEscrowBatch bounds how many expired escrow steps one run of the background job handles
+const expiredEscrowBatch = 100
+
+// CapturePayment collects the buyer's payment into escrow once they have paid at checkout. The buyer or an admin
+// asks for it; a provider webhook reporting the payment does the same.
+func (s *TransactionService) CapturePayment(id string, userID uuid.UUID, userRole types.Role) (*models.Transaction, error) {
+	transaction, err := s.GetTransaction(id, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && transaction.BuyerID != userID {
+		return nil, fmt.Errorf("unauthorized: only the buyer can pay for a purchase")
+	}
+	if transaction.EscrowStatus != models.EscrowStatusAwaitingPayment {
+		return nil, fmt.Errorf("transaction is not awaiting payment")
+	}
+	if transaction.ProviderReference == "" {
+		return nil, fmt.Errorf("transaction has no payment to capture")
+	}
+	if transaction.PaymentProvider != s.provider.Name() {
+		return nil, fmt.Errorf("payment provider %s of this transaction is not configured", transaction.PaymentProvider)
+	}
+
+	// capturing is idempotent at the provider, so it runs before the database transaction rather than holding locks over the network
+	capture, err := s.provider.Capture(transaction.ProviderReference, payment.ToMinorUnits(transaction.Amount))
+	if err != nil {
+		return nil, fmt.Errorf("payment provider could not capture the payment: %w", err)
+	}
+
+	switch capture.Status {
+	case payment.StatusSucceeded:
+		if !strings.EqualFold(capture.Currency, s.currency) {
+			return nil, s.rejectCapture(transaction, capture, userID, fmt.Sprintf("paid in '%s' instead of %s", capture.Currency, s.currency))
+		}
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			return s.holdFundsWithTx(tx, locked, &userID, capture.PaymentMethod, "payment captured")
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.notifyFundsHeld(transaction)
+
+	case payment.StatusFailed:
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if locked.EscrowStatus != models.EscrowStatusAwaitingPayment {
+				return nil
+			}
+			return s.transitionWithTx(tx, locked, transactionChange{
+				Status:  models.TransactionStatusFailed,
+				Escrow:  models.EscrowStatusCancelled,
+				ActorID: &userID,
+				Reason:  "payment declined: " + capture.Message,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.notifyParties(transaction, models.TransactionStatusFailed, uuid.Nil)
+		return nil, fmt.Errorf("payment was declined: %s", capture.Message)
+
+	default:
+		return nil, fmt.Errorf("payment has not been completed yet")
+	}
+
+	return s.repo.GetByID(id)
+}
+
+// rejectCapture sends a captured payment that can never be applied back to the buyer, as a webhook reporting it would:
+// the transaction fails if it is still awaiting payment and the amount captured is refunded. The webhook for the same
+// payment then finds it refunded already.
+func (s *TransactionService) rejectCapture(transaction *models.Transaction, capture *payment.Capture, userID uuid.UUID, why string) error {
+	reason := "payment could not be applied: " + why
+
+	var refund *models.Refund
+	err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != models.EscrowStatusAwaitingPayment {
+			// a webhook reporting the payment got here first and dealt with it
+			return nil
+		}
+
+		err = s.transitionWithTx(tx, locked, transactionChange{
+			Status:  models.TransactionStatusFailed,
+			Escrow:  models.EscrowStatusCancelled,
+			ActorID: &userID,
+			Reason:  reason,
+		})
+		if err != nil {
+			return err
+		}
+		refund, err = s.refunds.queueWithTx(tx, locked, capture.Amount, reason, nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if refund != nil {
+		s.refunds.issue(refund)
+		s.notifyParties(transaction, models.TransactionStatusFailed, uuid.Nil)
+	}
+	return fmt.Errorf("payment was declined: %s", why)
+}
+
+// holdFundsWithTx records the buyer's payment as held in escrow and takes the listing off the market as sold.
+// The seller then has the handover window to hand the vehicle over. A paid deposit instead keeps the listing
+// reserved for the hold window, and a paid inspection fee is held until the inspection is delivered.
+func (s *TransactionService) holdFundsWithTx(tx *gorm.DB, locked *models.Transaction, actorID *uuid.UUID, paymentMethod, reason string) error {
+	if locked.Status != models.TransactionStatusPending || locked.EscrowStatus != models.EscrowStatusAwaitingPayment {
+		return fmt.Errorf("transaction is not awaiting payment")
+	}
+
+	now := time.Now()
+	fields := map[string]any{"funds_held_at": now}
+	if paymentMethod != "" {
+		fields["payment_method"] = paymentMethod
+	}
+
+	// an inspection fee stays in escrow until the inspection is delivered and has nothing to do with the listing
+	if locked.Type == models.TransactionTypeInspectionFee {
+		return s.transitionWithTx(tx, locked, transactionChange{
+			Escrow:  models.EscrowStatusFundsHeld,
+			Fields:  fields,
+			ActorID: actorID,
+			Reason:  reason,
+			Amount:  locked.Amount,
+		})
+	}
+
+	listing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, locked.ListingID)
+	if err != nil {
+		return err
+	}
+
+	if locked.Type == models.TransactionTypeDeposit {
+		if listing.Status != models.ListingStatusReserved {
+			return fmt.Errorf("listing is no longer reserved")
+		}
+		deadline := now.Add(s.windows.Reservation)
+		return s.transitionWithTx(tx, locked, transactionChange{
+			Escrow:   models.EscrowStatusFundsHeld,
+			Deadline: &deadline,
+			Fields:   fields,
+			ActorID:  actorID,
+			Reason:   reason,
+			Amount:   locked.Amount,
+		})
+	}
+
+	// the buyer holding a reservation can buy the listing; their deposit stays in escrow until the sale settles
+	var deposit *models.Transaction
+	if listing.Status == models.ListingStatusReserved {
+		deposit, err = s.repo.GetOpenDepositByListingIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if listing.Status != models.ListingStatusActive && (deposit == nil || deposit.BuyerID != locked.BuyerID) {
+		return fmt.Errorf("listing is no longer available for sale")
+	}
+
+	change := &models.ListingStatusChange{
+		ListingID:   listing.ID,
+		FromStatus:  listing.Status,
+		ToStatus:    models.ListingStatusSold,
+		ChangedByID: locked.BuyerID,
+		Reason:      fmt.Sprintf("paid into escrow through transaction %s", locked.ID),
+	}
+	if err := s.listingRepo.ChangeStatusWithTx(tx, change); err != nil {
+		return err
+	}
+	if deposit != nil && deposit.EscrowStatus == models.EscrowStatusFundsHeld {
+		err := s.transitionWithTx(tx, deposit, transactionChange{
+			Escrow:  models.EscrowStatusFundsHeld,
+			ActorID: actorID,
+			Reason:  fmt.Sprintf("credited to sale %s, held until the sale settles", locked.ID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	deadline := now.Add(s.windows.Handover)
+
+	return s.transitionWithTx(tx, locked, transactionChange{
+		Escrow:   models.EscrowStatusFundsHeld,
+		Deadline: &deadline,
+		Fields:   fields,
+		ActorID:  actorID,
+		Reason:   reason,
+		Amount:   locked.Amount,
+	})
+}
+
+// MarkHandedOver records that the seller handed the vehicle over, starting the buyer's inspection window
+func (s *TransactionService) MarkHandedOver(id string, userID uuid.UUID, userRole types.Role) (*models.Transaction, error) {
+	transaction, err := s.GetTransaction(id, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && transaction.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: only the seller can hand over the vehicle")
+	}
+	if transaction.Type != models.TransactionTypeSale {
+		return nil, fmt.Errorf("only sale transactions can be handed over, deposits follow their sale")
+	}
+
+	deadline := time.Now().Add(s.windows.Inspection)
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != models.EscrowStatusFundsHeld {
+			return fmt.Errorf("funds must be held in escrow before the vehicle is handed over")
+		}
+
+		return s.transitionWithTx(tx, locked, transactionChange{
+			Escrow:   models.EscrowStatusHandedOver,
+			Deadline: &deadline,
+			Fields:   map[string]any{"handed_over_at": time.Now()},
+			ActorID:  &userID,
+			Reason:   "vehicle handed over",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyEscrow(transaction, fmt.Sprintf("The seller has handed the vehicle over. Please confirm you received it as described, or open a dispute, by %s. After that the payment is released to the seller.", email_helper.EscrowDeadline(deadline)), "")
+
+	return s.repo.GetByID(id)
+}
+
+// ConfirmReceipt records the buyer's acceptance of the vehicle and releases the funds to the seller
+func (s *TransactionService) ConfirmReceipt(id string, userID uuid.UUID, userRole types.Role) (*models.Transaction, error) {
+	transaction, err := s.GetTransaction(id, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && transaction.BuyerID != userID {
+		return nil, fmt.Errorf("unauthorized: only the buyer can confirm receipt")
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != models.EscrowStatusHandedOver {
+			return fmt.Errorf("only handed over vehicles can be confirmed")
+		}
+		return s.releaseWithTx(tx, locked, &userID, "buyer confirmed receipt")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyEscrow(transaction, "", "The buyer confirmed receipt of the vehicle. "+s.releaseSummary(transaction.ID, transaction.Amount))
+
+	return s.repo.GetByID(id)
+}
+
+// releaseWithTx pays the escrowed funds, less any partial refund, out to the seller and completes the sale
+func (s *TransactionService) releaseWithTx(tx *gorm.DB, locked *models.Transaction, actorID *uuid.UUID, reason string) error {
+	return s.transitionWithTx(tx, locked, transactionChange{
+		Status:  models.TransactionStatusCompleted,
+		Escrow:  models.EscrowStatusReleased,
+		Fields:  map[string]any{"released_at": time.Now()},
+		ActorID: actorID,
+		Reason:  reason,
+		Amount:  locked.Amount - locked.RefundedAmount,
+	})
+}
+
+// OpenDispute lets the buyer contest a handover within the inspection window, holding the funds until an admin decides
+func (s *TransactionService) OpenDispute(id string, userID uuid.UUID, reason string) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.BuyerID != userID {
+		return nil, fmt.Errorf("unauthorized: only the buyer can open a dispute")
+	}
+
+	deadline := time.Now().Add(s.windows.Dispute)
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != models.EscrowStatusHandedOver {
+			return fmt.Errorf("disputes can only be opened after handover")
+		}
+		if locked.EscrowDeadline != nil && time.Now().After(*locked.EscrowDeadline) {
+			return fmt.Errorf("the inspection window has ended")
+		}
+
+		return s.transitionWithTx(tx, locked, transactionChange{
+			Escrow:   models.EscrowStatusDisputed,
+			Deadline: &deadline,
+			Fields:   map[string]any{"disputed_at": time.Now(), "dispute_reason": reason},
+			ActorID:  &userID,
+			Reason:   "dispute opened: " + reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyEscrow(transaction, "", fmt.Sprintf("The buyer opened a dispute: %s. The payment stays in escrow until our team resolves it.", reason))
+
+	return s.repo.GetByID(id)
+}
+
+// ResolveDispute applies an admin's decision on a disputed sale: release the funds to the seller, refund the buyer
+// in full (putting the listing back on the market) or refund part of the price and release the rest
+func (s *TransactionService) ResolveDispute(id string, adminID uuid.UUID, outcome DisputeOutcome, refundAmount float64, note string) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.EscrowStatus != models.EscrowStatusDisputed {
+		return nil, fmt.Errorf("only disputed transactions can be resolved")
+	}
+
+	switch outcome {
+	case DisputeOutcomeRelease:
+		err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if locked.EscrowStatus != models.EscrowStatusDisputed {
+				return fmt.Errorf("only disputed transactions can be resolved")
+			}
+			return s.releaseWithTx(tx, locked, &adminID, "dispute resolved for the seller: "+note)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.notifyEscrow(transaction,
+			"Your dispute was resolved in the seller's favour and the payment has been released to them.",
+			"The dispute was resolved in your favour. "+s.releaseSummary(transaction.ID, transaction.Amount))
+
+	case DisputeOutcomeRefund:
+		if err := s.refund(transaction, transaction.Amount, &adminID, "dispute resolved with a full refund: "+note, models.EscrowStatusDisputed); err != nil {
+			return nil, err
+		}
+
+	case DisputeOutcomePartialRefund:
+		if refundAmount <= 0 || refundAmount >= transaction.Amount {
+			return nil, fmt.Errorf("partial refund must be more than 0 and less than the sale amount")
+		}
+		if err := s.refund(transaction, refundAmount, &adminID, "dispute resolved with a partial refund: "+note, models.EscrowStatusDisputed); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid dispute outcome '%s'", outcome)
+	}
+
+	return s.repo.GetByID(id)
+}
+
+// refund returns amount to the buyer and records it. A full refund cancels the sale and puts the listing back on the
+// market; a partial one completes the sale with the rest released to the seller.
+// The decision is taken with the transaction locked, so an automatic action and an admin decision can never both move
+// the same money; the provider is only asked for the money once the decision has committed.
+func (s *TransactionService) refund(transaction *models.Transaction, amount float64, actorID *uuid.UUID, reason string, expected models.EscrowStatus) error {
+	var refunds []*models.Refund
+
+	err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != expected {
+			return fmt.Errorf("escrow is no longer %s", expected)
+		}
+
+		refunds, err = s.refundWithTx(tx, locked, amount, actorID, reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.refunds.issue(refunds...)
+	s.notifyRefund(transaction, amount, reason)
+	return nil
+}
+
+// refundWithTx records the refund of a locked sale or deposit whose funds are in escrow and returns the provider
+// refunds it queued, which the caller issues once the database transaction has committed
+func (s *TransactionService) refundWithTx(tx *gorm.DB, locked *models.Transaction, amount float64, actorID *uuid.UUID, reason string) ([]*models.Refund, error) {
+	refunds := []*models.Refund{}
+	if locked.ProviderReference != "" {
+		refund, err := s.refunds.queueWithTx(tx, locked, payment.ToMinorUnits(amount), reason, nil)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if amount < locked.Amount {
+		return refunds, s.transitionWithTx(tx, locked, transactionChange{
+			Status:  models.TransactionStatusCompleted,
+			Escrow:  models.EscrowStatusPartiallyRefunded,
+			Fields:  map[string]any{"refunded_amount": amount, "released_at": time.Now()},
+			ActorID: actorID,
+			Reason:  reason,
+			Amount:  amount,
+		})
+	}
+
+	if err := s.restoreListingWithTx(tx, locked, actorID, reason); err != nil {
+		return nil, err
+	}
+	err := s.transitionWithTx(tx, locked, transactionChange{
+		Status:  models.TransactionStatusCancelled,
+		Escrow:  models.EscrowStatusRefunded,
+		Fields:  map[string]any{"refunded_amount": amount},
+		ActorID: actorID,
+		Reason:  reason,
+		Amount:  amount,
+	})
+	if err != nil || locked.Type != models.TransactionTypeSale {
+		return refunds, err
+	}
+
+	// a deposit credited to the sale goes back to the buyer with it
+	deposit, err := s.creditedDepositWithTx(tx, locked)
+	if err != nil || deposit == nil {
+		return refunds, err
+	}
+	depositRefunds, err := s.refundWithTx(tx, deposit, deposit.Amount, actorID, fmt.Sprintf("sale %s refunded: %s", locked.ID, reason))
+	return append(refunds, depositRefunds...), err
+}
+
+// notifyRefund tells both parties about a refund, and the seller what was released to them after a partial one
+func (s *TransactionService) notifyRefund(transaction *models.Transaction, amount float64, reason string) {
+	if amount >= transaction.Amount {
+		s.notifyEscrow(transaction,
+			fmt.Sprintf("Your payment of %.2f has been refunded (%s).", amount, reason),
+			fmt.Sprintf("The buyer's payment has been refunded (%s) and your listing is back on the market.", reason))
+		return
+	}
+	s.notifyEscrow(transaction,
+		fmt.Sprintf("%.2f of your payment has been refunded (%s).", amount, reason),
+		fmt.Sprintf("%.2f was refunded to the buyer (%s). ", amount, reason)+s.releaseSummary(transaction.ID, transaction.Amount-amount))
+}
+
+// restoreListingWithTx puts a listing sold through a refunded sale, or reserved by a deposit that fell through, back
+// on the market. It leaves the listing alone unless the transaction is what holds it and the listing is still in the
+// status that transaction put it in, so ending one transaction never frees a listing another one holds.
+func (s *TransactionService) restoreListingWithTx(tx *gorm.DB, locked *models.Transaction, actorID *uuid.UUID, reason string) error {
+	if !holdsListing(locked) {
+		return nil
+	}
+
+	listing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, locked.ListingID)
+	if err != nil {
+		return err
+	}
+
+	heldStatus, what := models.ListingStatusSold, "sale"
+	if locked.Type == models.TransactionTypeDeposit {
+		heldStatus, what = models.ListingStatusReserved, "reservation"
+	}
+	if listing.Status != heldStatus {
+		fmt.Printf("Warning: listing %s is %s, not %s by %s %s; leaving it unchanged\n", listing.ID, listing.Status, heldStatus, what, locked.ID)
+		return nil
+	}
+
+	// automatic actions have no user behind them
+	changedByID := uuid.Nil
+	if actorID != nil {
+		changedByID = *actorID
+	}
+	return s.listingRepo.ChangeStatusWithTx(tx, &models.ListingStatusChange{
+		ListingID:   listing.ID,
+		FromStatus:  listing.Status,
+		ToStatus:    models.ListingStatusActive,
+		ChangedByID: changedByID,
+		Reason:      fmt.Sprintf("%s %s ended: %s", what, locked.ID, reason),
+	})
+}
+
+// holdsListing reports whether a transaction is what keeps its listing off the market: a sale once its payment is in
+// escrow, and a deposit from the moment the reservation starts until it is credited to a sale; never an inspection fee
+func holdsListing(transaction *models.Transaction) bool {
+	switch transaction.Type {
+	case models.TransactionTypeInspectionFee:
+		return false
+	case models.TransactionTypeDeposit:
+		// a credited deposit has its hold deadline cleared; the sale holds the listing from then on
+		return transaction.EscrowStatus == models.EscrowStatusAwaitingPayment ||
+			(transaction.EscrowStatus == models.EscrowStatusFundsHeld && transaction.EscrowDeadline != nil)
+	default:
+		return transaction.EscrowStatus == models.EscrowStatusFundsHeld ||
+			transaction.EscrowStatus == models.EscrowStatusHandedOver ||
+			transaction.EscrowStatus == models.EscrowStatusDisputed
+	}
+}
+
+// ProcessExpiredEscrows is the background job running the automatic action of every escrow step whose window ended:
+// unpaid sales are cancelled, sellers who never handed over and unresolved disputes are refunded, and handovers
+// nobody disputed are released to the seller
+func (s *TransactionService) ProcessExpiredEscrows() error {
+	expired, err := s.repo.GetEscrowsPastDeadline(time.Now(), expiredEscrowBatch)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, transaction := range expired {
+		if err := s.expire(transaction.ID); err != nil {
+			errs = append(errs, fmt.Errorf("transaction %s: %w", transaction.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *TransactionService) expire(id uuid.UUID) error {
+	transaction, err := s.repo.GetByID(id.String())
+	if err != nil {
+		return err
+	}
+	if transaction.EscrowDeadline == nil || time.Now().Before(*transaction.EscrowDeadline) {
+		return nil
+	}
+
+	switch transaction.EscrowStatus {
+	case models.EscrowStatusAwaitingPayment:
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+			if err != nil {
+				return err
+			}
+			if locked.EscrowStatus != models.EscrowStatusAwaitingPayment {
+				return nil
+			}
+			if err := s.restoreListingWithTx(tx, locked, nil, "payment window expired"); err != nil {
+				return err
+			}
+			return s.transitionWithTx(tx, locked, transactionChange{
+				Status: models.TransactionStatusCancelled,
+				Escrow: models.EscrowStatusCancelled,
+				Reason: "payment window expired",
+			})
+		})
+		if err != nil {
+			return err
+		}
+		s.notifyParties(transaction, models.TransactionStatusCancelled, uuid.Nil)
+		return nil
+
+	case models.EscrowStatusFundsHeld:
+		if transaction.Type == models.TransactionTypeDeposit {
+			return s.lapseReservation(transaction)
+		}
+		return s.refund(transaction, transaction.Amount, nil, "the vehicle was not handed over in time", models.EscrowStatusFundsHeld)
+
+	case models.EscrowStatusHandedOver:
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+			if err != nil {
+				return err
+			}
+			if locked.EscrowStatus != models.EscrowStatusHandedOver {
+				return nil
+			}
+			return s.releaseWithTx(tx, locked, nil, "inspection window ended without a dispute")
+		})
+		if err != nil {
+			return err
+		}
+		s.notifyEscrow(transaction, "", "The buyer's inspection window ended without a dispute. "+s.releaseSummary(transaction.ID, transaction.Amount))
+		return nil
+
+	case models.EscrowStatusDisputed:
+		return s.refund(transaction, transaction.Amount, nil, "the dispute was not resolved in time", models.EscrowStatusDisputed)
+	}
+	return nil
+}
+
+// releaseSummary tells the seller what was released to them and the fees charged on it
+func (s *TransactionService) releaseSummary(transactionID uuid.UUID, released float64) string {
+	summary := fmt.Sprintf("The payment of %.2f has been released to you.", released)
+
+	settled, err := s.repo.GetByID(transactionID.String())
+	if err != nil {
+		fmt.Printf("Warning: Failed to load fees of transaction %s: %v\n", transactionID, err)
+		return summary
+	}
+
+	net := released
+	for _, fee := range settled.Fees {
+		if fee.Kind != models.FeeKindSaleCommission {
+			continue
+		}
+		summary += fmt.Sprintf(" %s: %.2f.", fee.Description, fee.Amount)
+		net -= fee.Amount
+	}
+	if net != released {
+		summary += fmt.Sprintf(" You will receive %.2f.", net)
+	}
+	return summary
+}
+
+// notifyFundsHeld tells both parties the buyer's payment is in escrow and the seller should hand the vehicle over
+func (s *TransactionService) notifyFundsHeld(transaction *models.Transaction) {
+	switch transaction.Type {
+	case models.TransactionTypeDeposit:
+		s.notifyReserved(transaction)
+		return
+	case models.TransactionTypeInspectionFee:
+		s.notifyEscrow(transaction, fmt.Sprintf("Your inspection fee of %.2f is paid. It is held until the inspection report is delivered and refunded if the inspection is called off.", transaction.Amount), "")
+		return
+	}
+	deadline := time.Now().Add(s.windows.Handover)
+	s.notifyEscrow(transaction,
+		fmt.Sprintf("Your payment of %.2f is held in escrow. It is only released to the seller after you confirm the handover or your inspection window ends.", transaction.Amount),
+		fmt.Sprintf("The buyer's payment of %.2f is held in escrow. Please hand the vehicle over and mark it handed over by %s, or the buyer will be refunded.", transaction.Amount, email_helper.EscrowDeadline(deadline)))
+}
+
+// notifyEscrow emails escrow updates to the buyer and seller; an empty update skips that party
+func (s *TransactionService) notifyEscrow(transaction *models.Transaction, buyerUpdate, sellerUpdate string) {
+	s.publish(transaction.ID)
+
+	updates := []struct {
+		recipient models.User
+		update    string
+	}{
+		{transaction.Buyer, buyerUpdate},
+		{transaction.Seller, sellerUpdate},
+	}
+
+	for _, u := range updates {
+		if u.update == "" {
+			continue
+		}
+		if err := email_helper.SendEscrowUpdateEmail(u.recipient.Email, transaction.Listing.Title, u.update); err != nil {
+			fmt.Printf("Warning: Failed to send escrow update email to %s: %v\n", u.recipient.Email, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// escrowStep is one action on a sale, the escrow status it leaves the sale in and, for an illegal move, the error
+// it is refused with
+type escrowStep struct {
+	action  string
+	want    models.EscrowStatus
+	wantErr string
+}
+
+func TestEscrowTransitions(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer, admin := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer), s.user(t, types.RoleAdmin)
+
+	const price, partialRefund = 1500000, 400000
+	actions := map[string]func(id string) (*models.Transaction, error){
+		"capture": func(id string) (*models.Transaction, error) {
+			return s.transactions.CapturePayment(id, buyer.ID, types.RoleBuyer)
+		},
+		"hand over": func(id string) (*models.Transaction, error) {
+			return s.transactions.MarkHandedOver(id, seller.ID, types.RoleSeller)
+		},
+		"confirm": func(id string) (*models.Transaction, error) {
+			return s.transactions.ConfirmReceipt(id, buyer.ID, types.RoleBuyer)
+		},
+		"seller confirms": func(id string) (*models.Transaction, error) {
+			return s.transactions.ConfirmReceipt(id, seller.ID, types.RoleSeller)
+		},
+		"dispute": func(id string) (*models.Transaction, error) {
+			return s.transactions.OpenDispute(id, buyer.ID, "gearbox slips")
+		},
+		"release": func(id string) (*models.Transaction, error) {
+			return s.transactions.ResolveDispute(id, admin.ID, DisputeOutcomeRelease, 0, "as described")
+		},
+		"refund": func(id string) (*models.Transaction, error) {
+			return s.transactions.ResolveDispute(id, admin.ID, DisputeOutcomeRefund, 0, "not as described")
+		},
+		"partial refund": func(id string) (*models.Transaction, error) {
+			return s.transactions.ResolveDispute(id, admin.ID, DisputeOutcomePartialRefund, partialRefund, "needs a new gearbox")
+		},
+	}
+
+	tests := []struct {
+		name        string
+		steps       []escrowStep
+		wantStatus  models.TransactionStatus
+		wantListing models.ListingStatus
+		refunded    float64
+	}{
+		{
+			name: "released once the buyer confirms receipt",
+			steps: []escrowStep{
+				{"capture", models.EscrowStatusFundsHeld, ""},
+				{"hand over", models.EscrowStatusHandedOver, ""},
+				{"confirm", models.EscrowStatusReleased, ""},
+			},
+			wantStatus: models.TransactionStatusCompleted, wantListing: models.ListingStatusSold,
+		},
+		{
+			name: "dispute resolved for the seller",
+			steps: []escrowStep{
+				{"capture", models.EscrowStatusFundsHeld, ""},
+				{"hand over", models.EscrowStatusHandedOver, ""},
+				{"dispute", models.EscrowStatusDisputed, ""},
+				{"release", models.EscrowStatusReleased, ""},
+			},
+			wantStatus: models.TransactionStatusCompleted, wantListing: models.ListingStatusSold,
+		},
+		{
+			name: "dispute refunded in full",
+			steps: []escrowStep{
+				{"capture", models.EscrowStatusFundsHeld, ""},
+				{"hand over", models.EscrowStatusHandedOver, ""},
+				{"dispute", models.EscrowStatusDisputed, ""},
+				{"refund", models.EscrowStatusRefunded, ""},
+			},
+			wantStatus: models.TransactionStatusCancelled, wantListing: models.ListingStatusActive, refunded: price,
+		},
+		{
+			name: "dispute partially refunded",
+			steps: []escrowStep{
+				{"capture", models.EscrowStatusFundsHeld, ""},
+				{"hand over", models.EscrowStatusHandedOver, ""},
+				{"dispute", models.EscrowStatusDisputed, ""},
+				{"partial refund", models.EscrowStatusPartiallyRefunded, ""},
+			},
+			wantStatus: models.TransactionStatusCompleted, wantListing: models.ListingStatusSold, refunded: partialRefund,
+		},
+		{
+			name: "illegal moves are refused and change nothing",
+			steps: []escrowStep{
+				{"hand over", models.EscrowStatusAwaitingPayment, "funds must be held in escrow before the vehicle is handed over"},
+				{"confirm", models.EscrowStatusAwaitingPayment, "only handed over vehicles can be confirmed"},
+				{"capture", models.EscrowStatusFundsHeld, ""},
+				{"capture", models.EscrowStatusFundsHeld, "transaction is not awaiting payment"},
+				{"dispute", models.EscrowStatusFundsHeld, "disputes can only be opened after handover"},
+				{"confirm", models.EscrowStatusFundsHeld, "only handed over vehicles can be confirmed"},
+				{"hand over", models.EscrowStatusHandedOver, ""},
+				{"hand over", models.EscrowStatusHandedOver, "funds must be held in escrow before the vehicle is handed over"},
+				{"release", models.EscrowStatusHandedOver, "only disputed transactions can be resolved"},
+				{"seller confirms", models.EscrowStatusHandedOver, "unauthorized: only the buyer can confirm receipt"},
+				{"confirm", models.EscrowStatusReleased, ""},
+				{"dispute", models.EscrowStatusReleased, "disputes can only be opened after handover"},
+				{"refund", models.EscrowStatusReleased, "only disputed transactions can be resolved"},
+			},
+			wantStatus: models.TransactionStatusCompleted, wantListing: models.ListingStatusSold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing := s.listing(t, seller, price)
+			transaction := s.purchase(t, listing, buyer)
+
+			for i, step := range tt.steps {
+				_, err := actions[step.action](transaction.ID.String())
+				switch {
+				case step.wantErr == "" && err != nil:
+					t.Fatalf("step %d %s: %v", i, step.action, err)
+				case step.wantErr != "" && (err == nil || err.Error() != step.wantErr):
+					t.Fatalf("step %d %s: error = %v, want %q", i, step.action, err, step.wantErr)
+				}
+				if got := s.reload(t, transaction.ID).EscrowStatus; got != step.want {
+					t.Fatalf("step %d %s: escrow = %s, want %s", i, step.action, got, step.want)
+				}
+			}
+
+			got := s.reload(t, transaction.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if status := s.listingStatus(t, listing.ID); status != tt.wantListing {
+				t.Errorf("listing status = %s, want %s", status, tt.wantListing)
+			}
+			var refunded int64
+			for _, refund := range s.refunds(t, transaction.ID) {
+				refunded += refund.Amount
+			}
+			if refunded != payment.ToMinorUnits(tt.refunded) {
+				t.Errorf("refunded %d, want %d", refunded, payment.ToMinorUnits(tt.refunded))
+			}
+		})
+	}
+}
+
+func TestCapturePaymentRejectsAnotherCurrency(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer)
+	listing := s.listing(t, seller, 1500000)
+	transaction := s.purchase(t, listing, buyer)
+
+	s.provider.Currency = "USD"
+	_, err := s.transactions.CapturePayment(transaction.ID.String(), buyer.ID, types.RoleBuyer)
+	s.provider.Currency = testCurrency
+	if err == nil || !strings.HasPrefix(err.Error(), "payment was declined") {
+		t.Fatalf("CapturePayment error = %v, want the payment declined", err)
+	}
+
+	got := s.reload(t, transaction.ID)
+	if got.Status != models.TransactionStatusFailed || got.EscrowStatus != models.EscrowStatusCancelled {
+		t.Errorf("transaction is %s/%s, want %s/%s", got.Status, got.EscrowStatus, models.TransactionStatusFailed, models.EscrowStatusCancelled)
+	}
+	if status := s.listingStatus(t, listing.ID); status != models.ListingStatusActive {
+		t.Errorf("listing status = %s, want %s", status, models.ListingStatusActive)
+	}
+
+	due := payment.ToMinorUnits(transaction.Amount)
+	refunds := s.refunds(t, transaction.ID)
+	if len(refunds) != 1 || refunds[0].Amount != due || refunds[0].Status != models.RefundStatusIssued {
+		t.Fatalf("refunds = %+v, want one of %d issued", refunds, due)
+	}
+
+	// the provider then reports the same payment, which must not be refunded a second time
+	event := s.webhook(t, transaction, uuid.NewString(), payment.StatusSucceeded, due, "USD")
+	if event.Status != models.PaymentWebhookStatusIgnored {
+		t.Errorf("event status = %s (%s), want %s", event.Status, event.Error, models.PaymentWebhookStatusIgnored)
+	}
+	refunds = s.refunds(t, transaction.ID)
+	if len(refunds) != 1 || refunds[0].WebhookEventID == nil || *refunds[0].WebhookEventID != event.ID {
+		t.Errorf("refunds = %+v, want the one refund tied to event %s", refunds, event.ID)
+	}
+}
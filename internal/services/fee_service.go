@@ -0,0 +1,397 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// FeeService runs the fee schedule: admins define fee rules and promotional waivers, and the charges they produce are
+// recorded as line items on the transactions they apply to
+type FeeService struct {
+	repo     *repositories.FeeRepository
+	userRepo *repositories.UserRepository
+	ledger   *LedgerService
+
+	// defaultInspectionFee is charged for pre-purchase inspections while no inspection fee rule is active
+	defaultInspectionFee float64
+}
+
+func NewFeeService(repo *repositories.FeeRepository, userRepo *repositories.UserRepository, ledger *LedgerService, defaultInspectionFee float64) *FeeService {
+	return &FeeService{
+		repo:                 repo,
+		userRepo:             userRepo,
+		ledger:               ledger,
+		defaultInspectionFee: defaultInspectionFee,
+	}
+}
+
+// CreateRule adds a rule to the fee schedule
+func (s *FeeService) CreateRule(rule *models.FeeRule) (*models.FeeRule, error) {
+	if err := validateFeeRule(rule); err != nil {
+		return nil, err
+	}
+
+	rule.Active = true
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetRules retrieves the fee schedule; deactivated rules are only listed on request
+func (s *FeeService) GetRules(kind models.FeeKind, includeInactive bool) ([]*models.FeeRule, error) {
+	return s.repo.GetRules(kind, includeInactive)
+}
+
+// UpdateRule replaces the definition of a fee rule or (de)activates it. Fees already charged keep what they were.
+func (s *FeeService) UpdateRule(id string, update *models.FeeRule) (*models.FeeRule, error) {
+	rule, err := s.repo.GetRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Name = update.Name
+	rule.Kind = update.Kind
+	rule.Role = update.Role
+	rule.Method = update.Method
+	rule.Percentage = update.Percentage
+	rule.FlatAmount = update.FlatAmount
+	rule.Tiers = update.Tiers
+	rule.MinAmount = update.MinAmount
+	rule.MaxAmount = update.MaxAmount
+	rule.Active = update.Active
+	if err := validateFeeRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// CreateWaiver starts a promotion waiving part or all of a fee
+func (s *FeeService) CreateWaiver(waiver *models.FeeWaiver) (*models.FeeWaiver, error) {
+	if waiver.StartsAt.IsZero() {
+		waiver.StartsAt = time.Now()
+	}
+	if err := validateFeeWaiver(waiver); err != nil {
+		return nil, err
+	}
+
+	waiver.Active = true
+	if err := s.repo.CreateWaiver(waiver); err != nil {
+		return nil, err
+	}
+	return waiver, nil
+}
+
+// GetWaivers retrieves the promotional waivers; deactivated ones are only listed on request
+func (s *FeeService) GetWaivers(kind models.FeeKind, includeInactive bool) ([]*models.FeeWaiver, error) {
+	return s.repo.GetWaivers(kind, includeInactive)
+}
+
+// UpdateWaiver changes when a waiver runs or ends it early
+func (s *FeeService) UpdateWaiver(id string, endsAt *time.Time, active *bool) (*models.FeeWaiver, error) {
+	waiver, err := s.repo.GetWaiverByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if endsAt != nil {
+		waiver.EndsAt = endsAt
+	}
+	if active != nil {
+		waiver.Active = *active
+	}
+	if err := validateFeeWaiver(waiver); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateWaiver(waiver); err != nil {
+		return nil, err
+	}
+	return waiver, nil
+}
+
+// Quote works out the fee a payer would be charged on an amount under the current schedule, without charging it.
+// It returns nil when no rule applies.
+func (s *FeeService) Quote(kind models.FeeKind, amount float64, payerID uuid.UUID, payerRole types.Role) (*models.TransactionFee, error) {
+	if kind != models.FeeKindSaleCommission && kind != models.FeeKindInspectionFee {
+		return nil, fmt.Errorf("invalid fee kind '%s'", kind)
+	}
+	return s.calculateWithTx(s.repo.DB, kind, amount, payerID, payerRole, time.Now())
+}
+
+// QuoteInspectionFee prices a pre-purchase inspection of a listing for a buyer under the current schedule
+func (s *FeeService) QuoteInspectionFee(listingPrice float64, buyerID uuid.UUID) (float64, error) {
+	fee, err := s.InspectionFeeWithTx(s.repo.DB, listingPrice, buyerID)
+	if err != nil {
+		return 0, err
+	}
+	return fee.Amount, nil
+}
+
+// InspectionFeeWithTx works out the line item of a pre-purchase inspection of a listing; the caller records it
+// once the fee transaction exists
+func (s *FeeService) InspectionFeeWithTx(tx *gorm.DB, listingPrice float64, buyerID uuid.UUID) (*models.TransactionFee, error) {
+	buyer, err := s.userRepo.GetByID(buyerID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := s.calculateWithTx(tx, models.FeeKindInspectionFee, listingPrice, buyerID, buyer.Role, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if fee == nil {
+		fee = &models.TransactionFee{
+			Kind:        models.FeeKindInspectionFee,
+			PayerID:     buyerID,
+			Description: "Pre-purchase inspection fee",
+			BaseAmount:  listingPrice,
+			GrossAmount: s.defaultInspectionFee,
+			Amount:      s.defaultInspectionFee,
+		}
+	}
+	return fee, nil
+}
+
+// RecordFeesWithTx stores line items worked out earlier on the transaction they were charged with
+func (s *FeeService) RecordFeesWithTx(tx *gorm.DB, transactionID uuid.UUID, fees ...*models.TransactionFee) error {
+	for _, fee := range fees {
+		fee.TransactionID = transactionID
+	}
+	return s.repo.CreateFeesWithTx(tx, fees)
+}
+
+// ChargeSaleCommissionWithTx charges the seller's commission on a completed sale, worked out on the amount the seller
+// was paid and never more than it, and moves it from the seller's payable to platform revenue. A sale is only charged
+// once, and not at all when the seller was paid nothing.
+func (s *FeeService) ChargeSaleCommissionWithTx(tx *gorm.DB, transaction *models.Transaction, settledAmount float64) error {
+	if payment.ToMinorUnits(settledAmount) <= 0 {
+		return nil
+	}
+	charged, err := s.repo.CountFeesWithTx(tx, transaction.ID, models.FeeKindSaleCommission)
+	if err != nil {
+		return err
+	}
+	if charged > 0 {
+		return nil
+	}
+
+	seller, err := s.userRepo.GetByID(transaction.SellerID.String())
+	if err != nil {
+		return err
+	}
+
+	fee, err := s.calculateWithTx(tx, models.FeeKindSaleCommission, settledAmount, seller.ID, seller.Role, time.Now())
+	if err != nil || fee == nil {
+		return err
+	}
+	capFee(fee, settledAmount)
+	if err := s.RecordFeesWithTx(tx, transaction.ID, fee); err != nil {
+		return err
+	}
+
+	amount := payment.ToMinorUnits(fee.Amount)
+	if amount == 0 {
+		return nil
+	}
+	return s.ledger.postWithTx(tx, &transaction.ID, nil, fmt.Sprintf("sale commission on transaction %s", transaction.ID), []ledgerLine{
+		{Code: models.LedgerAccountSellerPayable, OwnerID: transaction.SellerID, Debit: amount},
+		{Code: models.LedgerAccountPlatformRevenue, Credit: amount},
+	})
+}
+
+// capFee lowers the amount charged to limit when it is more, e.g. a flat or minimum commission on a sale that was
+// mostly refunded, so a fee never leaves its payer owing the platform
+func capFee(fee *models.TransactionFee, limit float64) {
+	if payment.ToMinorUnits(fee.Amount) > payment.ToMinorUnits(limit) {
+		fee.Amount = roundToMinorUnits(limit)
+	}
+}
+
+// calculateWithTx applies the schedule in force at a time to an amount. The newest active rule for the payer's role
+// wins over the newest rule for everyone, and the largest running waiver for the payer is taken off.
+// It returns nil when no rule applies.
+func (s *FeeService) calculateWithTx(tx *gorm.DB, kind models.FeeKind, amount float64, payerID uuid.UUID, payerRole types.Role, at time.Time) (*models.TransactionFee, error) {
+	rules, err := s.repo.GetActiveRulesWithTx(tx, kind)
+	if err != nil {
+		return nil, err
+	}
+	rule := selectFeeRule(rules, payerRole)
+	if rule == nil {
+		return nil, nil
+	}
+
+	waivers, err := s.repo.GetActiveWaiversWithTx(tx, kind, at)
+	if err != nil {
+		return nil, err
+	}
+	return newTransactionFee(kind, rule, selectFeeWaiver(waivers, payerID, payerRole), amount, payerID), nil
+}
+
+// selectFeeRule picks the rule charged to a payer from the active rules, newest first: the newest one for the payer's
+// role, or else the newest one for everyone
+func selectFeeRule(rules []*models.FeeRule, payerRole types.Role) *models.FeeRule {
+	var rule *models.FeeRule
+	for _, candidate := range rules {
+		if candidate.Role == payerRole {
+			return candidate
+		}
+		if candidate.Role == "" && rule == nil {
+			rule = candidate
+		}
+	}
+	return rule
+}
+
+// selectFeeWaiver picks the largest of the running waivers that applies to a payer, or nil
+func selectFeeWaiver(waivers []*models.FeeWaiver, payerID uuid.UUID, payerRole types.Role) *models.FeeWaiver {
+	var waiver *models.FeeWaiver
+	for _, candidate := range waivers {
+		applies := candidate.UserID == nil && (candidate.Role == "" || candidate.Role == payerRole)
+		if candidate.UserID != nil {
+			applies = *candidate.UserID == payerID
+		}
+		if applies && (waiver == nil || candidate.Percentage > waiver.Percentage) {
+			waiver = candidate
+		}
+	}
+	return waiver
+}
+
+// newTransactionFee works out the line item a rule, less an optional waiver, charges on an amount
+func newTransactionFee(kind models.FeeKind, rule *models.FeeRule, waiver *models.FeeWaiver, amount float64, payerID uuid.UUID) *models.TransactionFee {
+	gross := ruleAmount(rule, amount)
+	fee := &models.TransactionFee{
+		Kind:        kind,
+		PayerID:     payerID,
+		RuleID:      &rule.ID,
+		Description: rule.Name,
+		BaseAmount:  amount,
+		GrossAmount: gross,
+		Amount:      gross,
+	}
+	if waiver != nil {
+		fee.WaiverID = &waiver.ID
+		fee.WaivedAmount = roundToMinorUnits(gross * waiver.Percentage / 100)
+		fee.Amount = gross - fee.WaivedAmount
+		fee.Description = fmt.Sprintf("%s (%s)", rule.Name, waiver.Name)
+	}
+	return fee
+}
+
+// ruleAmount is the fee a rule charges on an amount, within the rule's minimum and cap
+func ruleAmount(rule *models.FeeRule, amount float64) float64 {
+	var fee float64
+	switch rule.Method {
+	case models.FeeMethodPercentage:
+		fee = amount * rule.Percentage / 100
+	case models.FeeMethodFlat:
+		fee = rule.FlatAmount
+	case models.FeeMethodTiered:
+		for _, tier := range rule.Tiers {
+			if tier.UpTo == 0 || amount <= tier.UpTo {
+				fee = amount*tier.Percentage/100 + tier.FlatAmount
+				break
+			}
+		}
+	}
+
+	if rule.MinAmount > 0 && fee < rule.MinAmount {
+		fee = rule.MinAmount
+	}
+	if rule.MaxAmount > 0 && fee > rule.MaxAmount {
+		fee = rule.MaxAmount
+	}
+	return roundToMinorUnits(fee)
+}
+
+// roundToMinorUnits rounds an amount to whole minor units, the precision money is settled in
+func roundToMinorUnits(amount float64) float64 {
+	return payment.ToMajorUnits(payment.ToMinorUnits(amount))
+}
+
+func validateFeeRule(rule *models.FeeRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("invalid fee rule: name is required")
+	}
+	if rule.Kind != models.FeeKindSaleCommission && rule.Kind != models.FeeKindInspectionFee {
+		return fmt.Errorf("invalid fee rule: kind must be sale_commission or inspection_fee")
+	}
+	if err := validateFeeRole(rule.Role); err != nil {
+		return err
+	}
+	if rule.Percentage < 0 || rule.Percentage > 100 {
+		return fmt.Errorf("invalid fee rule: percentage must be between 0 and 100")
+	}
+	if rule.FlatAmount < 0 || rule.MinAmount < 0 || rule.MaxAmount < 0 {
+		return fmt.Errorf("invalid fee rule: amounts cannot be negative")
+	}
+	if rule.MaxAmount > 0 && rule.MaxAmount < rule.MinAmount {
+		return fmt.Errorf("invalid fee rule: max_amount cannot be below min_amount")
+	}
+
+	switch rule.Method {
+	case models.FeeMethodPercentage, models.FeeMethodFlat:
+		rule.Tiers = nil
+	case models.FeeMethodTiered:
+		if len(rule.Tiers) == 0 {
+			return fmt.Errorf("invalid fee rule: tiered fees need at least one tier")
+		}
+		for i, tier := range rule.Tiers {
+			if tier.Percentage < 0 || tier.Percentage > 100 || tier.FlatAmount < 0 {
+				return fmt.Errorf("invalid fee rule: tier %d must have a percentage between 0 and 100 and no negative flat amount", i+1)
+			}
+			last := i == len(rule.Tiers)-1
+			if tier.UpTo < 0 || (tier.UpTo == 0 && !last) {
+				return fmt.Errorf("invalid fee rule: only the last tier may be unbounded")
+			}
+			if i > 0 && tier.UpTo != 0 && tier.UpTo <= rule.Tiers[i-1].UpTo {
+				return fmt.Errorf("invalid fee rule: tiers must be in ascending order of up_to")
+			}
+		}
+	default:
+		return fmt.Errorf("invalid fee rule: method must be percentage, flat or tiered")
+	}
+	return nil
+}
+
+func validateFeeWaiver(waiver *models.FeeWaiver) error {
+	waiver.Name = strings.TrimSpace(waiver.Name)
+	if waiver.Name == "" {
+		return fmt.Errorf("invalid fee waiver: name is required")
+	}
+	if waiver.Kind != models.FeeKindSaleCommission && waiver.Kind != models.FeeKindInspectionFee {
+		return fmt.Errorf("invalid fee waiver: kind must be sale_commission or inspection_fee")
+	}
+	if err := validateFeeRole(waiver.Role); err != nil {
+		return err
+	}
+	if waiver.Percentage <= 0 || waiver.Percentage > 100 {
+		return fmt.Errorf("invalid fee waiver: percentage must be more than 0 and at most 100")
+	}
+	if waiver.EndsAt != nil && !waiver.EndsAt.After(waiver.StartsAt) {
+		return fmt.Errorf("invalid fee waiver: ends_at must be after starts_at")
+	}
+	return nil
+}
+
+func validateFeeRole(role types.Role) error {
+	switch role {
+	case "", types.RoleBuyer, types.RoleSeller, types.RoleAdmin, types.RoleInspector:
+		return nil
+	}
+	return fmt.Errorf("invalid fee role '%s'", role)
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+func TestRuleAmount(t *testing.T) {
+	tiered := &models.FeeRule{Method: models.FeeMethodTiered, Tiers: []models.FeeTier{
+		{UpTo: 5000000, Percentage: 5},
+		{UpTo: 20000000, Percentage: 4, FlatAmount: 10000},
+		{UpTo: 0, Percentage: 3, FlatAmount: 50000},
+	}}
+
+	tests := []struct {
+		name   string
+		rule   *models.FeeRule
+		amount float64
+		want   float64
+	}{
+		{"percentage", &models.FeeRule{Method: models.FeeMethodPercentage, Percentage: 2.5}, 1000000, 25000},
+		{"percentage rounded to minor units", &models.FeeRule{Method: models.FeeMethodPercentage, Percentage: 1.5}, 333.33, 5},
+		{"flat", &models.FeeRule{Method: models.FeeMethodFlat, FlatAmount: 15000}, 1000000, 15000},
+		{"percentage raised to the minimum", &models.FeeRule{Method: models.FeeMethodPercentage, Percentage: 1, MinAmount: 5000}, 100000, 5000},
+		{"percentage capped", &models.FeeRule{Method: models.FeeMethodPercentage, Percentage: 10, MaxAmount: 250000}, 5000000, 250000},
+		{"first tier", tiered, 1000000, 50000},
+		{"top of the first tier", tiered, 5000000, 250000},
+		{"just above the first tier", tiered, 5000000.01, 210000},
+		{"middle tier", tiered, 10000000, 410000},
+		{"unbounded last tier", tiered, 30000000, 950000},
+		{"no tier matches", &models.FeeRule{Method: models.FeeMethodTiered, Tiers: []models.FeeTier{{UpTo: 100, Percentage: 5}}}, 1000, 0},
+	}
+
+	for _, tt := range tests {
+		if got := ruleAmount(tt.rule, tt.amount); payment.ToMinorUnits(got) != payment.ToMinorUnits(tt.want) {
+			t.Errorf("%s: ruleAmount(%v) = %v, want %v", tt.name, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestSelectFeeRule(t *testing.T) {
+	// newest first, as the repository returns them
+	newestForEveryone := &models.FeeRule{Name: "newest for everyone"}
+	forBuyers := &models.FeeRule{Name: "for buyers", Role: types.RoleBuyer}
+	olderForEveryone := &models.FeeRule{Name: "older for everyone"}
+	olderForBuyers := &models.FeeRule{Name: "older for buyers", Role: types.RoleBuyer}
+	forSellers := &models.FeeRule{Name: "for sellers", Role: types.RoleSeller}
+
+	tests := []struct {
+		name  string
+		rules []*models.FeeRule
+		role  types.Role
+		want  *models.FeeRule
+	}{
+		{"role rule wins over a newer rule for everyone", []*models.FeeRule{newestForEveryone, forBuyers, olderForBuyers}, types.RoleBuyer, forBuyers},
+		{"newest rule for everyone without a role rule", []*models.FeeRule{newestForEveryone, forSellers, olderForEveryone}, types.RoleBuyer, newestForEveryone},
+		{"another role's rule does not apply", []*models.FeeRule{forSellers}, types.RoleBuyer, nil},
+		{"no rules", nil, types.RoleBuyer, nil},
+	}
+
+	for _, tt := range tests {
+		got := selectFeeRule(tt.rules, tt.role)
+		if got != tt.want {
+			t.Errorf("%s: selectFeeRule = %v, want %v", tt.name, ruleName(got), ruleName(tt.want))
+		}
+	}
+}
+
+func TestSelectFeeWaiver(t *testing.T) {
+	payerID, otherID := uuid.New(), uuid.New()
+	everyone := &models.FeeWaiver{Name: "everyone 10%", Percentage: 10}
+	buyers := &models.FeeWaiver{Name: "buyers 25%", Role: types.RoleBuyer, Percentage: 25}
+	sellers := &models.FeeWaiver{Name: "sellers 50%", Role: types.RoleSeller, Percentage: 50}
+	payer := &models.FeeWaiver{Name: "payer 100%", UserID: &payerID, Percentage: 100}
+	other := &models.FeeWaiver{Name: "other user 100%", UserID: &otherID, Percentage: 100}
+
+	tests := []struct {
+		name    string
+		waivers []*models.FeeWaiver
+		want    *models.FeeWaiver
+	}{
+		{"largest applicable waiver", []*models.FeeWaiver{everyone, buyers}, buyers},
+		{"waiver for the payer", []*models.FeeWaiver{everyone, buyers, payer}, payer},
+		{"another role's waiver does not apply", []*models.FeeWaiver{sellers, everyone}, everyone},
+		{"another user's waiver does not apply", []*models.FeeWaiver{other, sellers}, nil},
+		{"no waivers", nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := selectFeeWaiver(tt.waivers, payerID, types.RoleBuyer)
+		if got != tt.want {
+			t.Errorf("%s: selectFeeWaiver = %v, want %v", tt.name, waiverName(got), waiverName(tt.want))
+		}
+	}
+}
+
+func TestNewTransactionFee(t *testing.T) {
+	payerID := uuid.New()
+	rule := &models.FeeRule{ID: uuid.New(), Name: "Inspection", Method: models.FeeMethodPercentage, Percentage: 1.501}
+
+	tests := []struct {
+		name                  string
+		waiver                *models.FeeWaiver
+		gross, waived, amount float64
+		description           string
+	}{
+		{"no waiver", nil, 150.10, 0, 150.10, "Inspection"},
+		{"partly waived", &models.FeeWaiver{ID: uuid.New(), Name: "Launch", Percentage: 10}, 150.10, 15.01, 135.09, "Inspection (Launch)"},
+		{"waived share rounded to minor units", &models.FeeWaiver{ID: uuid.New(), Name: "Third off", Percentage: 33.333}, 150.10, 50.03, 100.07, "Inspection (Third off)"},
+		{"waived in full", &models.FeeWaiver{ID: uuid.New(), Name: "Free", Percentage: 100}, 150.10, 150.10, 0, "Inspection (Free)"},
+	}
+
+	for _, tt := range tests {
+		fee := newTransactionFee(models.FeeKindInspectionFee, rule, tt.waiver, 10000, payerID)
+
+		if payment.ToMinorUnits(fee.GrossAmount) != payment.ToMinorUnits(tt.gross) ||
+			payment.ToMinorUnits(fee.WaivedAmount) != payment.ToMinorUnits(tt.waived) ||
+			payment.ToMinorUnits(fee.Amount) != payment.ToMinorUnits(tt.amount) {
+			t.Errorf("%s: gross %v, waived %v, charged %v; want %v, %v, %v", tt.name, fee.GrossAmount, fee.WaivedAmount, fee.Amount, tt.gross, tt.waived, tt.amount)
+		}
+		if fee.Description != tt.description {
+			t.Errorf("%s: description = %q, want %q", tt.name, fee.Description, tt.description)
+		}
+		if fee.RuleID == nil || *fee.RuleID != rule.ID || fee.PayerID != payerID || fee.BaseAmount != 10000 {
+			t.Errorf("%s: fee %+v does not record its rule, payer and base amount", tt.name, fee)
+		}
+		if (tt.waiver == nil) != (fee.WaiverID == nil) {
+			t.Errorf("%s: waiver id = %v", tt.name, fee.WaiverID)
+		}
+	}
+}
+
+func TestCapFee(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount, limit float64
+		want          float64
+	}{
+		{"below the limit", 15000, 900000, 15000},
+		{"at the limit", 15000, 15000, 15000},
+		{"flat fee on a mostly refunded sale", 15000, 2500.25, 2500.25},
+		{"limit rounded to minor units", 15000, 100.004, 100},
+	}
+
+	for _, tt := range tests {
+		fee := &models.TransactionFee{GrossAmount: tt.amount, Amount: tt.amount}
+		capFee(fee, tt.limit)
+		if payment.ToMinorUnits(fee.Amount) != payment.ToMinorUnits(tt.want) {
+			t.Errorf("%s: capFee(%v, %v) charged %v, want %v", tt.name, tt.amount, tt.limit, fee.Amount, tt.want)
+		}
+		if fee.GrossAmount != tt.amount {
+			t.Errorf("%s: gross amount = %v, want it kept at %v", tt.name, fee.GrossAmount, tt.amount)
+		}
+	}
+}
+
+func ruleName(rule *models.FeeRule) string {
+	if rule == nil {
+		return "no rule"
+	}
+	return rule.Name
+}
+
+func waiverName(waiver *models.FeeWaiver) string {
+	if waiver == nil {
+		return "no waiver"
+	}
+	return waiver.Name
+}
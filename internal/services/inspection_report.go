@@ -0,0 +1,417 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/pdf"
+	"github.com/zekeriyyah/lujay-autocity/pkg/qrcode"
+	"github.com/zekeriyyah/lujay-autocity/pkg/storage"
+)
+
+const (
+	reportMargin       = 48.0
+	reportFooterHeight = 36.0
+	reportContentWidth = pdf.A4Width - 2*reportMargin
+
+	maxReportPhotos    = 8
+	maxReportPhotoSize = 5 << 20
+)
+
+// reportLayout keeps track of the write position and starts a new page when content would run into the footer
+type reportLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func newReportLayout() *reportLayout {
+	layout := &reportLayout{doc: pdf.New()}
+	layout.newPage()
+	return layout
+}
+
+func (l *reportLayout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = reportMargin
+}
+
+// ensure starts a new page unless height points still fit on the current one
+func (l *reportLayout) ensure(height float64) {
+	if l.y+height > pdf.A4Height-reportMargin-reportFooterHeight {
+		l.newPage()
+	}
+}
+
+func (l *reportLayout) heading(text string) {
+	l.ensure(40)
+	l.y += 18
+	l.page.SetFillColor(20, 45, 90)
+	l.page.Text(reportMargin, l.y, pdf.HelveticaBold, 13, text)
+	l.y += 6
+	l.page.SetStrokeColor(20, 45, 90)
+	l.page.Line(reportMargin, l.y, pdf.A4Width-reportMargin, l.y, 0.8)
+	l.y += 14
+	l.page.SetFillColor(0, 0, 0)
+}
+
+// field writes a label/value row, wrapping long values under the value column
+func (l *reportLayout) field(label, value string) {
+	if value == "" {
+		value = "-"
+	}
+	const labelWidth = 140.0
+	lines := pdf.WrapText(pdf.Helvetica, 10, value, reportContentWidth-labelWidth)
+
+	l.ensure(float64(len(lines)) * 14)
+	l.page.Text(reportMargin, l.y, pdf.HelveticaBold, 10, label)
+	for _, line := range lines {
+		l.page.Text(reportMargin+labelWidth, l.y, pdf.Helvetica, 10, line)
+		l.y += 14
+	}
+}
+
+// paragraph writes wrapped text indented by indent points
+func (l *reportLayout) paragraph(text string, font pdf.Font, size, indent float64) {
+	lineHeight := size * 1.4
+	for _, line := range pdf.WrapText(font, size, text, reportContentWidth-indent) {
+		l.ensure(lineHeight)
+		l.page.Text(reportMargin+indent, l.y, font, size, line)
+		l.y += lineHeight
+	}
+}
+
+// renderInspectionReport lays out the branded PDF report of a finalised inspection.
+// template may be nil when the inspection predates structured checklists, and certificate is nil unless the inspection approved the listing.
+// Photos are only read from photoStorage; pictures hosted anywhere else are left out of the report.
+func renderInspectionReport(inspection *models.Inspection, listing *models.Listing, template *models.ChecklistTemplate, certificate *models.InspectionCertificate, verificationURL string, photoStorage storage.Storage) ([]byte, error) {
+	layout := newReportLayout()
+	page := layout.page
+
+	// header band
+	page.SetFillColor(20, 45, 90)
+	page.Rect(0, 0, pdf.A4Width, 90)
+	page.SetFillColor(255, 255, 255)
+	page.Text(reportMargin, 45, pdf.HelveticaBold, 24, "AutoCity")
+	reportTitle := "Vehicle Inspection Report"
+	if inspection.Type == models.InspectionTypePrePurchase {
+		reportTitle = "Pre-Purchase Inspection Report"
+	}
+	page.Text(reportMargin, 68, pdf.Helvetica, 12, reportTitle)
+	reference := "Ref. " + inspection.ID.String()
+	page.Text(pdf.A4Width-reportMargin-pdf.TextWidth(pdf.Helvetica, 9, reference), 68, pdf.Helvetica, 9, reference)
+	page.SetFillColor(0, 0, 0)
+	layout.y = 110
+
+	// condition rating badge
+	badgeR, badgeG, badgeB := ratingColor(inspection.ConditionRating)
+	page.SetFillColor(badgeR, badgeG, badgeB)
+	page.Rect(pdf.A4Width-reportMargin-110, layout.y, 110, 60)
+	page.SetFillColor(255, 255, 255)
+	page.Text(pdf.A4Width-reportMargin-100, layout.y+18, pdf.Helvetica, 9, "CONDITION RATING")
+	page.Text(pdf.A4Width-reportMargin-100, layout.y+48, pdf.HelveticaBold, 24, formatRating(inspection.ConditionRating))
+	page.SetFillColor(0, 0, 0)
+
+	layout.y += 14
+	for _, line := range pdf.WrapText(pdf.HelveticaBold, 16, listing.Title, reportContentWidth-130) {
+		page.Text(reportMargin, layout.y, pdf.HelveticaBold, 16, line)
+		layout.y += 20
+	}
+	layout.field("Outcome", strings.ToUpper(string(inspection.Status)))
+	layout.field("Inspection date", formatReportDate(inspection.InspectionDate))
+	layout.field("Inspector", inspection.Inspector.Name)
+	layout.y = max(layout.y, 190)
+
+	// vehicle specs as recorded on the listing, falling back to the canonical vehicle record
+	specs := listing.Vehicle.Specs().Merge(listing.VehicleSpecs)
+	layout.heading("Vehicle")
+	layout.field("VIN", listing.Vehicle.VIN)
+	layout.field("Make / Model", strings.TrimSpace(specs.Make+" "+specs.Model))
+	if specs.Year > 0 {
+		layout.field("Year", fmt.Sprintf("%d", specs.Year))
+	}
+	if listing.Mileage > 0 {
+		layout.field("Mileage", fmt.Sprintf("%d km", listing.Mileage))
+	}
+	layout.field("Engine size", specs.EngineSize)
+	layout.field("Fuel type", specs.FuelType)
+	layout.field("Transmission", specs.Transmission)
+	layout.field("Body type", specs.BodyType)
+	layout.field("Colour", specs.Color)
+	layout.field("Location", listing.Location)
+
+	if len(inspection.Checklist) > 0 {
+		layout.heading("Checklist")
+		if inspection.ChecklistVersion > 0 {
+			layout.paragraph(fmt.Sprintf("Assessed against checklist version %d.", inspection.ChecklistVersion), pdf.Helvetica, 9, 0)
+		}
+		writeChecklist(layout, inspection.Checklist, template)
+	}
+
+	if len(inspection.Findings) > 0 {
+		layout.heading("Inspector notes")
+		keys := make([]string, 0, len(inspection.Findings))
+		for key := range inspection.Findings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			layout.field(humanizeKey(key), fmt.Sprint(inspection.Findings[key]))
+		}
+	}
+
+	if certificate != nil {
+		if err := writeCertificate(layout, certificate, verificationURL); err != nil {
+			return nil, err
+		}
+	}
+
+	writePhotos(layout, reportPhotoURLs(inspection, listing), photoStorage)
+
+	// footers go on last so every page knows the page count
+	pages := layout.doc.Pages()
+	generatedAt := "Generated " + time.Now().UTC().Format("02 Jan 2006 15:04 MST")
+	for i, p := range pages {
+		footerY := pdf.A4Height - reportMargin + 10
+		p.SetStrokeColor(200, 200, 200)
+		p.Line(reportMargin, footerY-14, pdf.A4Width-reportMargin, footerY-14, 0.5)
+		p.SetFillColor(110, 110, 110)
+		p.Text(reportMargin, footerY, pdf.Helvetica, 8, "AutoCity inspection report - "+generatedAt)
+		pageLabel := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		p.Text(pdf.A4Width-reportMargin-pdf.TextWidth(pdf.Helvetica, 8, pageLabel), footerY, pdf.Helvetica, 8, pageLabel)
+	}
+
+	return layout.doc.Bytes()
+}
+
+// writeChecklist lists findings grouped by template section, using template labels where available
+func writeChecklist(layout *reportLayout, findings []models.ChecklistFinding, template *models.ChecklistTemplate) {
+	sectionNames := map[string]string{}
+	itemLabels := map[string]string{}
+	sectionOrder := []string{}
+	if template != nil {
+		for _, section := range template.Sections {
+			sectionNames[section.Key] = section.Name
+			sectionOrder = append(sectionOrder, section.Key)
+			for _, item := range section.Items {
+				itemLabels[section.Key+"."+item.Key] = item.Label
+			}
+		}
+	}
+
+	bySection := map[string][]models.ChecklistFinding{}
+	for _, finding := range findings {
+		if _, known := bySection[finding.Section]; !known && sectionNames[finding.Section] == "" {
+			sectionOrder = append(sectionOrder, finding.Section)
+		}
+		bySection[finding.Section] = append(bySection[finding.Section], finding)
+	}
+
+	for _, sectionKey := range sectionOrder {
+		sectionFindings := bySection[sectionKey]
+		if len(sectionFindings) == 0 {
+			continue
+		}
+
+		name := sectionNames[sectionKey]
+		if name == "" {
+			name = humanizeKey(sectionKey)
+		}
+		layout.ensure(36)
+		layout.y += 6
+		layout.page.Text(reportMargin, layout.y, pdf.HelveticaBold, 11, name)
+		layout.y += 16
+
+		for _, finding := range sectionFindings {
+			label := itemLabels[sectionKey+"."+finding.Item]
+			if label == "" {
+				label = humanizeKey(finding.Item)
+			}
+
+			result := strings.ToUpper(string(finding.Result))
+			if finding.Result == models.ChecklistResultFail && finding.Severity != "" {
+				result += " (" + string(finding.Severity) + ")"
+			}
+
+			layout.ensure(14)
+			layout.page.Text(reportMargin+10, layout.y, pdf.Helvetica, 10, label)
+			r, g, b := resultColor(finding.Result)
+			layout.page.SetFillColor(r, g, b)
+			layout.page.Text(pdf.A4Width-reportMargin-pdf.TextWidth(pdf.HelveticaBold, 10, result), layout.y, pdf.HelveticaBold, 10, result)
+			layout.page.SetFillColor(0, 0, 0)
+			layout.y += 14
+
+			if finding.Notes != "" {
+				layout.page.SetFillColor(90, 90, 90)
+				layout.paragraph(finding.Notes, pdf.Helvetica, 9, 20)
+				layout.page.SetFillColor(0, 0, 0)
+			}
+		}
+	}
+}
+
+// writeCertificate prints the certificate reference next to a QR code linking to its public verification page
+func writeCertificate(layout *reportLayout, certificate *models.InspectionCertificate, verificationURL string) error {
+	code, err := qrcode.Encode(verificationURL)
+	if err != nil {
+		return fmt.Errorf("failed to encode certificate qr code: %w", err)
+	}
+
+	const qrSize = 110.0
+	layout.heading("Certificate")
+	layout.ensure(qrSize)
+
+	moduleSize := qrSize / float64(code.Size)
+	left := pdf.A4Width - reportMargin - qrSize
+	top := layout.y
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; col++ {
+			if code.Modules[row][col] {
+				layout.page.Rect(left+float64(col)*moduleSize, top+float64(row)*moduleSize, moduleSize, moduleSize)
+			}
+		}
+	}
+
+	layout.y += 10
+	layout.page.Text(reportMargin, layout.y, pdf.HelveticaBold, 10, "Certificate ID")
+	layout.page.Text(reportMargin+100, layout.y, pdf.Helvetica, 10, certificate.ID.String())
+	layout.y += 14
+	layout.page.Text(reportMargin, layout.y, pdf.HelveticaBold, 10, "Issued")
+	layout.page.Text(reportMargin+100, layout.y, pdf.Helvetica, 10, certificate.IssuedAt.Format("02 Jan 2006 15:04 MST"))
+	layout.y += 14
+	layout.page.Text(reportMargin, layout.y, pdf.HelveticaBold, 10, "Signing key")
+	layout.page.Text(reportMargin+100, layout.y, pdf.Helvetica, 10, certificate.KeyID)
+	layout.y += 20
+	for _, line := range pdf.WrapText(pdf.Helvetica, 9, "Scan the code or open "+verificationURL+" to check that this report matches the signed certificate.", reportContentWidth-qrSize-20) {
+		layout.page.Text(reportMargin, layout.y, pdf.Helvetica, 9, line)
+		layout.y += 12
+	}
+
+	layout.y = max(layout.y, top+qrSize) + 10
+	return nil
+}
+
+// writePhotos embeds the photos that could be read from storage as JPEGs, two per row
+func writePhotos(layout *reportLayout, urls []string, photoStorage storage.Storage) {
+	images := []*pdf.Image{}
+	for _, url := range urls {
+		if len(images) == maxReportPhotos {
+			break
+		}
+		data, err := photoStorage.Download(url, maxReportPhotoSize)
+		if errors.Is(err, storage.ErrNotStored) {
+			fmt.Printf("Warning: skipping photo %s in inspection report: not hosted in our file storage\n", url)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Warning: skipping photo %s in inspection report: %v\n", url, err)
+			continue
+		}
+		img, err := layout.doc.AddJPEG(data)
+		if err != nil {
+			fmt.Printf("Warning: skipping photo %s in inspection report: %v\n", url, err)
+			continue
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	layout.heading("Photos")
+	const gap = 12.0
+	const boxHeight = 170.0
+	boxWidth := (reportContentWidth - gap) / 2
+
+	for i, img := range images {
+		if i%2 == 0 {
+			if i > 0 {
+				layout.y += boxHeight + gap
+			}
+			layout.ensure(boxHeight)
+		}
+
+		// fit the photo inside its box without distorting it
+		scale := min(boxWidth/float64(img.Width), boxHeight/float64(img.Height))
+		width, height := float64(img.Width)*scale, float64(img.Height)*scale
+		x := reportMargin + float64(i%2)*(boxWidth+gap) + (boxWidth-width)/2
+		layout.page.Image(img, x, layout.y+(boxHeight-height)/2, width, height)
+	}
+	layout.y += boxHeight + gap
+}
+
+// reportPhotoURLs returns the finding photos followed by the listing gallery, without duplicates
+func reportPhotoURLs(inspection *models.Inspection, listing *models.Listing) []string {
+	seen := map[string]bool{}
+	urls := []string{}
+	add := func(url string) {
+		if url != "" && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+
+	for _, finding := range inspection.Checklist {
+		for _, photo := range finding.Photos {
+			add(photo)
+		}
+	}
+
+	images := append([]models.Image{}, listing.Images...)
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Order < images[j].Order })
+	for _, image := range images {
+		add(image.URL)
+	}
+	return urls
+}
+
+func ratingColor(rating int) (uint8, uint8, uint8) {
+	switch {
+	case rating >= 8:
+		return 34, 139, 34
+	case rating >= 5:
+		return 230, 145, 30
+	case rating > 0:
+		return 200, 40, 40
+	default:
+		return 120, 120, 120
+	}
+}
+
+func resultColor(result models.ChecklistResult) (uint8, uint8, uint8) {
+	switch result {
+	case models.ChecklistResultPass:
+		return 34, 139, 34
+	case models.ChecklistResultFail:
+		return 200, 40, 40
+	default:
+		return 120, 120, 120
+	}
+}
+
+func formatRating(rating int) string {
+	if rating <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d / 10", rating)
+}
+
+func formatReportDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("02 Jan 2006")
+}
+
+// humanizeKey turns keys such as "brake_pads" into "Brake pads"
+func humanizeKey(key string) string {
+	text := strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(key))
+	if text == "" {
+		return key
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
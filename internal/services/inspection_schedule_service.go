@@ -0,0 +1,404 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// maxInspectionReschedules caps how often a seller can move the same booking
+const maxInspectionReschedules = 2
+
+type InspectionScheduleService struct {
+	slotRepo       *repositories.InspectionSlotRepository
+	bookingRepo    *repositories.InspectionBookingRepository
+	listingRepo    *repositories.ListingRepository
+	inspectionRepo *repositories.InspectionRepository
+	prePurchase    *PrePurchaseInspectionService
+	changeCutoff   time.Duration
+	reminderLead   time.Duration
+}
+
+func NewInspectionScheduleService(slotRepo *repositories.InspectionSlotRepository, bookingRepo *repositories.InspectionBookingRepository, listingRepo *repositories.ListingRepository, inspectionRepo *repositories.InspectionRepository, prePurchase *PrePurchaseInspectionService, changeCutoff, reminderLead time.Duration) *InspectionScheduleService {
+	return &InspectionScheduleService{
+		slotRepo:       slotRepo,
+		bookingRepo:    bookingRepo,
+		listingRepo:    listingRepo,
+		inspectionRepo: inspectionRepo,
+		prePurchase:    prePurchase,
+		changeCutoff:   changeCutoff,
+		reminderLead:   reminderLead,
+	}
+}
+
+// CreateSlot publishes an availability window for an inspector, rejecting overlaps with their other slots
+func (s *InspectionScheduleService) CreateSlot(inspectorID uuid.UUID, startsAt, endsAt time.Time) (*models.InspectionSlot, error) {
+	if !endsAt.After(startsAt) {
+		return nil, fmt.Errorf("slot must end after it starts")
+	}
+	if !startsAt.After(time.Now()) {
+		return nil, fmt.Errorf("slot must start in the future")
+	}
+
+	slot := &models.InspectionSlot{
+		InspectorID: inspectorID,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Status:      models.InspectionSlotStatusAvailable,
+	}
+
+	err := s.slotRepo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.slotRepo.LockInspectorWithTx(tx, inspectorID); err != nil {
+			return err
+		}
+
+		overlapping, err := s.slotRepo.CountOverlappingWithTx(tx, inspectorID, startsAt, endsAt)
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return fmt.Errorf("slot overlaps an existing slot for this inspector")
+		}
+
+		return s.slotRepo.CreateWithTx(tx, slot)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slot, nil
+}
+
+// GetInspectorSlots retrieves an inspector's current and upcoming slots
+func (s *InspectionScheduleService) GetInspectorSlots(inspectorID uuid.UUID) ([]*models.InspectionSlot, error) {
+	return s.slotRepo.GetByInspectorID(inspectorID.String(), time.Now())
+}
+
+// GetAvailableSlots retrieves bookable slots in the given window
+func (s *InspectionScheduleService) GetAvailableSlots(from, to time.Time) ([]*models.InspectionSlot, error) {
+	if from.Before(time.Now()) {
+		from = time.Now()
+	}
+	return s.slotRepo.GetAvailable(from, to)
+}
+
+// CancelSlot withdraws a slot; a booking holding it is cancelled and the seller is told to rebook,
+// while a pre-purchase inspection on it goes back to the buyer to pick another slot
+func (s *InspectionScheduleService) CancelSlot(slotID string, userID uuid.UUID, role types.Role) error {
+	parsedSlotID, err := uuid.Parse(slotID)
+	if err != nil {
+		return fmt.Errorf("invalid UUID format: %w", err)
+	}
+
+	var cancelledBooking *models.InspectionBooking
+	var releasedRequest *models.InspectionRequest
+	var refunds []*models.Refund
+	err = s.slotRepo.DB.Transaction(func(tx *gorm.DB) error {
+		slot, err := s.slotRepo.GetByIDForUpdateWithTx(tx, parsedSlotID)
+		if err != nil {
+			return err
+		}
+
+		if role != types.RoleAdmin && slot.InspectorID != userID {
+			return fmt.Errorf("unauthorized: you can only cancel your own slots")
+		}
+		if slot.Status == models.InspectionSlotStatusCancelled {
+			return fmt.Errorf("inspection slot is already cancelled")
+		}
+
+		if slot.Status == models.InspectionSlotStatusBooked {
+			booking, err := s.bookingRepo.GetScheduledBySlotIDWithTx(tx, slot.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if booking != nil {
+				if err := s.bookingRepo.UpdateFieldsWithTx(tx, booking.ID, map[string]any{
+					"status":        models.InspectionBookingStatusCancelled,
+					"cancelled_at":  time.Now(),
+					"cancel_reason": "inspector unavailable",
+				}); err != nil {
+					return err
+				}
+				cancelledBooking = booking
+			}
+
+			releasedRequest, refunds, err = s.prePurchase.releaseSlotWithTx(tx, slot.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.slotRepo.UpdateStatusWithTx(tx, slot.ID, models.InspectionSlotStatusCancelled)
+	})
+	if err != nil {
+		return err
+	}
+	s.prePurchase.transactions.refunds.issue(refunds...)
+
+	if cancelledBooking != nil {
+		if err := email_helper.SendInspectionCancelledEmail(cancelledBooking.Seller.Email, cancelledBooking.Listing.Title, cancelledBooking.ScheduledFor, "inspector unavailable"); err != nil {
+			fmt.Printf("Warning: Failed to send inspection cancellation email to seller %s: %v\n", cancelledBooking.Seller.Email, err)
+		}
+	}
+	if releasedRequest != nil {
+		if err := email_helper.SendInspectionRequestCancelledEmail(releasedRequest.Buyer.Email, releasedRequest.Listing.Title, "inspector unavailable, please pick another slot"); err != nil {
+			fmt.Printf("Warning: Failed to send inspection cancellation email to buyer %s: %v\n", releasedRequest.Buyer.Email, err)
+		}
+	}
+
+	return nil
+}
+
+// BookInspection reserves a slot for a seller's pending listing and assigns its inspector and date to the pending inspection
+func (s *InspectionScheduleService) BookInspection(listingID string, slotID uuid.UUID, sellerID uuid.UUID) (*models.InspectionBooking, error) {
+	listing, err := s.getOwnedListing(listingID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Status != models.ListingStatusPending {
+		return nil, fmt.Errorf("only listings pending review can be booked for inspection")
+	}
+
+	booking := &models.InspectionBooking{}
+	err = s.bookingRepo.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := s.bookingRepo.GetScheduledByListingIDForUpdateWithTx(tx, listing.ID)
+		if err == nil {
+			return fmt.Errorf("listing already has a scheduled inspection")
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		slot, err := s.claimSlotWithTx(tx, slotID)
+		if err != nil {
+			return err
+		}
+
+		booking.ListingID = listing.ID
+		booking.SlotID = slot.ID
+		booking.InspectorID = slot.InspectorID
+		booking.SellerID = sellerID
+		booking.ScheduledFor = slot.StartsAt
+		booking.Status = models.InspectionBookingStatusScheduled
+		if err := s.bookingRepo.CreateWithTx(tx, booking); err != nil {
+			return err
+		}
+
+		return s.assignInspectionWithTx(tx, listing.ID, slot.InspectorID, slot.StartsAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.GetScheduledByListingID(listing.ID)
+}
+
+// RescheduleInspection moves a listing's booking to another slot while outside the change cutoff
+func (s *InspectionScheduleService) RescheduleInspection(listingID string, slotID uuid.UUID, sellerID uuid.UUID) (*models.InspectionBooking, error) {
+	listing, err := s.getOwnedListing(listingID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.bookingRepo.DB.Transaction(func(tx *gorm.DB) error {
+		booking, err := s.getChangeableBookingWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if booking.RescheduleCount >= maxInspectionReschedules {
+			return fmt.Errorf("inspection has already been rescheduled the maximum number of times")
+		}
+		if booking.SlotID == slotID {
+			return fmt.Errorf("inspection is already booked in this slot")
+		}
+
+		slot, err := s.claimSlotWithTx(tx, slotID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.slotRepo.UpdateStatusWithTx(tx, booking.SlotID, models.InspectionSlotStatusAvailable); err != nil {
+			return err
+		}
+
+		if err := s.bookingRepo.UpdateFieldsWithTx(tx, booking.ID, map[string]any{
+			"slot_id":          slot.ID,
+			"inspector_id":     slot.InspectorID,
+			"scheduled_for":    slot.StartsAt,
+			"reschedule_count": booking.RescheduleCount + 1,
+			"reminder_sent_at": nil,
+		}); err != nil {
+			return err
+		}
+
+		return s.assignInspectionWithTx(tx, listing.ID, slot.InspectorID, slot.StartsAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.GetScheduledByListingID(listing.ID)
+}
+
+// CancelBooking releases a listing's booking while outside the change cutoff
+func (s *InspectionScheduleService) CancelBooking(listingID string, sellerID uuid.UUID) error {
+	listing, err := s.getOwnedListing(listingID, sellerID)
+	if err != nil {
+		return err
+	}
+
+	return s.bookingRepo.DB.Transaction(func(tx *gorm.DB) error {
+		booking, err := s.getChangeableBookingWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.slotRepo.UpdateStatusWithTx(tx, booking.SlotID, models.InspectionSlotStatusAvailable); err != nil {
+			return err
+		}
+
+		return s.bookingRepo.UpdateFieldsWithTx(tx, booking.ID, map[string]any{
+			"status":        models.InspectionBookingStatusCancelled,
+			"cancelled_at":  time.Now(),
+			"cancel_reason": "cancelled by seller",
+		})
+	})
+}
+
+// GetBooking retrieves the scheduled booking of a listing for its seller or an admin
+func (s *InspectionScheduleService) GetBooking(listingID string, userID uuid.UUID, role types.Role) (*models.InspectionBooking, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if role != types.RoleAdmin && listing.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view bookings of your own listings")
+	}
+
+	booking, err := s.bookingRepo.GetScheduledByListingID(listing.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no scheduled inspection for this listing")
+		}
+		return nil, err
+	}
+	return booking, nil
+}
+
+// SendDueReminders emails the seller and inspector of every booking starting within the reminder lead time
+func (s *InspectionScheduleService) SendDueReminders() error {
+	bookings, err := s.bookingRepo.GetDueForReminder(time.Now().Add(s.reminderLead))
+	if err != nil {
+		return err
+	}
+
+	for _, booking := range bookings {
+		if err := email_helper.SendInspectionReminderEmail(booking.Seller.Email, booking.Seller.Name, booking.Listing.Title, booking.ScheduledFor); err != nil {
+			fmt.Printf("Warning: Failed to send inspection reminder to seller %s: %v\n", booking.Seller.Email, err)
+			continue
+		}
+		if err := email_helper.SendInspectionReminderEmail(booking.Inspector.Email, booking.Inspector.Name, booking.Listing.Title, booking.ScheduledFor); err != nil {
+			fmt.Printf("Warning: Failed to send inspection reminder to inspector %s: %v\n", booking.Inspector.Email, err)
+		}
+
+		if err := s.bookingRepo.MarkReminderSent(booking.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *InspectionScheduleService) getOwnedListing(listingID string, sellerID uuid.UUID) (*models.Listing, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID != sellerID {
+		return nil, fmt.Errorf("unauthorized: you can only book inspections for your own listings")
+	}
+	return listing, nil
+}
+
+// getChangeableBookingWithTx locks the listing's booking and enforces the change cutoff
+func (s *InspectionScheduleService) getChangeableBookingWithTx(tx *gorm.DB, listingID uuid.UUID) (*models.InspectionBooking, error) {
+	booking, err := s.bookingRepo.GetScheduledByListingIDForUpdateWithTx(tx, listingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no scheduled inspection for this listing")
+		}
+		return nil, err
+	}
+
+	if time.Until(booking.ScheduledFor) < s.changeCutoff {
+		return nil, fmt.Errorf("inspection can no longer be changed within %s of the appointment", s.changeCutoff)
+	}
+	return booking, nil
+}
+
+// claimSlotWithTx locks an available future slot and marks it booked, so an inspector is never double-booked
+func (s *InspectionScheduleService) claimSlotWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionSlot, error) {
+	return claimInspectionSlotWithTx(tx, s.slotRepo, slotID)
+}
+
+// claimInspectionSlotWithTx is shared by seller bookings and buyer pre-purchase inspections, which draw on the same slots
+func claimInspectionSlotWithTx(tx *gorm.DB, slotRepo *repositories.InspectionSlotRepository, slotID uuid.UUID) (*models.InspectionSlot, error) {
+	slot, err := slotRepo.GetByIDForUpdateWithTx(tx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.Status != models.InspectionSlotStatusAvailable {
+		return nil, fmt.Errorf("inspection slot is no longer available")
+	}
+	if !slot.StartsAt.After(time.Now()) {
+		return nil, fmt.Errorf("inspection slot has already started")
+	}
+
+	if err := slotRepo.UpdateStatusWithTx(tx, slot.ID, models.InspectionSlotStatusBooked); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// assignInspectionWithTx records the booked inspector and date on the listing's pending inspection,
+// creating it as the listing's current inspection if needed
+func (s *InspectionScheduleService) assignInspectionWithTx(tx *gorm.DB, listingID, inspectorID uuid.UUID, scheduledFor time.Time) error {
+	inspectionID, err := s.inspectionRepo.GetPendingIDByListingIDWithTx(tx, listingID)
+	if err != nil {
+		return err
+	}
+
+	assignedAt := time.Now()
+	if inspectionID == uuid.Nil {
+		inspection := &models.Inspection{
+			ListingID:      listingID,
+			InspectorID:    inspectorID,
+			AssignedAt:     &assignedAt,
+			InspectionDate: scheduledFor,
+			Status:         models.InspectionStatusPending,
+			Type:           models.InspectionTypeVetting,
+		}
+		if err := s.inspectionRepo.CreateWithTx(tx, inspection); err != nil {
+			return err
+		}
+
+		return s.listingRepo.UpdateWithTx(tx, &models.Listing{
+			ID:                  listingID,
+			CurrentInspectionID: &inspection.ID,
+		})
+	}
+
+	return s.inspectionRepo.UpdateWithTx(tx, &models.Inspection{
+		ID:             inspectionID,
+		InspectorID:    inspectorID,
+		AssignedAt:     &assignedAt,
+		InspectionDate: scheduledFor,
+	})
+}
@@ -1,25 +1,47 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
 	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
 	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/storage"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
 	"gorm.io/gorm"
 )
 
+const (
+	// maxReportAttempts is how often the background generator tries a queued report before leaving it to an admin
+	maxReportAttempts = 5
+	reportBatch       = 20
+)
 
 type InspectionService struct {
 	repo *repositories.InspectionRepository
 	listingRepo *repositories.ListingRepository
+	templateRepo *repositories.ChecklistTemplateRepository
+	fileStorage storage.Storage
+	certificateService *CertificateService
+	rejectionReasonService *RejectionReasonService
+	prePurchaseService *PrePurchaseInspectionService
+	hub *events.Hub
 }
 
-func NewInspectionService(inspectionRepo *repositories.InspectionRepository, listingRepo *repositories.ListingRepository) *InspectionService {
+func NewInspectionService(inspectionRepo *repositories.InspectionRepository, listingRepo *repositories.ListingRepository, templateRepo *repositories.ChecklistTemplateRepository, fileStorage storage.Storage, certificateService *CertificateService, rejectionReasonService *RejectionReasonService, prePurchaseService *PrePurchaseInspectionService, hub *events.Hub) *InspectionService {
 	return &InspectionService{
 		repo: inspectionRepo,
 		listingRepo: listingRepo,
+		templateRepo: templateRepo,
+		fileStorage: fileStorage,
+		certificateService: certificateService,
+		rejectionReasonService: rejectionReasonService,
+		prePurchaseService: prePurchaseService,
+		hub: hub,
 	}
 }
 
@@ -65,7 +87,9 @@ func (s *InspectionService) GetInspectionByID(id string) (*models.Inspection, er
 
 
 // UpdateInspectionStatus updates the status of an inspection and triggers the associated listing status update.
-func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.InspectionStatus, adminID uuid.UUID) error {
+// A rejection must name at least one active rejection reason code; reason is free text kept in the listing's
+// status history and, for rejections, shown to the seller alongside the codes.
+func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.InspectionStatus, adminID uuid.UUID, reasonCodes []string, reason string) error {
 
 	rawInspection, err := s.repo.GetByID(id)
 	if err != nil {
@@ -77,6 +101,11 @@ func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.I
 	if err != nil {
 		return err
 	} 
+
+	// a buyer's inspection informs their purchase; it never decides whether the listing is live
+	if existingInspection.Type == models.InspectionTypePrePurchase {
+		return fmt.Errorf("pre-purchase inspections are completed by submitting findings, not approved or rejected")
+	}
 	
 	// Retrieve the associated listing *WITH THE SELLER* to get the email address and update its status
 	associatedListing, err := s.listingRepo.GetByID(existingInspection.ListingID.String())
@@ -86,21 +115,62 @@ func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.I
 	sellerEmail := associatedListing.Seller.Email 
 	listingTitle := associatedListing.Title       
 
-	inspectionToUpdate := &models.Inspection {
-			ID:	existingInspection.ID,
-			Status: newStatus,
+	// only the inspection in force may decide the listing's status; older reports are history
+	if associatedListing.CurrentInspectionID != nil && *associatedListing.CurrentInspectionID != existingInspection.ID {
+		return fmt.Errorf("inspection %s has been superseded by a newer inspection of the listing", id)
+	}
+
+	// a listing claimed in the review queue is decided by the admin holding the claim
+	if associatedListing.ReviewClaimedByOther(adminID, time.Now()) {
+		return fmt.Errorf("listing is claimed by another admin")
+	}
+
+	// once a checklist template is in force, nothing is approved without structured findings
+	if newStatus == models.InspectionStatusApproved && len(existingInspection.Checklist) == 0 {
+		_, err := s.templateRepo.GetActive()
+		if err == nil {
+			return fmt.Errorf("checklist findings must be submitted before approving inspection %s", id)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	// the seller is told exactly what to fix, so rejections carry structured reasons
+	var rejectionReasons []models.RejectionDetail
+	rejectionNote := ""
+	if newStatus == models.InspectionStatusRejected {
+		rejectionReasons, err = s.rejectionReasonService.ResolveActive(reasonCodes)
+		if err != nil {
+			return err
+		}
+		rejectionNote = reason
 	}
 
-	
 	// Handle listing and inspection update in a database transaction
+	var newListingStatus models.ListingStatus
 	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
 
-		if err := s.repo.UpdateWithTx(tx, inspectionToUpdate); err != nil {
+		// the listing is locked and re-checked so a decision can never pull a reserved or sold listing back on sale
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, associatedListing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status != models.ListingStatusPending {
+			return fmt.Errorf("only listings pending review can be decided")
+		}
+		if lockedListing.CurrentInspectionID != nil && *lockedListing.CurrentInspectionID != existingInspection.ID {
+			return fmt.Errorf("inspection %s has been superseded by a newer inspection of the listing", id)
+		}
+
+		if err := s.repo.UpdateDecisionWithTx(tx, existingInspection.ID, newStatus, rejectionReasons); err != nil {
 			return fmt.Errorf("failed to update inspection status within transaction: %w", err)
 		}
+		if err := s.repo.QueueReportWithTx(tx, existingInspection.ID); err != nil {
+			return err
+		}
 
 		// Update the associated listing status according to the current inspection status
-		var newListingStatus models.ListingStatus
 		switch newStatus {
 		case models.InspectionStatusApproved:
 			newListingStatus = models.ListingStatusActive
@@ -110,21 +180,53 @@ func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.I
 			return fmt.Errorf("cannot map inspection status '%s' to a listing status", newStatus)
 		}
 
-		// Prepare the listing object for update
-		listingToUpdate := &models.Listing{
-			ID:     associatedListing.ID,
-			Status: newListingStatus,    
+		statusChange := &models.ListingStatusChange{
+			ListingID:    associatedListing.ID,
+			FromStatus:   lockedListing.Status,
+			ToStatus:     newListingStatus,
+			InspectionID: &existingInspection.ID,
+			ChangedByID:  adminID,
+			Reason:       reason,
 		}
 
-		if err := s.listingRepo.UpdateWithTx(tx, listingToUpdate); err != nil {
+		if err := s.listingRepo.ChangeStatusWithTx(tx, statusChange); err != nil {
 			return fmt.Errorf("failed to update associated listing status within transaction: %w", err)
 		}
 
+		// an approval is backed by a signed certificate; any earlier certificate of the listing stops being valid
+		var certificateID *uuid.UUID
+		keepInspectionID := uuid.Nil
+		if newStatus == models.InspectionStatusApproved {
+			certificate, err := s.certificateService.IssueWithTx(tx, existingInspection, associatedListing)
+			if err != nil {
+				return fmt.Errorf("failed to issue inspection certificate within transaction: %w", err)
+			}
+			certificateID = &certificate.ID
+			keepInspectionID = existingInspection.ID
+		}
+
+		if err := s.certificateService.RevokeListingCertificatesWithTx(tx, associatedListing.ID, keepInspectionID, revocationReasonFor(existingInspection.ID, newStatus)); err != nil {
+			return err
+		}
+
+		if err := s.listingRepo.SetCertificateWithTx(tx, associatedListing.ID, certificateID); err != nil {
+			return err
+		}
+
+		// approval clears the reasons of an earlier rejection
+		if err := s.listingRepo.SetRejectionWithTx(tx, associatedListing.ID, rejectionReasons, rejectionNote); err != nil {
+			return err
+		}
+
 		return nil
 	})
 
 	// Handle error from the transaction
 	if err != nil {
+		if err.Error() == "only listings pending review can be decided" ||
+			err.Error() == fmt.Sprintf("inspection %s has been superseded by a newer inspection of the listing", id) {
+			return err
+		}
 		return fmt.Errorf("failed to update inspection and associated listing: %w", err)
 	}
 
@@ -137,13 +239,21 @@ func (s *InspectionService) UpdateInspectionStatus(id string, newStatus models.I
 		emailStatus = "rejected"
 	}
 
+	reasonLabels := make([]string, len(rejectionReasons))
+	for i, detail := range rejectionReasons {
+		reasonLabels[i] = detail.Label
+	}
+
 	// Call the email helper function
-	if err := email_helper.SendListingStatusEmail(sellerEmail, emailStatus, listingTitle); err != nil {
+	if err := email_helper.SendListingStatusEmail(sellerEmail, emailStatus, listingTitle, reasonLabels, rejectionNote); err != nil {
 		fmt.Printf("Warning: Failed to send status email to seller %s for listing '%s' (status: %s): %v\n", sellerEmail, listingTitle, emailStatus, err)
 	} else {
 		fmt.Printf("Status email sent successfully to seller %s for listing '%s' (status: %s)\n", sellerEmail, listingTitle, emailStatus)
 	}
 
+	// sellers watching their listings see the decision without refreshing
+	publishListingStatus(s.hub, associatedListing, models.ListingStatusPending, newListingStatus, reason)
+
 	return nil
 }
 
@@ -162,35 +272,69 @@ func (s *InspectionService) CreateInspection(inspectionToCreate *models.Inspecti
 		return nil, fmt.Errorf("failed to get associated listing for inspection: %w", err)
 	}
 
-	// switch to know if listing status should be updated or not
-	var newListingStatus models.ListingStatus
+	// findings submitted up front are validated like any later checklist submission
+	if len(inspectionToCreate.Checklist) > 0 {
+		if err := s.applyChecklist(inspectionToCreate, inspectionToCreate.Checklist); err != nil {
+			return nil, err
+		}
+	}
+
+	// a rejected listing goes back to review; the flag is settled under the listing lock below
+	newListingStatus := models.ListingStatusPending
 	statusUpdateRequired := false
 
-	if associatedListing.Status == models.ListingStatusRejected {
-		newListingStatus = models.ListingStatusPending
-		statusUpdateRequired = true
-	} else {
-		statusUpdateRequired = false
-	}
 
+	// admins only open vetting inspections; buyers order pre-purchase ones through an inspection request.
+	// Every inspection starts pending so approval and rejection always go through UpdateInspectionStatus.
+	inspectionToCreate.Status = models.InspectionStatusPending
+	inspectionToCreate.Type = models.InspectionTypeVetting
+	inspectionToCreate.RequestedByID = nil
+	assignedAt := time.Now()
+	inspectionToCreate.AssignedAt = &assignedAt
 
 	// Use a database transaction to handle inspection creation and listing update together
 	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+
+		// lock the listing so two admins cannot open parallel inspections
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, associatedListing.ID)
+		if err != nil {
+			return err
+		}
+		statusUpdateRequired = lockedListing.Status == models.ListingStatusRejected
+
+		pendingInspectionID, err := s.repo.GetPendingIDByListingIDWithTx(tx, associatedListing.ID)
+		if err != nil {
+			return err
+		}
+		if pendingInspectionID != uuid.Nil {
+			return fmt.Errorf("listing already has a pending inspection")
+		}
 	
 		if err := s.repo.CreateWithTx(tx, inspectionToCreate); err != nil {
 			return fmt.Errorf("failed to create inspection within transaction: %w", err)
 		}
 
+		// the new inspection becomes the one in force; earlier ones stay as history
+		listingToUpdate := &models.Listing{
+			ID:                  associatedListing.ID,
+			CurrentInspectionID: &inspectionToCreate.ID,
+		}
+
+		if err := s.listingRepo.UpdateWithTx(tx, listingToUpdate); err != nil {
+			return fmt.Errorf("failed to update associated listing within transaction: %w", err)
+		}
+
 		if statusUpdateRequired {
-		
-			listingToUpdate := &models.Listing{
-				ID:     associatedListing.ID, 
-				Status: newListingStatus,     
+			statusChange := &models.ListingStatusChange{
+				ListingID:    associatedListing.ID,
+				FromStatus:   models.ListingStatusRejected,
+				ToStatus:     newListingStatus,
+				InspectionID: &inspectionToCreate.ID,
+				ChangedByID:  adminID,
+				Reason:       "re-inspection opened",
 			}
-
-			// Update the listing status 
-			if err := s.listingRepo.UpdateWithTx(tx, listingToUpdate); err != nil {
-				return fmt.Errorf("failed to update associated listing status within transaction: %w", err)
+			if err := s.listingRepo.ChangeStatusWithTx(tx, statusChange); err != nil {
+				return fmt.Errorf("failed to update associated listing within transaction: %w", err)
 			}
 		}
 
@@ -202,5 +346,231 @@ func (s *InspectionService) CreateInspection(inspectionToCreate *models.Inspecti
 		return nil, fmt.Errorf("failed to create inspection and potentially update associated listing: %w", err)
 	}
 
+	if statusUpdateRequired {
+		publishListingStatus(s.hub, associatedListing, models.ListingStatusRejected, newListingStatus, "re-inspection opened")
+	}
+
 	return inspectionToCreate, nil
-}
\ No newline at end of file
+}
+
+
+// SubmitChecklist validates findings against the checklist template in force and stores them with the computed rating.
+// Inspectors may only submit for inspections assigned to them; admins may submit for any.
+func (s *InspectionService) SubmitChecklist(id string, findings []models.ChecklistFinding, userID uuid.UUID, role types.Role) (*models.Inspection, error) {
+	inspection, err := s.GetInspectionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == types.RoleInspector && inspection.InspectorID != userID {
+		return nil, fmt.Errorf("unauthorized: inspection is assigned to another inspector")
+	}
+
+	if inspection.Status != models.InspectionStatusPending {
+		return nil, fmt.Errorf("checklist can only be submitted for a pending inspection")
+	}
+
+	inspectionToUpdate := &models.Inspection{ID: inspection.ID}
+	if err := s.applyChecklist(inspectionToUpdate, findings); err != nil {
+		return nil, err
+	}
+
+	if inspection.Type != models.InspectionTypePrePurchase {
+		if err := s.repo.Update(inspectionToUpdate); err != nil {
+			return nil, err
+		}
+		return s.GetInspectionByID(id)
+	}
+
+	// a pre-purchase inspection has no review step: submitting its findings delivers it to the buyer
+	var request *models.InspectionRequest
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.UpdateWithTx(tx, inspectionToUpdate); err != nil {
+			return err
+		}
+		request, err = s.prePurchaseService.completeWithTx(tx, inspection.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.prePurchaseService.notifyReportReady(request, inspectionToUpdate.ConditionRating)
+
+	return s.GetInspectionByID(id)
+}
+
+// applyChecklist evaluates findings against the active template and records the template version and rating on inspection
+func (s *InspectionService) applyChecklist(inspection *models.Inspection, findings []models.ChecklistFinding) error {
+	template, err := s.templateRepo.GetActive()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no checklist template is currently in force")
+		}
+		return err
+	}
+
+	rating, err := template.Evaluate(findings)
+	if err != nil {
+		return fmt.Errorf("invalid checklist findings: %w", err)
+	}
+
+	submittedAt := time.Now()
+	inspection.ChecklistTemplateID = &template.ID
+	inspection.ChecklistVersion = template.Version
+	inspection.Checklist = findings
+	inspection.ConditionRating = rating
+	inspection.SubmittedAt = &submittedAt
+	return nil
+}
+
+
+// GetListingInspections retrieves every inspection of a listing for its seller or an admin
+func (s *InspectionService) GetListingInspections(listingID string, userID uuid.UUID, role types.Role) (*models.ListingInspectionHistory, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if role != types.RoleAdmin && listing.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view inspections of your own listings")
+	}
+
+	inspectionsInput, err := s.repo.GetAllByListingID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &models.ListingInspectionHistory{
+		ListingID:           listing.ID,
+		CurrentInspectionID: listing.CurrentInspectionID,
+		Inspections:         []*models.Inspection{},
+	}
+	for _, input := range inspectionsInput {
+		inspection, err := input.ParseInspectionInputToModel()
+		if err != nil {
+			return nil, err
+		}
+		history.Inspections = append(history.Inspections, inspection)
+	}
+
+	return history, nil
+}
+
+
+// RequestReport queues the PDF report of a finalised inspection for the background generator.
+// Requesting it again replaces the stored report, e.g. after photos were added or the layout changed.
+func (s *InspectionService) RequestReport(id string) (*models.Inspection, error) {
+	inspection, err := s.GetInspectionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if inspection.Status == models.InspectionStatusPending {
+		return nil, fmt.Errorf("report can only be generated for a finalised inspection")
+	}
+	if inspection.Type == models.InspectionTypePrePurchase {
+		return nil, fmt.Errorf("pre-purchase inspection reports are only delivered to the requesting buyer")
+	}
+
+	if err := s.repo.QueueReportWithTx(s.repo.DB, inspection.ID); err != nil {
+		return nil, err
+	}
+	inspection.ReportPending = true
+	return inspection, nil
+}
+
+// GeneratePendingReports renders and stores the queued reports; run by the scheduler so decisions never wait on a render.
+// A report that keeps failing stays queued after maxReportAttempts until an admin requests it again.
+func (s *InspectionService) GeneratePendingReports() error {
+	ids, err := s.repo.GetPendingReportIDs(maxReportAttempts, reportBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		inspection, err := s.GetInspectionByID(id.String())
+		if err == nil {
+			err = s.generateReport(inspection)
+		}
+		if err != nil {
+			fmt.Printf("Warning: Failed to generate report for inspection %s: %v\n", id, err)
+			if err := s.repo.UpdateFieldsWithTx(s.repo.DB, id, map[string]any{"report_attempts": gorm.Expr("report_attempts + 1")}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// generateReport renders the PDF report of a finalised inspection, stores it and records its URL
+func (s *InspectionService) generateReport(inspection *models.Inspection) error {
+	// the preloaded listing lacks the vehicle and gallery needed by the report
+	listing, err := s.listingRepo.GetByID(inspection.ListingID.String())
+	if err != nil {
+		return err
+	}
+
+	var template *models.ChecklistTemplate
+	if inspection.ChecklistTemplateID != nil {
+		template, err = s.templateRepo.GetByID(inspection.ChecklistTemplateID.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	certificate, err := s.certificateService.GetValidCertificateForInspection(inspection.ID)
+	if err != nil {
+		return err
+	}
+	var verificationURL string
+	if certificate != nil {
+		verificationURL = s.certificateService.VerificationURL(certificate.ID)
+	}
+
+	report, err := renderInspectionReport(inspection, listing, template, certificate, verificationURL, s.fileStorage)
+	if err != nil {
+		return fmt.Errorf("failed to render inspection report: %w", err)
+	}
+
+	reportURL, err := s.fileStorage.Upload(fmt.Sprintf("reports/inspections/%s.pdf", inspection.ID.String()), "application/pdf", report)
+	if err != nil {
+		return fmt.Errorf("failed to store inspection report: %w", err)
+	}
+
+	return s.repo.UpdateFieldsWithTx(s.repo.DB, inspection.ID, map[string]any{
+		"report_url":          reportURL,
+		"report_generated_at": time.Now(),
+		"report_pending":      false,
+		"report_attempts":     0,
+	})
+}
+
+// GetListingReport returns the report of the inspection in force for a listing that buyers can see
+func (s *InspectionService) GetListingReport(listingID string) (*models.InspectionReport, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if listing.Status != models.ListingStatusActive && listing.Status != models.ListingStatusReserved && listing.Status != models.ListingStatusSold {
+		return nil, fmt.Errorf("listing with id %s not found", listingID)
+	}
+	if listing.CurrentInspectionID == nil {
+		return nil, fmt.Errorf("no inspection report is available for this listing")
+	}
+
+	inspection, err := s.GetInspectionByID(listing.CurrentInspectionID.String())
+	if err != nil {
+		return nil, err
+	}
+	if inspection.ReportURL == "" {
+		return nil, fmt.Errorf("no inspection report is available for this listing")
+	}
+
+	return &models.InspectionReport{
+		ListingID:         listing.ID,
+		InspectionID:      inspection.ID,
+		ConditionRating:   inspection.ConditionRating,
+		ReportURL:         inspection.ReportURL,
+		ReportGeneratedAt: inspection.ReportGeneratedAt,
+	}, nil
+}
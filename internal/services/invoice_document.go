@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/pdf"
+)
+
+// renderInvoicePDF lays out an invoice on A4 in the same branding as the inspection reports
+func renderInvoicePDF(invoice *models.Invoice) ([]byte, error) {
+	layout := newReportLayout()
+	page := layout.page
+
+	// header band
+	page.SetFillColor(20, 45, 90)
+	page.Rect(0, 0, pdf.A4Width, 90)
+	page.SetFillColor(255, 255, 255)
+	page.Text(reportMargin, 45, pdf.HelveticaBold, 24, platformName)
+	page.Text(reportMargin, 68, pdf.Helvetica, 12, "Receipt")
+	page.Text(pdf.A4Width-reportMargin-pdf.TextWidth(pdf.HelveticaBold, 14, invoice.Number), 45, pdf.HelveticaBold, 14, invoice.Number)
+	issued := "Issued " + invoice.IssuedAt.Format("02 Jan 2006")
+	page.Text(pdf.A4Width-reportMargin-pdf.TextWidth(pdf.Helvetica, 9, issued), 68, pdf.Helvetica, 9, issued)
+	page.SetFillColor(0, 0, 0)
+	layout.y = 110
+
+	layout.heading("Parties")
+	layout.field("Buyer", invoiceParty(invoice.BuyerName, invoice.BuyerEmail))
+	layout.field("Seller", invoiceParty(invoice.SellerName, invoice.SellerEmail))
+	layout.field("Transaction", invoice.TransactionID.String())
+
+	layout.heading("Vehicle")
+	layout.field("Listing", invoice.ListingTitle)
+	layout.field("VIN", invoice.VIN)
+
+	layout.heading("Details")
+	amountX := pdf.A4Width - reportMargin
+	for _, line := range invoice.Lines {
+		lines := pdf.WrapText(pdf.Helvetica, 10, line.Description, reportContentWidth-200)
+		layout.ensure(float64(len(lines)) * 14)
+		layout.page.Text(reportMargin+280, layout.y, pdf.Helvetica, 9, "paid by "+line.PaidBy)
+		amount := formatMoney(invoice.Currency, line.Amount)
+		layout.page.Text(amountX-pdf.TextWidth(pdf.Helvetica, 10, amount), layout.y, pdf.Helvetica, 10, amount)
+		for _, text := range lines {
+			layout.page.Text(reportMargin, layout.y, pdf.Helvetica, 10, text)
+			layout.y += 14
+		}
+	}
+
+	layout.ensure(80)
+	layout.y += 4
+	layout.page.SetStrokeColor(200, 200, 200)
+	layout.page.Line(reportMargin, layout.y, pdf.A4Width-reportMargin, layout.y, 0.5)
+	layout.y += 16
+	totals := [][2]string{{"Total paid by the buyer", formatMoney(invoice.Currency, invoice.Total)}}
+	if invoice.Type != models.TransactionTypeInspectionFee {
+		totals = append(totals,
+			[2]string{"Fees paid by the seller", formatMoney(invoice.Currency, invoice.SellerFees)},
+			[2]string{"Net to the seller", formatMoney(invoice.Currency, invoice.SellerNet)})
+	}
+	if invoice.TaxRate > 0 {
+		totals = append(totals, [2]string{fmt.Sprintf("VAT included in %s fees (%g%%)", platformName, invoice.TaxRate), formatMoney(invoice.Currency, invoice.TaxAmount)})
+	}
+	for _, total := range totals {
+		layout.page.Text(reportMargin, layout.y, pdf.HelveticaBold, 10, total[0])
+		layout.page.Text(amountX-pdf.TextWidth(pdf.HelveticaBold, 10, total[1]), layout.y, pdf.HelveticaBold, 10, total[1])
+		layout.y += 16
+	}
+
+	footerY := pdf.A4Height - reportMargin + 10
+	layout.page.SetStrokeColor(200, 200, 200)
+	layout.page.Line(reportMargin, footerY-14, pdf.A4Width-reportMargin, footerY-14, 0.5)
+	layout.page.SetFillColor(110, 110, 110)
+	layout.page.Text(reportMargin, footerY, pdf.Helvetica, 8, fmt.Sprintf("%s receipt %s", platformName, invoice.Number))
+
+	return layout.doc.Bytes()
+}
+
+// renderInvoiceHTML lays out an invoice as an HTML fragment for emails and the receipt page
+func renderInvoiceHTML(invoice *models.Invoice) string {
+	var rows strings.Builder
+	for _, line := range invoice.Lines {
+		fmt.Fprintf(&rows, "<tr><td>%s</td><td>paid by %s</td><td style=\"text-align: right;\">%s</td></tr>\n",
+			html.EscapeString(line.Description), html.EscapeString(line.PaidBy), formatMoney(invoice.Currency, line.Amount))
+	}
+
+	var totals strings.Builder
+	fmt.Fprintf(&totals, "<tr><th colspan=\"2\" style=\"text-align: left;\">Total paid by the buyer</th><th style=\"text-align: right;\">%s</th></tr>\n", formatMoney(invoice.Currency, invoice.Total))
+	if invoice.Type != models.TransactionTypeInspectionFee {
+		fmt.Fprintf(&totals, "<tr><th colspan=\"2\" style=\"text-align: left;\">Fees paid by the seller</th><th style=\"text-align: right;\">%s</th></tr>\n", formatMoney(invoice.Currency, invoice.SellerFees))
+		fmt.Fprintf(&totals, "<tr><th colspan=\"2\" style=\"text-align: left;\">Net to the seller</th><th style=\"text-align: right;\">%s</th></tr>\n", formatMoney(invoice.Currency, invoice.SellerNet))
+	}
+	if invoice.TaxRate > 0 {
+		fmt.Fprintf(&totals, "<tr><td colspan=\"2\">VAT included in %s fees (%g%%)</td><td style=\"text-align: right;\">%s</td></tr>\n", platformName, invoice.TaxRate, formatMoney(invoice.Currency, invoice.TaxAmount))
+	}
+
+	return fmt.Sprintf(`<h2>Receipt %s</h2>
+<p>Issued %s for transaction %s</p>
+<p><strong>Buyer:</strong> %s<br><strong>Seller:</strong> %s</p>
+<p><strong>Vehicle:</strong> %s<br><strong>VIN:</strong> %s</p>
+<table style="width: 100%%; border-collapse: collapse;">
+%s%s</table>
+`, html.EscapeString(invoice.Number), invoice.IssuedAt.Format("02 Jan 2006"), invoice.TransactionID,
+		html.EscapeString(invoiceParty(invoice.BuyerName, invoice.BuyerEmail)), html.EscapeString(invoiceParty(invoice.SellerName, invoice.SellerEmail)),
+		html.EscapeString(invoice.ListingTitle), html.EscapeString(invoice.VIN), rows.String(), totals.String())
+}
+
+func invoiceParty(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+// formatMoney writes an amount with thousands separators, e.g. "NGN 1,250,000.00"
+func formatMoney(currency string, amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := fmt.Sprintf("%.2f", amount)
+	whole, fraction := digits[:len(digits)-3], digits[len(digits)-3:]
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s %s%s", sign, currency, grouped.String(), fraction)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+const invoiceEmailBatch = 50
+
+// platformName is the supplier on invoices for fees the platform itself charges
+const platformName = "AutoCity"
+
+// InvoiceService issues the numbered receipt of every transaction that completes and sends it to the parties.
+// Numbers run INV-<year>-000001, INV-<year>-000002, ... without gaps within a year.
+type InvoiceService struct {
+	repo     *repositories.InvoiceRepository
+	currency string
+
+	// taxRate is the VAT percentage included in the platform's fees, shown on invoices
+	taxRate float64
+}
+
+func NewInvoiceService(repo *repositories.InvoiceRepository, currency string, taxRate float64) *InvoiceService {
+	return &InvoiceService{
+		repo:     repo,
+		currency: currency,
+		taxRate:  taxRate,
+	}
+}
+
+// IssueWithTx issues the invoice of a transaction that has just completed, in the same database transaction, so the
+// invoice number is only used if the completion commits. A transaction is only ever invoiced once.
+func (s *InvoiceService) IssueWithTx(tx *gorm.DB, transactionID uuid.UUID) error {
+	exists, err := s.repo.ExistsForTransactionWithTx(tx, transactionID)
+	if err != nil || exists {
+		return err
+	}
+
+	transaction, err := s.repo.GetTransactionWithTx(tx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	issuedAt := time.Now().UTC()
+	sequence, err := s.repo.NextNumberWithTx(tx, issuedAt.Year())
+	if err != nil {
+		return err
+	}
+
+	invoice := s.build(transaction)
+	invoice.Year = issuedAt.Year()
+	invoice.Sequence = sequence
+	invoice.Number = invoiceNumber(invoice.Year, sequence)
+	invoice.IssuedAt = issuedAt
+	return s.repo.CreateWithTx(tx, invoice)
+}
+
+// invoiceNumber formats the number of the sequence-th invoice of a year
+func invoiceNumber(year, sequence int) string {
+	return fmt.Sprintf("INV-%d-%06d", year, sequence)
+}
+
+// build works out the lines and totals of a transaction's invoice
+func (s *InvoiceService) build(transaction *models.Transaction) *models.Invoice {
+	invoice := &models.Invoice{
+		TransactionID: transaction.ID,
+		Type:          transaction.Type,
+		BuyerID:       transaction.BuyerID,
+		SellerID:      transaction.SellerID,
+		BuyerName:     transaction.Buyer.Name,
+		BuyerEmail:    transaction.Buyer.Email,
+		SellerName:    transaction.Seller.Name,
+		SellerEmail:   transaction.Seller.Email,
+		ListingID:     transaction.ListingID,
+		ListingTitle:  transaction.Listing.Title,
+		VIN:           transaction.Listing.Vehicle.VIN,
+		Currency:      s.currency,
+		TaxRate:       s.taxRate,
+		Lines:         []models.InvoiceLine{},
+	}
+
+	// an inspection fee is the platform's own charge to the buyer
+	if transaction.Type == models.TransactionTypeInspectionFee {
+		invoice.SellerName = platformName
+		invoice.SellerEmail = ""
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Description: "Pre-purchase inspection of " + transaction.Listing.Title,
+			PaidBy:      "buyer",
+			Amount:      transaction.Amount,
+		})
+		invoice.Total = transaction.Amount
+		invoice.TaxAmount = includedTax(transaction.Amount, s.taxRate)
+		return invoice
+	}
+
+	description := "Vehicle: " + transaction.Listing.Title
+	if transaction.Type == models.TransactionTypeDeposit {
+		description = "Reservation deposit: " + transaction.Listing.Title
+	}
+	invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+		Description: description,
+		PaidBy:      "buyer",
+		Amount:      transaction.Amount,
+	})
+	if transaction.RefundedAmount > 0 {
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Description: "Refunded to the buyer",
+			PaidBy:      "buyer",
+			Amount:      -transaction.RefundedAmount,
+		})
+	}
+	invoice.Total = roundToMinorUnits(transaction.Amount - transaction.RefundedAmount)
+
+	for _, fee := range transaction.Fees {
+		if fee.Amount <= 0 || fee.PayerID != transaction.SellerID {
+			continue
+		}
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Description: fee.Description,
+			PaidBy:      "seller",
+			Amount:      fee.Amount,
+		})
+		invoice.SellerFees += fee.Amount
+	}
+	invoice.SellerFees = roundToMinorUnits(invoice.SellerFees)
+	invoice.SellerNet = roundToMinorUnits(invoice.Total - invoice.SellerFees)
+	invoice.TaxAmount = includedTax(invoice.SellerFees, s.taxRate)
+	return invoice
+}
+
+// GetReceipt retrieves the invoice of a transaction for one of its parties or an admin
+func (s *InvoiceService) GetReceipt(transactionID string, userID uuid.UUID, userRole types.Role) (*models.Invoice, error) {
+	parsedID, err := pkg.StringToUUID(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.repo.GetByTransactionID(parsedID)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && invoice.BuyerID != userID && invoice.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view receipts of your own transactions")
+	}
+	return invoice, nil
+}
+
+// RenderPDF lays out an invoice as a PDF document
+func (s *InvoiceService) RenderPDF(invoice *models.Invoice) ([]byte, error) {
+	return renderInvoicePDF(invoice)
+}
+
+// RenderHTML lays out an invoice as a standalone HTML page
+func (s *InvoiceService) RenderHTML(invoice *models.Invoice) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>%s %s</title></head>
+<body style="font-family: Helvetica, Arial, sans-serif; max-width: 720px; margin: 24px auto;">
+%s
+</body>
+</html>
+`, platformName, invoice.Number, renderInvoiceHTML(invoice))
+}
+
+// SendPendingEmails is the background job emailing newly issued invoices to both parties. An invoice that could not
+// be sent to everyone is tried again on the next run.
+func (s *InvoiceService) SendPendingEmails() error {
+	invoices, err := s.repo.GetUnemailed(invoiceEmailBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, invoice := range invoices {
+		document, err := renderInvoicePDF(invoice)
+		if err != nil {
+			fmt.Printf("Warning: Failed to render invoice %s: %v\n", invoice.Number, err)
+			continue
+		}
+		body := renderInvoiceHTML(invoice)
+
+		sent := true
+		for _, recipient := range []string{invoice.BuyerEmail, invoice.SellerEmail} {
+			if recipient == "" {
+				continue
+			}
+			if err := email_helper.SendInvoiceEmail(recipient, invoice.Number, body, document); err != nil {
+				fmt.Printf("Warning: Failed to send invoice %s to %s: %v\n", invoice.Number, recipient, err)
+				sent = false
+			}
+		}
+		if !sent {
+			continue
+		}
+
+		if err := s.repo.MarkEmailed(invoice.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// includedTax is the VAT part of an amount that already includes VAT at rate percent
+func includedTax(amount, rate float64) float64 {
+	if rate <= 0 || amount <= 0 {
+		return 0
+	}
+	return roundToMinorUnits(amount * rate / (100 + rate))
+}
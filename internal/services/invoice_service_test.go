@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		year, sequence int
+		want           string
+	}{
+		{2026, 1, "INV-2026-000001"},
+		{2026, 42, "INV-2026-000042"},
+		{2026, 999999, "INV-2026-999999"},
+		// the number keeps growing past six digits rather than wrapping
+		{2026, 1000000, "INV-2026-1000000"},
+	}
+
+	for _, tt := range tests {
+		if got := invoiceNumber(tt.year, tt.sequence); got != tt.want {
+			t.Errorf("invoiceNumber(%d, %d) = %q, want %q", tt.year, tt.sequence, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"gorm.io/gorm"
+)
+
+// LedgerService keeps the platform's double-entry ledger. Every change to a transaction that moves money posts a
+// balanced journal entry in the same database transaction, so the ledger always agrees with the transactions.
+//
+//	funds held         Dr buyer_funds (buyer)   Cr escrow (sales and reservation deposits alike)
+//	released           Dr escrow                Cr seller_payable (seller)
+//	refunded           Dr escrow                Cr buyer_funds (buyer)
+//	partially refunded Dr escrow                Cr buyer_funds (buyer), seller_payable (seller)
+//	inspection fee     Dr escrow                Cr platform_revenue (a paid fee released once the report is delivered)
+//	sale commission    Dr seller_payable        Cr platform_revenue (posted by FeeService)
+//	payout settled     Dr seller_payable        Cr paid_out
+type LedgerService struct {
+	repo     *repositories.LedgerRepository
+	currency string
+}
+
+func NewLedgerService(repo *repositories.LedgerRepository, currency string) *LedgerService {
+	return &LedgerService{
+		repo:     repo,
+		currency: currency,
+	}
+}
+
+// ledgerLine is one side of a posting, in minor units, before its account is resolved
+type ledgerLine struct {
+	Code    models.LedgerAccountCode
+	OwnerID uuid.UUID // uuid.Nil for platform accounts
+	Debit   int64
+	Credit  int64
+}
+
+// RecordTransactionChangeWithTx posts the journal entry for an audited transaction change, if it moved money
+func (s *LedgerService) RecordTransactionChangeWithTx(tx *gorm.DB, transaction *models.Transaction, event *models.TransactionEvent) error {
+	lines, description := s.transactionChangeLines(transaction, event)
+	if len(lines) == 0 {
+		return nil
+	}
+	return s.postWithTx(tx, &transaction.ID, &event.ID, description, lines)
+}
+
+// transactionChangeLines works out which accounts a transaction change moves money between
+func (s *LedgerService) transactionChangeLines(transaction *models.Transaction, event *models.TransactionEvent) ([]ledgerLine, string) {
+	total := payment.ToMinorUnits(transaction.Amount)
+	moved := payment.ToMinorUnits(event.Amount)
+
+	if event.ToEscrow == event.FromEscrow {
+		return nil, ""
+	}
+
+	switch event.ToEscrow {
+	case models.EscrowStatusFundsHeld:
+		return []ledgerLine{
+			{Code: models.LedgerAccountBuyerFunds, OwnerID: transaction.BuyerID, Debit: total},
+			{Code: models.LedgerAccountEscrow, Credit: total},
+		}, fmt.Sprintf("funds of %s %s held in escrow", transaction.Type, transaction.ID)
+
+	case models.EscrowStatusReleased:
+		// the inspection fee is only earned once it was captured and the inspection delivered
+		if transaction.Type == models.TransactionTypeInspectionFee {
+			return []ledgerLine{
+				{Code: models.LedgerAccountEscrow, Debit: moved},
+				{Code: models.LedgerAccountPlatformRevenue, Credit: moved},
+			}, fmt.Sprintf("inspection fee of transaction %s earned", transaction.ID)
+		}
+		return []ledgerLine{
+			{Code: models.LedgerAccountEscrow, Debit: moved},
+			{Code: models.LedgerAccountSellerPayable, OwnerID: transaction.SellerID, Credit: moved},
+		}, fmt.Sprintf("escrow of %s %s released to the seller", transaction.Type, transaction.ID)
+
+	case models.EscrowStatusRefunded:
+		return []ledgerLine{
+			{Code: models.LedgerAccountEscrow, Debit: moved},
+			{Code: models.LedgerAccountBuyerFunds, OwnerID: transaction.BuyerID, Credit: moved},
+		}, fmt.Sprintf("escrow of %s %s refunded to the buyer", transaction.Type, transaction.ID)
+
+	case models.EscrowStatusPartiallyRefunded:
+		return []ledgerLine{
+			{Code: models.LedgerAccountEscrow, Debit: total},
+			{Code: models.LedgerAccountBuyerFunds, OwnerID: transaction.BuyerID, Credit: moved},
+			{Code: models.LedgerAccountSellerPayable, OwnerID: transaction.SellerID, Credit: total - moved},
+		}, fmt.Sprintf("escrow of %s %s partially refunded, the rest released to the seller", transaction.Type, transaction.ID)
+	}
+	return nil, ""
+}
+
+// postWithTx checks that the lines balance and records them as one journal entry
+func (s *LedgerService) postWithTx(tx *gorm.DB, transactionID, eventID *uuid.UUID, description string, lines []ledgerLine) error {
+	entry := &models.JournalEntry{
+		TransactionID:      transactionID,
+		TransactionEventID: eventID,
+		Description:        description,
+		Currency:           s.currency,
+	}
+
+	var debits, credits int64
+	for _, line := range lines {
+		// zero lines, e.g. the seller's share of a refund that returned everything, are left out
+		if line.Debit == 0 && line.Credit == 0 {
+			continue
+		}
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit > 0 && line.Credit > 0) {
+			return fmt.Errorf("journal line for %s must either debit or credit a positive amount", line.Code)
+		}
+
+		account, err := s.repo.GetOrCreateAccountWithTx(tx, line.Code, line.OwnerID, s.currency)
+		if err != nil {
+			return err
+		}
+		entry.Lines = append(entry.Lines, models.JournalLine{AccountID: account.ID, Debit: line.Debit, Credit: line.Credit})
+		debits += line.Debit
+		credits += line.Credit
+	}
+
+	// nothing moved, e.g. a fee waived in full
+	if debits == 0 && credits == 0 {
+		return nil
+	}
+	if debits != credits {
+		return fmt.Errorf("journal entry does not balance: debits %d, credits %d", debits, credits)
+	}
+	if len(entry.Lines) < 2 {
+		return fmt.Errorf("journal entry needs at least two lines")
+	}
+
+	return s.repo.CreateEntryWithTx(tx, entry)
+}
+
+// RecordPayoutWithTx posts a settled payout: the money owed to the seller left the platform
+func (s *LedgerService) RecordPayoutWithTx(tx *gorm.DB, payout *models.Payout) error {
+	amount := payment.ToMinorUnits(payout.Amount)
+	return s.postWithTx(tx, nil, nil, fmt.Sprintf("payout %s to seller %s", payout.Reference, payout.SellerID), []ledgerLine{
+		{Code: models.LedgerAccountSellerPayable, OwnerID: payout.SellerID, Debit: amount},
+		{Code: models.LedgerAccountPaidOut, Credit: amount},
+	})
+}
+
+// SellerPayableBalance is what the ledger says the platform owes a seller, in major units
+func (s *LedgerService) SellerPayableBalance(sellerID uuid.UUID) (float64, error) {
+	report, err := s.GetTrialBalance(models.LedgerAccountSellerPayable, &sellerID)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, account := range report.Accounts {
+		if account.Currency == s.currency {
+			balance += account.Balance
+		}
+	}
+	return payment.ToMajorUnits(balance), nil
+}
+
+// GetEntries retrieves the newest journal entries, optionally only those of one transaction
+func (s *LedgerService) GetEntries(transactionID *uuid.UUID, limit int) ([]*models.JournalEntry, error) {
+	return s.repo.GetEntries(transactionID, limit)
+}
+
+// GetTrialBalance lists the balance of every account, optionally only those with a code or owner, and checks the
+// ledger's debits and credits match
+func (s *LedgerService) GetTrialBalance(code models.LedgerAccountCode, ownerID *uuid.UUID) (*models.TrialBalance, error) {
+	balances, err := s.repo.GetBalances(code, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.TrialBalance{Accounts: balances}
+	for _, balance := range balances {
+		if balance.Type == models.LedgerAccountTypeAsset {
+			balance.Balance = balance.Debits - balance.Credits
+		} else {
+			balance.Balance = balance.Credits - balance.Debits
+		}
+		report.TotalDebits += balance.Debits
+		report.TotalCredits += balance.Credits
+	}
+	// a filtered listing only balances when it happens to cover whole entries
+	report.Balanced = report.TotalDebits == report.TotalCredits
+
+	return report, nil
+}
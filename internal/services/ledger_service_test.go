@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+)
+
+// ledgerKey identifies an account in the balances the tests add up
+type ledgerKey struct {
+	Code    models.LedgerAccountCode
+	OwnerID uuid.UUID
+}
+
+func TestTransactionChangeLinesBalance(t *testing.T) {
+	buyerID, sellerID := uuid.New(), uuid.New()
+	sale := &models.Transaction{ID: uuid.New(), Type: models.TransactionTypeSale, BuyerID: buyerID, SellerID: sellerID, Amount: 900000}
+	deposit := &models.Transaction{ID: uuid.New(), Type: models.TransactionTypeDeposit, BuyerID: buyerID, SellerID: sellerID, Amount: 100000}
+	fee := &models.Transaction{ID: uuid.New(), Type: models.TransactionTypeInspectionFee, BuyerID: buyerID, SellerID: sellerID, Amount: 25000.50}
+
+	type change struct {
+		transaction *models.Transaction
+		from, to    models.EscrowStatus
+		amount      float64
+	}
+	tests := []struct {
+		name    string
+		changes []change
+		// balance is debits less credits, in minor units; accounts left out must net to zero
+		want map[ledgerKey]int64
+	}{
+		{
+			name: "sale released to the seller",
+			changes: []change{
+				{sale, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 900000},
+				{sale, models.EscrowStatusHandedOver, models.EscrowStatusReleased, 900000},
+			},
+			want: map[ledgerKey]int64{
+				{models.LedgerAccountBuyerFunds, buyerID}:     90000000,
+				{models.LedgerAccountSellerPayable, sellerID}: -90000000,
+			},
+		},
+		{
+			name: "sale refunded in full",
+			changes: []change{
+				{sale, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 900000},
+				{sale, models.EscrowStatusFundsHeld, models.EscrowStatusRefunded, 900000},
+			},
+			want: map[ledgerKey]int64{},
+		},
+		{
+			name: "sale partially refunded with a credited deposit",
+			changes: []change{
+				{deposit, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 100000},
+				{sale, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 900000},
+				{sale, models.EscrowStatusDisputed, models.EscrowStatusPartiallyRefunded, 200000.25},
+				{deposit, models.EscrowStatusFundsHeld, models.EscrowStatusReleased, 100000},
+			},
+			want: map[ledgerKey]int64{
+				{models.LedgerAccountBuyerFunds, buyerID}:     79999975,
+				{models.LedgerAccountSellerPayable, sellerID}: -79999975,
+			},
+		},
+		{
+			name: "sale refunded with its credited deposit",
+			changes: []change{
+				{deposit, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 100000},
+				{sale, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 900000},
+				{sale, models.EscrowStatusHandedOver, models.EscrowStatusRefunded, 900000},
+				{deposit, models.EscrowStatusFundsHeld, models.EscrowStatusRefunded, 100000},
+			},
+			want: map[ledgerKey]int64{},
+		},
+		{
+			name: "inspection fee earned once the report is delivered",
+			changes: []change{
+				{fee, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 25000.50},
+				{fee, models.EscrowStatusFundsHeld, models.EscrowStatusReleased, 25000.50},
+			},
+			want: map[ledgerKey]int64{
+				{models.LedgerAccountBuyerFunds, buyerID}:       2500050,
+				{models.LedgerAccountPlatformRevenue, uuid.Nil}: -2500050,
+			},
+		},
+		{
+			name: "inspection fee refunded when the inspection is called off",
+			changes: []change{
+				{fee, models.EscrowStatusAwaitingPayment, models.EscrowStatusFundsHeld, 25000.50},
+				{fee, models.EscrowStatusFundsHeld, models.EscrowStatusRefunded, 25000.50},
+			},
+			want: map[ledgerKey]int64{},
+		},
+		{
+			name: "unpaid changes move no money",
+			changes: []change{
+				{sale, models.EscrowStatusAwaitingPayment, models.EscrowStatusCancelled, 0},
+				{fee, models.EscrowStatusAwaitingPayment, models.EscrowStatusAwaitingPayment, 0},
+			},
+			want: map[ledgerKey]int64{},
+		},
+	}
+
+	ledger := &LedgerService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balances := map[ledgerKey]int64{}
+			for _, c := range tt.changes {
+				event := &models.TransactionEvent{FromEscrow: c.from, ToEscrow: c.to, Amount: c.amount}
+				lines, _ := ledger.transactionChangeLines(c.transaction, event)
+
+				var debits, credits int64
+				for _, line := range lines {
+					if line.Debit < 0 || line.Credit < 0 || (line.Debit > 0 && line.Credit > 0) {
+						t.Fatalf("%s -> %s of %s: line %+v must either debit or credit a positive amount", c.from, c.to, c.transaction.Type, line)
+					}
+					debits += line.Debit
+					credits += line.Credit
+					balances[ledgerKey{line.Code, line.OwnerID}] += line.Debit - line.Credit
+				}
+				if debits != credits {
+					t.Errorf("%s -> %s of %s: debits %d, credits %d", c.from, c.to, c.transaction.Type, debits, credits)
+				}
+			}
+
+			for key, balance := range balances {
+				if balance != tt.want[key] {
+					t.Errorf("%s balance = %d, want %d", key.Code, balance, tt.want[key])
+				}
+			}
+			for key, want := range tt.want {
+				if _, ok := balances[key]; !ok {
+					t.Errorf("%s was never posted to, want balance %d", key.Code, want)
+				}
+			}
+		})
+	}
+}
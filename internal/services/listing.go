@@ -3,8 +3,11 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
 	"github.com/zekeriyyah/lujay-autocity/internal/models"
 	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
 	"github.com/zekeriyyah/lujay-autocity/pkg/types"
@@ -16,13 +19,15 @@ type ListingService struct {
 	repo *repositories.ListingRepository
 	vehicleRepo *repositories.VehicleRepository
 	userRepo *repositories.UserRepository
+	hub *events.Hub
 }
 
-func NewListingService(repo *repositories.ListingRepository, vehicleRepo *repositories.VehicleRepository, userRepo *repositories.UserRepository) *ListingService {
+func NewListingService(repo *repositories.ListingRepository, vehicleRepo *repositories.VehicleRepository, userRepo *repositories.UserRepository, hub *events.Hub) *ListingService {
 	return &ListingService{
 		repo: repo,
 		vehicleRepo: vehicleRepo,
 		userRepo: userRepo,
+		hub: hub,
 	}
 }
 
@@ -33,6 +38,10 @@ func (s *ListingService) CreateListingWithVehicle(listing *models.Listing, vehic
 		return nil, fmt.Errorf("unauthorized: seller ID does not match authenticated user")
 	}
 
+	// snapshot the specs and odometer reading submitted for this listing before the vehicle may be swapped for an existing record
+	listing.Mileage = vehicle.Mileage
+	listing.VehicleSpecs = vehicle.Specs()
+
 	// using GORM's Transaction to handle operations on vehicle and listing in a roll
 	var createdListing *models.Listing
 	err := s.repo.DB.Transaction(func(tx *gorm.DB) error { 
@@ -44,17 +53,39 @@ func (s *ListingService) CreateListingWithVehicle(listing *models.Listing, vehic
 				return fmt.Errorf("error checking for existing vehicle: %w", err)
 			}
 			
+			vehicle.OwnerID = &authenticatedUserID
 			if err := s.vehicleRepo.CreateWithTx(tx, vehicle); err != nil {
 				return fmt.Errorf("failed to create vehicle: %w", err)
 			}
 		
 		} else {
+			// another seller still selling this vehicle keeps the record
+			if existingVehicle.OwnerID != nil && *existingVehicle.OwnerID != authenticatedUserID {
+				openListings, err := s.repo.CountOpenByVehicleIDWithTx(tx, existingVehicle.ID, authenticatedUserID)
+				if err != nil {
+					return err
+				}
+				if openListings > 0 {
+					return fmt.Errorf("vehicle with VIN %s is already listed by another seller", vehicle.VIN)
+				}
+			}
+
+			// the seller now holds the record, so their submitted specs become the canonical ones
+			existingVehicle.OwnerID = &authenticatedUserID
+			existingVehicle.ApplySpecs(listing.VehicleSpecs)
+			existingVehicle.Mileage = vehicle.Mileage
+			if err := s.vehicleRepo.UpdateWithTx(tx, existingVehicle); err != nil {
+				return fmt.Errorf("failed to update existing vehicle: %w", err)
+			}
+
 			vehicle = existingVehicle 
 		}
 
 		// link the listing to the newly created or existing vehicle
 		listing.VehicleID = vehicle.ID
 		listing.Status = models.ListingStatusPending 
+		statusChangedAt := time.Now()
+		listing.StatusChangedAt = &statusChangedAt
 
 		// create the listing within the same transaction
 		if err := s.repo.CreateWithTx(tx, listing); err != nil {
@@ -70,6 +101,8 @@ func (s *ListingService) CreateListingWithVehicle(listing *models.Listing, vehic
 		return nil, err
 	}
 
+	publishListingStatus(s.hub, createdListing, "", createdListing.Status, "")
+
 	return createdListing, nil
 }
 
@@ -78,9 +111,17 @@ func (s *ListingService) GetAllListings() ([]*models.Listing, error) {
 	return s.repo.GetAll()
 }
 
-// GetActiveListings retrieves all listings with the status 'active'.
+// GetActiveListings retrieves all listings with the status 'active' for the public, without the sellers' contact details.
 func (s *ListingService) GetActiveListings() ([]*models.Listing, error) {
-	return s.repo.GetByStatus(models.ListingStatusActive)
+	listings, err := s.repo.GetByStatus(models.ListingStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listing := range listings {
+		listing.Seller.HideContactDetails()
+	}
+	return listings, nil
 }
 
 
@@ -130,9 +171,233 @@ func (s *ListingService) UpdateListing(listingToUpdate *models.Listing, authenti
 		return fmt.Errorf("unauthorized: invalid role for update operation")
 	}
 
-	return s.repo.Update(listingToUpdate)
+	// vehicle specs and mileage are only changed through UpdateListingVehicle
+	listingToUpdate.VehicleSpecs = models.VehicleSpecs{}
+	listingToUpdate.Mileage = 0
+
+	// status moves are recorded separately so time in state and status history stay accurate
+	newStatus := listingToUpdate.Status
+	listingToUpdate.Status = ""
+
+	statusChanged := newStatus != "" && newStatus != existingListing.Status
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.UpdateWithTx(tx, listingToUpdate); err != nil {
+			return err
+		}
+
+		if !statusChanged {
+			return nil
+		}
+		return s.repo.ChangeStatusWithTx(tx, &models.ListingStatusChange{
+			ListingID:   existingListing.ID,
+			FromStatus:  existingListing.Status,
+			ToStatus:    newStatus,
+			ChangedByID: authenticatedUserID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if statusChanged {
+		publishListingStatus(s.hub, existingListing, existingListing.Status, newStatus, "")
+	}
+	return nil
+}
+
+// UpdateListingVehicle corrects the vehicle specs of a seller's own non-active listing.
+// The listing snapshot is always updated; the shared vehicle record only when the seller owns it.
+func (s *ListingService) UpdateListingVehicle(listingID string, specs models.VehicleSpecs, mileage uint, authenticatedUserID uuid.UUID) (*models.Listing, error) {
+	existingListing, err := s.repo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingListing.SellerID != authenticatedUserID {
+		return nil, fmt.Errorf("unauthorized: you can only update your own listings")
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		// the status check runs under the row lock so a concurrent approval cannot slip in before the write
+		lockedListing, err := s.repo.GetByIDForUpdateWithTx(tx, existingListing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status == models.ListingStatusActive || lockedListing.Status == models.ListingStatusReserved || lockedListing.Status == models.ListingStatusSold {
+			return fmt.Errorf("vehicle specs cannot be changed on a %s listing", lockedListing.Status)
+		}
+
+		listingToUpdate := &models.Listing{
+			ID:           lockedListing.ID,
+			Mileage:      mileage,
+			VehicleSpecs: specs,
+		}
+		if err := s.repo.UpdateWithTx(tx, listingToUpdate); err != nil {
+			return err
+		}
+
+		vehicle := existingListing.Vehicle
+		if vehicle.OwnerID == nil || *vehicle.OwnerID != authenticatedUserID {
+			return nil
+		}
+
+		vehicle.ApplySpecs(specs)
+		if mileage != 0 {
+			vehicle.Mileage = mileage
+		}
+		return s.vehicleRepo.UpdateWithTx(tx, &vehicle)
+	})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "vehicle specs cannot be changed on a") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update listing vehicle: %w", err)
+	}
+
+	return s.repo.GetByID(listingID)
+}
+
+// ListingResubmissionInput is what a seller sends back with a rejected listing; unset fields stay as they are
+type ListingResubmissionInput struct {
+	Note         string
+	Title        string
+	Description  *string
+	Price        float64
+	Location     string
+	Mileage      uint
+	VehicleSpecs models.VehicleSpecs
+}
+
+// ResubmitListing applies a seller's fixes to their rejected listing, records what changed against the
+// rejection it answers, and returns the listing to the review queue.
+func (s *ListingService) ResubmitListing(listingID string, input ListingResubmissionInput, authenticatedUserID uuid.UUID) (*models.Listing, error) {
+	existingListing, err := s.repo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingListing.SellerID != authenticatedUserID {
+		return nil, fmt.Errorf("unauthorized: you can only resubmit your own listings")
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		// the status check runs under the row lock so a concurrent resubmit or review decision cannot interleave
+		lockedListing, err := s.repo.GetByIDForUpdateWithTx(tx, existingListing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status != models.ListingStatusRejected {
+			return fmt.Errorf("only rejected listings can be resubmitted")
+		}
+
+		changes := lockedListing.VehicleSpecs.Changes(input.VehicleSpecs)
+		specsChanged := len(changes) > 0
+		listingToUpdate := &models.Listing{
+			ID:           lockedListing.ID,
+			VehicleSpecs: input.VehicleSpecs,
+		}
+		if input.Title != "" && input.Title != lockedListing.Title {
+			changes["title"] = models.ListingFieldChange{From: lockedListing.Title, To: input.Title}
+			listingToUpdate.Title = input.Title
+		}
+		if input.Description != nil && *input.Description != lockedListing.Description {
+			changes["description"] = models.ListingFieldChange{From: lockedListing.Description, To: *input.Description}
+			listingToUpdate.Description = *input.Description
+		}
+		if input.Price != 0 && input.Price != lockedListing.Price {
+			changes["price"] = models.ListingFieldChange{From: lockedListing.Price, To: input.Price}
+			listingToUpdate.Price = input.Price
+		}
+		if input.Location != "" && input.Location != lockedListing.Location {
+			changes["location"] = models.ListingFieldChange{From: lockedListing.Location, To: input.Location}
+			listingToUpdate.Location = input.Location
+		}
+		if input.Mileage != 0 && input.Mileage != lockedListing.Mileage {
+			changes["mileage"] = models.ListingFieldChange{From: lockedListing.Mileage, To: input.Mileage}
+			listingToUpdate.Mileage = input.Mileage
+		}
+
+		if err := s.repo.UpdateWithTx(tx, listingToUpdate); err != nil {
+			return err
+		}
+
+		// keep the seller's own vehicle record in step, as UpdateListingVehicle does
+		vehicle := existingListing.Vehicle
+		if vehicle.OwnerID != nil && *vehicle.OwnerID == authenticatedUserID && (specsChanged || listingToUpdate.Mileage != 0) {
+			vehicle.ApplySpecs(input.VehicleSpecs)
+			if listingToUpdate.Mileage != 0 {
+				vehicle.Mileage = listingToUpdate.Mileage
+			}
+			if err := s.vehicleRepo.UpdateWithTx(tx, &vehicle); err != nil {
+				return err
+			}
+		}
+
+		resubmission := &models.ListingResubmission{
+			ListingID:        lockedListing.ID,
+			SellerID:         authenticatedUserID,
+			Note:             input.Note,
+			Changes:          changes,
+			RejectionReasons: lockedListing.RejectionReasons,
+			RejectionNote:    lockedListing.RejectionNote,
+		}
+		if err := s.repo.CreateResubmissionWithTx(tx, resubmission); err != nil {
+			return err
+		}
+
+		if err := s.repo.ChangeStatusWithTx(tx, &models.ListingStatusChange{
+			ListingID:   lockedListing.ID,
+			FromStatus:  lockedListing.Status,
+			ToStatus:    models.ListingStatusPending,
+			ChangedByID: authenticatedUserID,
+			Reason:      "resubmitted by seller: " + input.Note,
+		}); err != nil {
+			return err
+		}
+
+		return s.repo.SetRejectionWithTx(tx, lockedListing.ID, nil, "")
+	})
+	if err != nil {
+		if err.Error() == "only rejected listings can be resubmitted" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to resubmit listing: %w", err)
+	}
+
+	publishListingStatus(s.hub, existingListing, models.ListingStatusRejected, models.ListingStatusPending, "resubmitted by seller: "+input.Note)
+
+	return s.repo.GetByID(listingID)
+}
+
+// GetListingResubmissions retrieves a listing's resubmissions; sellers only see their own listings'
+func (s *ListingService) GetListingResubmissions(listingID string, authenticatedUserID uuid.UUID, role types.Role) ([]*models.ListingResubmission, error) {
+	existingListing, err := s.repo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role != types.RoleAdmin && existingListing.SellerID != authenticatedUserID {
+		return nil, fmt.Errorf("unauthorized: you can only view your own listings")
+	}
+
+	return s.repo.GetResubmissions(listingID)
 }
 
 func (s *ListingService) DeleteListing(id string) error {
 	return s.repo.Delete(id)
-}
\ No newline at end of file
+}
+
+// publishListingStatus tells the seller their listing moved to a new status, and admins when it enters review
+func publishListingStatus(hub *events.Hub, listing *models.Listing, from, to models.ListingStatus, reason string) {
+	change := events.ListingStatusChanged{
+		ListingID:  listing.ID,
+		Title:      listing.Title,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+	}
+	hub.Publish(events.TypeListingStatus, change, listing.SellerID)
+	if to == models.ListingStatusPending {
+		hub.PublishToRole(types.RoleAdmin, events.TypeListingStatus, change)
+	}
+}
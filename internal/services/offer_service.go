@@ -0,0 +1,453 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+const offerExpiryBatch = 100
+
+// OfferService runs price negotiations on listings. A buyer offers below the asking price, the seller and buyer take
+// turns to counter, accept or decline, and an accepted offer opens a pending sale at the agreed amount. Whoever's turn
+// it is has the expiry window to respond, after which the offer expires.
+type OfferService struct {
+	repo         *repositories.OfferRepository
+	listingRepo  *repositories.ListingRepository
+	transactions *TransactionService
+	expiry       time.Duration
+	hub          *events.Hub
+}
+
+func NewOfferService(repo *repositories.OfferRepository, listingRepo *repositories.ListingRepository, transactions *TransactionService, expiry time.Duration, hub *events.Hub) *OfferService {
+	return &OfferService{
+		repo:         repo,
+		listingRepo:  listingRepo,
+		transactions: transactions,
+		expiry:       expiry,
+		hub:          hub,
+	}
+}
+
+// MakeOffer opens a negotiation on an active listing. A buyer can have one active offer per listing.
+func (s *OfferService) MakeOffer(listingID string, buyerID uuid.UUID, amount float64, message string) (*models.Offer, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot make an offer on your own listing")
+	}
+	if amount >= listing.Price {
+		return nil, fmt.Errorf("offer must be below the asking price, purchase the listing instead")
+	}
+
+	offer := &models.Offer{
+		ListingID: listing.ID,
+		BuyerID:   buyerID,
+		SellerID:  listing.SellerID,
+		Amount:    amount,
+		Status:    models.OfferStatusPending,
+		ExpiresAt: time.Now().Add(s.expiry),
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		// the listing lock serialises a buyer's offers so two requests cannot both pass the one-offer check
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status != models.ListingStatusActive {
+			return fmt.Errorf("offers can only be made on active listings")
+		}
+
+		active, err := s.repo.CountActiveByBuyerWithTx(tx, listing.ID, buyerID)
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return fmt.Errorf("you already have an active offer on this listing")
+		}
+
+		if err := s.repo.CreateWithTx(tx, offer); err != nil {
+			return err
+		}
+		return s.repo.CreateEventWithTx(tx, &models.OfferEvent{
+			OfferID: offer.ID,
+			ActorID: &buyerID,
+			Action:  models.OfferActionOffered,
+			Amount:  amount,
+			Message: message,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(listing.Seller.Email, listing.Title, withMessage(
+		fmt.Sprintf("A buyer offered %.2f against your asking price of %.2f. Please accept, decline or counter it by %s.",
+			amount, listing.Price, email_helper.EscrowDeadline(offer.ExpiresAt)), message))
+	s.publish(offer, offer.Status, amount)
+
+	return s.repo.GetByID(offer.ID.String())
+}
+
+// Counter replaces the amount on the table. Only the party whose turn it is can counter, which hands the turn over.
+func (s *OfferService) Counter(id string, userID uuid.UUID, amount float64, message string) (*models.Offer, error) {
+	offer, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if amount > offer.Listing.Price {
+		return nil, fmt.Errorf("counter-offer cannot be more than the asking price")
+	}
+
+	nextStatus := models.OfferStatusCountered
+	if userID == offer.BuyerID {
+		nextStatus = models.OfferStatusPending
+	}
+	expiresAt := time.Now().Add(s.expiry)
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.lockTurnWithTx(tx, offer.ID, userID)
+		if err != nil {
+			return err
+		}
+		if amount == locked.Amount {
+			return fmt.Errorf("counter-offer must change the amount, accept the offer instead")
+		}
+
+		err = s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"amount":     amount,
+			"status":     nextStatus,
+			"expires_at": expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		return s.repo.CreateEventWithTx(tx, &models.OfferEvent{
+			OfferID: locked.ID,
+			ActorID: &userID,
+			Action:  models.OfferActionCountered,
+			Amount:  amount,
+			Message: message,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, counterparty := offer.Buyer.Email, "seller"
+	if userID == offer.BuyerID {
+		recipient, counterparty = offer.Listing.Seller.Email, "buyer"
+	}
+	s.notify(recipient, offer.Listing.Title, withMessage(
+		fmt.Sprintf("The %s countered with %.2f. Please accept, decline or counter it by %s.",
+			counterparty, amount, email_helper.EscrowDeadline(expiresAt)), message))
+	s.publish(offer, nextStatus, amount)
+
+	return s.repo.GetByID(id)
+}
+
+// Accept agrees to the amount on the table. The party whose turn it is accepts, which opens a pending sale of the
+// listing at that amount awaiting the buyer's payment, and declines every other active offer on the listing. The
+// accepted offer keeps the sale's id; if the payment provider cannot start the payment the sale is marked failed.
+func (s *OfferService) Accept(id string, userID uuid.UUID, paymentMethod string) (*models.Transaction, error) {
+	offer, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sale *models.Transaction
+	declined := []*models.Offer{}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.lockTurnWithTx(tx, offer.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		sale, err = s.transactions.openSaleWithTx(tx, &offer.Listing, locked.BuyerID, paymentMethod, locked.Amount,
+			fmt.Sprintf("offer of %.2f accepted", locked.Amount))
+		if err != nil {
+			return err
+		}
+
+		err = s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":         models.OfferStatusAccepted,
+			"transaction_id": sale.ID,
+		})
+		if err != nil {
+			return err
+		}
+		err = s.repo.CreateEventWithTx(tx, &models.OfferEvent{
+			OfferID: locked.ID,
+			ActorID: &userID,
+			Action:  models.OfferActionAccepted,
+			Amount:  locked.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the listing is spoken for, so the other negotiations on it end here
+		others, err := s.repo.GetActiveByListingIDForUpdateWithTx(tx, locked.ListingID)
+		if err != nil {
+			return err
+		}
+		for _, other := range others {
+			if other.ID == locked.ID {
+				continue
+			}
+			if err := s.closeWithTx(tx, other, models.OfferStatusDeclined, models.OfferActionDeclined, nil, "another offer was accepted"); err != nil {
+				return err
+			}
+			declined = append(declined, other)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == offer.SellerID {
+		s.notify(offer.Buyer.Email, offer.Listing.Title,
+			fmt.Sprintf("The seller accepted your offer of %.2f. Please complete your payment into escrow to secure the vehicle.", sale.Amount))
+	} else {
+		s.notify(offer.Listing.Seller.Email, offer.Listing.Title,
+			fmt.Sprintf("The buyer accepted your counter-offer of %.2f and a sale at that amount was opened.", sale.Amount))
+	}
+	s.publish(offer, models.OfferStatusAccepted, sale.Amount)
+	for _, other := range declined {
+		s.notifyBuyer(other, "The seller accepted another offer on this vehicle, so your offer was declined.")
+		s.publish(other, models.OfferStatusDeclined, other.Amount)
+	}
+
+	return s.transactions.startPayment(sale.ID, &offer.Listing, paymentMethod)
+}
+
+// Decline turns down the amount on the table and ends the negotiation. Only the party whose turn it is can decline.
+func (s *OfferService) Decline(id string, userID uuid.UUID, message string) (*models.Offer, error) {
+	offer, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.lockTurnWithTx(tx, offer.ID, userID)
+		if err != nil {
+			return err
+		}
+		return s.closeWithTx(tx, locked, models.OfferStatusDeclined, models.OfferActionDeclined, &userID, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == offer.SellerID {
+		s.notify(offer.Buyer.Email, offer.Listing.Title,
+			withMessage(fmt.Sprintf("The seller declined your offer of %.2f.", offer.Amount), message))
+	} else {
+		s.notify(offer.Listing.Seller.Email, offer.Listing.Title,
+			withMessage(fmt.Sprintf("The buyer declined your counter-offer of %.2f.", offer.Amount), message))
+	}
+	s.publish(offer, models.OfferStatusDeclined, offer.Amount)
+
+	return s.repo.GetByID(id)
+}
+
+// Withdraw is the buyer pulling out of a negotiation, whoever's turn it is
+func (s *OfferService) Withdraw(id string, buyerID uuid.UUID) (*models.Offer, error) {
+	offer, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if offer.BuyerID != buyerID {
+		return nil, fmt.Errorf("unauthorized: only the buyer can withdraw an offer")
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, offer.ID)
+		if err != nil {
+			return err
+		}
+		if !offerActive(locked) {
+			return fmt.Errorf("offer is no longer open")
+		}
+		return s.closeWithTx(tx, locked, models.OfferStatusWithdrawn, models.OfferActionWithdrawn, &buyerID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(offer.Listing.Seller.Email, offer.Listing.Title, fmt.Sprintf("The buyer withdrew their offer of %.2f.", offer.Amount))
+	s.publish(offer, models.OfferStatusWithdrawn, offer.Amount)
+
+	return s.repo.GetByID(id)
+}
+
+// GetOffer retrieves an offer with its history for its buyer, the seller or an admin
+func (s *OfferService) GetOffer(id string, userID uuid.UUID, userRole types.Role) (*models.Offer, error) {
+	offer, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && offer.BuyerID != userID && offer.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view your own offers")
+	}
+	return offer, nil
+}
+
+// GetListingOffers retrieves the offers on a listing for its seller or an admin
+func (s *OfferService) GetListingOffers(listingID string, userID uuid.UUID, userRole types.Role, status models.OfferStatus) ([]*models.Offer, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && listing.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view offers on your own listings")
+	}
+	return s.repo.GetByListingID(listing.ID, status)
+}
+
+// GetInbox retrieves the offers on a seller's listings, the active ones unless a status is given
+func (s *OfferService) GetInbox(sellerID uuid.UUID, status models.OfferStatus) ([]*models.Offer, error) {
+	return s.repo.GetBySellerID(sellerID, status)
+}
+
+// GetMyOffers retrieves the offers a buyer made
+func (s *OfferService) GetMyOffers(buyerID uuid.UUID) ([]*models.Offer, error) {
+	return s.repo.GetByBuyerID(buyerID)
+}
+
+// ExpireOffers is the background job closing negotiations nobody responded to in time
+func (s *OfferService) ExpireOffers() error {
+	now := time.Now()
+	offers, err := s.repo.GetExpired(now, offerExpiryBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, offer := range offers {
+		expired := false
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, offer.ID)
+			if err != nil {
+				return err
+			}
+			// a response may have come in since the offer was picked up
+			if !offerActive(locked) || locked.ExpiresAt.After(now) {
+				return nil
+			}
+			expired = true
+			return s.closeWithTx(tx, locked, models.OfferStatusExpired, models.OfferActionExpired, nil, "")
+		})
+		if err != nil {
+			fmt.Printf("Warning: Failed to expire offer %s: %v\n", offer.ID, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		full, err := s.repo.GetByID(offer.ID.String())
+		if err != nil {
+			continue
+		}
+		update := fmt.Sprintf("The offer of %.2f expired without a response.", full.Amount)
+		s.notify(full.Buyer.Email, full.Listing.Title, update)
+		s.notify(full.Listing.Seller.Email, full.Listing.Title, update)
+		s.publish(full, models.OfferStatusExpired, full.Amount)
+	}
+
+	return nil
+}
+
+// lockTurnWithTx locks an offer and checks it is still open and waiting on userID
+func (s *OfferService) lockTurnWithTx(tx *gorm.DB, id uuid.UUID, userID uuid.UUID) (*models.Offer, error) {
+	locked, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if locked.BuyerID != userID && locked.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only respond to your own offers")
+	}
+	if !offerActive(locked) {
+		return nil, fmt.Errorf("offer is no longer open")
+	}
+	if !locked.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("offer has expired")
+	}
+	if offerTurn(locked) != userID {
+		return nil, fmt.Errorf("offer is waiting on the other party")
+	}
+	return locked, nil
+}
+
+// closeWithTx ends a locked negotiation and records why
+func (s *OfferService) closeWithTx(tx *gorm.DB, locked *models.Offer, status models.OfferStatus, action models.OfferAction, actorID *uuid.UUID, message string) error {
+	if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{"status": status}); err != nil {
+		return err
+	}
+	return s.repo.CreateEventWithTx(tx, &models.OfferEvent{
+		OfferID: locked.ID,
+		ActorID: actorID,
+		Action:  action,
+		Amount:  locked.Amount,
+		Message: message,
+	})
+}
+
+// notifyBuyer emails the buyer of an offer loaded without its relations
+func (s *OfferService) notifyBuyer(offer *models.Offer, update string) {
+	full, err := s.repo.GetByID(offer.ID.String())
+	if err != nil {
+		fmt.Printf("Warning: Failed to load offer %s for its email: %v\n", offer.ID, err)
+		return
+	}
+	s.notify(full.Buyer.Email, full.Listing.Title, update)
+}
+
+// publish pushes an offer's new status to its buyer and seller
+func (s *OfferService) publish(offer *models.Offer, status models.OfferStatus, amount float64) {
+	s.hub.Publish(events.TypeOffer, events.OfferUpdated{
+		OfferID:   offer.ID,
+		ListingID: offer.ListingID,
+		Status:    status,
+		Amount:    amount,
+	}, offer.BuyerID, offer.SellerID)
+}
+
+func (s *OfferService) notify(recipient, listingTitle, update string) {
+	if err := email_helper.SendOfferUpdateEmail(recipient, listingTitle, update); err != nil {
+		fmt.Printf("Warning: Failed to send offer update email to %s: %v\n", recipient, err)
+	}
+}
+
+// offerActive reports whether an offer is still under negotiation
+func offerActive(offer *models.Offer) bool {
+	return offer.Status == models.OfferStatusPending || offer.Status == models.OfferStatusCountered
+}
+
+// offerTurn is the user an active offer is waiting on: the seller for a buyer's offer, the buyer for a counter-offer
+func offerTurn(offer *models.Offer) uuid.UUID {
+	if offer.Status == models.OfferStatusCountered {
+		return offer.BuyerID
+	}
+	return offer.SellerID
+}
+
+// withMessage appends the note a party left with their move
+func withMessage(update, message string) string {
+	if message == "" {
+		return update
+	}
+	return fmt.Sprintf("%s Their message: \"%s\"", update, message)
+}
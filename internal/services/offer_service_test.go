@@ -0,0 +1,165 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// offerStep is one party's move on an offer, the status it leaves the offer in and, for a move out of turn, the
+// error it is refused with
+type offerStep struct {
+	who     string
+	action  string
+	amount  float64
+	want    models.OfferStatus
+	wantErr string
+}
+
+func TestOfferTurns(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer, rival, stranger := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer), s.user(t, types.RoleBuyer), s.user(t, types.RoleBuyer)
+	users := map[string]*models.User{"buyer": buyer, "seller": seller, "stranger": stranger}
+
+	const price, offered = 1500000, 1200000
+	tests := []struct {
+		name      string
+		steps     []offerStep
+		wantSale  float64 // amount of the sale opened by an accepted offer
+		wantRival models.OfferStatus
+	}{
+		{
+			name: "seller accepts the buyer's offer",
+			steps: []offerStep{
+				{"seller", "accept", 0, models.OfferStatusAccepted, ""},
+			},
+			wantSale: offered, wantRival: models.OfferStatusDeclined,
+		},
+		{
+			name: "buyer accepts the seller's counter-offer",
+			steps: []offerStep{
+				{"seller", "counter", 1400000, models.OfferStatusCountered, ""},
+				{"buyer", "accept", 0, models.OfferStatusAccepted, ""},
+			},
+			wantSale: 1400000, wantRival: models.OfferStatusDeclined,
+		},
+		{
+			name: "seller accepts the buyer's counter to their counter",
+			steps: []offerStep{
+				{"seller", "counter", 1400000, models.OfferStatusCountered, ""},
+				{"buyer", "counter", 1300000.50, models.OfferStatusPending, ""},
+				{"seller", "accept", 0, models.OfferStatusAccepted, ""},
+			},
+			wantSale: 1300000.50, wantRival: models.OfferStatusDeclined,
+		},
+		{
+			name: "moves out of turn are refused",
+			steps: []offerStep{
+				{"buyer", "accept", 0, models.OfferStatusPending, "offer is waiting on the other party"},
+				{"buyer", "counter", 1250000, models.OfferStatusPending, "offer is waiting on the other party"},
+				{"stranger", "accept", 0, models.OfferStatusPending, "unauthorized: you can only respond to your own offers"},
+				{"seller", "counter", price + 1, models.OfferStatusPending, "counter-offer cannot be more than the asking price"},
+				{"seller", "counter", offered, models.OfferStatusPending, "counter-offer must change the amount, accept the offer instead"},
+				{"seller", "counter", 1400000, models.OfferStatusCountered, ""},
+				{"seller", "accept", 0, models.OfferStatusCountered, "offer is waiting on the other party"},
+				{"seller", "decline", 0, models.OfferStatusCountered, "offer is waiting on the other party"},
+				{"buyer", "decline", 0, models.OfferStatusDeclined, ""},
+			},
+			wantRival: models.OfferStatusPending,
+		},
+		{
+			name: "closed offers cannot be answered",
+			steps: []offerStep{
+				{"seller", "counter", 1400000, models.OfferStatusCountered, ""},
+				{"buyer", "withdraw", 0, models.OfferStatusWithdrawn, ""},
+				{"buyer", "accept", 0, models.OfferStatusWithdrawn, "offer is no longer open"},
+				{"seller", "counter", 1350000, models.OfferStatusWithdrawn, "offer is no longer open"},
+				{"buyer", "withdraw", 0, models.OfferStatusWithdrawn, "offer is no longer open"},
+			},
+			wantRival: models.OfferStatusPending,
+		},
+		{
+			name: "expired offers cannot be accepted",
+			steps: []offerStep{
+				{"seller", "expire", 0, models.OfferStatusPending, ""},
+				{"seller", "accept", 0, models.OfferStatusPending, "offer has expired"},
+			},
+			wantRival: models.OfferStatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing := s.listing(t, seller, price)
+			other, err := s.offers.MakeOffer(listing.ID.String(), rival.ID, 1100000, "")
+			if err != nil {
+				t.Fatalf("MakeOffer: %v", err)
+			}
+			offer, err := s.offers.MakeOffer(listing.ID.String(), buyer.ID, offered, "best I can do")
+			if err != nil {
+				t.Fatalf("MakeOffer: %v", err)
+			}
+			id := offer.ID.String()
+
+			for i, step := range tt.steps {
+				user := users[step.who]
+				switch step.action {
+				case "counter":
+					_, err = s.offers.Counter(id, user.ID, step.amount, "")
+				case "accept":
+					_, err = s.offers.Accept(id, user.ID, "card")
+				case "decline":
+					_, err = s.offers.Decline(id, user.ID, "")
+				case "withdraw":
+					_, err = s.offers.Withdraw(id, user.ID)
+				case "expire":
+					err = s.db.Model(&models.Offer{}).Where("id = ?", offer.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error
+				}
+				switch {
+				case step.wantErr == "" && err != nil:
+					t.Fatalf("step %d %s %s: %v", i, step.who, step.action, err)
+				case step.wantErr != "" && (err == nil || err.Error() != step.wantErr):
+					t.Fatalf("step %d %s %s: error = %v, want %q", i, step.who, step.action, err, step.wantErr)
+				}
+				if got := s.offer(t, id).Status; got != step.want {
+					t.Fatalf("step %d %s %s: status = %s, want %s", i, step.who, step.action, got, step.want)
+				}
+			}
+
+			got := s.offer(t, id)
+			if tt.wantSale == 0 {
+				if got.TransactionID != nil {
+					t.Errorf("offer opened sale %s, want none", got.TransactionID)
+				}
+			} else {
+				if got.TransactionID == nil {
+					t.Fatalf("accepted offer has no sale")
+				}
+				sale := s.reload(t, *got.TransactionID)
+				if payment.ToMinorUnits(sale.Amount) != payment.ToMinorUnits(tt.wantSale) || sale.BuyerID != buyer.ID {
+					t.Errorf("sale of %v to %s, want %v to the buyer %s", sale.Amount, sale.BuyerID, tt.wantSale, buyer.ID)
+				}
+				if sale.Status != models.TransactionStatusPending || sale.EscrowStatus != models.EscrowStatusAwaitingPayment {
+					t.Errorf("sale is %s/%s, want %s/%s", sale.Status, sale.EscrowStatus, models.TransactionStatusPending, models.EscrowStatusAwaitingPayment)
+				}
+			}
+			if status := s.offer(t, other.ID.String()).Status; status != tt.wantRival {
+				t.Errorf("other buyer's offer is %s, want %s", status, tt.wantRival)
+			}
+		})
+	}
+}
+
+// offer reads an offer back from the database
+func (s *testServices) offer(t *testing.T, id string) *models.Offer {
+	t.Helper()
+
+	offer, err := s.offers.repo.GetByID(id)
+	if err != nil {
+		t.Fatalf("failed to reload offer %s: %v", id, err)
+	}
+	return offer
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxWebhookAttempts is how often the background retry applies a failed event before leaving it to an admin
+	maxWebhookAttempts = 5
+	// webhookReplayBatch bounds how many events a single replay run handles
+	webhookReplayBatch = 100
+)
+
+// PaymentWebhookService keeps an inbox of verified provider notifications and applies them to transactions.
+// Every event is stored before it is applied, applied at most once, and failures stay in the inbox for replay.
+type PaymentWebhookService struct {
+	repo               *repositories.PaymentWebhookRepository
+	transactionService *TransactionService
+	providers          map[string]payment.Provider
+}
+
+func NewPaymentWebhookService(repo *repositories.PaymentWebhookRepository, transactionService *TransactionService, providers ...payment.Provider) *PaymentWebhookService {
+	byName := map[string]payment.Provider{}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &PaymentWebhookService{
+		repo:               repo,
+		transactionService: transactionService,
+		providers:          byName,
+	}
+}
+
+// Receive verifies a provider notification, stores it and applies it. A redelivered event is not applied again:
+// the stored copy is returned with duplicate set.
+func (s *PaymentWebhookService) Receive(providerName string, payload []byte, headers http.Header) (event *models.PaymentWebhookEvent, duplicate bool, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown payment provider '%s'", providerName)
+	}
+
+	verified, err := provider.VerifyWebhook(payload, headers)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	event = &models.PaymentWebhookEvent{
+		Provider:          provider.Name(),
+		EventID:           verified.ID,
+		EventType:         verified.Type,
+		ProviderReference: verified.ProviderReference,
+		PaymentStatus:     string(verified.Status),
+		Amount:            verified.Amount,
+		Currency:          strings.ToUpper(verified.Currency),
+		Payload:           string(payload),
+		Status:            models.PaymentWebhookStatusReceived,
+	}
+	created, err := s.repo.CreateIfNew(event)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		existing, err := s.repo.GetByProviderEventID(provider.Name(), verified.ID)
+		return existing, true, err
+	}
+
+	if err := s.process(event.ID); err != nil {
+		return nil, false, err
+	}
+	event, err = s.repo.GetByID(event.ID.String())
+	return event, false, err
+}
+
+// GetEvents lists the newest inbox events, optionally in one status
+func (s *PaymentWebhookService) GetEvents(status models.PaymentWebhookStatus, limit int) ([]*models.PaymentWebhookEvent, error) {
+	return s.repo.GetAll(status, limit)
+}
+
+func (s *PaymentWebhookService) GetEvent(id string) (*models.PaymentWebhookEvent, error) {
+	return s.repo.GetByID(id)
+}
+
+// Replay applies a failed or unfinished event again
+func (s *PaymentWebhookService) Replay(id string) (*models.PaymentWebhookEvent, error) {
+	event, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if applied(event) {
+		return nil, fmt.Errorf("payment webhook event was already applied")
+	}
+
+	if err := s.process(event.ID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// ReplayFailed applies every failed or unfinished event again, however often it was tried, and returns them
+func (s *PaymentWebhookService) ReplayFailed() ([]*models.PaymentWebhookEvent, error) {
+	return s.replay(0)
+}
+
+// RetryFailed is the background job applying failed events that still have attempts left
+func (s *PaymentWebhookService) RetryFailed() error {
+	_, err := s.replay(maxWebhookAttempts)
+	return err
+}
+
+func (s *PaymentWebhookService) replay(maxAttempts int) ([]*models.PaymentWebhookEvent, error) {
+	events, err := s.repo.GetRetryable(maxAttempts, webhookReplayBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make([]*models.PaymentWebhookEvent, 0, len(events))
+	for _, event := range events {
+		if err := s.process(event.ID); err != nil {
+			return replayed, err
+		}
+		updated, err := s.repo.GetByID(event.ID.String())
+		if err != nil {
+			return replayed, err
+		}
+		replayed = append(replayed, updated)
+	}
+	return replayed, nil
+}
+
+// webhookOutcome is what applying an event did, for recording it and acting on it once committed
+type webhookOutcome struct {
+	status      models.PaymentWebhookStatus
+	note        string
+	transaction *models.Transaction
+	escrow      models.EscrowStatus // the transaction's new escrow status, when applying changed it
+	refund      *models.Refund      // money sent back to the payer, issued after commit
+}
+
+// process applies a stored event under its row lock. An event that cannot be applied is marked failed with the
+// reason; a succeeded payment that still cannot be applied after maxWebhookAttempts is refunded to the payer instead.
+// Only failures to record the outcome are returned.
+func (s *PaymentWebhookService) process(id uuid.UUID) error {
+	outcome, applyErr := s.commit(id, s.applyWithTx)
+	if applyErr != nil {
+		event, err := s.repo.GetByID(id.String())
+		if err != nil {
+			return err
+		}
+		if payment.Status(event.PaymentStatus) == payment.StatusSucceeded && event.Attempts+1 >= maxWebhookAttempts {
+			// the money must not stay with us just because the sale it was meant for can no longer take it
+			outcome, err = s.commit(id, func(tx *gorm.DB, event *models.PaymentWebhookEvent) (webhookOutcome, error) {
+				return s.giveUpWithTx(tx, event, applyErr)
+			})
+			if err == nil {
+				applyErr = nil
+			}
+		}
+	}
+	if applyErr != nil {
+		// everything the attempt changed was rolled back; record why so it can be replayed
+		return s.repo.UpdateFieldsWithTx(s.repo.DB, id, map[string]any{
+			"status":   models.PaymentWebhookStatusFailed,
+			"error":    applyErr.Error(),
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	}
+
+	if outcome.refund != nil {
+		s.transactionService.refunds.issue(outcome.refund)
+	}
+	if outcome.escrow != "" {
+		if transaction, err := s.transactionService.repo.GetByID(outcome.transaction.ID.String()); err == nil {
+			if outcome.escrow == models.EscrowStatusFundsHeld {
+				s.transactionService.notifyFundsHeld(transaction)
+			} else {
+				s.transactionService.notifyParties(transaction, models.TransactionStatusFailed, uuid.Nil)
+			}
+		}
+	}
+	return nil
+}
+
+// commit runs apply on a stored event under its row lock and records the outcome in the same database transaction.
+// Events that were already applied are left alone.
+func (s *PaymentWebhookService) commit(id uuid.UUID, apply func(*gorm.DB, *models.PaymentWebhookEvent) (webhookOutcome, error)) (webhookOutcome, error) {
+	var outcome webhookOutcome
+
+	err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		event, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if applied(event) {
+			return nil
+		}
+
+		outcome, err = apply(tx, event)
+		if err != nil {
+			return err
+		}
+
+		fields := map[string]any{
+			"status":       outcome.status,
+			"error":        outcome.note,
+			"attempts":     event.Attempts + 1,
+			"processed_at": time.Now(),
+		}
+		if outcome.transaction != nil {
+			fields["transaction_id"] = outcome.transaction.ID
+		}
+		return s.repo.UpdateFieldsWithTx(tx, event.ID, fields)
+	})
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+	return outcome, nil
+}
+
+// applied reports whether an event reached a final outcome and must not be applied again
+func applied(event *models.PaymentWebhookEvent) bool {
+	return event.Status == models.PaymentWebhookStatusProcessed ||
+		event.Status == models.PaymentWebhookStatusIgnored ||
+		event.Status == models.PaymentWebhookStatusRefunded
+}
+
+// applyWithTx moves the event's transaction to the state the payment reached. Applying an event whose transition
+// already happened changes nothing, so deliveries in any order and repeats are safe. A succeeded payment that can
+// never be applied, because its sale is over or it is not the amount or currency due, is refunded.
+func (s *PaymentWebhookService) applyWithTx(tx *gorm.DB, event *models.PaymentWebhookEvent) (webhookOutcome, error) {
+	transaction, err := s.transactionService.repo.GetByProviderReferenceForUpdateWithTx(tx, event.Provider, event.ProviderReference)
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+	switch payment.Status(event.PaymentStatus) {
+	case payment.StatusSucceeded:
+		switch transaction.EscrowStatus {
+		case models.EscrowStatusAwaitingPayment:
+			if !strings.EqualFold(event.Currency, s.transactionService.currency) {
+				return s.refundUnappliedWithTx(tx, event, transaction, fmt.Sprintf("paid in '%s' instead of %s", event.Currency, s.transactionService.currency))
+			}
+			due := payment.ToMinorUnits(transaction.Amount)
+			if event.Amount < due {
+				return s.refundUnappliedWithTx(tx, event, transaction, fmt.Sprintf("paid amount %d is less than the %d due", event.Amount, due))
+			}
+			// the buyer's payment is what moves the funds into escrow
+			if err := s.transactionService.holdFundsWithTx(tx, transaction, nil, "", "payment confirmed by "+event.Provider); err != nil {
+				return webhookOutcome{}, err
+			}
+			return webhookOutcome{status: models.PaymentWebhookStatusProcessed, transaction: transaction, escrow: models.EscrowStatusFundsHeld}, nil
+		case models.EscrowStatusCancelled, "":
+			return s.refundUnappliedWithTx(tx, event, transaction, fmt.Sprintf("the transaction is %s", transaction.Status))
+		default:
+			// the funds already reached escrow
+			return webhookOutcome{status: models.PaymentWebhookStatusProcessed, transaction: transaction}, nil
+		}
+
+	case payment.StatusFailed:
+		switch {
+		case transaction.Status == models.TransactionStatusFailed:
+			return webhookOutcome{status: models.PaymentWebhookStatusProcessed, transaction: transaction}, nil
+		case transaction.EscrowStatus == models.EscrowStatusAwaitingPayment:
+			err := s.transactionService.transitionWithTx(tx, transaction, transactionChange{
+				Status: models.TransactionStatusFailed,
+				Escrow: models.EscrowStatusCancelled,
+				Reason: "payment failed at " + event.Provider,
+			})
+			if err != nil {
+				return webhookOutcome{}, err
+			}
+			return webhookOutcome{status: models.PaymentWebhookStatusProcessed, transaction: transaction, escrow: models.EscrowStatusCancelled}, nil
+		default:
+			return webhookOutcome{status: models.PaymentWebhookStatusIgnored, note: fmt.Sprintf("transaction is already %s", transaction.Status), transaction: transaction}, nil
+		}
+
+	case payment.StatusRefunded:
+		return webhookOutcome{status: models.PaymentWebhookStatusIgnored, note: "refunds are recorded when they are issued", transaction: transaction}, nil
+
+	default:
+		return webhookOutcome{status: models.PaymentWebhookStatusIgnored, note: "payment is still pending", transaction: transaction}, nil
+	}
+}
+
+// giveUpWithTx refunds a succeeded payment that kept failing to apply, e.g. because the listing was sold to someone
+// else in the meantime, unless its funds reached escrow after all
+func (s *PaymentWebhookService) giveUpWithTx(tx *gorm.DB, event *models.PaymentWebhookEvent, cause error) (webhookOutcome, error) {
+	transaction, err := s.transactionService.repo.GetByProviderReferenceForUpdateWithTx(tx, event.Provider, event.ProviderReference)
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+
+	switch transaction.EscrowStatus {
+	case models.EscrowStatusAwaitingPayment, models.EscrowStatusCancelled, "":
+		return s.refundUnappliedWithTx(tx, event, transaction, fmt.Sprintf("it could not be applied after %d attempts: %v", event.Attempts+1, cause))
+	default:
+		return webhookOutcome{status: models.PaymentWebhookStatusProcessed, transaction: transaction}, nil
+	}
+}
+
+// refundUnappliedWithTx sends a succeeded payment that cannot be applied back to the payer. A transaction still
+// awaiting it fails, since its payment reference is spent.
+func (s *PaymentWebhookService) refundUnappliedWithTx(tx *gorm.DB, event *models.PaymentWebhookEvent, transaction *models.Transaction, why string) (webhookOutcome, error) {
+	// the refund is keyed on the event that reported this payment, not the transaction: a buyer charged twice has a
+	// second event whose payment must be sent back even though the first one already was
+	existing, err := s.transactionService.refunds.repo.CountByWebhookEventIDWithTx(tx, event.ID)
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+	if existing > 0 {
+		return webhookOutcome{status: models.PaymentWebhookStatusIgnored, note: "payment was already refunded", transaction: transaction}, nil
+	}
+	// a payment rejected when the buyer captured it was refunded before its event arrived; this event reports it
+	claimed, err := s.transactionService.refunds.repo.AssignWebhookEventWithTx(tx, transaction.ID, event.Amount, event.ID)
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+	if claimed {
+		return webhookOutcome{status: models.PaymentWebhookStatusIgnored, note: "payment was already refunded when it was captured", transaction: transaction}, nil
+	}
+
+	reason := "payment could not be applied: " + why
+	outcome := webhookOutcome{status: models.PaymentWebhookStatusRefunded, note: reason, transaction: transaction}
+	if transaction.EscrowStatus == models.EscrowStatusAwaitingPayment {
+		err := s.transactionService.transitionWithTx(tx, transaction, transactionChange{
+			Status: models.TransactionStatusFailed,
+			Escrow: models.EscrowStatusCancelled,
+			Reason: reason,
+		})
+		if err != nil {
+			return webhookOutcome{}, err
+		}
+		outcome.escrow = models.EscrowStatusCancelled
+	}
+
+	outcome.refund, err = s.transactionService.refunds.queueWithTx(tx, transaction, event.Amount, reason, &event.ID)
+	if err != nil {
+		return webhookOutcome{}, err
+	}
+	return outcome, nil
+}
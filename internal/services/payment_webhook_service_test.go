@@ -0,0 +1,155 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+func TestWebhookApply(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer)
+
+	tests := []struct {
+		name        string
+		status      payment.Status
+		short       int64 // minor units paid less than due
+		currency    string
+		wantEvent   models.PaymentWebhookStatus
+		wantEscrow  models.EscrowStatus
+		wantListing models.ListingStatus
+		refunded    bool // the payment is sent back in full
+	}{
+		{"paid in full", payment.StatusSucceeded, 0, testCurrency, models.PaymentWebhookStatusProcessed, models.EscrowStatusFundsHeld, models.ListingStatusSold, false},
+		{"currency reported in lower case", payment.StatusSucceeded, 0, "ngn", models.PaymentWebhookStatusProcessed, models.EscrowStatusFundsHeld, models.ListingStatusSold, false},
+		{"underpaid", payment.StatusSucceeded, 1, testCurrency, models.PaymentWebhookStatusRefunded, models.EscrowStatusCancelled, models.ListingStatusActive, true},
+		{"paid in another currency", payment.StatusSucceeded, 0, "USD", models.PaymentWebhookStatusRefunded, models.EscrowStatusCancelled, models.ListingStatusActive, true},
+		{"payment failed", payment.StatusFailed, 0, testCurrency, models.PaymentWebhookStatusProcessed, models.EscrowStatusCancelled, models.ListingStatusActive, false},
+		{"payment still pending", payment.StatusPending, 0, testCurrency, models.PaymentWebhookStatusIgnored, models.EscrowStatusAwaitingPayment, models.ListingStatusActive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing := s.listing(t, seller, 1500000)
+			transaction := s.purchase(t, listing, buyer)
+			paid := payment.ToMinorUnits(transaction.Amount) - tt.short
+
+			event := s.webhook(t, transaction, uuid.NewString(), tt.status, paid, tt.currency)
+			if event.Status != tt.wantEvent {
+				t.Errorf("event status = %s (%s), want %s", event.Status, event.Error, tt.wantEvent)
+			}
+			if event.TransactionID == nil || *event.TransactionID != transaction.ID {
+				t.Errorf("event transaction = %v, want %s", event.TransactionID, transaction.ID)
+			}
+
+			got := s.reload(t, transaction.ID)
+			if got.EscrowStatus != tt.wantEscrow {
+				t.Errorf("escrow = %s, want %s", got.EscrowStatus, tt.wantEscrow)
+			}
+			if tt.wantEscrow == models.EscrowStatusCancelled && got.Status != models.TransactionStatusFailed {
+				t.Errorf("status = %s, want %s", got.Status, models.TransactionStatusFailed)
+			}
+			if status := s.listingStatus(t, listing.ID); status != tt.wantListing {
+				t.Errorf("listing status = %s, want %s", status, tt.wantListing)
+			}
+
+			refunds := s.refunds(t, transaction.ID)
+			if !tt.refunded {
+				if len(refunds) != 0 {
+					t.Errorf("%d refunds recorded, want none", len(refunds))
+				}
+				return
+			}
+			if len(refunds) != 1 {
+				t.Fatalf("%d refunds recorded, want 1", len(refunds))
+			}
+			if refunds[0].Amount != paid || refunds[0].Status != models.RefundStatusIssued {
+				t.Errorf("refund of %d is %s, want %d issued", refunds[0].Amount, refunds[0].Status, paid)
+			}
+			if refunds[0].WebhookEventID == nil || *refunds[0].WebhookEventID != event.ID {
+				t.Errorf("refund webhook event = %v, want %s", refunds[0].WebhookEventID, event.ID)
+			}
+		})
+	}
+}
+
+func TestWebhookRefundsEveryPaymentOfACancelledSale(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer)
+	transaction := s.purchase(t, s.listing(t, seller, 1500000), buyer)
+	if _, err := s.transactions.UpdateStatus(transaction.ID.String(), models.TransactionStatusCancelled, buyer.ID, types.RoleBuyer); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// the buyer was charged twice before the cancellation reached the provider, and the first event is redelivered
+	due := payment.ToMinorUnits(transaction.Amount)
+	first, second := uuid.NewString(), uuid.NewString()
+	for _, eventID := range []string{first, second, first} {
+		event := s.webhook(t, transaction, eventID, payment.StatusSucceeded, due, testCurrency)
+		if event.Status != models.PaymentWebhookStatusRefunded {
+			t.Errorf("event %s status = %s (%s), want %s", eventID, event.Status, event.Error, models.PaymentWebhookStatusRefunded)
+		}
+	}
+
+	refunds := s.refunds(t, transaction.ID)
+	if len(refunds) != 2 {
+		t.Fatalf("%d refunds recorded, want one for each charge", len(refunds))
+	}
+	if *refunds[0].WebhookEventID == *refunds[1].WebhookEventID {
+		t.Errorf("both refunds are for event %s", *refunds[0].WebhookEventID)
+	}
+	for _, refund := range refunds {
+		if refund.Amount != due || refund.Status != models.RefundStatusIssued {
+			t.Errorf("refund of %d is %s, want %d issued", refund.Amount, refund.Status, due)
+		}
+	}
+	if got := s.reload(t, transaction.ID); got.Status != models.TransactionStatusCancelled {
+		t.Errorf("status = %s, want it to stay %s", got.Status, models.TransactionStatusCancelled)
+	}
+}
+
+func TestWebhookGivesUpAfterMaxAttempts(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer)
+	listing := s.listing(t, seller, 1500000)
+	transaction := s.purchase(t, listing, buyer)
+
+	// the listing went off the market before the payment came in, so it can never be applied
+	if err := s.db.Model(&models.Listing{}).Where("id = ?", listing.ID).Update("status", models.ListingStatusSold).Error; err != nil {
+		t.Fatalf("failed to take the listing off the market: %v", err)
+	}
+
+	due := payment.ToMinorUnits(transaction.Amount)
+	event := s.webhook(t, transaction, uuid.NewString(), payment.StatusSucceeded, due, testCurrency)
+	for attempt := 1; attempt < maxWebhookAttempts; attempt++ {
+		if event.Status != models.PaymentWebhookStatusFailed || event.Attempts != attempt {
+			t.Fatalf("after attempt %d event is %s with %d attempts, want %s", attempt, event.Status, event.Attempts, models.PaymentWebhookStatusFailed)
+		}
+		if refunds := s.refunds(t, transaction.ID); len(refunds) != 0 {
+			t.Fatalf("refunded after attempt %d, want the payment kept for another attempt", attempt)
+		}
+
+		var err error
+		if event, err = s.webhooks.Replay(event.ID.String()); err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+	}
+
+	if event.Status != models.PaymentWebhookStatusRefunded || event.Attempts != maxWebhookAttempts {
+		t.Fatalf("after %d attempts event is %s with %d attempts, want %s", maxWebhookAttempts, event.Status, event.Attempts, models.PaymentWebhookStatusRefunded)
+	}
+	refunds := s.refunds(t, transaction.ID)
+	if len(refunds) != 1 || refunds[0].Amount != due || *refunds[0].WebhookEventID != event.ID {
+		t.Fatalf("refunds = %+v, want one of %d for event %s", refunds, due, event.ID)
+	}
+	got := s.reload(t, transaction.ID)
+	if got.Status != models.TransactionStatusFailed || got.EscrowStatus != models.EscrowStatusCancelled {
+		t.Errorf("transaction is %s/%s, want %s/%s", got.Status, got.EscrowStatus, models.TransactionStatusFailed, models.EscrowStatusCancelled)
+	}
+	if _, err := s.webhooks.Replay(event.ID.String()); err == nil {
+		t.Errorf("Replay of a refunded event succeeded, want it refused")
+	}
+}
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/encryption"
+	"gorm.io/gorm"
+)
+
+// PayoutService pays sellers what their completed sales earned. Admins batch up the money owed, approve the batch,
+// upload its CSV to the bank and then settle it with what the bank reported; only settled payouts leave the ledger.
+type PayoutService struct {
+	repo     *repositories.PayoutRepository
+	ledger   *LedgerService
+	cipher   *encryption.Cipher
+	currency string
+}
+
+func NewPayoutService(repo *repositories.PayoutRepository, ledger *LedgerService, cipher *encryption.Cipher, currency string) *PayoutService {
+	return &PayoutService{
+		repo:     repo,
+		ledger:   ledger,
+		cipher:   cipher,
+		currency: currency,
+	}
+}
+
+// nubanWeights are the weights of the NUBAN check digit over the bank code and the first nine account digits
+var nubanWeights = []int{3, 7, 3, 3, 7, 3, 3, 7, 3, 3, 7, 3}
+
+// SaveAccount registers the bank account a seller is paid out to, replacing the one they had.
+// Payouts already in a batch keep the account they were created with.
+func (s *PayoutService) SaveAccount(sellerID uuid.UUID, bankName, bankCode, accountName, accountNumber string) (*models.PayoutAccount, error) {
+	bankName, bankCode, accountName = strings.TrimSpace(bankName), strings.TrimSpace(bankCode), strings.TrimSpace(accountName)
+	accountNumber = strings.ReplaceAll(strings.TrimSpace(accountNumber), " ", "")
+
+	if bankName == "" || accountName == "" {
+		return nil, fmt.Errorf("invalid payout account: bank name and account name are required")
+	}
+	if len(bankCode) < 3 || len(bankCode) > 6 || !isDigits(bankCode) {
+		return nil, fmt.Errorf("invalid bank code '%s', expected 3 to 6 digits", bankCode)
+	}
+	if len(accountNumber) != 10 || !isDigits(accountNumber) {
+		return nil, fmt.Errorf("invalid account number, expected 10 digits")
+	}
+	// only the classic 3-digit bank codes take part in the NUBAN check digit
+	if len(bankCode) == 3 && !validNUBAN(bankCode, accountNumber) {
+		return nil, fmt.Errorf("invalid account number for bank code %s", bankCode)
+	}
+
+	sealed, err := s.cipher.Seal(accountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt account number: %w", err)
+	}
+
+	account := &models.PayoutAccount{
+		SellerID:               sellerID,
+		BankName:               bankName,
+		BankCode:               bankCode,
+		AccountName:            accountName,
+		AccountNumberEncrypted: sealed,
+		AccountNumberLast4:     accountNumber[len(accountNumber)-4:],
+	}
+	if err := s.repo.SaveAccount(account); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAccountBySellerID(sellerID)
+}
+
+func (s *PayoutService) GetAccount(sellerID uuid.UUID) (*models.PayoutAccount, error) {
+	return s.repo.GetAccountBySellerID(sellerID)
+}
+
+// GetBalance reports what a seller is owed: the ledger balance, the part already in payout batches and the completed
+// sales not paid out yet
+func (s *PayoutService) GetBalance(sellerID uuid.UUID) (*models.SellerBalance, error) {
+	ledgerBalance, err := s.ledger.SellerPayableBalance(sellerID)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.repo.SumPendingBySellerID(sellerID)
+	if err != nil {
+		return nil, err
+	}
+	sales, err := s.repo.GetPayableSalesWithTx(s.repo.DB, &sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &models.SellerBalance{
+		SellerID:      sellerID,
+		Currency:      s.currency,
+		LedgerBalance: ledgerBalance,
+		PendingPayout: pending,
+		Unpaid:        []*models.PayableSale{},
+	}
+	for _, sale := range sales {
+		payable := payableSale(sale)
+		balance.Unpaid = append(balance.Unpaid, payable)
+		balance.Available += payable.Amount
+	}
+	balance.Available = roundToMinorUnits(balance.Available)
+	return balance, nil
+}
+
+// GetSellerPayouts lists a seller's payouts, newest first
+func (s *PayoutService) GetSellerPayouts(sellerID uuid.UUID) ([]*models.Payout, error) {
+	return s.repo.GetPayoutsBySellerID(sellerID)
+}
+
+// CreateBatch gathers every completed sale and credited deposit not paid out yet into one payout per seller, awaiting approval.
+// Sellers without a payout account, or whose sales net to nothing, are left for a later batch. Sales that can never be
+// paid on their own, e.g. one whose commission took the whole amount, are netted against the seller's other sales or,
+// when the seller has nothing else to be paid, closed in a payout settled at zero so no later batch picks them up again.
+func (s *PayoutService) CreateBatch(adminID uuid.UUID) (*models.PayoutBatch, error) {
+	var batchID uuid.UUID
+
+	err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		sales, err := s.repo.GetPayableSalesWithTx(tx, nil)
+		if err != nil {
+			return err
+		}
+
+		bySeller := map[uuid.UUID][]*models.PayableSale{}
+		sellerIDs := []uuid.UUID{}
+		for _, sale := range sales {
+			if _, ok := bySeller[sale.SellerID]; !ok {
+				sellerIDs = append(sellerIDs, sale.SellerID)
+			}
+			bySeller[sale.SellerID] = append(bySeller[sale.SellerID], payableSale(sale))
+		}
+
+		accounts, err := s.repo.GetAccountsBySellerIDsWithTx(tx, sellerIDs)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		batch := &models.PayoutBatch{
+			ID:          uuid.New(),
+			Status:      models.PayoutBatchStatusPendingApproval,
+			Currency:    s.currency,
+			CreatedByID: adminID,
+		}
+		for _, sellerID := range sellerIDs {
+			payout := models.Payout{
+				ID:       uuid.New(),
+				BatchID:  batch.ID,
+				SellerID: sellerID,
+				Status:   models.PayoutStatusPending,
+			}
+			payout.Reference = payoutReference(payout.ID)
+			payable := false
+			for _, sale := range bySeller[sellerID] {
+				payout.Items = append(payout.Items, models.PayoutItem{TransactionID: sale.TransactionID, Amount: sale.Amount})
+				payout.Amount += sale.Amount
+				payable = payable || sale.Amount > 0
+			}
+			payout.Amount = roundToMinorUnits(payout.Amount)
+
+			account, ok := accounts[sellerID]
+			switch {
+			case !payable:
+				// nothing the bank could be asked for; closing the sales at zero keeps them out of every later batch
+				payout.Amount = 0
+				payout.Status = models.PayoutStatusSettled
+				payout.SettledAt = &now
+			case !ok || payout.Amount <= 0:
+				// the seller has no account yet, or owes more than their sales made; their next sales net against it
+				continue
+			}
+			if ok {
+				payout.BankName = account.BankName
+				payout.BankCode = account.BankCode
+				payout.AccountName = account.AccountName
+				payout.AccountNumberEncrypted = account.AccountNumberEncrypted
+				payout.AccountNumberLast4 = account.AccountNumberLast4
+			}
+
+			batch.Payouts = append(batch.Payouts, payout)
+			if payout.Status == models.PayoutStatusPending {
+				batch.TotalAmount += payout.Amount
+				batch.PayoutCount++
+			}
+		}
+		if batch.PayoutCount == 0 {
+			return fmt.Errorf("nothing to pay out")
+		}
+		batch.TotalAmount = roundToMinorUnits(batch.TotalAmount)
+
+		if err := s.repo.CreateBatchWithTx(tx, batch); err != nil {
+			return err
+		}
+		batchID = batch.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBatchByID(batchID.String())
+}
+
+// GetBatches lists the newest payout batches, optionally only those in one status
+func (s *PayoutService) GetBatches(status models.PayoutBatchStatus, limit int) ([]*models.PayoutBatch, error) {
+	return s.repo.GetBatches(status, limit)
+}
+
+func (s *PayoutService) GetBatch(id string) (*models.PayoutBatch, error) {
+	return s.repo.GetBatchByID(id)
+}
+
+// Approve releases a batch for export to the bank
+func (s *PayoutService) Approve(id string, adminID uuid.UUID) (*models.PayoutBatch, error) {
+	batch, err := s.repo.GetBatchByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetBatchByIDForUpdateWithTx(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.PayoutBatchStatusPendingApproval {
+			return fmt.Errorf("payout batch is %s, only batches pending approval can be approved", locked.Status)
+		}
+
+		now := time.Now()
+		return s.repo.UpdateBatchFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":         models.PayoutBatchStatusApproved,
+			"approved_by_id": adminID,
+			"approved_at":    now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBatchByID(id)
+}
+
+// Cancel drops a batch that has not been settled; its sales are paid out in a later batch
+func (s *PayoutService) Cancel(id string) (*models.PayoutBatch, error) {
+	batch, err := s.repo.GetBatchByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetBatchByIDForUpdateWithTx(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.PayoutBatchStatusPendingApproval && locked.Status != models.PayoutBatchStatusApproved {
+			return fmt.Errorf("payout batch is %s, only batches not settled yet can be cancelled", locked.Status)
+		}
+
+		for _, payout := range locked.Payouts {
+			if payout.Status != models.PayoutStatusPending {
+				// settled at zero when the batch was created; its sales stay closed
+				continue
+			}
+			if err := s.repo.UpdatePayoutFieldsWithTx(tx, payout.ID, map[string]any{"status": models.PayoutStatusCancelled}); err != nil {
+				return err
+			}
+			if err := s.repo.ReleaseItemsWithTx(tx, payout.ID); err != nil {
+				return err
+			}
+		}
+		return s.repo.UpdateBatchFieldsWithTx(tx, locked.ID, map[string]any{"status": models.PayoutBatchStatusCancelled})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBatchByID(id)
+}
+
+// ExportCSV writes an approved batch as a bulk transfer file for the bank, one row per payout the bank has to make
+func (s *PayoutService) ExportCSV(id string, w io.Writer) error {
+	batch, err := s.repo.GetBatchByID(id)
+	if err != nil {
+		return err
+	}
+	if batch.Status != models.PayoutBatchStatusApproved {
+		return fmt.Errorf("payout batch is %s, only approved batches can be exported", batch.Status)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Serial Number", "Beneficiary Name", "Account Number", "Bank Code", "Amount", "Narration", "Reference"}); err != nil {
+		return fmt.Errorf("failed to write payout export: %w", err)
+	}
+	serial := 0
+	for _, payout := range batch.Payouts {
+		if payout.Status != models.PayoutStatusPending {
+			continue
+		}
+		serial++
+		accountNumber, err := s.cipher.Open(payout.AccountNumberEncrypted)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt account number of payout %s: %w", payout.Reference, err)
+		}
+		row := []string{
+			fmt.Sprintf("%d", serial),
+			payout.AccountName,
+			accountNumber,
+			payout.BankCode,
+			fmt.Sprintf("%.2f", payout.Amount),
+			fmt.Sprintf("AutoCity payout %s", payout.Reference),
+			payout.Reference,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write payout export: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write payout export: %w", err)
+	}
+	return nil
+}
+
+// Settle records what the bank did with an approved batch. Payouts listed in failures were rejected, with the bank's
+// reason, and their sales go back to the seller's available balance; every other payout was paid and is posted to the
+// ledger.
+func (s *PayoutService) Settle(id string, adminID uuid.UUID, bankReference string, failures map[uuid.UUID]string) (*models.PayoutBatch, error) {
+	batch, err := s.repo.GetBatchByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetBatchByIDForUpdateWithTx(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.PayoutBatchStatusApproved {
+			return fmt.Errorf("payout batch is %s, only approved batches can be settled", locked.Status)
+		}
+
+		inBatch := map[uuid.UUID]bool{}
+		for _, payout := range locked.Payouts {
+			inBatch[payout.ID] = payout.Status == models.PayoutStatusPending
+		}
+		for payoutID := range failures {
+			if !inBatch[payoutID] {
+				return fmt.Errorf("invalid failed payout: payout %s is not in this batch", payoutID)
+			}
+		}
+
+		now := time.Now()
+		for i := range locked.Payouts {
+			payout := &locked.Payouts[i]
+			if payout.Status != models.PayoutStatusPending {
+				continue
+			}
+			if reason, failed := failures[payout.ID]; failed {
+				if err := s.repo.UpdatePayoutFieldsWithTx(tx, payout.ID, map[string]any{
+					"status":         models.PayoutStatusFailed,
+					"failure_reason": reason,
+				}); err != nil {
+					return err
+				}
+				if err := s.repo.ReleaseItemsWithTx(tx, payout.ID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.repo.UpdatePayoutFieldsWithTx(tx, payout.ID, map[string]any{
+				"status":     models.PayoutStatusSettled,
+				"settled_at": now,
+			}); err != nil {
+				return err
+			}
+			if err := s.ledger.RecordPayoutWithTx(tx, payout); err != nil {
+				return err
+			}
+		}
+
+		return s.repo.UpdateBatchFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":         models.PayoutBatchStatusSettled,
+			"settled_by_id":  adminID,
+			"settled_at":     now,
+			"bank_reference": strings.TrimSpace(bankReference),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settled, err := s.repo.GetBatchByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.notifySettlement(settled)
+	return settled, nil
+}
+
+// notifySettlement tells every seller in a settled batch whether the bank paid them
+func (s *PayoutService) notifySettlement(batch *models.PayoutBatch) {
+	for _, payout := range batch.Payouts {
+		var err error
+		switch {
+		case payout.Status == models.PayoutStatusSettled && payout.Amount > 0:
+			err = email_helper.SendPayoutSettledEmail(payout.Seller.Email, payout.Reference, payout.Amount, payout.AccountNumberLast4, len(payout.Items))
+		case payout.Status == models.PayoutStatusFailed:
+			err = email_helper.SendPayoutFailedEmail(payout.Seller.Email, payout.Reference, payout.Amount, payout.FailureReason)
+		default:
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Warning: Failed to send payout email to %s: %v\n", payout.Seller.Email, err)
+		}
+	}
+}
+
+// payableSale works out what a completed sale or released deposit owes its seller: the amount less refunds and
+// commission
+func payableSale(transaction *models.Transaction) *models.PayableSale {
+	sale := &models.PayableSale{
+		TransactionID: transaction.ID,
+		Type:          transaction.Type,
+		ListingID:     transaction.ListingID,
+		CompletedAt:   transaction.UpdatedAt,
+		SaleAmount:    transaction.Amount,
+		Refunded:      transaction.RefundedAmount,
+	}
+	if transaction.ReleasedAt != nil {
+		sale.CompletedAt = *transaction.ReleasedAt
+	}
+	for _, fee := range transaction.Fees {
+		if fee.Kind == models.FeeKindSaleCommission {
+			sale.Fees += fee.Amount
+		}
+	}
+	sale.Amount = roundToMinorUnits(sale.SaleAmount - sale.Refunded - sale.Fees)
+	return sale
+}
+
+// payoutReference is the narration reference the bank shows on the seller's statement
+func payoutReference(payoutID uuid.UUID) string {
+	return "AUTOCITY-" + strings.ToUpper(strings.ReplaceAll(payoutID.String(), "-", "")[:16])
+}
+
+// validNUBAN checks the check digit of a 10-digit Nigerian account number against its 3-digit bank code
+func validNUBAN(bankCode, accountNumber string) bool {
+	digits := bankCode + accountNumber[:9]
+	sum := 0
+	for i, weight := range nubanWeights {
+		sum += int(digits[i]-'0') * weight
+	}
+	check := (10 - sum%10) % 10
+	return int(accountNumber[9]-'0') == check
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
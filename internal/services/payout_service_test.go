@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+func TestCreateBatchSelection(t *testing.T) {
+	s := newTestServices(t)
+	buyer, admin := s.user(t, types.RoleBuyer), s.user(t, types.RoleAdmin)
+
+	// a sale pays its seller its amount less commission; a commission above the amount is left from before it was capped
+	type sale struct{ amount, commission float64 }
+	tests := []struct {
+		name       string
+		account    bool
+		sales      []sale
+		wantStatus models.PayoutStatus // empty when the seller gets no payout
+		wantAmount float64
+		wantLeft   int // sales still payable after the batch
+	}{
+		{"sales paid in one payout", true, []sale{{100000, 5000}, {250000, 0}}, models.PayoutStatusPending, 345000, 0},
+		{"sale that pays nothing rides along", true, []sale{{100000, 0}, {80000, 80000}}, models.PayoutStatusPending, 100000, 0},
+		{"sale the seller owes on is netted", true, []sale{{100000, 0}, {10000, 40000}}, models.PayoutStatusPending, 70000, 0},
+		{"sale that pays nothing on its own is closed at zero", true, []sale{{80000, 80000}}, models.PayoutStatusSettled, 0, 0},
+		{"closed at zero without a payout account", false, []sale{{80000, 80000}}, models.PayoutStatusSettled, 0, 0},
+		{"no payout account leaves the sales for later", false, []sale{{100000, 0}, {80000, 80000}}, "", 0, 2},
+		{"seller owing more than they made waits", true, []sale{{100000, 0}, {10000, 150000}}, "", 0, 2},
+	}
+
+	sellers := make([]*models.User, len(tests))
+	for i, tt := range tests {
+		sellers[i] = s.user(t, types.RoleSeller)
+		if tt.account {
+			if _, err := s.payouts.SaveAccount(sellers[i].ID, "Test Bank", "100004", "Test Seller", "0123456789"); err != nil {
+				t.Fatalf("SaveAccount: %v", err)
+			}
+		}
+		for _, sl := range tt.sales {
+			s.releasedSale(t, sellers[i], buyer, sl.amount, sl.commission)
+		}
+	}
+
+	batch, err := s.payouts.CreateBatch(admin.ID)
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	bySeller := map[uuid.UUID]models.Payout{}
+	for _, payout := range batch.Payouts {
+		bySeller[payout.SellerID] = payout
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seller := sellers[i]
+			payout, ok := bySeller[seller.ID]
+			switch {
+			case tt.wantStatus == "" && ok:
+				t.Errorf("payout of %v is %s, want none", payout.Amount, payout.Status)
+			case tt.wantStatus != "" && !ok:
+				t.Errorf("no payout, want one of %v %s", tt.wantAmount, tt.wantStatus)
+			case ok:
+				if payout.Status != tt.wantStatus || payment.ToMinorUnits(payout.Amount) != payment.ToMinorUnits(tt.wantAmount) {
+					t.Errorf("payout of %v is %s, want %v %s", payout.Amount, payout.Status, tt.wantAmount, tt.wantStatus)
+				}
+				if len(payout.Items) != len(tt.sales) {
+					t.Errorf("payout has %d items, want every one of the %d sales", len(payout.Items), len(tt.sales))
+				}
+			}
+
+			left, err := s.payouts.repo.GetPayableSalesWithTx(s.db, &seller.ID)
+			if err != nil {
+				t.Fatalf("GetPayableSalesWithTx: %v", err)
+			}
+			if len(left) != tt.wantLeft {
+				t.Errorf("%d sales still payable, want %d", len(left), tt.wantLeft)
+			}
+		})
+	}
+
+	// only the payouts the bank has to make are exported, settled and counted
+	if _, err := s.payouts.Approve(batch.ID.String(), admin.ID); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	var export bytes.Buffer
+	if err := s.payouts.ExportCSV(batch.ID.String(), &export); err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	pending := 0
+	for _, payout := range batch.Payouts {
+		exported := strings.Contains(export.String(), payout.Reference)
+		if exported != (payout.Status == models.PayoutStatusPending) {
+			t.Errorf("payout %s of %v (%s) exported = %v", payout.Reference, payout.Amount, payout.Status, exported)
+		}
+		if payout.Status == models.PayoutStatusPending {
+			pending++
+		}
+	}
+	if batch.PayoutCount != pending {
+		t.Errorf("batch payout count = %d, want the %d payouts sent to the bank", batch.PayoutCount, pending)
+	}
+
+	settled, err := s.payouts.Settle(batch.ID.String(), admin.ID, "BANK-REF", nil)
+	if err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	for _, payout := range settled.Payouts {
+		if payout.Status != models.PayoutStatusSettled {
+			t.Errorf("payout %s is %s after settlement, want %s", payout.Reference, payout.Status, models.PayoutStatusSettled)
+		}
+	}
+}
+
+// releasedSale records a completed sale of a new listing whose money was released to the seller, with the commission
+// it was charged
+func (s *testServices) releasedSale(t *testing.T, seller, buyer *models.User, amount, commission float64) *models.Transaction {
+	t.Helper()
+
+	listing := s.listing(t, seller, amount)
+	if err := s.db.Model(listing).Update("status", models.ListingStatusSold).Error; err != nil {
+		t.Fatalf("failed to mark listing sold: %v", err)
+	}
+
+	now := time.Now()
+	transaction := &models.Transaction{
+		ListingID:       listing.ID,
+		Type:            models.TransactionTypeSale,
+		BuyerID:         buyer.ID,
+		SellerID:        seller.ID,
+		Amount:          amount,
+		Status:          models.TransactionStatusCompleted,
+		TransactionDate: now,
+		EscrowStatus:    models.EscrowStatusReleased,
+		FundsHeldAt:     &now,
+		ReleasedAt:      &now,
+	}
+	if err := s.db.Create(transaction).Error; err != nil {
+		t.Fatalf("failed to create sale: %v", err)
+	}
+	if commission > 0 {
+		fee := &models.TransactionFee{
+			TransactionID: transaction.ID,
+			Kind:          models.FeeKindSaleCommission,
+			PayerID:       seller.ID,
+			Description:   "Sale commission",
+			BaseAmount:    amount,
+			GrossAmount:   commission,
+			Amount:        commission,
+		}
+		if err := s.db.Create(fee).Error; err != nil {
+			t.Fatalf("failed to create commission: %v", err)
+		}
+	}
+	return transaction
+}
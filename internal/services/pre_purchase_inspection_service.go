@@ -0,0 +1,619 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/storage"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// unpaidInspectionBatch bounds how many bookings with an unpaid fee one run of the background job releases
+const unpaidInspectionBatch = 100
+
+// PrePurchaseInspectionService runs buyer-ordered inspections of active listings: seller consent, scheduling on
+// the shared inspector slots, the fee transaction and private delivery of the report to the buyer. The fee is paid
+// through the payment provider like a sale: it is held in escrow once captured, earned when the report is delivered
+// and refunded if the inspection is called off.
+type PrePurchaseInspectionService struct {
+	requestRepo     *repositories.InspectionRequestRepository
+	listingRepo     *repositories.ListingRepository
+	inspectionRepo  *repositories.InspectionRepository
+	slotRepo        *repositories.InspectionSlotRepository
+	transactionRepo *repositories.TransactionRepository
+	templateRepo    *repositories.ChecklistTemplateRepository
+	transactions    *TransactionService
+	fees            *FeeService
+	fileStorage     storage.Storage
+	changeCutoff    time.Duration
+}
+
+func NewPrePurchaseInspectionService(requestRepo *repositories.InspectionRequestRepository, listingRepo *repositories.ListingRepository, inspectionRepo *repositories.InspectionRepository, slotRepo *repositories.InspectionSlotRepository, transactionRepo *repositories.TransactionRepository, templateRepo *repositories.ChecklistTemplateRepository, transactions *TransactionService, fees *FeeService, fileStorage storage.Storage, changeCutoff time.Duration) *PrePurchaseInspectionService {
+	return &PrePurchaseInspectionService{
+		requestRepo:     requestRepo,
+		listingRepo:     listingRepo,
+		inspectionRepo:  inspectionRepo,
+		slotRepo:        slotRepo,
+		transactionRepo: transactionRepo,
+		templateRepo:    templateRepo,
+		transactions:    transactions,
+		fees:            fees,
+		fileStorage:     fileStorage,
+		changeCutoff:    changeCutoff,
+	}
+}
+
+// RequestInspection records a buyer's order for an inspection of an active listing and asks the seller for consent
+func (s *PrePurchaseInspectionService) RequestInspection(listingID string, buyerID uuid.UUID, note string) (*models.InspectionRequest, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Status != models.ListingStatusActive {
+		return nil, fmt.Errorf("only active listings can be inspected before purchase")
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot request an inspection of your own listing")
+	}
+
+	// the quote may change with the fee schedule until the inspection is booked
+	quote, err := s.fees.QuoteInspectionFee(listing.Price, buyerID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.InspectionRequest{
+		ListingID: listing.ID,
+		BuyerID:   buyerID,
+		SellerID:  listing.SellerID,
+		Status:    models.InspectionRequestStatusAwaitingConsent,
+		Note:      note,
+		Fee:       quote,
+	}
+
+	err = s.requestRepo.DB.Transaction(func(tx *gorm.DB) error {
+		// serialise requests on the listing so a double submit cannot open two
+		if _, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID); err != nil {
+			return err
+		}
+
+		open, err := s.requestRepo.CountOpenByListingAndBuyerWithTx(tx, listing.ID, buyerID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("you already have an open inspection request for this listing")
+		}
+
+		return s.requestRepo.CreateWithTx(tx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := email_helper.SendInspectionRequestEmail(listing.Seller.Email, listing.Title, note); err != nil {
+		fmt.Printf("Warning: Failed to send inspection request email to seller %s: %v\n", listing.Seller.Email, err)
+	}
+
+	return s.requestRepo.GetByID(request.ID.String())
+}
+
+// RespondToRequest records the seller's consent to, or refusal of, a buyer's inspection request
+func (s *PrePurchaseInspectionService) RespondToRequest(id string, sellerID uuid.UUID, consent bool, reason string) (*models.InspectionRequest, error) {
+	request, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.SellerID != sellerID {
+		return nil, fmt.Errorf("unauthorized: you can only respond to requests for your own listings")
+	}
+
+	err = s.requestRepo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.requestRepo.GetByIDForUpdateWithTx(tx, request.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.InspectionRequestStatusAwaitingConsent {
+			return fmt.Errorf("inspection request is not awaiting your consent")
+		}
+
+		status := models.InspectionRequestStatusConsented
+		if !consent {
+			status = models.InspectionRequestStatusDeclined
+		}
+		return s.requestRepo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":              status,
+			"seller_responded_at": time.Now(),
+			"decline_reason":      reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := email_helper.SendInspectionRequestResponseEmail(request.Buyer.Email, request.Listing.Title, consent, reason); err != nil {
+		fmt.Printf("Warning: Failed to send inspection request response email to buyer %s: %v\n", request.Buyer.Email, err)
+	}
+
+	return s.requestRepo.GetByID(id)
+}
+
+// ScheduleInspection books a slot for a consented request, creating the pre-purchase inspection and its fee transaction,
+// and starts the buyer's payment of the fee. The booking is released again if the fee is not paid by the payment
+// deadline, or by the start of the slot if that is sooner.
+func (s *PrePurchaseInspectionService) ScheduleInspection(id string, buyerID uuid.UUID, slotID uuid.UUID, paymentMethod string) (*models.InspectionRequest, error) {
+	request, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.BuyerID != buyerID {
+		return nil, fmt.Errorf("unauthorized: you can only schedule your own inspection requests")
+	}
+
+	var scheduledFor time.Time
+	var fee *models.Transaction
+	err = s.requestRepo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.requestRepo.GetByIDForUpdateWithTx(tx, request.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.InspectionRequestStatusConsented {
+			return fmt.Errorf("only requests the seller has consented to can be scheduled")
+		}
+
+		listing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, locked.ListingID)
+		if err != nil {
+			return err
+		}
+		if listing.Status != models.ListingStatusActive {
+			return fmt.Errorf("only active listings can be inspected before purchase")
+		}
+
+		slot, err := claimInspectionSlotWithTx(tx, s.slotRepo, slotID)
+		if err != nil {
+			return err
+		}
+		scheduledFor = slot.StartsAt
+
+		assignedAt := time.Now()
+		inspection := &models.Inspection{
+			ListingID:      locked.ListingID,
+			InspectorID:    slot.InspectorID,
+			AssignedAt:     &assignedAt,
+			InspectionDate: slot.StartsAt,
+			Status:         models.InspectionStatusPending,
+			Type:           models.InspectionTypePrePurchase,
+			RequestedByID:  &locked.BuyerID,
+		}
+		if err := s.inspectionRepo.CreateWithTx(tx, inspection); err != nil {
+			return err
+		}
+
+		feeItem, err := s.fees.InspectionFeeWithTx(tx, listing.Price, locked.BuyerID)
+		if err != nil {
+			return err
+		}
+		fee = &models.Transaction{
+			ListingID:    locked.ListingID,
+			Type:         models.TransactionTypeInspectionFee,
+			InspectionID: &inspection.ID,
+			BuyerID:      locked.BuyerID,
+			SellerID:     locked.SellerID,
+			Amount:       feeItem.Amount,
+			Status:       models.TransactionStatusPending,
+		}
+		// a fee waived in full has nothing to pay
+		if fee.Amount > 0 {
+			paymentDeadline := time.Now().Add(s.transactions.windows.Payment)
+			if slot.StartsAt.Before(paymentDeadline) {
+				paymentDeadline = slot.StartsAt
+			}
+			fee.PaymentMethod = paymentMethod
+			fee.EscrowStatus = models.EscrowStatusAwaitingPayment
+			fee.EscrowDeadline = &paymentDeadline
+		}
+		if err := s.transactionRepo.CreateWithTx(tx, fee); err != nil {
+			return err
+		}
+		if err := s.fees.RecordFeesWithTx(tx, fee.ID, feeItem); err != nil {
+			return err
+		}
+
+		return s.requestRepo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":         models.InspectionRequestStatusScheduled,
+			"slot_id":        slot.ID,
+			"scheduled_for":  slot.StartsAt,
+			"inspection_id":  inspection.ID,
+			"transaction_id": fee.ID,
+			"fee":            fee.Amount,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkoutURL := ""
+	if fee.EscrowStatus == models.EscrowStatusAwaitingPayment {
+		started, err := s.transactions.startPayment(fee.ID, &request.Listing, paymentMethod)
+		if err != nil {
+			// the fee was marked failed, so the slot is freed now rather than by the background job
+			if _, releaseErr := s.releaseUnpaid(request.ID, "the inspection fee payment could not be started"); releaseErr != nil {
+				fmt.Printf("Warning: Failed to release inspection request %s: %v\n", request.ID, releaseErr)
+			}
+			return nil, err
+		}
+		checkoutURL = started.CheckoutURL
+	}
+
+	if err := email_helper.SendPrePurchaseInspectionScheduledEmail(request.Seller.Email, request.Listing.Title, scheduledFor); err != nil {
+		fmt.Printf("Warning: Failed to send pre-purchase inspection schedule email to seller %s: %v\n", request.Seller.Email, err)
+	}
+
+	scheduled, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	scheduled.FeeCheckoutURL = checkoutURL
+	return scheduled, nil
+}
+
+// CancelRequest lets the buyer or seller call off an open request; a scheduled one frees its slot and cancels the fee,
+// refunding it if it was paid
+func (s *PrePurchaseInspectionService) CancelRequest(id string, userID uuid.UUID, reason string) error {
+	request, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if request.BuyerID != userID && request.SellerID != userID {
+		return fmt.Errorf("unauthorized: you can only cancel your own inspection requests")
+	}
+
+	var refunds []*models.Refund
+	err = s.requestRepo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.requestRepo.GetByIDForUpdateWithTx(tx, request.ID)
+		if err != nil {
+			return err
+		}
+		if !locked.IsOpen() {
+			return fmt.Errorf("inspection request can no longer be cancelled")
+		}
+		if locked.Status == models.InspectionRequestStatusScheduled && locked.ScheduledFor != nil && time.Until(*locked.ScheduledFor) < s.changeCutoff {
+			return fmt.Errorf("inspection can no longer be changed within %s of the appointment", s.changeCutoff)
+		}
+
+		refunds, err = s.unscheduleWithTx(tx, locked, true, "inspection request cancelled")
+		if err != nil {
+			return err
+		}
+
+		return s.requestRepo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+			"status":       models.InspectionRequestStatusCancelled,
+			"cancelled_at": time.Now(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.transactions.refunds.issue(refunds...)
+
+	// tell the other party
+	recipient := request.Seller.Email
+	if userID == request.SellerID {
+		recipient = request.Buyer.Email
+	}
+	if reason == "" {
+		reason = "cancelled on request"
+	}
+	if err := email_helper.SendInspectionRequestCancelledEmail(recipient, request.Listing.Title, reason); err != nil {
+		fmt.Printf("Warning: Failed to send inspection request cancellation email to %s: %v\n", recipient, err)
+	}
+
+	return nil
+}
+
+// releaseSlotWithTx is called when an inspector withdraws a slot: a request scheduled on it goes back to
+// consented so the buyer can pick another slot. The returned request, if any, should be notified, and the returned
+// refunds of a paid fee issued once the caller's database transaction has committed.
+func (s *PrePurchaseInspectionService) releaseSlotWithTx(tx *gorm.DB, slotID uuid.UUID) (*models.InspectionRequest, []*models.Refund, error) {
+	request, err := s.requestRepo.GetScheduledBySlotIDWithTx(tx, slotID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	// the slot itself is being cancelled by the caller
+	refunds, err := s.unscheduleWithTx(tx, request, false, "inspector unavailable")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.reopenWithTx(tx, request.ID); err != nil {
+		return nil, nil, err
+	}
+	return request, refunds, nil
+}
+
+// ReleaseUnpaidInspections is the background job freeing the slots of scheduled inspections whose fee was not paid
+// by its deadline or whose payment failed; the buyer can book another slot and pay again
+func (s *PrePurchaseInspectionService) ReleaseUnpaidInspections() error {
+	requests, err := s.requestRepo.GetScheduledWithUnpaidFee(time.Now(), unpaidInspectionBatch)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, request := range requests {
+		released, err := s.releaseUnpaid(request.ID, "the inspection fee was not paid")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inspection request %s: %w", request.ID, err))
+			continue
+		}
+		if released == nil {
+			continue
+		}
+
+		if err := email_helper.SendInspectionRequestCancelledEmail(released.Buyer.Email, released.Listing.Title, "the inspection fee was not paid, please book another slot and pay to keep it"); err != nil {
+			fmt.Printf("Warning: Failed to send inspection cancellation email to buyer %s: %v\n", released.Buyer.Email, err)
+		}
+		if err := email_helper.SendInspectionRequestCancelledEmail(released.Seller.Email, released.Listing.Title, "the buyer did not pay the inspection fee"); err != nil {
+			fmt.Printf("Warning: Failed to send inspection cancellation email to seller %s: %v\n", released.Seller.Email, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releaseUnpaid sends a scheduled request whose fee can no longer be paid in time back to consented, freeing its
+// slot. It returns the released request, or nil if the buyer paid in the meantime.
+func (s *PrePurchaseInspectionService) releaseUnpaid(id uuid.UUID, reason string) (*models.InspectionRequest, error) {
+	released := false
+	err := s.requestRepo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.requestRepo.GetByIDForUpdateWithTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.InspectionRequestStatusScheduled || locked.TransactionID == nil {
+			return nil
+		}
+
+		fee, err := s.transactionRepo.GetByIDForUpdateWithTx(tx, *locked.TransactionID)
+		if err != nil {
+			return err
+		}
+		unpaid := fee.Status == models.TransactionStatusFailed || fee.Status == models.TransactionStatusCancelled ||
+			(fee.EscrowStatus == models.EscrowStatusAwaitingPayment && fee.EscrowDeadline != nil && !fee.EscrowDeadline.After(time.Now()))
+		if !unpaid {
+			return nil
+		}
+
+		// an unpaid fee has nothing to refund
+		if _, err := s.unscheduleWithTx(tx, locked, true, reason); err != nil {
+			return err
+		}
+		released = true
+		return s.reopenWithTx(tx, locked.ID)
+	})
+	if err != nil || !released {
+		return nil, err
+	}
+	return s.requestRepo.GetByID(id.String())
+}
+
+// reopenWithTx sends an unscheduled request back to consented so the buyer can book another slot
+func (s *PrePurchaseInspectionService) reopenWithTx(tx *gorm.DB, id uuid.UUID) error {
+	return s.requestRepo.UpdateFieldsWithTx(tx, id, map[string]any{
+		"status":         models.InspectionRequestStatusConsented,
+		"slot_id":        nil,
+		"scheduled_for":  nil,
+		"inspection_id":  nil,
+		"transaction_id": nil,
+	})
+}
+
+// unscheduleWithTx undoes what ScheduleInspection created for a request, optionally reopening its slot, and returns
+// the refund of its fee if it was paid, to be issued once the database transaction has committed
+func (s *PrePurchaseInspectionService) unscheduleWithTx(tx *gorm.DB, request *models.InspectionRequest, reopenSlot bool, reason string) ([]*models.Refund, error) {
+	if request.Status != models.InspectionRequestStatusScheduled {
+		return nil, nil
+	}
+
+	if reopenSlot && request.SlotID != nil {
+		if err := s.slotRepo.UpdateStatusWithTx(tx, *request.SlotID, models.InspectionSlotStatusAvailable); err != nil {
+			return nil, err
+		}
+	}
+	var refunds []*models.Refund
+	if request.TransactionID != nil {
+		var err error
+		if refunds, err = s.cancelFeeWithTx(tx, *request.TransactionID, reason); err != nil {
+			return nil, err
+		}
+	}
+	// nothing has been inspected yet, so the placeholder inspection is dropped rather than kept as history
+	if request.InspectionID != nil {
+		if err := s.inspectionRepo.DeleteWithTx(tx, *request.InspectionID); err != nil {
+			return nil, err
+		}
+	}
+	return refunds, nil
+}
+
+// completeWithTx delivers a pre-purchase inspection once its findings are in: the inspection and request are
+// completed and the fee is settled. It returns the request so the buyer can be notified after commit.
+func (s *PrePurchaseInspectionService) completeWithTx(tx *gorm.DB, inspectionID uuid.UUID) (*models.InspectionRequest, error) {
+	request, err := s.requestRepo.GetByInspectionIDWithTx(tx, inspectionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no inspection request found for inspection %s", inspectionID.String())
+		}
+		return nil, err
+	}
+
+	if err := s.inspectionRepo.UpdateWithTx(tx, &models.Inspection{ID: inspectionID, Status: models.InspectionStatusCompleted}); err != nil {
+		return nil, err
+	}
+	if request.TransactionID != nil {
+		if err := s.settleFeeWithTx(tx, *request.TransactionID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.requestRepo.UpdateFieldsWithTx(tx, request.ID, map[string]any{
+		"status":       models.InspectionRequestStatusCompleted,
+		"completed_at": time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// settleFeeWithTx completes the fee of a delivered inspection. A paid fee is released from escrow, which is when it is
+// earned; an unpaid one keeps the inspection from being delivered.
+func (s *PrePurchaseInspectionService) settleFeeWithTx(tx *gorm.DB, transactionID uuid.UUID) error {
+	fee, err := s.transactionRepo.GetByIDForUpdateWithTx(tx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fee.Status == models.TransactionStatusCompleted:
+		return nil
+	case fee.EscrowStatus == models.EscrowStatusFundsHeld:
+		return s.transactions.transitionWithTx(tx, fee, transactionChange{
+			Status: models.TransactionStatusCompleted,
+			Escrow: models.EscrowStatusReleased,
+			Fields: map[string]any{"released_at": time.Now()},
+			Reason: "inspection delivered",
+			Amount: fee.Amount,
+		})
+	case fee.Status == models.TransactionStatusPending && fee.EscrowStatus == "":
+		// a fee waived in full has nothing to collect
+		return s.transactions.transitionWithTx(tx, fee, transactionChange{
+			Status: models.TransactionStatusCompleted,
+			Reason: "inspection delivered",
+		})
+	default:
+		return fmt.Errorf("the inspection fee has not been paid yet")
+	}
+}
+
+// cancelFeeWithTx cancels the fee of an inspection that was called off and returns the refund of a paid one
+func (s *PrePurchaseInspectionService) cancelFeeWithTx(tx *gorm.DB, transactionID uuid.UUID, reason string) ([]*models.Refund, error) {
+	fee, err := s.transactionRepo.GetByIDForUpdateWithTx(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Status != models.TransactionStatusPending {
+		return nil, nil
+	}
+
+	if fee.EscrowStatus == models.EscrowStatusFundsHeld {
+		return s.transactions.refundWithTx(tx, fee, fee.Amount, nil, reason)
+	}
+	change := transactionChange{Status: models.TransactionStatusCancelled, Reason: reason}
+	if fee.EscrowStatus == models.EscrowStatusAwaitingPayment {
+		// a payment arriving after this is refunded by the webhook
+		change.Escrow = models.EscrowStatusCancelled
+	}
+	return nil, s.transactions.transitionWithTx(tx, fee, change)
+}
+
+// notifyReportReady emails the buyer that their report can be downloaded
+func (s *PrePurchaseInspectionService) notifyReportReady(request *models.InspectionRequest, conditionRating int) {
+	fullRequest, err := s.requestRepo.GetByID(request.ID.String())
+	if err != nil {
+		fmt.Printf("Warning: Failed to load inspection request %s for report notification: %v\n", request.ID.String(), err)
+		return
+	}
+	if err := email_helper.SendPrePurchaseReportReadyEmail(fullRequest.Buyer.Email, fullRequest.Listing.Title, conditionRating); err != nil {
+		fmt.Printf("Warning: Failed to send report ready email to buyer %s: %v\n", fullRequest.Buyer.Email, err)
+	}
+}
+
+// GetRequest retrieves an inspection request for its buyer, the listing's seller or an admin. The buyer also gets the
+// checkout of a fee they still have to pay.
+func (s *PrePurchaseInspectionService) GetRequest(id string, userID uuid.UUID, role types.Role) (*models.InspectionRequest, error) {
+	request, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role != types.RoleAdmin && request.BuyerID != userID && request.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view your own inspection requests")
+	}
+
+	if request.BuyerID == userID && request.Status == models.InspectionRequestStatusScheduled && request.TransactionID != nil {
+		fee, err := s.transactionRepo.GetByID(request.TransactionID.String())
+		if err != nil {
+			return nil, err
+		}
+		if fee.EscrowStatus == models.EscrowStatusAwaitingPayment {
+			request.FeeCheckoutURL = fee.CheckoutURL
+		}
+	}
+	return request, nil
+}
+
+// GetBuyerRequests retrieves the inspection requests a buyer has made
+func (s *PrePurchaseInspectionService) GetBuyerRequests(buyerID uuid.UUID) ([]*models.InspectionRequest, error) {
+	return s.requestRepo.GetByBuyerID(buyerID)
+}
+
+// GetListingRequests retrieves the inspection requests made for a listing, for its seller or an admin
+func (s *PrePurchaseInspectionService) GetListingRequests(listingID string, userID uuid.UUID, role types.Role) ([]*models.InspectionRequest, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if role != types.RoleAdmin && listing.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view inspection requests for your own listings")
+	}
+	return s.requestRepo.GetByListingID(listing.ID)
+}
+
+// GetReport renders the PDF report of a completed pre-purchase inspection. It is never stored publicly:
+// only the buyer who ordered it, or an admin, can download it.
+func (s *PrePurchaseInspectionService) GetReport(id string, userID uuid.UUID, role types.Role) ([]byte, error) {
+	request, err := s.requestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role != types.RoleAdmin && request.BuyerID != userID {
+		return nil, fmt.Errorf("unauthorized: only the buyer who requested the inspection can view its report")
+	}
+	if request.Status != models.InspectionRequestStatusCompleted || request.InspectionID == nil {
+		return nil, fmt.Errorf("inspection report is not available yet")
+	}
+
+	rawInspection, err := s.inspectionRepo.GetByID(request.InspectionID.String())
+	if err != nil {
+		return nil, err
+	}
+	inspection, err := rawInspection.ParseInspectionInputToModel()
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := s.listingRepo.GetByID(inspection.ListingID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var template *models.ChecklistTemplate
+	if inspection.ChecklistTemplateID != nil {
+		template, err = s.templateRepo.GetByID(inspection.ChecklistTemplateID.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := renderInspectionReport(inspection, listing, template, nil, "", s.fileStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render inspection report: %w", err)
+	}
+	return report, nil
+}
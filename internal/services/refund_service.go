@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxRefundAttempts is how often a refund is sent to the provider before it is left to an admin
+	maxRefundAttempts = 5
+	// refundRetryBatch bounds how many refunds a single retry run sends
+	refundRetryBatch = 100
+	// refundRetryDelay leaves a new refund to the request that recorded it before the background retry picks it up
+	refundRetryDelay = time.Minute
+)
+
+// RefundService returns money to buyers through the payment provider. A refund is recorded together with the
+// decision that caused it and only sent once that database transaction has committed, so no row lock is ever held
+// over the network; the provider sees the same idempotency key on every attempt, so retries cannot refund twice.
+type RefundService struct {
+	repo     *repositories.RefundRepository
+	provider payment.Provider
+}
+
+func NewRefundService(repo *repositories.RefundRepository, provider payment.Provider) *RefundService {
+	return &RefundService{
+		repo:     repo,
+		provider: provider,
+	}
+}
+
+// queueWithTx records a pending refund of amount minor units of a transaction's captured payment
+func (s *RefundService) queueWithTx(tx *gorm.DB, transaction *models.Transaction, amount int64, reason string, webhookEventID *uuid.UUID) (*models.Refund, error) {
+	if transaction.PaymentProvider != s.provider.Name() {
+		return nil, fmt.Errorf("payment provider %s of this transaction is not configured", transaction.PaymentProvider)
+	}
+
+	refund := &models.Refund{
+		TransactionID:     transaction.ID,
+		WebhookEventID:    webhookEventID,
+		Provider:          transaction.PaymentProvider,
+		ProviderReference: transaction.ProviderReference,
+		Amount:            amount,
+		Reason:            reason,
+		Status:            models.RefundStatusPending,
+	}
+	if err := s.repo.CreateWithTx(tx, refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// issue sends refunds recorded by a committed database transaction to the provider. A refund the provider could not
+// be reached for stays pending and is sent again by the background retry.
+func (s *RefundService) issue(refunds ...*models.Refund) {
+	for _, refund := range refunds {
+		if err := s.send(refund); err != nil {
+			fmt.Printf("Warning: refund %s of transaction %s was not issued yet: %v\n", refund.ID, refund.TransactionID, err)
+		}
+	}
+}
+
+// send asks the provider for a pending refund and records the outcome
+func (s *RefundService) send(refund *models.Refund) error {
+	if refund.Provider != s.provider.Name() {
+		return s.fail(refund, fmt.Errorf("payment provider %s of this refund is not configured", refund.Provider), true)
+	}
+
+	result, err := s.provider.Refund(refund.ProviderReference, refund.Amount, refund.Reason, refund.IdempotencyKey)
+	if err != nil {
+		return s.fail(refund, fmt.Errorf("payment provider could not refund the payment: %w", err), refund.Attempts+1 >= maxRefundAttempts)
+	}
+	if result.Status == payment.StatusFailed {
+		return s.fail(refund, fmt.Errorf("payment provider declined the refund"), true)
+	}
+
+	_, err = s.repo.UpdateIfStatus(refund.ID, models.RefundStatusPending, map[string]any{
+		"status":           models.RefundStatusIssued,
+		"refund_reference": result.RefundReference,
+		"error":            "",
+		"attempts":         gorm.Expr("attempts + 1"),
+		"issued_at":        time.Now(),
+	})
+	return err
+}
+
+// fail records an attempt that did not issue the refund, giving up on it when final
+func (s *RefundService) fail(refund *models.Refund, cause error, final bool) error {
+	fields := map[string]any{
+		"error":    cause.Error(),
+		"attempts": gorm.Expr("attempts + 1"),
+	}
+	if final {
+		fields["status"] = models.RefundStatusFailed
+	}
+	if _, err := s.repo.UpdateIfStatus(refund.ID, models.RefundStatusPending, fields); err != nil {
+		return err
+	}
+	return cause
+}
+
+// RetryPending is the background job sending the refunds that were recorded but not issued yet
+func (s *RefundService) RetryPending() error {
+	refunds, err := s.repo.GetRetryable(maxRefundAttempts, time.Now().Add(-refundRetryDelay), refundRetryBatch)
+	if err != nil {
+		return err
+	}
+	s.issue(refunds...)
+	return nil
+}
+
+// GetRefunds lists the newest refunds, optionally in one status
+func (s *RefundService) GetRefunds(status models.RefundStatus, limit int) ([]*models.Refund, error) {
+	return s.repo.GetAll(status, limit)
+}
+
+func (s *RefundService) GetRefund(id string) (*models.Refund, error) {
+	return s.repo.GetByID(id)
+}
+
+// Retry sends a failed refund again with fresh attempts, under its original idempotency key
+func (s *RefundService) Retry(id string) (*models.Refund, error) {
+	refund, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != models.RefundStatusFailed {
+		return nil, fmt.Errorf("only failed refunds can be retried")
+	}
+
+	reopened, err := s.repo.UpdateIfStatus(refund.ID, models.RefundStatusFailed, map[string]any{
+		"status":   models.RefundStatusPending,
+		"attempts": 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !reopened {
+		return nil, fmt.Errorf("only failed refunds can be retried")
+	}
+
+	refund.Status, refund.Attempts = models.RefundStatusPending, 0
+	if err := s.send(refund); err != nil {
+		fmt.Printf("Warning: refund %s of transaction %s was not issued on retry: %v\n", refund.ID, refund.TransactionID, err)
+	}
+	return s.repo.GetByID(id)
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+)
+
+type RejectionReasonService struct {
+	repo *repositories.RejectionReasonRepository
+}
+
+func NewRejectionReasonService(repo *repositories.RejectionReasonRepository) *RejectionReasonService {
+	return &RejectionReasonService{
+		repo: repo,
+	}
+}
+
+// CreateReason adds a new rejection reason code to the list admins choose from
+func (s *RejectionReasonService) CreateReason(reason *models.RejectionReason) (*models.RejectionReason, error) {
+	reason.Code = strings.ToLower(strings.TrimSpace(reason.Code))
+
+	if _, err := s.repo.GetByCode(reason.Code); err == nil {
+		return nil, fmt.Errorf("rejection reason '%s' already exists", reason.Code)
+	}
+
+	reason.Active = true
+	if err := s.repo.Create(reason); err != nil {
+		return nil, err
+	}
+	return reason, nil
+}
+
+// GetReasons retrieves the rejection reason codes; deactivated codes are only listed on request
+func (s *RejectionReasonService) GetReasons(includeInactive bool) ([]*models.RejectionReason, error) {
+	return s.repo.GetAll(includeInactive)
+}
+
+// UpdateReason changes the wording of a rejection reason or (de)activates it; the code itself is fixed
+func (s *RejectionReasonService) UpdateReason(code string, label string, description *string, active *bool) (*models.RejectionReason, error) {
+	reason, err := s.repo.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if label != "" {
+		reason.Label = label
+	}
+	if description != nil {
+		reason.Description = *description
+	}
+	if active != nil {
+		reason.Active = *active
+	}
+
+	if err := s.repo.Update(reason); err != nil {
+		return nil, err
+	}
+	return reason, nil
+}
+
+// ResolveActive turns the codes chosen by an admin into the details recorded on the rejection.
+// Every code must exist and be active; duplicates are collapsed.
+func (s *RejectionReasonService) ResolveActive(codes []string) ([]models.RejectionDetail, error) {
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("at least one rejection reason is required")
+	}
+
+	reasons, err := s.repo.GetByCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*models.RejectionReason, len(reasons))
+	for _, reason := range reasons {
+		byCode[reason.Code] = reason
+	}
+
+	details := make([]models.RejectionDetail, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		reason, ok := byCode[code]
+		if !ok || !reason.Active {
+			return nil, fmt.Errorf("unknown rejection reason code '%s'", code)
+		}
+		details = append(details, models.RejectionDetail{
+			Code:        reason.Code,
+			Label:       reason.Label,
+			Description: reason.Description,
+		})
+	}
+	return details, nil
+}
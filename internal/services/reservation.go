@@ -0,0 +1,219 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Reserve holds an active listing for a buyer arranging financing. The listing is reserved straight away, under its
+// row lock so two buyers cannot reserve it together, and the buyer pays a deposit into escrow:
+//
+//	deposit unpaid within the payment window   the reservation ends and the listing goes back on sale
+//	deposit paid                               the listing stays reserved for the reservation window
+//	buyer purchases during the hold            the deposit is credited against the price and paid to the seller with the sale
+//	hold ends without a purchase               the deposit is refunded and the listing goes back on sale
+//
+// Nobody else can buy, or make offers on, a reserved listing.
+func (s *TransactionService) Reserve(listingID string, buyerID uuid.UUID, paymentMethod string) (*models.Transaction, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot reserve your own listing")
+	}
+
+	paymentDeadline := time.Now().Add(s.windows.Payment)
+	deposit := &models.Transaction{
+		ListingID:      listing.ID,
+		Type:           models.TransactionTypeDeposit,
+		BuyerID:        buyerID,
+		SellerID:       listing.SellerID,
+		PaymentMethod:  paymentMethod,
+		Status:         models.TransactionStatusPending,
+		EscrowStatus:   models.EscrowStatusAwaitingPayment,
+		EscrowDeadline: &paymentDeadline,
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status == models.ListingStatusReserved {
+			return fmt.Errorf("listing is already reserved")
+		}
+		if lockedListing.Status != models.ListingStatusActive {
+			return fmt.Errorf("only active listings can be reserved")
+		}
+
+		open, err := s.repo.CountOpenSalesByListingIDWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("listing already has a purchase in progress")
+		}
+
+		deposit.Amount = roundToMinorUnits(lockedListing.Price * s.depositPercent / 100)
+		if deposit.Amount <= 0 {
+			return fmt.Errorf("reservations are not available")
+		}
+		if err := s.repo.CreateWithTx(tx, deposit); err != nil {
+			return err
+		}
+		err = s.repo.CreateEventWithTx(tx, &models.TransactionEvent{
+			TransactionID: deposit.ID,
+			ToStatus:      deposit.Status,
+			ToEscrow:      deposit.EscrowStatus,
+			ActorID:       &buyerID,
+			Reason:        "reservation started",
+			Amount:        deposit.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.listingRepo.ChangeStatusWithTx(tx, &models.ListingStatusChange{
+			ListingID:   listing.ID,
+			FromStatus:  lockedListing.Status,
+			ToStatus:    models.ListingStatusReserved,
+			ChangedByID: buyerID,
+			Reason:      fmt.Sprintf("reserved through deposit %s", deposit.ID),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startPayment(deposit.ID, listing, paymentMethod)
+}
+
+// CancelReservation ends a reservation early and puts the listing back on sale. The reserving buyer, the seller or
+// an admin can cancel; a paid deposit is refunded in full. A reservation credited to a sale in progress can only end
+// with that sale.
+func (s *TransactionService) CancelReservation(listingID string, userID uuid.UUID, userRole types.Role, reason string) (*models.Transaction, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deposit *models.Transaction
+	var refunds []*models.Refund
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		deposit, err = s.repo.GetOpenDepositByListingIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if deposit == nil {
+			return fmt.Errorf("listing is not reserved")
+		}
+		if userRole != types.RoleAdmin && deposit.BuyerID != userID && deposit.SellerID != userID {
+			return fmt.Errorf("unauthorized: only the reserving buyer, the seller or an admin can cancel a reservation")
+		}
+
+		open, err := s.repo.CountOpenSalesByListingIDWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if open > 0 || lockedListing.Status != models.ListingStatusReserved {
+			return fmt.Errorf("reservation is credited to a sale in progress")
+		}
+
+		if deposit.EscrowStatus == models.EscrowStatusFundsHeld {
+			refunds, err = s.refundWithTx(tx, deposit, deposit.Amount, &userID, "reservation cancelled: "+reason)
+			return err
+		}
+		if err := s.restoreListingWithTx(tx, deposit, &userID, "reservation cancelled: "+reason); err != nil {
+			return err
+		}
+		return s.transitionWithTx(tx, deposit, transactionChange{
+			Status:  models.TransactionStatusCancelled,
+			Escrow:  models.EscrowStatusCancelled,
+			ActorID: &userID,
+			Reason:  "reservation cancelled: " + reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.refunds.issue(refunds...)
+
+	cancelled, err := s.repo.GetByID(deposit.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if cancelled.EscrowStatus == models.EscrowStatusRefunded {
+		s.notifyRefund(cancelled, cancelled.Amount, "reservation cancelled: "+reason)
+	} else {
+		s.notifyParties(cancelled, models.TransactionStatusCancelled, userID)
+	}
+	return cancelled, nil
+}
+
+// lapseReservation refunds a paid deposit whose hold ended without a purchase and puts the listing back on sale.
+// A hold does not lapse while the buyer's sale is still awaiting payment; it is picked up again once that sale ends.
+func (s *TransactionService) lapseReservation(transaction *models.Transaction) error {
+	reason := "the reservation ended without a purchase"
+	var refunds []*models.Refund
+	lapsed := false
+
+	err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.EscrowStatus != models.EscrowStatusFundsHeld || locked.EscrowDeadline == nil {
+			return nil
+		}
+
+		open, err := s.repo.CountOpenSalesByListingIDWithTx(tx, locked.ListingID)
+		if err != nil || open > 0 {
+			return err
+		}
+
+		lapsed = true
+		refunds, err = s.refundWithTx(tx, locked, locked.Amount, nil, reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.refunds.issue(refunds...)
+
+	if lapsed {
+		s.notifyRefund(transaction, transaction.Amount, reason)
+	}
+	return nil
+}
+
+// creditedDepositWithTx locks the paid deposit the buyer of a sale reserved its listing with, or returns nil
+func (s *TransactionService) creditedDepositWithTx(tx *gorm.DB, sale *models.Transaction) (*models.Transaction, error) {
+	deposit, err := s.repo.GetOpenDepositByListingIDForUpdateWithTx(tx, sale.ListingID)
+	if err != nil || deposit == nil {
+		return nil, err
+	}
+	if deposit.BuyerID != sale.BuyerID || deposit.EscrowStatus != models.EscrowStatusFundsHeld {
+		return nil, nil
+	}
+	return deposit, nil
+}
+
+// notifyReserved tells both parties a deposit is held and until when the listing is reserved
+func (s *TransactionService) notifyReserved(deposit *models.Transaction) {
+	until := email_helper.EscrowDeadline(time.Now().Add(s.windows.Reservation))
+	s.notifyEscrow(deposit,
+		fmt.Sprintf("Your deposit of %.2f is held in escrow and the vehicle is reserved for you until %s. It is credited against the price when you buy, and refunded if you do not.", deposit.Amount, until),
+		fmt.Sprintf("A buyer paid a deposit of %.2f and your listing is reserved for them until %s. The deposit is paid to you with the sale, or refunded to the buyer if they do not buy.", deposit.Amount, until))
+}
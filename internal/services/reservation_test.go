@@ -0,0 +1,209 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+// reservation is the listing a test reserves, with the deposit and the sale it opens along the way
+type reservation struct {
+	listing *models.Listing
+	deposit *models.Transaction
+	sale    *models.Transaction
+}
+
+// reservationStep is one action on a reservation and, if it is refused, the error it is refused with
+type reservationStep struct {
+	action  string
+	wantErr string
+}
+
+func TestReservations(t *testing.T) {
+	s := newTestServices(t)
+	seller, buyer, rival, admin := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer), s.user(t, types.RoleBuyer), s.user(t, types.RoleAdmin)
+
+	// the test services take a deposit of 10% of the price
+	const price, deposit = 1500000, 150000
+	actions := map[string]func(t *testing.T, r *reservation) error{
+		"reserve": func(t *testing.T, r *reservation) error {
+			reserved, err := s.transactions.Reserve(r.listing.ID.String(), buyer.ID, "card")
+			if err == nil {
+				r.deposit = reserved
+			}
+			return err
+		},
+		"pay deposit": func(t *testing.T, r *reservation) error {
+			r.deposit = s.pay(t, r.deposit)
+			return nil
+		},
+		"purchase": func(t *testing.T, r *reservation) error {
+			sale, err := s.transactions.Purchase(r.listing.ID.String(), buyer.ID, "card")
+			if err == nil {
+				r.sale = sale
+			}
+			return err
+		},
+		"other buyer purchases": func(t *testing.T, r *reservation) error {
+			_, err := s.transactions.Purchase(r.listing.ID.String(), rival.ID, "card")
+			return err
+		},
+		"pay sale": func(t *testing.T, r *reservation) error {
+			r.sale = s.pay(t, r.sale)
+			return nil
+		},
+		"hand over": func(t *testing.T, r *reservation) error {
+			_, err := s.transactions.MarkHandedOver(r.sale.ID.String(), seller.ID, types.RoleSeller)
+			return err
+		},
+		"confirm": func(t *testing.T, r *reservation) error {
+			_, err := s.transactions.ConfirmReceipt(r.sale.ID.String(), buyer.ID, types.RoleBuyer)
+			return err
+		},
+		"refund sale": func(t *testing.T, r *reservation) error {
+			_, err := s.transactions.Refund(r.sale.ID.String(), admin.ID, 0, "test")
+			return err
+		},
+		"cancel": func(t *testing.T, r *reservation) error {
+			_, err := s.transactions.CancelReservation(r.listing.ID.String(), buyer.ID, types.RoleBuyer, "found another car")
+			return err
+		},
+		"hold ends": func(t *testing.T, r *reservation) error {
+			return s.expireNow(t, r.deposit.ID)
+		},
+		"sale payment window ends": func(t *testing.T, r *reservation) error {
+			return s.expireNow(t, r.sale.ID)
+		},
+	}
+
+	tests := []struct {
+		name            string
+		steps           []reservationStep
+		wantDeposit     models.EscrowStatus
+		wantSale        models.EscrowStatus // empty when no sale is opened
+		wantListing     models.ListingStatus
+		depositRefunded float64
+		saleRefunded    float64
+	}{
+		{
+			name: "deposit credited to the sale and released with it",
+			steps: []reservationStep{
+				{"reserve", ""}, {"pay deposit", ""}, {"purchase", ""}, {"pay sale", ""}, {"hand over", ""}, {"confirm", ""},
+			},
+			wantDeposit: models.EscrowStatusReleased, wantSale: models.EscrowStatusReleased, wantListing: models.ListingStatusSold,
+		},
+		{
+			name: "only the reserving buyer can purchase, once the deposit is paid",
+			steps: []reservationStep{
+				{"reserve", ""},
+				{"purchase", "reservation deposit has not been paid yet"},
+				{"pay deposit", ""},
+				{"other buyer purchases", "listing is reserved by another buyer"},
+				{"reserve", "listing is already reserved"},
+			},
+			wantDeposit: models.EscrowStatusFundsHeld, wantListing: models.ListingStatusReserved,
+		},
+		{
+			name:        "unpaid deposit lapses at the end of the payment window",
+			steps:       []reservationStep{{"reserve", ""}, {"hold ends", ""}},
+			wantDeposit: models.EscrowStatusCancelled, wantListing: models.ListingStatusActive,
+		},
+		{
+			name:        "hold ends without a purchase",
+			steps:       []reservationStep{{"reserve", ""}, {"pay deposit", ""}, {"hold ends", ""}},
+			wantDeposit: models.EscrowStatusRefunded, wantListing: models.ListingStatusActive, depositRefunded: deposit,
+		},
+		{
+			name: "hold waits for the buyer's sale to end",
+			steps: []reservationStep{
+				{"reserve", ""}, {"pay deposit", ""}, {"purchase", ""}, {"hold ends", ""}, {"sale payment window ends", ""}, {"hold ends", ""},
+			},
+			wantDeposit: models.EscrowStatusRefunded, wantSale: models.EscrowStatusCancelled, wantListing: models.ListingStatusActive, depositRefunded: deposit,
+		},
+		{
+			name: "hold does not lapse while the buyer's sale is paid",
+			steps: []reservationStep{
+				{"reserve", ""}, {"pay deposit", ""}, {"purchase", ""}, {"pay sale", ""}, {"hold ends", ""},
+			},
+			wantDeposit: models.EscrowStatusFundsHeld, wantSale: models.EscrowStatusFundsHeld, wantListing: models.ListingStatusSold,
+		},
+		{
+			name: "refunded sale refunds the deposit credited to it",
+			steps: []reservationStep{
+				{"reserve", ""}, {"pay deposit", ""}, {"purchase", ""}, {"pay sale", ""}, {"refund sale", ""},
+			},
+			wantDeposit: models.EscrowStatusRefunded, wantSale: models.EscrowStatusRefunded, wantListing: models.ListingStatusActive, depositRefunded: deposit, saleRefunded: price - deposit,
+		},
+		{
+			name:        "buyer cancels the reservation",
+			steps:       []reservationStep{{"reserve", ""}, {"pay deposit", ""}, {"cancel", ""}},
+			wantDeposit: models.EscrowStatusRefunded, wantListing: models.ListingStatusActive, depositRefunded: deposit,
+		},
+		{
+			name: "reservation credited to a sale in progress cannot be cancelled",
+			steps: []reservationStep{
+				{"reserve", ""}, {"pay deposit", ""}, {"purchase", ""}, {"cancel", "reservation is credited to a sale in progress"},
+			},
+			wantDeposit: models.EscrowStatusFundsHeld, wantSale: models.EscrowStatusAwaitingPayment, wantListing: models.ListingStatusReserved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &reservation{listing: s.listing(t, seller, price)}
+			for i, step := range tt.steps {
+				err := actions[step.action](t, r)
+				switch {
+				case step.wantErr == "" && err != nil:
+					t.Fatalf("step %d %s: %v", i, step.action, err)
+				case step.wantErr != "" && (err == nil || err.Error() != step.wantErr):
+					t.Fatalf("step %d %s: error = %v, want %q", i, step.action, err, step.wantErr)
+				}
+			}
+
+			got := s.reload(t, r.deposit.ID)
+			if payment.ToMinorUnits(got.Amount) != payment.ToMinorUnits(deposit) {
+				t.Errorf("deposit = %v, want %v", got.Amount, deposit)
+			}
+			if got.EscrowStatus != tt.wantDeposit {
+				t.Errorf("deposit escrow = %s, want %s", got.EscrowStatus, tt.wantDeposit)
+			}
+			s.assertRefunded(t, got, tt.depositRefunded)
+
+			switch {
+			case tt.wantSale == "" && r.sale != nil:
+				t.Errorf("sale %s was opened, want none", r.sale.ID)
+			case tt.wantSale != "":
+				sale := s.reload(t, r.sale.ID)
+				// the buyer only pays what the deposit does not cover
+				if payment.ToMinorUnits(sale.Amount) != payment.ToMinorUnits(price-deposit) {
+					t.Errorf("sale amount = %v, want %v with the deposit credited", sale.Amount, price-deposit)
+				}
+				if sale.EscrowStatus != tt.wantSale {
+					t.Errorf("sale escrow = %s, want %s", sale.EscrowStatus, tt.wantSale)
+				}
+				s.assertRefunded(t, sale, tt.saleRefunded)
+			}
+
+			if status := s.listingStatus(t, r.listing.ID); status != tt.wantListing {
+				t.Errorf("listing status = %s, want %s", status, tt.wantListing)
+			}
+		})
+	}
+}
+
+// expireNow moves a transaction's escrow deadline into the past and runs the action the expired escrow job would
+func (s *testServices) expireNow(t *testing.T, id uuid.UUID) error {
+	t.Helper()
+
+	err := s.db.Model(&models.Transaction{}).Where("id = ? AND escrow_deadline IS NOT NULL", id).
+		Update("escrow_deadline", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		t.Fatalf("failed to move the escrow deadline: %v", err)
+	}
+	return s.transactions.expire(id)
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+)
+
+// maxBulkReviewDecisions caps how many listings one bulk decision may touch
+const maxBulkReviewDecisions = 100
+
+// ReviewQueueFilter narrows and orders the review queue
+type ReviewQueueFilter struct {
+	NewestFirst  bool
+	BreachedOnly bool
+	Claimed      string // "mine", "unclaimed", "others" or empty for every item
+}
+
+type ReviewQueueService struct {
+	listingRepo       *repositories.ListingRepository
+	inspectionRepo    *repositories.InspectionRepository
+	inspectionService *InspectionService
+	sla               time.Duration
+	claimTTL          time.Duration
+}
+
+func NewReviewQueueService(listingRepo *repositories.ListingRepository, inspectionRepo *repositories.InspectionRepository, inspectionService *InspectionService, sla time.Duration, claimTTL time.Duration) *ReviewQueueService {
+	return &ReviewQueueService{
+		listingRepo:       listingRepo,
+		inspectionRepo:    inspectionRepo,
+		inspectionService: inspectionService,
+		sla:               sla,
+		claimTTL:          claimTTL,
+	}
+}
+
+// GetQueue lists listings pending review with their time in state, SLA standing and claim
+func (s *ReviewQueueService) GetQueue(filter ReviewQueueFilter, adminID uuid.UUID) (*models.ReviewQueue, error) {
+	listings, err := s.listingRepo.GetReviewQueue()
+	if err != nil {
+		return nil, err
+	}
+
+	listingIDs := make([]uuid.UUID, 0, len(listings))
+	for _, listing := range listings {
+		listingIDs = append(listingIDs, listing.ID)
+	}
+	inspectionsInput, err := s.inspectionRepo.GetByListingIDs(listingIDs)
+	if err != nil {
+		return nil, err
+	}
+	inspections := map[uuid.UUID]*models.InspectionFetchInput{}
+	for _, input := range inspectionsInput {
+		inspections[input.ID] = input
+	}
+
+	now := time.Now()
+	queue := &models.ReviewQueue{
+		SLAHours: int(s.sla.Hours()),
+		Items:    []*models.ReviewQueueItem{},
+	}
+
+	for _, listing := range listings {
+		item := s.queueItem(listing, inspections, now)
+
+		claimedByMe := item.ClaimedByID != nil && *item.ClaimedByID == adminID
+		switch filter.Claimed {
+		case "mine":
+			if !claimedByMe {
+				continue
+			}
+		case "unclaimed":
+			if item.ClaimedByID != nil {
+				continue
+			}
+		case "others":
+			if item.ClaimedByID == nil || claimedByMe {
+				continue
+			}
+		}
+		if filter.BreachedOnly && !item.SLABreached {
+			continue
+		}
+
+		if item.SLABreached {
+			queue.BreachedCount++
+		}
+		queue.Items = append(queue.Items, item)
+	}
+
+	sort.SliceStable(queue.Items, func(i, j int) bool {
+		if filter.NewestFirst {
+			return queue.Items[i].PendingSince.After(queue.Items[j].PendingSince)
+		}
+		return queue.Items[i].PendingSince.Before(queue.Items[j].PendingSince)
+	})
+	queue.Total = len(queue.Items)
+
+	return queue, nil
+}
+
+// queueItem computes the time in state of a pending listing; expired claims are shown as unclaimed
+func (s *ReviewQueueService) queueItem(listing *models.Listing, inspections map[uuid.UUID]*models.InspectionFetchInput, now time.Time) *models.ReviewQueueItem {
+	pendingSince := listing.CreatedAt
+	if listing.StatusChangedAt != nil {
+		pendingSince = *listing.StatusChangedAt
+	}
+	deadline := pendingSince.Add(s.sla)
+
+	item := &models.ReviewQueueItem{
+		ListingID:    listing.ID,
+		Title:        listing.Title,
+		SellerID:     listing.SellerID,
+		SellerName:   listing.Seller.Name,
+		Status:       listing.Status,
+		PendingSince: pendingSince,
+		HoursInState: hoursSince(pendingSince, now),
+		SLADeadline:  deadline,
+		SLABreached:  now.After(deadline),
+	}
+
+	if listing.ReviewClaimedByID != nil && listing.ReviewClaimExpiresAt != nil && listing.ReviewClaimExpiresAt.After(now) {
+		item.ClaimedByID = listing.ReviewClaimedByID
+		item.ClaimExpiresAt = listing.ReviewClaimExpiresAt
+	}
+
+	if listing.CurrentInspectionID != nil {
+		if inspection, ok := inspections[*listing.CurrentInspectionID]; ok {
+			inStateSince := inspection.UpdatedAt
+			if inspection.Status == models.InspectionStatusPending {
+				inStateSince = inspection.CreatedAt
+			}
+			item.CurrentInspection = &models.ReviewInspection{
+				ID:                 inspection.ID,
+				Status:             inspection.Status,
+				InspectorID:        inspection.InspectorID,
+				ConditionRating:    inspection.ConditionRating,
+				ChecklistSubmitted: len(inspection.Checklist) > 0,
+				CreatedAt:          inspection.CreatedAt,
+				HoursInState:       hoursSince(inStateSince, now),
+			}
+		}
+	}
+
+	return item
+}
+
+// ClaimListing reserves a pending listing for adminID for the claim period; claiming one's own listing again extends the claim
+func (s *ReviewQueueService) ClaimListing(listingID string, adminID uuid.UUID) (*models.Listing, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if listing.Status != models.ListingStatusPending {
+		return nil, fmt.Errorf("only listings pending review can be claimed")
+	}
+
+	claimed, err := s.listingRepo.ClaimForReview(listing.ID, adminID, time.Now().Add(s.claimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, fmt.Errorf("listing is already claimed by another admin")
+	}
+
+	return s.listingRepo.GetByID(listingID)
+}
+
+// UnclaimListing gives up adminID's claim on a listing
+func (s *ReviewQueueService) UnclaimListing(listingID string, adminID uuid.UUID) error {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return err
+	}
+
+	released, err := s.listingRepo.ReleaseReviewClaim(listing.ID, adminID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return fmt.Errorf("listing is not claimed by you")
+	}
+	return nil
+}
+
+// DecideListings approves or rejects the current inspection of each listing; one failure does not stop the others.
+// Rejections apply the same reason codes and note to every listing.
+func (s *ReviewQueueService) DecideListings(listingIDs []uuid.UUID, decision models.InspectionStatus, reasonCodes []string, reason string, adminID uuid.UUID) ([]*models.ReviewDecisionResult, error) {
+	if decision != models.InspectionStatusApproved && decision != models.InspectionStatusRejected {
+		return nil, fmt.Errorf("decision must be approved or rejected")
+	}
+	// bad codes would fail every listing alike, so reject the whole request up front
+	if decision == models.InspectionStatusRejected {
+		if _, err := s.inspectionService.rejectionReasonService.ResolveActive(reasonCodes); err != nil {
+			return nil, err
+		}
+	}
+	if len(listingIDs) > maxBulkReviewDecisions {
+		return nil, fmt.Errorf("at most %d listings can be decided at once", maxBulkReviewDecisions)
+	}
+
+	results := []*models.ReviewDecisionResult{}
+	seen := map[uuid.UUID]bool{}
+	for _, listingID := range listingIDs {
+		if seen[listingID] {
+			continue
+		}
+		seen[listingID] = true
+
+		result := &models.ReviewDecisionResult{ListingID: listingID}
+		results = append(results, result)
+
+		listing, err := s.listingRepo.GetByID(listingID.String())
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		if listing.Status != models.ListingStatusPending {
+			result.Error = "only listings pending review can be decided"
+			continue
+		}
+		if listing.CurrentInspectionID == nil {
+			result.Error = "listing has no inspection to decide"
+			continue
+		}
+
+		result.InspectionID = listing.CurrentInspectionID
+		if err := s.inspectionService.UpdateInspectionStatus(listing.CurrentInspectionID.String(), decision, adminID, reasonCodes, reason); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Status = decision
+		result.Succeeded = true
+	}
+
+	return results, nil
+}
+
+// GetStatusHistory retrieves every status change of a listing, oldest first
+func (s *ReviewQueueService) GetStatusHistory(listingID string) ([]*models.ListingStatusChange, error) {
+	if _, err := s.listingRepo.GetByID(listingID); err != nil {
+		return nil, err
+	}
+	return s.listingRepo.GetStatusChanges(listingID)
+}
+
+// hoursSince returns the hours elapsed since t, to one decimal place
+func hoursSince(t time.Time, now time.Time) float64 {
+	return math.Round(now.Sub(t).Hours()*10) / 10
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+const (
+	// testDriveLength is how long a test drive appointment is booked for
+	testDriveLength = time.Hour
+	// maxTestDriveReschedules caps how often the same test drive can be moved
+	maxTestDriveReschedules = 3
+	testDriveExpiryBatch    = 100
+)
+
+// TestDriveService arranges test drives between buyers and sellers. A buyer proposes time windows, the seller confirms
+// a time within them or proposes another for the buyer to confirm, and either party can move a confirmed test drive.
+// Confirmed, moved and cancelled appointments are emailed to both parties with a calendar invite, a reminder goes
+// out before the test drive, and afterwards either party reports whether it took place or who did not show up.
+type TestDriveService struct {
+	repo         *repositories.TestDriveRepository
+	listingRepo  *repositories.ListingRepository
+	reminderLead time.Duration
+}
+
+func NewTestDriveService(repo *repositories.TestDriveRepository, listingRepo *repositories.ListingRepository, reminderLead time.Duration) *TestDriveService {
+	return &TestDriveService{
+		repo:         repo,
+		listingRepo:  listingRepo,
+		reminderLead: reminderLead,
+	}
+}
+
+// RequestTestDrive asks the seller of an active listing for a test drive in one of the proposed windows. A buyer can
+// have one open test drive per listing.
+func (s *TestDriveService) RequestTestDrive(listingID string, buyerID uuid.UUID, windows []models.TestDriveWindow, note string) (*models.TestDrive, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot request a test drive of your own listing")
+	}
+	expiresAt, err := validateTestDriveWindows(windows)
+	if err != nil {
+		return nil, err
+	}
+
+	drive := &models.TestDrive{
+		ListingID: listing.ID,
+		BuyerID:   buyerID,
+		SellerID:  listing.SellerID,
+		Status:    models.TestDriveStatusRequested,
+		Note:      note,
+		ExpiresAt: expiresAt,
+		Windows:   windows,
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		// the listing lock serialises a buyer's requests so two cannot both pass the one-request check
+		lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return err
+		}
+		if lockedListing.Status != models.ListingStatusActive {
+			return fmt.Errorf("test drives can only be requested on active listings")
+		}
+
+		open, err := s.repo.CountOpenByBuyerWithTx(tx, listing.ID, buyerID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("you already have an open test drive for this listing")
+		}
+
+		return s.repo.CreateWithTx(tx, drive)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(listing.Seller.Email, listing.Title, withMessage(
+		fmt.Sprintf("A buyer would like to test drive your vehicle and proposed %s. Please confirm a time or propose another.",
+			describeTestDriveWindows(windows)), note))
+
+	return s.repo.GetByID(drive.ID.String())
+}
+
+// Accept agrees on a time. The seller confirms a request at a startsAt within one of the buyer's windows, meeting at
+// location or else the listing's location; the buyer confirms the time the seller proposed.
+func (s *TestDriveService) Accept(id string, userID uuid.UUID, startsAt *time.Time, location string) (*models.TestDrive, error) {
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if drive.Listing.Status != models.ListingStatusActive && drive.Listing.Status != models.ListingStatusReserved {
+		return nil, fmt.Errorf("listing is no longer available for test drives")
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.lockTurnWithTx(tx, drive.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		fields := map[string]any{
+			"status":           models.TestDriveStatusConfirmed,
+			"sequence":         locked.Sequence + 1,
+			"reminder_sent_at": nil,
+		}
+		slot := locked.ScheduledFor
+		if locked.Status == models.TestDriveStatusRequested {
+			if startsAt == nil {
+				return fmt.Errorf("starts_at is required to confirm a test drive request")
+			}
+			if !withinTestDriveWindows(locked.Windows, *startsAt) {
+				return fmt.Errorf("test drive time must fall within one of the buyer's proposed windows")
+			}
+			slot = startsAt
+			fields["scheduled_for"] = *startsAt
+			fields["location"] = testDriveLocation(location, locked.Location, drive.Listing.Location)
+		} else if !locked.ScheduledFor.After(time.Now()) {
+			return fmt.Errorf("test drive time must be in the future")
+		}
+		if err := s.checkSlotWithTx(tx, locked, *slot, locked.SellerID, locked.BuyerID); err != nil {
+			return err
+		}
+
+		return s.repo.UpdateFieldsWithTx(tx, locked.ID, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyBoth(confirmed, fmt.Sprintf("The test drive is confirmed for %s at %s. The invite is attached for your calendar.",
+		email_helper.EscrowDeadline(*confirmed.ScheduledFor), confirmed.Location), false)
+
+	return confirmed, nil
+}
+
+// Reschedule moves a test drive. On their turn, or once it is confirmed, the seller proposes a new startsAt (and
+// optionally location) for the buyer to confirm, and the buyer proposes new windows for the seller to pick from.
+// Moving a confirmed test drive takes it out of both calendars until the new time is agreed.
+func (s *TestDriveService) Reschedule(id string, userID uuid.UUID, windows []models.TestDriveWindow, startsAt *time.Time, location, message string) (*models.TestDrive, error) {
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTestDriveParty(drive, userID); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{"reminder_sent_at": nil}
+	var update string
+	if userID == drive.SellerID {
+		if startsAt == nil {
+			return nil, fmt.Errorf("starts_at is required to propose a new time")
+		}
+		if !startsAt.After(time.Now()) {
+			return nil, fmt.Errorf("test drive time must be in the future")
+		}
+		fields["status"] = models.TestDriveStatusRescheduled
+		fields["scheduled_for"] = *startsAt
+		fields["expires_at"] = *startsAt
+		fields["location"] = testDriveLocation(location, drive.Location, drive.Listing.Location)
+		update = fmt.Sprintf("The seller proposed to meet on %s at %s instead. Please confirm, decline or propose other times.",
+			email_helper.EscrowDeadline(*startsAt), fields["location"])
+	} else {
+		if len(windows) == 0 {
+			return nil, fmt.Errorf("windows are required to propose new times")
+		}
+		expiresAt, err := validateTestDriveWindows(windows)
+		if err != nil {
+			return nil, err
+		}
+		fields["status"] = models.TestDriveStatusRequested
+		fields["scheduled_for"] = nil
+		fields["expires_at"] = expiresAt
+		update = fmt.Sprintf("The buyer proposed %s instead. Please confirm a time or propose another.", describeTestDriveWindows(windows))
+	}
+
+	var confirmedFor *time.Time
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, drive.ID)
+		if err != nil {
+			return err
+		}
+		if !testDriveOpen(locked) {
+			return fmt.Errorf("test drive is no longer open")
+		}
+		wasConfirmed := locked.Status == models.TestDriveStatusConfirmed
+		if wasConfirmed {
+			confirmedFor = locked.ScheduledFor
+		}
+		if !wasConfirmed && testDriveTurn(locked) != userID {
+			return fmt.Errorf("test drive is waiting on the other party")
+		}
+		if locked.RescheduleCount >= maxTestDriveReschedules {
+			return fmt.Errorf("test drive has already been rescheduled the maximum number of times")
+		}
+		// the buyer's calendar is checked once they confirm the proposed time
+		if userID == locked.SellerID {
+			if err := s.checkSlotWithTx(tx, locked, *startsAt, locked.SellerID); err != nil {
+				return err
+			}
+		}
+
+		fields["reschedule_count"] = locked.RescheduleCount + 1
+		if wasConfirmed {
+			fields["sequence"] = locked.Sequence + 1
+		}
+		if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, fields); err != nil {
+			return err
+		}
+		if userID == locked.BuyerID {
+			return s.repo.ReplaceWindowsWithTx(tx, locked.ID, windows)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rescheduled, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if confirmedFor != nil {
+		// the cancellation has to name the time that is coming out of the calendars
+		previous := *rescheduled
+		previous.ScheduledFor = confirmedFor
+		s.notifyBoth(&previous, withMessage(fmt.Sprintf("The test drive planned for %s is off until a new time is agreed. %s",
+			email_helper.EscrowDeadline(*confirmedFor), update), message), true)
+	} else {
+		s.notify(s.counterpartyEmail(rescheduled, userID), rescheduled.Listing.Title, withMessage(update, message))
+	}
+
+	return rescheduled, nil
+}
+
+// Decline turns down a request or a proposed time and ends the test drive. Only the party whose turn it is can decline.
+func (s *TestDriveService) Decline(id string, userID uuid.UUID, message string) (*models.TestDrive, error) {
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.lockTurnWithTx(tx, drive.ID, userID)
+		if err != nil {
+			return err
+		}
+		return s.closeWithTx(tx, locked, models.TestDriveStatusDeclined, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	declined, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	update := "The seller declined the test drive request."
+	if userID == drive.BuyerID {
+		update = "The buyer declined the time you proposed, so the test drive will not go ahead."
+	}
+	s.notify(s.counterpartyEmail(declined, userID), declined.Listing.Title, withMessage(update, message))
+
+	return declined, nil
+}
+
+// Cancel calls off an open test drive; either party can cancel at any time before it is reported on
+func (s *TestDriveService) Cancel(id string, userID uuid.UUID, reason string) (*models.TestDrive, error) {
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	wasConfirmed := false
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, drive.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkTestDriveParty(locked, userID); err != nil {
+			return err
+		}
+		if !testDriveOpen(locked) {
+			return fmt.Errorf("test drive is no longer open")
+		}
+		wasConfirmed = locked.Status == models.TestDriveStatusConfirmed
+		if wasConfirmed {
+			if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{"sequence": locked.Sequence + 1}); err != nil {
+				return err
+			}
+		}
+		return s.closeWithTx(tx, locked, models.TestDriveStatusCancelled, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	party := "seller"
+	if userID == drive.BuyerID {
+		party = "buyer"
+	}
+	if wasConfirmed {
+		s.notifyBoth(cancelled, withMessage(fmt.Sprintf("The %s cancelled the test drive planned for %s.",
+			party, email_helper.EscrowDeadline(*cancelled.ScheduledFor)), reason), true)
+	} else {
+		s.notify(s.counterpartyEmail(cancelled, userID), cancelled.Listing.Title,
+			withMessage(fmt.Sprintf("The %s cancelled the test drive request.", party), reason))
+	}
+
+	return cancelled, nil
+}
+
+// ReportOutcome records, once a confirmed test drive was due to start, whether it took place (completed) or the other
+// party did not turn up (no_show). The first report by either party closes the test drive.
+func (s *TestDriveService) ReportOutcome(id string, userID uuid.UUID, outcome models.TestDriveStatus) (*models.TestDrive, error) {
+	if outcome != models.TestDriveStatusCompleted && outcome != models.TestDriveStatusNoShow {
+		return nil, fmt.Errorf("outcome must be completed or no_show")
+	}
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, drive.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkTestDriveParty(locked, userID); err != nil {
+			return err
+		}
+		if locked.Status != models.TestDriveStatusConfirmed {
+			return fmt.Errorf("only confirmed test drives can be reported on")
+		}
+		if locked.ScheduledFor.After(time.Now()) {
+			return fmt.Errorf("test drive has not started yet")
+		}
+
+		fields := map[string]any{"reported_by_id": userID}
+		if outcome == models.TestDriveStatusNoShow {
+			noShow := locked.SellerID
+			if userID == locked.SellerID {
+				noShow = locked.BuyerID
+			}
+			fields["no_show_user_id"] = noShow
+		}
+		if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, fields); err != nil {
+			return err
+		}
+		return s.closeWithTx(tx, locked, outcome, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reported, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if outcome == models.TestDriveStatusNoShow {
+		s.notify(s.counterpartyEmail(reported, userID), reported.Listing.Title,
+			fmt.Sprintf("The other party reported that you did not turn up to the test drive on %s. Contact support if this is wrong.",
+				email_helper.EscrowDeadline(*reported.ScheduledFor)))
+	}
+
+	return reported, nil
+}
+
+// GetTestDrive retrieves a test drive for its buyer, the seller or an admin, with the buyer's no-show record
+func (s *TestDriveService) GetTestDrive(id string, userID uuid.UUID, userRole types.Role) (*models.TestDrive, error) {
+	drive, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && drive.BuyerID != userID && drive.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view your own test drives")
+	}
+
+	drive.BuyerNoShows, err = s.repo.CountNoShowsByUserID(drive.BuyerID)
+	if err != nil {
+		return nil, err
+	}
+	return drive, nil
+}
+
+// GetInbox retrieves the test drives of a seller's listings with each buyer's no-show record, the open ones unless a
+// status is given
+func (s *TestDriveService) GetInbox(sellerID uuid.UUID, status models.TestDriveStatus) ([]*models.TestDrive, error) {
+	drives, err := s.repo.GetBySellerID(sellerID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	noShows := map[uuid.UUID]int64{}
+	for _, drive := range drives {
+		count, seen := noShows[drive.BuyerID]
+		if !seen {
+			count, err = s.repo.CountNoShowsByUserID(drive.BuyerID)
+			if err != nil {
+				return nil, err
+			}
+			noShows[drive.BuyerID] = count
+		}
+		drive.BuyerNoShows = count
+	}
+	return drives, nil
+}
+
+// GetMyTestDrives retrieves the test drives a buyer requested
+func (s *TestDriveService) GetMyTestDrives(buyerID uuid.UUID) ([]*models.TestDrive, error) {
+	return s.repo.GetByBuyerID(buyerID)
+}
+
+// ExpireTestDrives is the background job closing requests and proposals whose times passed before anyone confirmed
+func (s *TestDriveService) ExpireTestDrives() error {
+	now := time.Now()
+	drives, err := s.repo.GetExpired(now, testDriveExpiryBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, drive := range drives {
+		expired := false
+		err := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, drive.ID)
+			if err != nil {
+				return err
+			}
+			// a response may have come in since the test drive was picked up
+			if locked.Status == models.TestDriveStatusConfirmed || !testDriveOpen(locked) || locked.ExpiresAt.After(now) {
+				return nil
+			}
+			expired = true
+			return s.closeWithTx(tx, locked, models.TestDriveStatusExpired, "no time was agreed before the proposed times passed")
+		})
+		if err != nil {
+			fmt.Printf("Warning: Failed to expire test drive %s: %v\n", drive.ID, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		full, err := s.repo.GetByID(drive.ID.String())
+		if err != nil {
+			continue
+		}
+		update := "The test drive request expired because no time was agreed before the proposed times passed."
+		s.notify(full.Buyer.Email, full.Listing.Title, update)
+		s.notify(full.Listing.Seller.Email, full.Listing.Title, update)
+	}
+
+	return nil
+}
+
+// SendDueReminders emails both parties of every confirmed test drive starting within the reminder lead time
+func (s *TestDriveService) SendDueReminders() error {
+	drives, err := s.repo.GetDueForReminder(time.Now().Add(s.reminderLead))
+	if err != nil {
+		return err
+	}
+
+	for _, drive := range drives {
+		buyerInvite := s.invite(drive, drive.Buyer.Email, false)
+		if err := email_helper.SendTestDriveReminderEmail(drive.Buyer.Email, drive.Buyer.Name, drive.Listing.Title, drive.Location, *drive.ScheduledFor, buyerInvite); err != nil {
+			fmt.Printf("Warning: Failed to send test drive reminder to buyer %s: %v\n", drive.Buyer.Email, err)
+			continue
+		}
+		sellerInvite := s.invite(drive, drive.Listing.Seller.Email, false)
+		if err := email_helper.SendTestDriveReminderEmail(drive.Listing.Seller.Email, drive.Listing.Seller.Name, drive.Listing.Title, drive.Location, *drive.ScheduledFor, sellerInvite); err != nil {
+			fmt.Printf("Warning: Failed to send test drive reminder to seller %s: %v\n", drive.Listing.Seller.Email, err)
+		}
+
+		if err := s.repo.MarkReminderSent(drive.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockTurnWithTx locks a test drive and checks it is still unconfirmed and waiting on userID
+func (s *TestDriveService) lockTurnWithTx(tx *gorm.DB, id uuid.UUID, userID uuid.UUID) (*models.TestDrive, error) {
+	locked, err := s.repo.GetByIDForUpdateWithTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTestDriveParty(locked, userID); err != nil {
+		return nil, err
+	}
+	if locked.Status != models.TestDriveStatusRequested && locked.Status != models.TestDriveStatusRescheduled {
+		return nil, fmt.Errorf("test drive is no longer open")
+	}
+	if !locked.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("test drive times have passed")
+	}
+	if testDriveTurn(locked) != userID {
+		return nil, fmt.Errorf("test drive is waiting on the other party")
+	}
+	return locked, nil
+}
+
+// checkSlotWithTx locks the calendars of the given parties of a test drive and checks that a test drive starting at
+// startsAt would not overlap another one they have confirmed
+func (s *TestDriveService) checkSlotWithTx(tx *gorm.DB, locked *models.TestDrive, startsAt time.Time, partyIDs ...uuid.UUID) error {
+	if err := s.repo.LockUsersWithTx(tx, partyIDs...); err != nil {
+		return err
+	}
+	for _, partyID := range partyIDs {
+		clashes, err := s.repo.CountConfirmedStartingBetweenWithTx(tx, partyID, locked.ID, startsAt.Add(-testDriveLength), startsAt.Add(testDriveLength))
+		if err != nil {
+			return err
+		}
+		if clashes == 0 {
+			continue
+		}
+		if partyID == locked.SellerID {
+			return fmt.Errorf("seller already has a test drive booked at that time")
+		}
+		return fmt.Errorf("buyer already has a test drive booked at that time")
+	}
+	return nil
+}
+
+// closeWithTx ends a locked test drive and records why
+func (s *TestDriveService) closeWithTx(tx *gorm.DB, locked *models.TestDrive, status models.TestDriveStatus, reason string) error {
+	return s.repo.UpdateFieldsWithTx(tx, locked.ID, map[string]any{
+		"status":       status,
+		"close_reason": reason,
+		"closed_at":    time.Now(),
+	})
+}
+
+// invite builds the calendar entry of a test drive for one of its parties. Only the recipient is listed as an
+// attendee, so the invite does not hand either party the other's email address.
+func (s *TestDriveService) invite(drive *models.TestDrive, recipient string, cancelled bool) *email_helper.CalendarInvite {
+	return &email_helper.CalendarInvite{
+		UID:         fmt.Sprintf("test-drive-%s@autocity", drive.ID),
+		Sequence:    drive.Sequence,
+		Summary:     fmt.Sprintf("Test drive: %s", drive.Listing.Title),
+		Description: fmt.Sprintf("Test drive of %s arranged on AutoCity.", drive.Listing.Title),
+		Location:    drive.Location,
+		StartsAt:    *drive.ScheduledFor,
+		EndsAt:      drive.ScheduledFor.Add(testDriveLength),
+		Attendees:   []string{recipient},
+		Cancelled:   cancelled,
+	}
+}
+
+// notifyBoth emails both parties the same update with their calendar invite, or its cancellation, attached
+func (s *TestDriveService) notifyBoth(drive *models.TestDrive, update string, cancelled bool) {
+	for _, recipient := range []string{drive.Buyer.Email, drive.Listing.Seller.Email} {
+		if err := email_helper.SendTestDriveUpdateEmail(recipient, drive.Listing.Title, update, s.invite(drive, recipient, cancelled)); err != nil {
+			fmt.Printf("Warning: Failed to send test drive update email to %s: %v\n", recipient, err)
+		}
+	}
+}
+
+func (s *TestDriveService) notify(recipient, listingTitle, update string) {
+	if err := email_helper.SendTestDriveUpdateEmail(recipient, listingTitle, update, nil); err != nil {
+		fmt.Printf("Warning: Failed to send test drive update email to %s: %v\n", recipient, err)
+	}
+}
+
+// counterpartyEmail is the address of the party of a test drive other than userID
+func (s *TestDriveService) counterpartyEmail(drive *models.TestDrive, userID uuid.UUID) string {
+	if userID == drive.BuyerID {
+		return drive.Listing.Seller.Email
+	}
+	return drive.Buyer.Email
+}
+
+func checkTestDriveParty(drive *models.TestDrive, userID uuid.UUID) error {
+	if drive.BuyerID != userID && drive.SellerID != userID {
+		return fmt.Errorf("unauthorized: you can only manage your own test drives")
+	}
+	return nil
+}
+
+// testDriveOpen reports whether a test drive has not ended yet
+func testDriveOpen(drive *models.TestDrive) bool {
+	switch drive.Status {
+	case models.TestDriveStatusRequested, models.TestDriveStatusRescheduled, models.TestDriveStatusConfirmed:
+		return true
+	}
+	return false
+}
+
+// testDriveTurn is the user an unconfirmed test drive is waiting on: the seller for a request, the buyer for a
+// time the seller proposed
+func testDriveTurn(drive *models.TestDrive) uuid.UUID {
+	if drive.Status == models.TestDriveStatusRescheduled {
+		return drive.BuyerID
+	}
+	return drive.SellerID
+}
+
+// validateTestDriveWindows checks the windows a buyer proposed and returns the latest time a test drive can start in them
+func validateTestDriveWindows(windows []models.TestDriveWindow) (time.Time, error) {
+	var latestStart time.Time
+	for _, window := range windows {
+		if !window.StartsAt.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("proposed windows must be in the future")
+		}
+		if window.EndsAt.Sub(window.StartsAt) < testDriveLength {
+			return time.Time{}, fmt.Errorf("each proposed window must leave room for a %.0f-minute test drive", testDriveLength.Minutes())
+		}
+		if start := window.EndsAt.Add(-testDriveLength); start.After(latestStart) {
+			latestStart = start
+		}
+	}
+	return latestStart, nil
+}
+
+// withinTestDriveWindows reports whether a test drive starting at startsAt fits in one of the proposed windows
+func withinTestDriveWindows(windows []models.TestDriveWindow, startsAt time.Time) bool {
+	if !startsAt.After(time.Now()) {
+		return false
+	}
+	for _, window := range windows {
+		if !startsAt.Before(window.StartsAt) && !startsAt.Add(testDriveLength).After(window.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// testDriveLocation picks where to meet: the location given, else the one already agreed, else the listing's
+func testDriveLocation(given, current, listingLocation string) string {
+	if given != "" {
+		return given
+	}
+	if current != "" {
+		return current
+	}
+	return listingLocation
+}
+
+func describeTestDriveWindows(windows []models.TestDriveWindow) string {
+	description := ""
+	for i, window := range windows {
+		if i > 0 {
+			description += ", "
+		}
+		description += fmt.Sprintf("%s to %s", email_helper.EscrowDeadline(window.StartsAt), window.EndsAt.Format("15:04 MST"))
+	}
+	return description
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+)
+
+func TestTestDriveSlotConflicts(t *testing.T) {
+	s := newTestServices(t)
+	base := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+
+	tests := []struct {
+		name           string
+		buyersOwn      bool // the test drive already booked is the buyer's with another seller, not the seller's with another buyer
+		cancelExisting bool
+		propose        bool // the seller proposes the time and the buyer confirms it, rather than the seller picking it
+		offset         time.Duration
+		wantErr        string
+		wantStatus     models.TestDriveStatus
+	}{
+		{"seller already booked at that time", false, false, false, 0, "seller already has a test drive booked at that time", models.TestDriveStatusRequested},
+		{"overlaps the seller's test drive", false, false, false, 30 * time.Minute, "seller already has a test drive booked at that time", models.TestDriveStatusRequested},
+		{"starts as the seller's test drive ends", false, false, false, testDriveLength, "", models.TestDriveStatusConfirmed},
+		{"ends as the seller's test drive starts", false, false, false, -testDriveLength, "", models.TestDriveStatusConfirmed},
+		{"overlaps the buyer's test drive with another seller", true, false, false, -30 * time.Minute, "buyer already has a test drive booked at that time", models.TestDriveStatusRequested},
+		{"seller proposes a time they are booked", false, false, true, 0, "seller already has a test drive booked at that time", models.TestDriveStatusRequested},
+		{"buyer confirms a proposed time they are booked", true, false, true, 0, "buyer already has a test drive booked at that time", models.TestDriveStatusRescheduled},
+		{"cancelled test drive frees its slot", false, true, false, 0, "", models.TestDriveStatusConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seller, buyer := s.user(t, types.RoleSeller), s.user(t, types.RoleBuyer)
+			existingSeller, existingBuyer := seller, s.user(t, types.RoleBuyer)
+			if tt.buyersOwn {
+				existingSeller, existingBuyer = s.user(t, types.RoleSeller), buyer
+			}
+			existing := s.testDrive(t, existingSeller, existingBuyer, base.Add(-time.Hour), base.Add(2*time.Hour))
+			if _, err := s.testDrives.Accept(existing.ID.String(), existingSeller.ID, &base, ""); err != nil {
+				t.Fatalf("Accept existing test drive: %v", err)
+			}
+			if tt.cancelExisting {
+				if _, err := s.testDrives.Cancel(existing.ID.String(), existingBuyer.ID, "plans changed"); err != nil {
+					t.Fatalf("Cancel: %v", err)
+				}
+			}
+
+			drive := s.testDrive(t, seller, buyer, base.Add(-2*time.Hour), base.Add(3*time.Hour))
+			startsAt := base.Add(tt.offset)
+			var err error
+			if tt.propose {
+				_, err = s.testDrives.Reschedule(drive.ID.String(), seller.ID, nil, &startsAt, "", "")
+				if err == nil {
+					_, err = s.testDrives.Accept(drive.ID.String(), buyer.ID, nil, "")
+				}
+			} else {
+				_, err = s.testDrives.Accept(drive.ID.String(), seller.ID, &startsAt, "")
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("confirming: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("confirming: error = %v, want %q", err, tt.wantErr)
+			}
+
+			got, err := s.testDrives.repo.GetByID(drive.ID.String())
+			if err != nil {
+				t.Fatalf("failed to reload test drive: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == models.TestDriveStatusConfirmed && !got.ScheduledFor.Equal(startsAt) {
+				t.Errorf("scheduled for %v, want %v", got.ScheduledFor, startsAt)
+			}
+		})
+	}
+}
+
+// testDrive has the buyer request a test drive of a new listing of the seller's within a single window
+func (s *testServices) testDrive(t *testing.T, seller, buyer *models.User, from, until time.Time) *models.TestDrive {
+	t.Helper()
+
+	listing := s.listing(t, seller, 1500000)
+	drive, err := s.testDrives.RequestTestDrive(listing.ID.String(), buyer.ID, []models.TestDriveWindow{{StartsAt: from, EndsAt: until}}, "")
+	if err != nil {
+		t.Fatalf("RequestTestDrive: %v", err)
+	}
+	return drive
+}
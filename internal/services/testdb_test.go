@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/database"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	"github.com/zekeriyyah/lujay-autocity/pkg/encryption"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testCurrency is the currency the test services take payment in
+const testCurrency = "NGN"
+
+// testWebhookSecret signs the mock webhooks the tests send
+const testWebhookSecret = "whsec_test"
+
+var migrateTestDatabase sync.Once
+
+// testServices are the services wired up the way routes.go does it, on the Postgres database in TEST_DATABASE_URL
+type testServices struct {
+	db           *gorm.DB
+	provider     *payment.MockProvider
+	transactions *TransactionService
+	webhooks     *PaymentWebhookService
+	fees         *FeeService
+	payouts      *PayoutService
+	offers       *OfferService
+	testDrives   *TestDriveService
+}
+
+// newTestServices connects to the Postgres database in TEST_DATABASE_URL and migrates it once, skipping the test
+// without one. Tests share the database, so every fixture they create is new and they only look at their own rows.
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	migrateTestDatabase.Do(func() {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("failed to connect to the test database: %v", err)
+		}
+		database.DB = db
+		database.Run()
+	})
+	db := database.DB
+	if db == nil {
+		t.Fatal("the test database could not be migrated")
+	}
+
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{7}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	provider := payment.NewMockProvider(testWebhookSecret, testCurrency)
+	hub := events.NewHub()
+
+	transactionRepo := repositories.NewTransactionRepository(db)
+	listingRepo := repositories.NewListingRepository(db)
+	ledger := NewLedgerService(repositories.NewLedgerRepository(db), testCurrency)
+	fees := NewFeeService(repositories.NewFeeRepository(db), repositories.NewUserRepository(db), ledger, 25000)
+	invoices := NewInvoiceService(repositories.NewInvoiceRepository(db), testCurrency, 0)
+	refunds := NewRefundService(repositories.NewRefundRepository(db), provider)
+	transactions := NewTransactionService(transactionRepo, listingRepo, ledger, fees, invoices, provider, refunds, testCurrency, "http://localhost", EscrowWindows{
+		Payment:     time.Hour,
+		Handover:    24 * time.Hour,
+		Inspection:  24 * time.Hour,
+		Dispute:     24 * time.Hour,
+		Reservation: 48 * time.Hour,
+	}, 10, hub)
+
+	return &testServices{
+		db:           db,
+		provider:     provider,
+		transactions: transactions,
+		webhooks:     NewPaymentWebhookService(repositories.NewPaymentWebhookRepository(db), transactions, provider),
+		fees:         fees,
+		payouts:      NewPayoutService(repositories.NewPayoutRepository(db), ledger, cipher, testCurrency),
+		offers:       NewOfferService(repositories.NewOfferRepository(db), listingRepo, transactions, 48*time.Hour, hub),
+		testDrives:   NewTestDriveService(repositories.NewTestDriveRepository(db), listingRepo, time.Hour),
+	}
+}
+
+// user creates a user with the given role
+func (s *testServices) user(t *testing.T, role types.Role) *models.User {
+	t.Helper()
+
+	id := uuid.New()
+	user := &models.User{
+		ID:       id,
+		Email:    id.String() + "@test.autocity",
+		Name:     "Test " + string(role),
+		Password: "not a hash",
+		Role:     role,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create %s: %v", role, err)
+	}
+	return user
+}
+
+// listing creates an active listing of a new vehicle for the seller at the given price
+func (s *testServices) listing(t *testing.T, seller *models.User, price float64) *models.Listing {
+	t.Helper()
+
+	vehicle := &models.Vehicle{
+		VIN:     strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))[:17],
+		Make:    "Toyota",
+		Model:   "Corolla",
+		Year:    2018,
+		OwnerID: &seller.ID,
+	}
+	if err := s.db.Create(vehicle).Error; err != nil {
+		t.Fatalf("failed to create vehicle: %v", err)
+	}
+
+	now := time.Now()
+	listing := &models.Listing{
+		Title:           "2018 Toyota Corolla",
+		Price:           price,
+		Location:        "Lagos",
+		VehicleSpecs:    vehicle.Specs(),
+		Status:          models.ListingStatusActive,
+		StatusChangedAt: &now,
+		SellerID:        seller.ID,
+		VehicleID:       vehicle.ID,
+	}
+	if err := s.db.Create(listing).Error; err != nil {
+		t.Fatalf("failed to create listing: %v", err)
+	}
+	return listing
+}
+
+// purchase opens a sale of the listing for the buyer, awaiting payment
+func (s *testServices) purchase(t *testing.T, listing *models.Listing, buyer *models.User) *models.Transaction {
+	t.Helper()
+
+	transaction, err := s.transactions.Purchase(listing.ID.String(), buyer.ID, "card")
+	if err != nil {
+		t.Fatalf("Purchase: %v", err)
+	}
+	return transaction
+}
+
+// webhook sends a signed mock webhook reporting a payment of the transaction and returns the stored event
+func (s *testServices) webhook(t *testing.T, transaction *models.Transaction, eventID string, status payment.Status, amount int64, currency string) *models.PaymentWebhookEvent {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]any{
+		"id":        eventID,
+		"type":      "payment." + string(status),
+		"reference": transaction.ProviderReference,
+		"status":    status,
+		"amount":    amount,
+		"currency":  currency,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode webhook: %v", err)
+	}
+	headers := http.Header{}
+	headers.Set(payment.MockSignatureHeader, s.provider.Sign(payload))
+
+	event, _, err := s.webhooks.Receive(s.provider.Name(), payload, headers)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	return event
+}
+
+// pay settles the transaction in full through a succeeded webhook, moving its funds into escrow
+func (s *testServices) pay(t *testing.T, transaction *models.Transaction) *models.Transaction {
+	t.Helper()
+
+	s.webhook(t, transaction, uuid.NewString(), payment.StatusSucceeded, payment.ToMinorUnits(transaction.Amount), testCurrency)
+	paid := s.reload(t, transaction.ID)
+	if paid.EscrowStatus != models.EscrowStatusFundsHeld {
+		t.Fatalf("paid transaction escrow = %s, want %s", paid.EscrowStatus, models.EscrowStatusFundsHeld)
+	}
+	return paid
+}
+
+// reload reads a transaction back from the database
+func (s *testServices) reload(t *testing.T, id uuid.UUID) *models.Transaction {
+	t.Helper()
+
+	transaction, err := s.transactions.repo.GetByID(id.String())
+	if err != nil {
+		t.Fatalf("failed to reload transaction %s: %v", id, err)
+	}
+	return transaction
+}
+
+// listingStatus reads a listing's current status from the database
+func (s *testServices) listingStatus(t *testing.T, id uuid.UUID) models.ListingStatus {
+	t.Helper()
+
+	listing := &models.Listing{}
+	if err := s.db.First(listing, "id = ?", id).Error; err != nil {
+		t.Fatalf("failed to reload listing %s: %v", id, err)
+	}
+	return listing.Status
+}
+
+// refunds returns the refunds recorded for a transaction, oldest first
+func (s *testServices) refunds(t *testing.T, transactionID uuid.UUID) []*models.Refund {
+	t.Helper()
+
+	refunds := []*models.Refund{}
+	if err := s.db.Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&refunds).Error; err != nil {
+		t.Fatalf("failed to load refunds: %v", err)
+	}
+	return refunds
+}
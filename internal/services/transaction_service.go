@@ -0,0 +1,439 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/events"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+	email_helper "github.com/zekeriyyah/lujay-autocity/pkg/email"
+	"github.com/zekeriyyah/lujay-autocity/pkg/payment"
+	"github.com/zekeriyyah/lujay-autocity/pkg/types"
+	"gorm.io/gorm"
+)
+
+// EscrowWindows are the time limits of the escrow steps; when one passes, the step's automatic action runs
+type EscrowWindows struct {
+	Payment    time.Duration // awaiting_payment: the sale is cancelled
+	Handover   time.Duration // funds_held: the buyer is refunded
+	Inspection time.Duration // handed_over: the funds are released to the seller
+	Dispute    time.Duration // disputed: the buyer is refunded
+
+	Reservation time.Duration // funds_held deposit: the buyer is refunded and the listing goes back on sale
+}
+
+// TransactionService runs vehicle purchases: a buyer opens a pending sale on an active listing and pays through the
+// payment provider into escrow, and the money then moves through the escrow steps (see escrow.go). A buyer can also
+// reserve a listing with a deposit first (see reservation.go).
+type TransactionService struct {
+	repo          *repositories.TransactionRepository
+	listingRepo   *repositories.ListingRepository
+	ledger        *LedgerService
+	fees          *FeeService
+	invoices      *InvoiceService
+	provider      payment.Provider
+	refunds       *RefundService
+	currency      string
+	publicBaseURL string
+	windows       EscrowWindows
+	hub           *events.Hub
+
+	// depositPercent is the share of the asking price a buyer pays to reserve a listing
+	depositPercent float64
+}
+
+func NewTransactionService(repo *repositories.TransactionRepository, listingRepo *repositories.ListingRepository, ledger *LedgerService, fees *FeeService, invoices *InvoiceService, provider payment.Provider, refunds *RefundService, currency, publicBaseURL string, windows EscrowWindows, depositPercent float64, hub *events.Hub) *TransactionService {
+	return &TransactionService{
+		repo:           repo,
+		listingRepo:    listingRepo,
+		ledger:         ledger,
+		fees:           fees,
+		invoices:       invoices,
+		provider:       provider,
+		refunds:        refunds,
+		currency:       currency,
+		publicBaseURL:  publicBaseURL,
+		windows:        windows,
+		depositPercent: depositPercent,
+		hub:            hub,
+	}
+}
+
+// transactionChange describes one audited change made by transitionWithTx
+type transactionChange struct {
+	Status   models.TransactionStatus // empty keeps the current status
+	Escrow   models.EscrowStatus      // empty keeps the current escrow status
+	Deadline *time.Time               // deadline of the new escrow step; nil clears it
+	Fields   map[string]any           // further columns to set
+	ActorID  *uuid.UUID               // nil for automatic actions
+	Reason   string
+	Amount   float64 // money moved by the change, if any
+}
+
+// transitionWithTx applies a change to a locked transaction, records it in the transaction's audit trail and posts
+// the money it moved to the ledger; a sale completing is charged its commission and invoiced, and releases any deposit
+// credited to it. Every status and escrow change goes through here.
+func (s *TransactionService) transitionWithTx(tx *gorm.DB, locked *models.Transaction, change transactionChange) error {
+	fields := map[string]any{}
+	for column, value := range change.Fields {
+		fields[column] = value
+	}
+
+	toStatus := locked.Status
+	if change.Status != "" {
+		toStatus = change.Status
+		fields["status"] = change.Status
+		if change.Status == models.TransactionStatusCompleted {
+			fields["transaction_date"] = time.Now()
+		}
+	}
+	toEscrow := locked.EscrowStatus
+	if change.Escrow != "" {
+		toEscrow = change.Escrow
+		fields["escrow_status"] = change.Escrow
+		fields["escrow_deadline"] = change.Deadline
+	}
+
+	if err := s.repo.UpdateFieldsWithTx(tx, locked.ID, fields); err != nil {
+		return err
+	}
+
+	event := &models.TransactionEvent{
+		TransactionID: locked.ID,
+		FromStatus:    locked.Status,
+		ToStatus:      toStatus,
+		FromEscrow:    locked.EscrowStatus,
+		ToEscrow:      toEscrow,
+		ActorID:       change.ActorID,
+		Reason:        change.Reason,
+		Amount:        change.Amount,
+	}
+	if err := s.repo.CreateEventWithTx(tx, event); err != nil {
+		return err
+	}
+	if err := s.ledger.RecordTransactionChangeWithTx(tx, locked, event); err != nil {
+		return err
+	}
+	if toStatus == models.TransactionStatusCompleted && locked.Status != models.TransactionStatusCompleted {
+		// commission is charged on what the seller was paid, i.e. after any partial refund and with any reservation
+		// deposit credited to the sale, which is paid to the seller along with it
+		settled := change.Amount
+		if toEscrow == models.EscrowStatusPartiallyRefunded {
+			settled = locked.Amount - change.Amount
+		}
+		var deposit *models.Transaction
+		if locked.Type == models.TransactionTypeSale {
+			var err error
+			if deposit, err = s.creditedDepositWithTx(tx, locked); err != nil {
+				return err
+			}
+			if deposit != nil {
+				settled += deposit.Amount
+			}
+			if err := s.fees.ChargeSaleCommissionWithTx(tx, locked, settled); err != nil {
+				return err
+			}
+		}
+		if err := s.invoices.IssueWithTx(tx, locked.ID); err != nil {
+			return err
+		}
+		if deposit != nil {
+			if err := s.releaseWithTx(tx, deposit, change.ActorID, fmt.Sprintf("credited to sale %s, which completed", locked.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	if (toStatus == models.TransactionStatusCompleted || toStatus == models.TransactionStatusCancelled) && locked.Status == models.TransactionStatusPending {
+		// a settled sale has nothing left to cancel
+		if err := s.repo.ClosePendingCancellationRequestsWithTx(tx, locked.ID); err != nil {
+			return err
+		}
+	}
+
+	locked.Status = toStatus
+	locked.EscrowStatus = toEscrow
+	if change.Escrow != "" {
+		locked.EscrowDeadline = change.Deadline
+	}
+	return nil
+}
+
+// Purchase opens a pending sale of an active listing at its asking price and starts the buyer's payment into escrow
+func (s *TransactionService) Purchase(listingID string, buyerID uuid.UUID, paymentMethod string) (*models.Transaction, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyerID {
+		return nil, fmt.Errorf("you cannot purchase your own listing")
+	}
+
+	var transaction *models.Transaction
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		transaction, err = s.openSaleWithTx(tx, listing, buyerID, paymentMethod, 0, "purchase started")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startPayment(transaction.ID, listing, paymentMethod)
+}
+
+// openSaleWithTx creates a pending sale of a listing awaiting the buyer's payment, at amount or at the asking price
+// when amount is zero. The availability checks run under the listing lock so two buyers cannot both open a sale.
+// A reserved listing can only be bought by the buyer who paid its deposit, which is credited against the amount.
+func (s *TransactionService) openSaleWithTx(tx *gorm.DB, listing *models.Listing, buyerID uuid.UUID, paymentMethod string, amount float64, reason string) (*models.Transaction, error) {
+	lockedListing, err := s.listingRepo.GetByIDForUpdateWithTx(tx, listing.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deposit *models.Transaction
+	switch lockedListing.Status {
+	case models.ListingStatusActive:
+	case models.ListingStatusReserved:
+		deposit, err = s.repo.GetOpenDepositByListingIDForUpdateWithTx(tx, listing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if deposit == nil || deposit.BuyerID != buyerID {
+			return nil, fmt.Errorf("listing is reserved by another buyer")
+		}
+		if deposit.EscrowStatus != models.EscrowStatusFundsHeld {
+			return nil, fmt.Errorf("reservation deposit has not been paid yet")
+		}
+	default:
+		return nil, fmt.Errorf("only active listings can be purchased")
+	}
+
+	open, err := s.repo.CountOpenSalesByListingIDWithTx(tx, listing.ID)
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, fmt.Errorf("listing already has a purchase in progress")
+	}
+
+	if amount <= 0 {
+		amount = lockedListing.Price
+	}
+	if deposit != nil {
+		if deposit.Amount >= amount {
+			return nil, fmt.Errorf("reservation deposit cannot cover the whole price")
+		}
+		amount = roundToMinorUnits(amount - deposit.Amount)
+		reason = fmt.Sprintf("%s, reservation deposit of %.2f credited", reason, deposit.Amount)
+	}
+	paymentDeadline := time.Now().Add(s.windows.Payment)
+	transaction := &models.Transaction{
+		ListingID:      listing.ID,
+		Type:           models.TransactionTypeSale,
+		BuyerID:        buyerID,
+		SellerID:       listing.SellerID,
+		Amount:         amount,
+		PaymentMethod:  paymentMethod,
+		Status:         models.TransactionStatusPending,
+		EscrowStatus:   models.EscrowStatusAwaitingPayment,
+		EscrowDeadline: &paymentDeadline,
+	}
+	if err := s.repo.CreateWithTx(tx, transaction); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateEventWithTx(tx, &models.TransactionEvent{
+		TransactionID: transaction.ID,
+		ToStatus:      transaction.Status,
+		ToEscrow:      transaction.EscrowStatus,
+		ActorID:       &buyerID,
+		Reason:        reason,
+		Amount:        transaction.Amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// startPayment asks the payment provider for the buyer's checkout of a newly opened sale, deposit or inspection fee and
+// tells the seller about a purchase. A transaction the provider cannot take payment for is marked failed so it frees
+// the listing again.
+func (s *TransactionService) startPayment(transactionID uuid.UUID, listing *models.Listing, paymentMethod string) (*models.Transaction, error) {
+	created, err := s.repo.GetByID(transactionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	description := listing.Title
+	switch created.Type {
+	case models.TransactionTypeDeposit:
+		description = "Reservation deposit for " + listing.Title
+	case models.TransactionTypeInspectionFee:
+		description = "Pre-purchase inspection of " + listing.Title
+	}
+	intent, err := s.provider.CreateIntent(payment.IntentRequest{
+		Reference:     created.ID.String(),
+		Amount:        payment.ToMinorUnits(created.Amount),
+		Currency:      s.currency,
+		CustomerEmail: created.Buyer.Email,
+		PaymentMethod: paymentMethod,
+		Description:   description,
+		RedirectURL:   fmt.Sprintf("%s/transactions/%s", s.publicBaseURL, created.ID),
+	})
+	if err != nil {
+		// the sale cannot be paid for, so it must not keep the listing from other buyers
+		failErr := s.repo.DB.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIDForUpdateWithTx(tx, created.ID)
+			if err != nil {
+				return err
+			}
+			if err := s.restoreListingWithTx(tx, locked, nil, "payment could not be started"); err != nil {
+				return err
+			}
+			return s.transitionWithTx(tx, locked, transactionChange{
+				Status: models.TransactionStatusFailed,
+				Escrow: models.EscrowStatusCancelled,
+				Reason: "payment could not be started",
+			})
+		})
+		if failErr != nil {
+			fmt.Printf("Warning: Failed to mark transaction %s failed: %v\n", created.ID, failErr)
+		}
+		return nil, fmt.Errorf("payment provider could not start the payment: %w", err)
+	}
+
+	err = s.repo.UpdateFieldsWithTx(s.repo.DB, created.ID, map[string]any{
+		"payment_provider":   s.provider.Name(),
+		"provider_reference": intent.ProviderReference,
+		"checkout_url":       intent.CheckoutURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch created.Type {
+	case models.TransactionTypeDeposit:
+		s.notifyEscrow(created, "", fmt.Sprintf("A buyer is reserving your listing with a deposit of %.2f. It stays off the market while they pay.", created.Amount))
+	case models.TransactionTypeInspectionFee:
+		// the seller hears about the inspection from the inspection request
+		s.publish(created.ID)
+	default:
+		if err := email_helper.SendPurchaseStartedEmail(listing.Seller.Email, listing.Title, created.Amount); err != nil {
+			fmt.Printf("Warning: Failed to send purchase email to seller %s: %v\n", listing.Seller.Email, err)
+		}
+		s.publish(created.ID)
+	}
+
+	return s.repo.GetByID(created.ID.String())
+}
+
+// UpdateStatus cancels or fails a sale that is still awaiting payment. Either party may cancel it and only an admin
+// can mark it failed. Paid sales move on through escrow instead.
+func (s *TransactionService) UpdateStatus(id string, newStatus models.TransactionStatus, userID uuid.UUID, userRole types.Role) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Type != models.TransactionTypeSale {
+		return nil, fmt.Errorf("only sale transactions can be updated, fees follow their inspection request")
+	}
+
+	isAdmin := userRole == types.RoleAdmin
+	if !isAdmin && transaction.BuyerID != userID && transaction.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only update your own transactions")
+	}
+
+	switch newStatus {
+	case models.TransactionStatusFailed:
+		if !isAdmin {
+			return nil, fmt.Errorf("unauthorized: only an admin can mark a transaction failed")
+		}
+	case models.TransactionStatusCancelled:
+	default:
+		return nil, fmt.Errorf("invalid transaction status '%s'", newStatus)
+	}
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := s.repo.GetByIDForUpdateWithTx(tx, transaction.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.TransactionStatusPending || locked.EscrowStatus != models.EscrowStatusAwaitingPayment {
+			return fmt.Errorf("only transactions awaiting payment can be cancelled or failed")
+		}
+
+		return s.transitionWithTx(tx, locked, transactionChange{
+			Status:  newStatus,
+			Escrow:  models.EscrowStatusCancelled,
+			ActorID: &userID,
+			Reason:  fmt.Sprintf("marked %s", newStatus),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// tell whichever parties did not make the change
+	s.notifyParties(transaction, newStatus, userID)
+
+	return s.repo.GetByID(id)
+}
+
+// notifyParties emails the buyer and seller of a transaction about its new status, skipping the user who changed it
+func (s *TransactionService) notifyParties(transaction *models.Transaction, status models.TransactionStatus, exceptUserID uuid.UUID) {
+	s.publish(transaction.ID)
+	for _, party := range []models.User{transaction.Buyer, transaction.Seller} {
+		if party.ID == exceptUserID {
+			continue
+		}
+		if err := email_helper.SendTransactionStatusEmail(party.Email, transaction.Listing.Title, string(status), transaction.Amount); err != nil {
+			fmt.Printf("Warning: Failed to send transaction status email to %s: %v\n", party.Email, err)
+		}
+	}
+}
+
+// publish pushes a transaction's current state to its buyer and seller. It runs wherever the parties are told about
+// a change, after the change is committed, and reloads the transaction since callers hold the copy from before it.
+func (s *TransactionService) publish(id uuid.UUID) {
+	transaction, err := s.repo.GetByID(id.String())
+	if err != nil {
+		fmt.Printf("Warning: Failed to load transaction %s for its event: %v\n", id, err)
+		return
+	}
+	s.hub.Publish(events.TypeTransaction, events.TransactionUpdated{
+		TransactionID: transaction.ID,
+		ListingID:     transaction.ListingID,
+		Type:          transaction.Type,
+		Status:        transaction.Status,
+		EscrowStatus:  transaction.EscrowStatus,
+		ListingStatus: transaction.Listing.Status,
+	}, transaction.BuyerID, transaction.SellerID)
+}
+
+// GetTransaction retrieves a transaction for one of its parties or an admin
+func (s *TransactionService) GetTransaction(id string, userID uuid.UUID, userRole types.Role) (*models.Transaction, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if userRole != types.RoleAdmin && transaction.BuyerID != userID && transaction.SellerID != userID {
+		return nil, fmt.Errorf("unauthorized: you can only view your own transactions")
+	}
+	return transaction, nil
+}
+
+// GetTransactionEvents retrieves the audit trail of a transaction for one of its parties or an admin
+func (s *TransactionService) GetTransactionEvents(id string, userID uuid.UUID, userRole types.Role) ([]*models.TransactionEvent, error) {
+	transaction, err := s.GetTransaction(id, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetEvents(transaction.ID)
+}
+
+// GetUserTransactions retrieves the transactions a user bought or sold in, newest first
+func (s *TransactionService) GetUserTransactions(userID uuid.UUID) ([]*models.Transaction, error) {
+	return s.repo.GetByUserID(userID)
+}
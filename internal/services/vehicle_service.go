@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zekeriyyah/lujay-autocity/internal/models"
+	"github.com/zekeriyyah/lujay-autocity/internal/repositories"
+)
+
+type VehicleService struct {
+	repo            *repositories.VehicleRepository
+	listingRepo     *repositories.ListingRepository
+	inspectionRepo  *repositories.InspectionRepository
+	transactionRepo *repositories.TransactionRepository
+}
+
+func NewVehicleService(repo *repositories.VehicleRepository, listingRepo *repositories.ListingRepository, inspectionRepo *repositories.InspectionRepository, transactionRepo *repositories.TransactionRepository) *VehicleService {
+	return &VehicleService{
+		repo:            repo,
+		listingRepo:     listingRepo,
+		inspectionRepo:  inspectionRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// GetVehicleHistory builds the listing timeline of a vehicle and flags odometer rollbacks
+func (s *VehicleService) GetVehicleHistory(vehicleID string) (*models.VehicleHistory, error) {
+	vehicle, err := s.repo.GetByID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	listings, err := s.listingRepo.GetByVehicleID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	listingIDs := make([]uuid.UUID, 0, len(listings))
+	for _, listing := range listings {
+		listingIDs = append(listingIDs, listing.ID)
+	}
+
+	inspections, err := s.inspectionRepo.GetByListingIDs(listingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspections for vehicle %s: %w", vehicleID, err)
+	}
+	inspectionsByListing := map[uuid.UUID]*models.InspectionFetchInput{}
+	for _, inspection := range inspections {
+		inspectionsByListing[inspection.ListingID] = inspection
+	}
+
+	sales, err := s.transactionRepo.GetByListingIDs(listingIDs, models.TransactionStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales for vehicle %s: %w", vehicleID, err)
+	}
+	salesByListing := map[uuid.UUID]*models.Transaction{}
+	for _, sale := range sales {
+		salesByListing[sale.ListingID] = sale
+	}
+
+	history := &models.VehicleHistory{
+		Vehicle: *vehicle,
+		Entries: []models.VehicleHistoryEntry{},
+	}
+
+	// highest odometer reading seen so far; a later listing reporting less is a rollback
+	var highestMileage uint
+	for _, listing := range listings {
+		entry := models.VehicleHistoryEntry{
+			ListingID:    listing.ID,
+			Title:        listing.Title,
+			Price:        listing.Price,
+			Mileage:      listing.Mileage,
+			VehicleSpecs: listing.VehicleSpecs,
+			Status:       listing.Status,
+			ListedAt:     listing.CreatedAt,
+		}
+
+		if inspection, ok := inspectionsByListing[listing.ID]; ok {
+			entry.Inspection = &models.VehicleHistoryInspection{
+				Status:          inspection.Status,
+				ConditionRating: inspection.ConditionRating,
+				InspectionDate:  inspection.InspectionDate,
+			}
+		}
+
+		if sale, ok := salesByListing[listing.ID]; ok {
+			entry.Sale = &models.VehicleHistorySale{
+				Amount:          sale.Amount,
+				TransactionDate: sale.TransactionDate,
+			}
+		}
+
+		// listings created before mileage was recorded per listing carry no reading
+		if listing.Mileage > 0 {
+			if listing.Mileage < highestMileage {
+				entry.OdometerRollback = true
+				history.RollbackDetected = true
+			} else {
+				highestMileage = listing.Mileage
+			}
+		}
+
+		history.Entries = append(history.Entries, entry)
+	}
+
+	return history, nil
+}
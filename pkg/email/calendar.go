@@ -0,0 +1,100 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const icsTimeFormat = "20060102T150405Z"
+
+// CalendarInvite is an appointment sent as an iCalendar (.ics) attachment so it lands in the recipient's calendar.
+// Invites sharing a UID update the same calendar entry; a higher Sequence replaces an earlier one.
+type CalendarInvite struct {
+	UID         string
+	Sequence    int
+	Summary     string
+	Description string
+	Location    string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Attendees   []string
+	Cancelled   bool // removes the entry from the attendees' calendars
+}
+
+// ICS renders the invite as an RFC 5545 calendar with a single event.
+func (i *CalendarInvite) ICS() []byte {
+	method, status := "REQUEST", "CONFIRMED"
+	if i.Cancelled {
+		method, status = "CANCEL", "CANCELLED"
+	}
+
+	var ics bytes.Buffer
+	line := func(format string, args ...any) {
+		writeICSLine(&ics, fmt.Sprintf(format, args...))
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//AutoCity//Appointments//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:%s", method)
+	line("BEGIN:VEVENT")
+	line("UID:%s", i.UID)
+	line("SEQUENCE:%d", i.Sequence)
+	line("DTSTAMP:%s", time.Now().UTC().Format(icsTimeFormat))
+	line("DTSTART:%s", i.StartsAt.UTC().Format(icsTimeFormat))
+	line("DTEND:%s", i.EndsAt.UTC().Format(icsTimeFormat))
+	line("SUMMARY:%s", escapeICSText(i.Summary))
+	if i.Description != "" {
+		line("DESCRIPTION:%s", escapeICSText(i.Description))
+	}
+	if i.Location != "" {
+		line("LOCATION:%s", escapeICSText(i.Location))
+	}
+	line("STATUS:%s", status)
+	line("ORGANIZER;CN=AutoCity:mailto:%s", os.Getenv("SMTP_USERNAME"))
+	for _, attendee := range i.Attendees {
+		line("ATTENDEE;ROLE=REQ-PARTICIPANT:mailto:%s", attendee)
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+
+	return ics.Bytes()
+}
+
+// attachment wraps the invite for sendHTMLEmailWithAttachments
+func (i *CalendarInvite) attachment() Attachment {
+	method := "REQUEST"
+	if i.Cancelled {
+		method = "CANCEL"
+	}
+	return Attachment{
+		Filename:    "invite.ics",
+		ContentType: fmt.Sprintf("text/calendar; charset=UTF-8; method=%s", method),
+		Data:        i.ICS(),
+	}
+}
+
+// writeICSLine writes a content line ending in CRLF, folded so no line is longer than 75 octets
+func writeICSLine(ics *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// never split a multi-byte character
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		ics.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts against their length
+		limit = 74
+	}
+	ics.WriteString(line + "\r\n")
+}
+
+// escapeICSText escapes the characters that have a meaning in iCalendar text values
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
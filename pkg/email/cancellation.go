@@ -0,0 +1,39 @@
+package email
+
+import (
+	"fmt"
+	"html"
+)
+
+// SendCancellationRequestedEmail asks the other party of a sale to accept or decline a cancellation request.
+// requesterRole is "buyer" or "seller".
+func SendCancellationRequestedEmail(recipientEmail, listingTitle, requesterRole, reason string) error {
+	subject := fmt.Sprintf("Cancellation requested for %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The %s has asked to cancel the sale of "<strong>%s</strong>":</p>
+<p>%s</p>
+<p>Please accept or decline the request from your dashboard. If you accept, the buyer's payment is refunded in full. The payment stays in escrow until the request is answered.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, requesterRole, listingTitle, html.EscapeString(reason))
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
+
+// SendCancellationDeclinedEmail tells the requester that their cancellation request was declined.
+func SendCancellationDeclinedEmail(recipientEmail, listingTitle, note string) error {
+	subject := fmt.Sprintf("Cancellation declined for %s", listingTitle)
+
+	details := ""
+	if note != "" {
+		details = fmt.Sprintf("<p>Note: %s</p>\n", html.EscapeString(note))
+	}
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>Your request to cancel the sale of "<strong>%s</strong>" was declined and the sale continues.</p>
+%s<p>If you cannot agree, the buyer can open a dispute after handover for our team to decide.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, details)
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
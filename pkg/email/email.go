@@ -1,21 +1,25 @@
 package email
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"html"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"os"
+	"strings"
 )
 
 // SendListingStatusEmail sends an HTML email to the seller about the status of their listing.
-func SendListingStatusEmail(sellerEmail, status, listingTitle string) error {
+// For rejections, reasons and note explain what the seller needs to fix before resubmitting.
+func SendListingStatusEmail(sellerEmail, status, listingTitle string, reasons []string, note string) error {
 	if status != "approved" && status != "rejected" {
 		return fmt.Errorf("invalid status: %s, must be 'approved' or 'rejected'", status)
 	}
 
 	subject := fmt.Sprintf("Your AutoCity Listing Status: %s", listingTitle)
-	from := os.Getenv("SMTP_USERNAME")
-	smtpHost := os.Getenv("SMTP_HOST") 
-	smtpPort := os.Getenv("SMTP_PORT") 
 
 	var statusMessage, statusExplanation string
 	switch status {
@@ -27,21 +31,99 @@ func SendListingStatusEmail(sellerEmail, status, listingTitle string) error {
 		statusExplanation = "Unfortunately, your listing did not meet our current standards and has been rejected."
 	}
 
+	var details strings.Builder
+	if status == "rejected" {
+		if len(reasons) > 0 {
+			details.WriteString("<p>The reviewer gave the following reasons:</p>\n<ul>\n")
+			for _, reason := range reasons {
+				fmt.Fprintf(&details, "<li>%s</li>\n", html.EscapeString(reason))
+			}
+			details.WriteString("</ul>\n")
+		}
+		if note != "" {
+			fmt.Fprintf(&details, "<p>Reviewer's note: %s</p>\n", html.EscapeString(note))
+		}
+		details.WriteString("<p>Once you have addressed these points you can resubmit the listing for review from your dashboard.</p>\n")
+	}
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>Your vehicle listing "<strong>%s</strong>" has been <strong>%s</strong>.</p>
+<p>%s</p>
+%s<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, statusMessage, statusExplanation, details.String())
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
+
+// sendHTMLEmail sends an HTML email through the configured SMTP server.
+func sendHTMLEmail(to, subject, htmlBody string) error {
+	from := os.Getenv("SMTP_USERNAME")
+	smtpHost := os.Getenv("SMTP_HOST") 
+	smtpPort := os.Getenv("SMTP_PORT") 
+
 	// Email headers and body with proper MIME and HTML formatting
-	body := fmt.Sprintf(`To: %s
+	message := fmt.Sprintf(`To: %s
 Subject: %s
 MIME-version: 1.0
 Content-Type: text/html; charset="UTF-8"
 
-<p>Hello,</p>
-<p>Your vehicle listing "<strong>%s</strong>" has been <strong>%s</strong>.</p>
-<p>%s</p>
-<p>Thanks,<br>The AutoCity Team</p>
-`, sellerEmail, subject, listingTitle, statusMessage, statusExplanation)
+%s`, to, subject, htmlBody)
 
 	// Set up authentication and SMTP address
 	auth := smtp.PlainAuth("", from, os.Getenv("SMTP_PASSWORD"), smtpHost)
 	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
 
-	return smtp.SendMail(addr, auth, from, []string{sellerEmail}, []byte(body))
-}
\ No newline at end of file
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(message))
+}
+
+// Attachment is a file sent along with an email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// sendHTMLEmailWithAttachments sends an HTML email with files attached as a multipart/mixed message.
+func sendHTMLEmailWithAttachments(to, subject, htmlBody string, attachments ...Attachment) error {
+	from := os.Getenv("SMTP_USERNAME")
+	smtpHost := os.Getenv("SMTP_HOST")
+	smtpPort := os.Getenv("SMTP_PORT")
+
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "To: %s\r\nSubject: %s\r\nMIME-version: 1.0\r\nContent-Type: multipart/mixed; boundary=%q\r\n\r\n", to, subject, writer.Boundary())
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {`text/html; charset="UTF-8"`}})
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	if _, err := part.Write([]byte(htmlBody)); err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", attachment.ContentType, attachment.Filename)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Filename)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build email: %w", err)
+		}
+		// base64 bodies are wrapped at 76 characters per line as MIME requires
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	auth := smtp.PlainAuth("", from, os.Getenv("SMTP_PASSWORD"), smtpHost)
+	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
+
+	return smtp.SendMail(addr, auth, from, []string{to}, message.Bytes())
+}
@@ -0,0 +1,34 @@
+package email
+
+import (
+	"fmt"
+	"time"
+)
+
+const appointmentTimeFormat = "Mon, 02 Jan 2006 at 15:04 MST"
+
+// SendInspectionReminderEmail reminds a seller or inspector of an upcoming inspection appointment.
+func SendInspectionReminderEmail(recipientEmail, recipientName, listingTitle string, scheduledFor time.Time) error {
+	subject := fmt.Sprintf("Reminder: AutoCity inspection for %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello %s,</p>
+<p>This is a reminder that the inspection of "<strong>%s</strong>" is scheduled for <strong>%s</strong>.</p>
+<p>Please make sure the vehicle and its documents are available at the agreed location.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, recipientName, listingTitle, scheduledFor.Format(appointmentTimeFormat))
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
+
+// SendInspectionCancelledEmail tells a seller their inspection appointment was cancelled and needs rebooking.
+func SendInspectionCancelledEmail(sellerEmail, listingTitle string, scheduledFor time.Time, reason string) error {
+	subject := fmt.Sprintf("Your AutoCity inspection for %s was cancelled", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The inspection of "<strong>%s</strong>" scheduled for <strong>%s</strong> has been cancelled (%s).</p>
+<p>Please pick a new slot so we can continue reviewing your listing.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, scheduledFor.Format(appointmentTimeFormat), reason)
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
@@ -0,0 +1,86 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// SendInspectionRequestEmail asks a seller to allow a buyer's pre-purchase inspection of their vehicle.
+func SendInspectionRequestEmail(sellerEmail, listingTitle, buyerNote string) error {
+	subject := fmt.Sprintf("A buyer wants to inspect %s", listingTitle)
+
+	note := ""
+	if buyerNote != "" {
+		note = fmt.Sprintf("<p>The buyer's note: %s</p>\n", html.EscapeString(buyerNote))
+	}
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>A buyer interested in "<strong>%s</strong>" has ordered an independent pre-purchase inspection.</p>
+%s<p>Please accept or decline the request from your dashboard. The buyer pays the inspection fee and the report is shared with them only.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, note)
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
+
+// SendInspectionRequestResponseEmail tells a buyer whether the seller allowed their pre-purchase inspection.
+func SendInspectionRequestResponseEmail(buyerEmail, listingTitle string, consented bool, reason string) error {
+	subject := fmt.Sprintf("Your inspection request for %s", listingTitle)
+
+	var outcome string
+	if consented {
+		outcome = "<p>The seller has agreed. You can now pick an inspection slot from your dashboard.</p>"
+	} else {
+		outcome = "<p>Unfortunately the seller has declined the inspection.</p>"
+		if reason != "" {
+			outcome += fmt.Sprintf("\n<p>Reason given: %s</p>", html.EscapeString(reason))
+		}
+	}
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>You requested a pre-purchase inspection of "<strong>%s</strong>".</p>
+%s
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, outcome)
+
+	return sendHTMLEmail(buyerEmail, subject, body)
+}
+
+// SendPrePurchaseInspectionScheduledEmail tells a seller when a buyer's inspector will examine their vehicle.
+func SendPrePurchaseInspectionScheduledEmail(sellerEmail, listingTitle string, scheduledFor time.Time) error {
+	subject := fmt.Sprintf("Pre-purchase inspection of %s scheduled", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The pre-purchase inspection of "<strong>%s</strong>" is scheduled for <strong>%s</strong>.</p>
+<p>Please make sure the vehicle and its documents are available at the agreed location.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, scheduledFor.Format(appointmentTimeFormat))
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
+
+// SendPrePurchaseReportReadyEmail tells a buyer the report of their pre-purchase inspection can be downloaded.
+func SendPrePurchaseReportReadyEmail(buyerEmail, listingTitle string, conditionRating int) error {
+	subject := fmt.Sprintf("Your inspection report for %s is ready", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The pre-purchase inspection of "<strong>%s</strong>" is complete with a condition rating of <strong>%d / 10</strong>.</p>
+<p>The full report is available to you only, from your inspection requests in the AutoCity app.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, conditionRating)
+
+	return sendHTMLEmail(buyerEmail, subject, body)
+}
+
+// SendInspectionRequestCancelledEmail tells a buyer or seller that a pre-purchase inspection was called off.
+func SendInspectionRequestCancelledEmail(recipientEmail, listingTitle, reason string) error {
+	subject := fmt.Sprintf("Pre-purchase inspection of %s cancelled", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The pre-purchase inspection of "<strong>%s</strong>" has been cancelled (%s).</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, html.EscapeString(reason))
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
@@ -0,0 +1,23 @@
+package email
+
+import (
+	"fmt"
+)
+
+// SendInvoiceEmail sends a party of a completed transaction its receipt, with the invoice in the body and attached as a PDF.
+// invoiceHTML must already be escaped, as rendered by the invoice service.
+func SendInvoiceEmail(recipientEmail, invoiceNumber, invoiceHTML string, invoicePDF []byte) error {
+	subject := fmt.Sprintf("Your AutoCity receipt %s", invoiceNumber)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>Thank you for using AutoCity. Your receipt <strong>%s</strong> is below and attached as a PDF for your records.</p>
+%s
+<p>Thanks,<br>The AutoCity Team</p>
+`, invoiceNumber, invoiceHTML)
+
+	return sendHTMLEmailWithAttachments(recipientEmail, subject, body, Attachment{
+		Filename:    invoiceNumber + ".pdf",
+		ContentType: "application/pdf",
+		Data:        invoicePDF,
+	})
+}
@@ -0,0 +1,17 @@
+package email
+
+import "fmt"
+
+// SendNewMessageEmail tells a buyer or seller they have an unread message about a listing. Only the first unread
+// message of a thread is emailed, so a conversation in progress does not flood the inbox.
+func SendNewMessageEmail(recipientEmail, listingTitle, senderParty string) error {
+	subject := fmt.Sprintf("New message about %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The %s sent you a message about "<strong>%s</strong>".</p>
+<p>You can read and reply to it from your dashboard.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, senderParty, listingTitle)
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
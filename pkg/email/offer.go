@@ -0,0 +1,20 @@
+package email
+
+import (
+	"fmt"
+	"html"
+)
+
+// SendOfferUpdateEmail tells a party of a price negotiation what the other side did and what they can do next.
+func SendOfferUpdateEmail(recipientEmail, listingTitle, update string) error {
+	subject := fmt.Sprintf("Offer update for %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>There is news on the offer for "<strong>%s</strong>".</p>
+<p>%s</p>
+<p>You can respond to offers from your dashboard.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, html.EscapeString(update))
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
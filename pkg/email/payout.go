@@ -0,0 +1,32 @@
+package email
+
+import (
+	"fmt"
+	"html"
+)
+
+// SendPayoutSettledEmail tells a seller that the bank has paid their earnings into their payout account.
+func SendPayoutSettledEmail(sellerEmail, reference string, amount float64, accountLast4 string, sales int) error {
+	subject := fmt.Sprintf("Payout %s has been paid", reference)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>We have paid <strong>%.2f</strong> for %d completed sale(s) into your account ending in <strong>%s</strong>.</p>
+<p>The transfer reference is <strong>%s</strong>; it may take a few hours to show on your statement.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, amount, sales, html.EscapeString(accountLast4), html.EscapeString(reference))
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
+
+// SendPayoutFailedEmail tells a seller that the bank rejected their payout and the money will be paid in a later batch.
+func SendPayoutFailedEmail(sellerEmail, reference string, amount float64, reason string) error {
+	subject := fmt.Sprintf("Payout %s could not be paid", reference)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>The bank could not pay your payout <strong>%s</strong> of <strong>%.2f</strong>: %s</p>
+<p>Please check your payout bank details. The money stays in your balance and will be paid in the next payout batch.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, html.EscapeString(reference), amount, html.EscapeString(reason))
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
@@ -0,0 +1,38 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// SendTestDriveUpdateEmail tells a party of a test drive what changed. An invite, when given, is attached so the
+// appointment is added to, moved in or removed from the recipient's calendar.
+func SendTestDriveUpdateEmail(recipientEmail, listingTitle, update string, invite *CalendarInvite) error {
+	subject := fmt.Sprintf("Test drive update for %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>There is news on the test drive of "<strong>%s</strong>".</p>
+<p>%s</p>
+<p>You can manage your test drives from your dashboard.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, html.EscapeString(update))
+
+	if invite == nil {
+		return sendHTMLEmail(recipientEmail, subject, body)
+	}
+	return sendHTMLEmailWithAttachments(recipientEmail, subject, body, invite.attachment())
+}
+
+// SendTestDriveReminderEmail reminds a buyer or seller of an upcoming test drive, attaching the calendar invite again.
+func SendTestDriveReminderEmail(recipientEmail, recipientName, listingTitle, location string, scheduledFor time.Time, invite *CalendarInvite) error {
+	subject := fmt.Sprintf("Reminder: test drive of %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello %s,</p>
+<p>This is a reminder that the test drive of "<strong>%s</strong>" is scheduled for <strong>%s</strong> at <strong>%s</strong>.</p>
+<p>If you can no longer make it, please cancel from your dashboard so the other party is not left waiting.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, html.EscapeString(recipientName), listingTitle, scheduledFor.Format(appointmentTimeFormat), html.EscapeString(location))
+
+	return sendHTMLEmailWithAttachments(recipientEmail, subject, body, invite.attachment())
+}
@@ -0,0 +1,61 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// SendPurchaseStartedEmail tells a seller that a buyer has started purchasing their vehicle.
+func SendPurchaseStartedEmail(sellerEmail, listingTitle string, amount float64) error {
+	subject := fmt.Sprintf("A buyer is purchasing %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>A buyer has started the purchase of "<strong>%s</strong>" for <strong>%.2f</strong>.</p>
+<p>Once you have received the payment, confirm the sale from your dashboard and the listing will be marked as sold.</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, amount)
+
+	return sendHTMLEmail(sellerEmail, subject, body)
+}
+
+// SendTransactionStatusEmail tells a party of a sale that it was completed, failed or was cancelled.
+func SendTransactionStatusEmail(recipientEmail, listingTitle, status string, amount float64) error {
+	subject := fmt.Sprintf("Purchase of %s %s", listingTitle, status)
+
+	var outcome string
+	switch status {
+	case "completed":
+		outcome = "<p>The sale is complete and the listing has been marked as sold.</p>"
+	case "failed":
+		outcome = "<p>The payment did not go through, so the sale could not be completed. The listing remains available.</p>"
+	default:
+		outcome = "<p>The purchase has been cancelled. The listing remains available.</p>"
+	}
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>This is an update on the purchase of "<strong>%s</strong>" for <strong>%.2f</strong>.</p>
+%s
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, amount, outcome)
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
+
+// SendEscrowUpdateEmail tells a party of a sale what happened to the money held in escrow and what to do next.
+func SendEscrowUpdateEmail(recipientEmail, listingTitle, update string) error {
+	subject := fmt.Sprintf("Escrow update for %s", listingTitle)
+
+	body := fmt.Sprintf(`<p>Hello,</p>
+<p>This is an update on the escrow for "<strong>%s</strong>".</p>
+<p>%s</p>
+<p>Thanks,<br>The AutoCity Team</p>
+`, listingTitle, html.EscapeString(update))
+
+	return sendHTMLEmail(recipientEmail, subject, body)
+}
+
+// EscrowDeadline formats an escrow deadline for use in an update
+func EscrowDeadline(deadline time.Time) string {
+	return deadline.Format(appointmentTimeFormat)
+}
@@ -0,0 +1,87 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	for _, plaintext := range []string{"", "0123456789", "GTBank 0123456789 Adé Ọlá", string(bytes.Repeat([]byte("x"), 4096))} {
+		sealed, err := c.Seal(plaintext)
+		if err != nil {
+			t.Fatalf("Seal(%q): %v", plaintext, err)
+		}
+		if plaintext != "" && bytes.Contains([]byte(sealed), []byte(plaintext)) {
+			t.Errorf("sealed value %q contains the plaintext", sealed)
+		}
+
+		opened, err := c.Open(sealed)
+		if err != nil {
+			t.Fatalf("Open(Seal(%q)): %v", plaintext, err)
+		}
+		if opened != plaintext {
+			t.Errorf("Open(Seal(%q)) = %q", plaintext, opened)
+		}
+
+		// a fresh nonce every time, so equal values do not give equal ciphertexts
+		again, err := c.Seal(plaintext)
+		if err != nil {
+			t.Fatalf("Seal(%q): %v", plaintext, err)
+		}
+		if again == sealed {
+			t.Errorf("sealing %q twice gave the same value", plaintext)
+		}
+	}
+}
+
+func TestOpenRejectsForeignValues(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	other, err := NewCipher(bytes.Repeat([]byte{8}, KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	sealed, err := c.Seal("0123456789")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	flipped := append([]byte{}, raw...)
+	flipped[len(flipped)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		cipher *Cipher
+		sealed string
+	}{
+		{"other key", other, sealed},
+		{"tampered ciphertext", c, base64.StdEncoding.EncodeToString(flipped)},
+		{"truncated", c, base64.StdEncoding.EncodeToString(raw[:8])},
+		{"not base64", c, "not base64!"},
+		{"empty", c, ""},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.cipher.Open(tt.sealed); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: Open error = %v, want %v", tt.name, err, ErrMalformed)
+		}
+	}
+}
+
+func TestNewCipherKeySize(t *testing.T) {
+	for _, size := range []int{0, 16, 31, 33, 64} {
+		if _, err := NewCipher(make([]byte, size)); err == nil {
+			t.Errorf("NewCipher accepted a %d-byte key", size)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// apiClient is the JSON-over-HTTPS client shared by the real provider adapters
+type apiClient struct {
+	provider  string
+	baseURL   string
+	secretKey string
+	client    *http.Client
+}
+
+func newAPIClient(provider, baseURL, secretKey string) apiClient {
+	return apiClient{
+		provider:  provider,
+		baseURL:   baseURL,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends body (if any) as JSON with bearer authentication and decodes the response into out
+func (c apiClient) do(method, path string, body any, out any) error {
+	return c.send(method, path, "", body, out)
+}
+
+// doIdempotent is do with an Idempotency-Key header, so the provider treats a repeated request as the first one
+func (c apiClient) doIdempotent(method, path, idempotencyKey string, body any, out any) error {
+	return c.send(method, path, idempotencyKey, body, out)
+}
+
+func (c apiClient) send(method, path, idempotencyKey string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s request: %w", c.provider, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", c.provider, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.provider, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", c.provider, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s request failed with status %d: %s", c.provider, resp.StatusCode, truncate(string(raw), 300))
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.provider, err)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,130 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// MockSignatureHeader carries the hex HMAC-SHA256 of a mock webhook payload
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockDeclinedSuffix makes the mock provider decline any amount whose minor units end in 51, e.g. 1000.51
+const MockDeclinedSuffix = 51
+
+// MockProvider is a deterministic local provider for development and tests. It keeps no state and makes no network
+// calls: references are derived from ours, captures succeed in the configured currency unless the amount ends in
+// MockDeclinedSuffix minor units, and webhooks are signed with the configured secret.
+type MockProvider struct {
+	WebhookSecret string
+	Currency      string // currency every capture is reported in
+}
+
+func NewMockProvider(webhookSecret, currency string) *MockProvider {
+	return &MockProvider{WebhookSecret: webhookSecret, Currency: currency}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// CreateIntent returns a pending intent whose reference is derived from req.Reference
+func (p *MockProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	if req.Reference == "" || req.Amount <= 0 {
+		return nil, fmt.Errorf("mock payment intent needs a reference and a positive amount")
+	}
+
+	reference := p.reference("pi", req.Reference)
+	return &Intent{
+		ProviderReference: reference,
+		CheckoutURL:       "mock://checkout/" + reference,
+		Status:            StatusPending,
+	}, nil
+}
+
+// Capture succeeds for the full amount, or is declined when the amount ends in MockDeclinedSuffix minor units
+func (p *MockProvider) Capture(providerReference string, amount int64) (*Capture, error) {
+	capture := &Capture{
+		ProviderReference: providerReference,
+		Status:            StatusSucceeded,
+		Amount:            amount,
+		Currency:          p.Currency,
+		PaymentMethod:     "card",
+	}
+	if amount%100 == MockDeclinedSuffix {
+		capture.Status = StatusFailed
+		capture.Amount = 0
+		capture.Message = "card declined by mock provider"
+	}
+	return capture, nil
+}
+
+// Refund always succeeds; the same idempotency key gives the same refund reference
+func (p *MockProvider) Refund(providerReference string, amount int64, reason, idempotencyKey string) (*Refund, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("refund amount must be positive")
+	}
+	return &Refund{
+		RefundReference: p.reference("rf", idempotencyKey),
+		Status:          StatusSucceeded,
+		Amount:          amount,
+	}, nil
+}
+
+// mockWebhookPayload is the body of a mock webhook
+type mockWebhookPayload struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+	Status    Status `json:"status"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+}
+
+// VerifyWebhook checks MockSignatureHeader against the payload and decodes it; without a secret nothing verifies
+func (p *MockProvider) VerifyWebhook(payload []byte, headers http.Header) (*WebhookEvent, error) {
+	if p.WebhookSecret == "" {
+		return nil, ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(headers.Get(MockSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	event := mockWebhookPayload{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode mock webhook: %w", err)
+	}
+	if event.ID == "" || event.Reference == "" {
+		return nil, fmt.Errorf("mock webhook is missing its id or reference")
+	}
+
+	return &WebhookEvent{
+		ID:                event.ID,
+		Type:              event.Type,
+		ProviderReference: event.Reference,
+		Status:            event.Status,
+		Amount:            event.Amount,
+		Currency:          event.Currency,
+	}, nil
+}
+
+// Sign returns the MockSignatureHeader value for a payload, for simulating webhooks locally
+func (p *MockProvider) Sign(payload []byte) string {
+	return hex.EncodeToString(p.sign(payload))
+}
+
+func (p *MockProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(p.WebhookSecret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (p *MockProvider) reference(prefix, seed string) string {
+	digest := sha256.Sum256([]byte(seed))
+	return fmt.Sprintf("mock_%s_%s", prefix, hex.EncodeToString(digest[:12]))
+}
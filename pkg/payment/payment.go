@@ -0,0 +1,88 @@
+// Package payment takes money through a payment provider. Amounts are in minor units (kobo for NGN).
+package payment
+
+import (
+	"errors"
+	"math"
+	"net/http"
+)
+
+// Status is the provider-neutral state of a payment or refund
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusRefunded  Status = "refunded"
+)
+
+// ErrInvalidSignature is returned by VerifyWebhook when a payload was not signed by the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// IntentRequest asks a provider to start collecting a payment. Reference is ours and must be unique per payment.
+type IntentRequest struct {
+	Reference     string
+	Amount        int64
+	Currency      string
+	CustomerEmail string
+	PaymentMethod string // preferred channel, e.g. card or bank_transfer; empty lets the provider offer all
+	Description   string
+	RedirectURL   string // where the provider sends the customer after checkout
+}
+
+// Intent is a payment waiting for the customer to pay at CheckoutURL
+type Intent struct {
+	ProviderReference string
+	CheckoutURL       string
+	Status            Status
+}
+
+// Capture is the outcome of collecting an intent's funds
+type Capture struct {
+	ProviderReference string
+	Status            Status
+	Amount            int64
+	Currency          string // ISO 4217 code Amount was paid in
+	PaymentMethod     string // channel the customer actually paid with
+	Message           string
+}
+
+// Refund is a refund issued against a captured payment
+type Refund struct {
+	RefundReference string
+	Status          Status
+	Amount          int64
+}
+
+// WebhookEvent is a verified provider notification about a payment
+type WebhookEvent struct {
+	ID                string // unique per event, for deduplication
+	Type              string // the provider's event name
+	ProviderReference string
+	Status            Status
+	Amount            int64
+	Currency          string // ISO 4217 code of Amount
+}
+
+// Provider is implemented by every payment gateway adapter
+type Provider interface {
+	// Name identifies the provider in stored transactions and webhook URLs
+	Name() string
+	CreateIntent(req IntentRequest) (*Intent, error)
+	// Capture collects the funds of a paid intent; capturing an already captured payment returns it again
+	Capture(providerReference string, amount int64) (*Capture, error)
+	// Refund returns amount of a captured payment; a request repeated with the same idempotencyKey refunds only once
+	Refund(providerReference string, amount int64, reason, idempotencyKey string) (*Refund, error)
+	VerifyWebhook(payload []byte, headers http.Header) (*WebhookEvent, error)
+}
+
+// ToMinorUnits converts a major-unit amount such as a listing price to minor units
+func ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// ToMajorUnits converts minor units back to a major-unit amount
+func ToMajorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestToMinorUnits(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   int64
+	}{
+		{0, 0},
+		{1, 100},
+		{19.99, 1999},
+		// 0.29 * 100 is 28.999999999999996 in floating point; truncating would lose a minor unit
+		{0.29, 29},
+		{0.1 + 0.2, 30},
+		{1234.56, 123456},
+		{12345678.91, 1234567891},
+		// half a minor unit rounds away from zero
+		{0.125, 13},
+		{-10.5, -1050},
+	}
+
+	for _, tt := range tests {
+		if got := ToMinorUnits(tt.amount); got != tt.want {
+			t.Errorf("ToMinorUnits(%v) = %d, want %d", tt.amount, got, tt.want)
+		}
+		if back := ToMinorUnits(ToMajorUnits(tt.want)); back != tt.want {
+			t.Errorf("ToMinorUnits(ToMajorUnits(%d)) = %d, want it unchanged", tt.want, back)
+		}
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	mockPayload := []byte(`{"id":"evt_1","type":"payment.succeeded","reference":"mock_pi_1","status":"succeeded","amount":150000,"currency":"NGN"}`)
+	paystackPayload := []byte(`{"event":"charge.success","data":{"id":7,"reference":"ref_1","status":"success","amount":150000,"currency":"NGN"}}`)
+	flutterwavePayload := []byte(`{"event":"charge.completed","data":{"id":7,"tx_ref":"ref_1","status":"successful","amount":1500,"currency":"NGN"}}`)
+	tampered := func(payload []byte) []byte {
+		changed := append([]byte{}, payload...)
+		changed[len(changed)-3] = 'X'
+		return changed
+	}
+
+	tests := []struct {
+		name     string
+		provider Provider
+		payload  []byte
+		header   string
+		value    string
+		valid    bool
+	}{
+		{"mock signed", NewMockProvider("whsec", "NGN"), mockPayload, MockSignatureHeader, NewMockProvider("whsec", "NGN").Sign(mockPayload), true},
+		{"mock tampered payload", NewMockProvider("whsec", "NGN"), tampered(mockPayload), MockSignatureHeader, NewMockProvider("whsec", "NGN").Sign(mockPayload), false},
+		{"mock signed with another secret", NewMockProvider("whsec", "NGN"), mockPayload, MockSignatureHeader, NewMockProvider("other", "NGN").Sign(mockPayload), false},
+		{"mock missing signature", NewMockProvider("whsec", "NGN"), mockPayload, MockSignatureHeader, "", false},
+		{"mock empty secret", NewMockProvider("", "NGN"), mockPayload, MockSignatureHeader, NewMockProvider("", "NGN").Sign(mockPayload), false},
+
+		{"paystack signed", NewPaystackProvider("sk_test"), paystackPayload, PaystackSignatureHeader, paystackSignature("sk_test", paystackPayload), true},
+		{"paystack tampered payload", NewPaystackProvider("sk_test"), tampered(paystackPayload), PaystackSignatureHeader, paystackSignature("sk_test", paystackPayload), false},
+		{"paystack signed with another key", NewPaystackProvider("sk_test"), paystackPayload, PaystackSignatureHeader, paystackSignature("sk_other", paystackPayload), false},
+		{"paystack malformed signature", NewPaystackProvider("sk_test"), paystackPayload, PaystackSignatureHeader, "not-hex", false},
+		{"paystack empty secret", NewPaystackProvider(""), paystackPayload, PaystackSignatureHeader, paystackSignature("", paystackPayload), false},
+
+		{"flutterwave matching hash", NewFlutterwaveProvider("sk_test", "hash"), flutterwavePayload, FlutterwaveSignatureHeader, "hash", true},
+		{"flutterwave wrong hash", NewFlutterwaveProvider("sk_test", "hash"), flutterwavePayload, FlutterwaveSignatureHeader, "hash2", false},
+		{"flutterwave missing hash", NewFlutterwaveProvider("sk_test", "hash"), flutterwavePayload, FlutterwaveSignatureHeader, "", false},
+		{"flutterwave empty secret", NewFlutterwaveProvider("sk_test", ""), flutterwavePayload, FlutterwaveSignatureHeader, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			headers.Set(tt.header, tt.value)
+
+			event, err := tt.provider.VerifyWebhook(tt.payload, headers)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Fatalf("VerifyWebhook error = %v, want %v", err, ErrInvalidSignature)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyWebhook error = %v", err)
+			}
+			if event.Status != StatusSucceeded || event.Amount != 150000 || event.Currency != "NGN" {
+				t.Errorf("event = %+v, want a succeeded payment of 150000 NGN", event)
+			}
+		})
+	}
+}
+
+func paystackSignature(secretKey string, payload []byte) string {
+	mac := hmac.New(sha512.New, []byte(secretKey))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
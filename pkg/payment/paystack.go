@@ -0,0 +1,168 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PaystackSignatureHeader carries the hex HMAC-SHA512 of a Paystack webhook payload, keyed with the secret key
+const PaystackSignatureHeader = "X-Paystack-Signature"
+
+// PaystackProvider takes payments through Paystack. Paystack charges at checkout, so Capture verifies the charge.
+type PaystackProvider struct {
+	api apiClient
+}
+
+func NewPaystackProvider(secretKey string) *PaystackProvider {
+	return &PaystackProvider{api: newAPIClient("paystack", "https://api.paystack.co", secretKey)}
+}
+
+func (p *PaystackProvider) Name() string {
+	return "paystack"
+}
+
+// paystackResponse is the envelope of every Paystack API response
+type paystackResponse[T any] struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+type paystackTransaction struct {
+	ID        int64  `json:"id"`
+	Reference string `json:"reference"`
+	// set instead of Reference on refund events
+	TransactionReference string `json:"transaction_reference"`
+	Status               string `json:"status"`
+	Amount               int64  `json:"amount"`
+	Currency             string `json:"currency"`
+	Channel              string `json:"channel"`
+}
+
+// CreateIntent initialises a Paystack transaction under our reference
+func (p *PaystackProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	body := map[string]any{
+		"reference":    req.Reference,
+		"amount":       req.Amount,
+		"currency":     req.Currency,
+		"email":        req.CustomerEmail,
+		"callback_url": req.RedirectURL,
+		"metadata":     map[string]string{"description": req.Description},
+	}
+	if req.PaymentMethod != "" {
+		body["channels"] = []string{req.PaymentMethod}
+	}
+
+	resp := paystackResponse[struct {
+		AuthorizationURL string `json:"authorization_url"`
+		Reference        string `json:"reference"`
+	}]{}
+	if err := p.api.do(http.MethodPost, "/transaction/initialize", body, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Status {
+		return nil, fmt.Errorf("paystack could not initialise the payment: %s", resp.Message)
+	}
+
+	return &Intent{ProviderReference: resp.Data.Reference, CheckoutURL: resp.Data.AuthorizationURL, Status: StatusPending}, nil
+}
+
+// Capture verifies the charge and checks the full amount was paid
+func (p *PaystackProvider) Capture(providerReference string, amount int64) (*Capture, error) {
+	resp := paystackResponse[paystackTransaction]{}
+	if err := p.api.do(http.MethodGet, "/transaction/verify/"+url.PathEscape(providerReference), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	capture := &Capture{
+		ProviderReference: providerReference,
+		Status:            paystackStatus(resp.Data.Status),
+		Amount:            resp.Data.Amount,
+		Currency:          resp.Data.Currency,
+		PaymentMethod:     resp.Data.Channel,
+		Message:           resp.Message,
+	}
+	if capture.Status == StatusSucceeded && capture.Amount < amount {
+		capture.Status = StatusFailed
+		capture.Message = fmt.Sprintf("paid amount %d is less than the %d due", capture.Amount, amount)
+	}
+	return capture, nil
+}
+
+func (p *PaystackProvider) Refund(providerReference string, amount int64, reason, idempotencyKey string) (*Refund, error) {
+	body := map[string]any{"transaction": providerReference, "amount": amount, "merchant_note": reason}
+
+	resp := paystackResponse[struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+		Amount int64  `json:"amount"`
+	}]{}
+	if err := p.api.doIdempotent(http.MethodPost, "/refund", idempotencyKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Status {
+		return nil, fmt.Errorf("paystack could not refund the payment: %s", resp.Message)
+	}
+
+	status := StatusPending
+	if resp.Data.Status == "processed" {
+		status = StatusSucceeded
+	}
+	return &Refund{RefundReference: fmt.Sprint(resp.Data.ID), Status: status, Amount: resp.Data.Amount}, nil
+}
+
+// VerifyWebhook checks the payload's HMAC-SHA512 signature and decodes the charge event; without a secret key
+// nothing verifies
+func (p *PaystackProvider) VerifyWebhook(payload []byte, headers http.Header) (*WebhookEvent, error) {
+	if p.api.secretKey == "" {
+		return nil, ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(headers.Get(PaystackSignatureHeader))
+	mac := hmac.New(sha512.New, []byte(p.api.secretKey))
+	mac.Write(payload)
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	event := struct {
+		Event string              `json:"event"`
+		Data  paystackTransaction `json:"data"`
+	}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode paystack webhook: %w", err)
+	}
+
+	status := paystackStatus(event.Data.Status)
+	reference := event.Data.Reference
+	if event.Event == "refund.processed" {
+		status = StatusRefunded
+		reference = event.Data.TransactionReference
+	}
+
+	// Paystack events carry no id of their own; an event type happens once per transaction
+	return &WebhookEvent{
+		ID:                fmt.Sprintf("%s:%d", event.Event, event.Data.ID),
+		Type:              event.Event,
+		ProviderReference: reference,
+		Status:            status,
+		Amount:            event.Data.Amount,
+		Currency:          event.Data.Currency,
+	}, nil
+}
+
+func paystackStatus(status string) Status {
+	switch status {
+	case "success":
+		return StatusSucceeded
+	case "failed", "abandoned", "reversed":
+		return StatusFailed
+	default:
+		return StatusPending
+	}
+}
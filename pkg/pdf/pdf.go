@@ -0,0 +1,300 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica fonts,
+// lines, filled rectangles and embedded JPEG images. Coordinates are in points
+// measured from the top-left corner of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+func (f Font) resourceName() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Document is a PDF being assembled in memory.
+type Document struct {
+	pages  []*Page
+	images []*Image
+}
+
+// Page is a single A4 page of a Document.
+type Page struct {
+	content bytes.Buffer
+}
+
+// Image is a JPEG embedded once in a Document and drawable on any of its pages.
+type Image struct {
+	name       string
+	data       []byte
+	Width      int
+	Height     int
+	colorSpace string
+}
+
+// New creates an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends a blank A4 page.
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far, in order.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// AddJPEG embeds JPEG data so it can be drawn with Page.Image.
+func (d *Document) AddJPEG(data []byte) (*Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid jpeg image: %w", err)
+	}
+
+	colorSpace := "DeviceRGB"
+	switch config.ColorModel {
+	case color.GrayModel:
+		colorSpace = "DeviceGray"
+	case color.CMYKModel:
+		colorSpace = "DeviceCMYK"
+	}
+
+	img := &Image{
+		name:       fmt.Sprintf("Im%d", len(d.images)+1),
+		data:       data,
+		Width:      config.Width,
+		Height:     config.Height,
+		colorSpace: colorSpace,
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// SetFillColor sets the colour used for text and filled shapes.
+func (p *Page) SetFillColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// SetStrokeColor sets the colour used for lines.
+func (p *Page) SetStrokeColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// Text draws a single line of text with its baseline at y.
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font.resourceName(), size, x, A4Height-y, escapeText(text))
+}
+
+// Rect draws a filled rectangle whose top-left corner is at x, y.
+func (p *Page) Rect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re f\n", x, A4Height-y-height, width, height)
+}
+
+// Line draws a straight line.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, A4Height-y1, x2, A4Height-y2)
+}
+
+// Image draws an embedded image scaled into the box whose top-left corner is at x, y.
+func (p *Page) Image(img *Image, x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", width, height, x, A4Height-y-height, img.name)
+}
+
+// TextWidth returns the width in points of text set in font at size.
+func TextWidth(font Font, size float64, text string) float64 {
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+
+	var total int
+	for _, r := range text {
+		if r >= 32 && r < 127 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WrapText splits text into lines no wider than maxWidth, breaking on spaces.
+func WrapText(font Font, size float64, text string, maxWidth float64) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(font, size, candidate) > maxWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Bytes serialises the document.
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		return nil, fmt.Errorf("pdf document has no pages")
+	}
+
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// fixed objects: 1 catalog, 2 page tree, 3-4 fonts; images and pages follow
+	const catalogID, pagesID, regularFontID, boldFontID = 1, 2, 3, 4
+	nextID := 5
+
+	imageIDs := make([]int, len(d.images))
+	for i := range d.images {
+		imageIDs[i] = nextID
+		nextID++
+	}
+	pageIDs := make([]int, len(d.pages))
+	contentIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = nextID
+		contentIDs[i] = nextID + 1
+		nextID += 2
+	}
+
+	w.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs)))
+
+	w.object(regularFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.object(boldFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	xObjects := make([]string, len(d.images))
+	for i, img := range d.images {
+		header := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			img.Width, img.Height, img.colorSpace, len(img.data))
+		w.stream(imageIDs[i], header, img.data)
+		xObjects[i] = fmt.Sprintf("/%s %d 0 R", img.name, imageIDs[i])
+	}
+
+	resources := fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s >> >>", regularFontID, boldFontID, strings.Join(xObjects, " "))
+	for i, page := range d.pages {
+		w.object(pageIDs[i], fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			pagesID, A4Width, A4Height, resources, contentIDs[i]))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to compress page content: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress page content: %w", err)
+		}
+		w.stream(contentIDs[i], fmt.Sprintf("<< /Filter /FlateDecode /Length %d >>", compressed.Len()), compressed.Bytes())
+	}
+
+	xrefOffset := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", nextID)
+	for id := 1; id < nextID; id++ {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", w.offsets[id])
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", nextID, catalogID, xrefOffset)
+
+	return w.buf.Bytes(), nil
+}
+
+// writer tracks the byte offset of every object for the cross-reference table
+type writer struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (w *writer) object(id int, body string) {
+	w.mark(id)
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *writer) stream(id int, header string, data []byte) {
+	w.mark(id)
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nstream\n", id, header)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (w *writer) mark(id int) {
+	if w.offsets == nil {
+		w.offsets = map[int]int{}
+	}
+	w.offsets[id] = w.buf.Len()
+}
+
+// escapeText encodes text as a WinAnsi literal string; characters outside Latin-1 become '?'
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Glyph widths of the printable ASCII range (32-126) in 1/1000 em, from the standard font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
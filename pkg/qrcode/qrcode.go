@@ -0,0 +1,501 @@
+// Package qrcode encodes short texts such as URLs as QR codes (byte mode, error correction level M, versions 1-10).
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Code is an encoded QR symbol; Modules[row][col] is true for dark modules.
+type Code struct {
+	Version int
+	Size    int
+	Modules [][]bool
+}
+
+// blockLayout describes the error correction blocks of one version at level M
+type blockLayout struct {
+	ecPerBlock   int
+	group1Blocks int
+	group1Data   int
+	group2Blocks int
+	group2Data   int
+}
+
+func (b blockLayout) dataCodewords() int {
+	return b.group1Blocks*b.group1Data + b.group2Blocks*b.group2Data
+}
+
+var levelMBlocks = [11]blockLayout{
+	1:  {10, 1, 16, 0, 0},
+	2:  {16, 1, 28, 0, 0},
+	3:  {26, 1, 44, 0, 0},
+	4:  {18, 2, 32, 0, 0},
+	5:  {24, 2, 43, 0, 0},
+	6:  {16, 4, 27, 0, 0},
+	7:  {18, 4, 31, 0, 0},
+	8:  {22, 2, 38, 2, 39},
+	9:  {22, 3, 36, 2, 37},
+	10: {26, 4, 43, 1, 44},
+}
+
+var alignmentPositions = [11][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Encode builds the smallest QR code holding text.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= 10; v++ {
+		if 4+countBits(v)+8*len(data) <= levelMBlocks[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("text of %d bytes is too long for a QR code", len(data))
+	}
+
+	codewords := addErrorCorrection(encodeData(data, version), version)
+
+	code := newCode(version)
+	code.drawFunctionPatterns()
+	code.drawCodewords(codewords)
+
+	// keep the mask with the lowest penalty
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask)
+		code.drawFormatBits(mask)
+		if penalty := code.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		code.applyMask(mask) // masking is its own inverse
+	}
+	code.applyMask(bestMask)
+	code.drawFormatBits(bestMask)
+
+	return &Code{Version: code.version, Size: code.size, Modules: code.modules}, nil
+}
+
+// PNG renders the code with scale pixels per module and the standard four-module quiet zone.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	const quietZone = 4
+	width := (c.Size + 2*quietZone) * scale
+
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for row := 0; row < c.Size; row++ {
+		for col := 0; col < c.Size; col++ {
+			if !c.Modules[row][col] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((col+quietZone)*scale+dx, (row+quietZone)*scale+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// countBits is the width of the byte-mode character count field
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData produces the padded data codewords: mode, length, payload, terminator and pad bytes
+func encodeData(data []byte, version int) []byte {
+	capacity := levelMBlocks[version].dataCodewords() * 8
+	bits := &bitBuffer{}
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	bits.append(0, min(4, capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := 0xEC; bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// addErrorCorrection splits data into blocks, appends Reed-Solomon codewords and interleaves the result
+func addErrorCorrection(data []byte, version int) []byte {
+	layout := levelMBlocks[version]
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+
+	dataBlocks := [][]byte{}
+	ecBlocks := [][]byte{}
+	offset := 0
+	for i := 0; i < layout.group1Blocks+layout.group2Blocks; i++ {
+		length := layout.group1Data
+		if i >= layout.group1Blocks {
+			length = layout.group2Data
+		}
+		block := data[offset : offset+length]
+		offset += length
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	result := []byte{}
+	for i := 0; i < max(layout.group1Data, layout.group2Data); i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, len(b.bits)/8)
+	for i, bit := range b.bits {
+		if bit {
+			result[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(256) modulo the QR polynomial x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// matrix is a code under construction; function modules are excluded from data placement and masking
+type matrix struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newCode(version int) *matrix {
+	size := 17 + 4*version
+	m := &matrix{version: version, size: size}
+	m.modules = make([][]bool, size)
+	m.isFunction = make([][]bool, size)
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.isFunction[i] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *matrix) setFunction(row, col int, dark bool) {
+	m.modules[row][col] = dark
+	m.isFunction[row][col] = true
+}
+
+func (m *matrix) drawFunctionPatterns() {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	m.drawFinder(3, 3)
+	m.drawFinder(3, m.size-4)
+	m.drawFinder(m.size-4, 3)
+
+	positions := alignmentPositions[m.version]
+	last := len(positions) - 1
+	for i, row := range positions {
+		for j, col := range positions {
+			// alignment patterns never overlap the finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.drawAlignment(row, col)
+		}
+	}
+
+	// reserve format areas now; the real bits are drawn once the mask is chosen
+	m.drawFormatBits(0)
+	m.drawVersionBits()
+}
+
+// drawFinder draws a finder pattern and its separator centred on row, col
+func (m *matrix) drawFinder(row, col int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			r, c := row+dy, col+dx
+			if r < 0 || r >= m.size || c < 0 || c >= m.size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			m.setFunction(r, c, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (m *matrix) drawAlignment(row, col int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(row+dy, col+dx, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits writes both copies of the level M format information for mask, plus the dark module
+func (m *matrix) drawFormatBits(mask int) {
+	const levelM = 0b00
+	data := levelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(i, 8, bit(i))
+	}
+	m.setFunction(7, 8, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(8, 7, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(8, 14-i, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(8, m.size-1-i, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(m.size-15+i, 8, bit(i))
+	}
+	m.setFunction(m.size-8, 8, true)
+}
+
+// drawVersionBits writes the version information blocks required from version 7
+func (m *matrix) drawVersionBits() {
+	if m.version < 7 {
+		return
+	}
+
+	rem := m.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := m.version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(b, a, dark)
+		m.setFunction(a, b, dark)
+	}
+}
+
+// drawCodewords places data bits in the two-column zigzag from the bottom-right corner
+func (m *matrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < m.size; vert++ {
+			for j := 0; j < 2; j++ {
+				col := right - j
+				row := vert
+				if (right+1)&2 == 0 {
+					row = m.size - 1 - vert
+				}
+				if !m.isFunction[row][col] && i < len(codewords)*8 {
+					m.modules[row][col] = (codewords[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (m *matrix) applyMask(mask int) {
+	for row := 0; row < m.size; row++ {
+		for col := 0; col < m.size; col++ {
+			if m.isFunction[row][col] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (row+col)%2 == 0
+			case 1:
+				invert = row%2 == 0
+			case 2:
+				invert = col%3 == 0
+			case 3:
+				invert = (row+col)%3 == 0
+			case 4:
+				invert = (row/2+col/3)%2 == 0
+			case 5:
+				invert = row*col%2+row*col%3 == 0
+			case 6:
+				invert = (row*col%2+row*col%3)%2 == 0
+			case 7:
+				invert = ((row+col)%2+row*col%3)%2 == 0
+			}
+			if invert {
+				m.modules[row][col] = !m.modules[row][col]
+			}
+		}
+	}
+}
+
+var finderLikePatterns = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores the symbol with the four standard rules; lower is easier to scan
+func (m *matrix) penalty() int {
+	score := 0
+	at := func(row, col int, vertical bool) bool {
+		if vertical {
+			return m.modules[col][row]
+		}
+		return m.modules[row][col]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for line := 0; line < m.size; line++ {
+			run := 1
+			for i := 1; i < m.size; i++ {
+				if at(line, i, vertical) == at(line, i-1, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+
+			for i := 0; i+11 <= m.size; i++ {
+				for _, pattern := range finderLikePatterns {
+					matches := true
+					for k, dark := range pattern {
+						if at(line, i+k, vertical) != dark {
+							matches = false
+							break
+						}
+					}
+					if matches {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for row := 0; row < m.size; row++ {
+		for col := 0; col < m.size; col++ {
+			if m.modules[row][col] {
+				dark++
+			}
+			if row+1 < m.size && col+1 < m.size {
+				c := m.modules[row][col]
+				if c == m.modules[row+1][col] && c == m.modules[row][col+1] && c == m.modules[row+1][col+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (m.size * m.size)
+	score += abs(percent-50) / 5 * 10
+
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
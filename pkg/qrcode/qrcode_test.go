@@ -0,0 +1,412 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// The expected values below come from ISO/IEC 18004 (format and version information tables, symbol capacities) and
+// its well-known "HELLO WORLD" 1-M worked example; the decoder in this file reads symbols independently of the encoder.
+
+func TestReedSolomonKnownVector(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("error correction codewords = %v, want %v", got, want)
+	}
+}
+
+func TestFormatBitsKnownVectors(t *testing.T) {
+	// level M, masks 0-7
+	want := []string{
+		"101010000010010",
+		"101000100100101",
+		"101111001111100",
+		"101101101001011",
+		"100010111111001",
+		"100000011001110",
+		"100111110010111",
+		"100101010100000",
+	}
+
+	for mask, bits := range want {
+		m := newCode(1)
+		m.drawFormatBits(mask)
+		first, second := readFormatBits(m.modules)
+		if got := fmt.Sprintf("%015b", first); got != bits {
+			t.Errorf("mask %d: format bits = %s, want %s", mask, got, bits)
+		}
+		if first != second {
+			t.Errorf("mask %d: format copies differ: %015b and %015b", mask, first, second)
+		}
+		if !m.modules[m.size-8][8] {
+			t.Errorf("mask %d: dark module is not set", mask)
+		}
+	}
+}
+
+func TestVersionBitsKnownVectors(t *testing.T) {
+	want := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+	for version, bits := range want {
+		m := newCode(version)
+		m.drawVersionBits()
+
+		topRight, bottomLeft := 0, 0
+		for i := 0; i < 18; i++ {
+			if m.modules[i/3][m.size-11+i%3] {
+				topRight |= 1 << i
+			}
+			if m.modules[m.size-11+i%3][i/3] {
+				bottomLeft |= 1 << i
+			}
+		}
+		if topRight != bits || bottomLeft != bits {
+			t.Errorf("version %d: version bits = %#x and %#x, want %#x", version, topRight, bottomLeft, bits)
+		}
+	}
+}
+
+func TestBlockLayoutsMatchSymbolCapacity(t *testing.T) {
+	// total codewords of versions 1-10, whatever the error correction level
+	totals := []int{0, 26, 44, 70, 100, 134, 172, 196, 242, 292, 346}
+
+	for version := 1; version <= 10; version++ {
+		layout := levelMBlocks[version]
+		total := layout.dataCodewords() + layout.ecPerBlock*(layout.group1Blocks+layout.group2Blocks)
+		if total != totals[version] {
+			t.Errorf("version %d: layout holds %d codewords, want %d", version, total, totals[version])
+		}
+	}
+}
+
+func TestEncodeChoosesSmallestVersion(t *testing.T) {
+	// byte mode capacities at level M
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{0, 1}, {14, 1}, {15, 2}, {26, 2}, {27, 3}, {62, 4}, {84, 5},
+		{106, 6}, {122, 7}, {152, 8}, {180, 9}, {181, 10}, {213, 10},
+	}
+
+	for _, tt := range tests {
+		code, err := Encode(strings.Repeat("a", tt.length))
+		if err != nil {
+			t.Errorf("%d bytes: %v", tt.length, err)
+			continue
+		}
+		if code.Version != tt.version {
+			t.Errorf("%d bytes: version = %d, want %d", tt.length, code.Version, tt.version)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); err == nil {
+		t.Error("214 bytes: expected an error, text does not fit version 10")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	texts := []string{
+		"",
+		"A",
+		"HELLO WORLD",
+		"https://autocity.example/certificates/7f9c2a4e-3b1d-4c8e-9a6f-2d5e8b1c0f37",
+		"Lagos – Toyota Corolla 2015 ✓",
+		strings.Repeat("0123456789", 12),
+		strings.Repeat("x", 213),
+	}
+
+	for _, text := range texts {
+		code, err := Encode(text)
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+			continue
+		}
+		got, err := decode(code)
+		if err != nil {
+			t.Errorf("%q (version %d): %v", text, code.Version, err)
+			continue
+		}
+		if got != text {
+			t.Errorf("decoded %q, want %q", got, text)
+		}
+	}
+}
+
+func TestPNGMatchesModules(t *testing.T) {
+	code, err := Encode("https://autocity.example/certificates/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const scale, quietZone = 3, 4
+
+	data, err := code.PNG(scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	width := (code.Size + 2*quietZone) * scale
+	if img.Bounds().Dx() != width || img.Bounds().Dy() != width {
+		t.Fatalf("image is %v, want %dx%d", img.Bounds(), width, width)
+	}
+	for row := -quietZone; row < code.Size+quietZone; row++ {
+		for col := -quietZone; col < code.Size+quietZone; col++ {
+			r, _, _, _ := img.At((col+quietZone)*scale+scale/2, (row+quietZone)*scale+scale/2).RGBA()
+			dark := r < 0x8000
+			want := row >= 0 && row < code.Size && col >= 0 && col < code.Size && code.Modules[row][col]
+			if dark != want {
+				t.Fatalf("module %d,%d: dark = %v, want %v", row, col, dark, want)
+			}
+		}
+	}
+}
+
+// readFormatBits reads both copies of the 15 format bits, most significant bit first
+func readFormatBits(modules [][]bool) (int, int) {
+	size := len(modules)
+	first, second := 0, 0
+	set := func(value *int, i int, dark bool) {
+		if dark {
+			*value |= 1 << i
+		}
+	}
+
+	// first copy: down column 8 from the top, then left along row 8, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		set(&first, i, modules[i][8])
+	}
+	set(&first, 6, modules[7][8])
+	set(&first, 7, modules[8][8])
+	set(&first, 8, modules[8][7])
+	for i := 9; i < 15; i++ {
+		set(&first, i, modules[8][14-i])
+	}
+
+	// second copy: split between the top-right and bottom-left finders
+	for i := 0; i < 8; i++ {
+		set(&second, i, modules[8][size-1-i])
+	}
+	for i := 8; i < 15; i++ {
+		set(&second, i, modules[size-15+i][8])
+	}
+	return first, second
+}
+
+// decode reads a level M byte-mode symbol back into its text, checking every block's Reed-Solomon syndromes
+func decode(code *Code) (string, error) {
+	if code.Size != 17+4*code.Version || len(code.Modules) != code.Size {
+		return "", fmt.Errorf("size %d does not match version %d", code.Size, code.Version)
+	}
+	for _, corner := range [][2]int{{0, 0}, {0, code.Size - 7}, {code.Size - 7, 0}} {
+		if !isFinder(code.Modules, corner[0], corner[1]) {
+			return "", fmt.Errorf("no finder pattern at %v", corner)
+		}
+	}
+
+	first, second := readFormatBits(code.Modules)
+	if first != second {
+		return "", fmt.Errorf("format copies differ")
+	}
+	level, mask := -1, -1
+	for candidate := 0; candidate < 32; candidate++ {
+		rem := candidate
+		for i := 0; i < 10; i++ {
+			rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+		}
+		if (candidate<<10|rem)^0x5412 == first {
+			level, mask = candidate>>3, candidate&7
+		}
+	}
+	if level != 0b00 {
+		return "", fmt.Errorf("format bits %015b are not a level M format", first)
+	}
+
+	reference := newCode(code.Version)
+	reference.drawFunctionPatterns()
+
+	// read the data modules in placement order, undoing the mask
+	// column pairs run right to left, skipping the vertical timing pattern, alternately upward and downward
+	bits := []bool{}
+	upward := true
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for step := 0; step < code.Size; step++ {
+			row := step
+			if upward {
+				row = code.Size - 1 - step
+			}
+			for _, col := range []int{right, right - 1} {
+				if reference.isFunction[row][col] {
+					continue
+				}
+				bits = append(bits, code.Modules[row][col] != masked(mask, row, col))
+			}
+		}
+		upward = !upward
+	}
+
+	layout := levelMBlocks[code.Version]
+	blockCount := layout.group1Blocks + layout.group2Blocks
+	total := layout.dataCodewords() + layout.ecPerBlock*blockCount
+	if len(bits) < total*8 {
+		return "", fmt.Errorf("symbol holds %d bits, want at least %d", len(bits), total*8)
+	}
+	codewords := make([]byte, total)
+	for i := 0; i < total*8; i++ {
+		if bits[i] {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	// de-interleave into blocks of data followed by error correction
+	blocks := make([][]byte, blockCount)
+	dataLength := func(block int) int {
+		if block < layout.group1Blocks {
+			return layout.group1Data
+		}
+		return layout.group2Data
+	}
+	next := 0
+	for i := 0; i < max(layout.group1Data, layout.group2Data); i++ {
+		for b := range blocks {
+			if i < dataLength(b) {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	data := []byte{}
+	for b, block := range blocks {
+		for i := 0; i < layout.ecPerBlock; i++ {
+			if syndrome := evaluate(block, gfPower(i)); syndrome != 0 {
+				return "", fmt.Errorf("block %d: syndrome %d is %d, the codewords are corrupt", b, i, syndrome)
+			}
+		}
+		data = append(data, block[:dataLength(b)]...)
+	}
+
+	reader := &bitReader{data: data}
+	if modeIndicator := reader.read(4); modeIndicator != 0b0100 {
+		return "", fmt.Errorf("mode indicator %04b is not byte mode", modeIndicator)
+	}
+	length := reader.read(countBits(code.Version))
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(reader.read(8))
+	}
+	if reader.overrun {
+		return "", fmt.Errorf("character count %d runs past the data", length)
+	}
+	return string(text), nil
+}
+
+func isFinder(modules [][]bool, top, left int) bool {
+	for dy := 0; dy < 7; dy++ {
+		for dx := 0; dx < 7; dx++ {
+			ring := min(dy, dx, 6-dy, 6-dx)
+			if modules[top+dy][left+dx] != (ring != 1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// masked reports whether mask pattern inverts the module at row i, column j
+func masked(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// gfPower is alpha^n in GF(256) with the QR polynomial, built by repeated doubling
+func gfPower(n int) byte {
+	x := 1
+	for i := 0; i < n; i++ {
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	return byte(x)
+}
+
+// evaluate computes the polynomial with the given coefficients, highest degree first, at x using Horner's rule
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for _, c := range coefficients {
+		result = gfMultiplySlow(result, x) ^ c
+	}
+	return result
+}
+
+// gfMultiplySlow is shift-and-add multiplication, kept separate from the encoder's on purpose
+func gfMultiplySlow(a, b byte) byte {
+	var product byte
+	x, y := int(a), int(b)
+	for y > 0 {
+		if y&1 != 0 {
+			product ^= byte(x)
+		}
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+		y >>= 1
+	}
+	return product
+}
+
+type bitReader struct {
+	data    []byte
+	pos     int
+	overrun bool
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.overrun = true
+			return value
+		}
+		bit := (r.data[r.pos/8] >> (7 - r.pos%8)) & 1
+		value = value<<1 | int(bit)
+		r.pos++
+	}
+	return value
+}
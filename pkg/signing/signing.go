@@ -0,0 +1,66 @@
+// Package signing signs documents with Ed25519 so third parties can verify them offline with the published public key.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+// Signer holds the private key used to sign documents.
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	KeyID      string // Short fingerprint of the public key, so verifiers can tell rotated keys apart
+}
+
+// NewSigner creates a signer from a 32-byte Ed25519 seed.
+func NewSigner(seed []byte) (*Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key seed must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	fingerprint := sha256.Sum256(privateKey.Public().(ed25519.PublicKey))
+
+	return &Signer{
+		privateKey: privateKey,
+		KeyID:      hex.EncodeToString(fingerprint[:8]),
+	}, nil
+}
+
+// Sign returns the base64-encoded signature of message.
+func (s *Signer) Sign(message []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, message))
+}
+
+// Verify checks a base64-encoded signature made by this signer.
+func (s *Signer) Verify(message []byte, signature string) bool {
+	return Verify(s.PublicKey(), message, signature)
+}
+
+// PublicKey returns the key verifiers need.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+// PublicKeyPEM returns the public key as a PEM-encoded SubjectPublicKeyInfo block, as read by openssl.
+func (s *Signer) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.PublicKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Verify checks a base64-encoded Ed25519 signature of message.
+func Verify(publicKey ed25519.PublicKey, message []byte, signature string) bool {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, message, raw)
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CloudinaryStorage uploads files to Cloudinary as raw assets using a signed upload request.
+type CloudinaryStorage struct {
+	CloudName string
+	APIKey    string
+	APISecret string
+	client    *http.Client
+
+	downloadClient *http.Client
+}
+
+func NewCloudinaryStorage(cloudName, apiKey, apiSecret string) *CloudinaryStorage {
+	return &CloudinaryStorage{
+		CloudName: cloudName,
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		downloadClient: &http.Client{
+			Timeout: 10 * time.Second,
+			// a delivery URL is only trusted for the host it names
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Upload stores data with key as its public id and returns the secure delivery URL
+func (s *CloudinaryStorage) Upload(key, contentType string, data []byte) (string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// signed parameters must be listed alphabetically
+	params := map[string]string{
+		"overwrite": "true",
+		"public_id": key,
+		"timestamp": timestamp,
+	}
+	toSign := fmt.Sprintf("overwrite=%s&public_id=%s&timestamp=%s", params["overwrite"], params["public_id"], params["timestamp"])
+	digest := sha1.Sum([]byte(toSign + s.APISecret))
+	params["signature"] = hex.EncodeToString(digest[:])
+	params["api_key"] = s.APIKey
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range params {
+		if err := form.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("failed to build upload request: %w", err)
+		}
+	}
+	file, err := form.CreateFormFile("file", path.Base(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		return "", fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to build upload request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://api.cloudinary.com/v1_1/%s/raw/upload", s.CloudName)
+	resp, err := s.client.Post(endpoint, form.FormDataContentType(), &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file to cloudinary: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		SecureURL string `json:"secure_url"`
+		Error     struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to read cloudinary response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(result.SecureURL) == "" {
+		return "", fmt.Errorf("cloudinary upload failed with status %d: %s", resp.StatusCode, result.Error.Message)
+	}
+
+	return result.SecureURL, nil
+}
+
+// deliveryPrefix is the start of every delivery URL of the cloud's assets
+func (s *CloudinaryStorage) deliveryPrefix() string {
+	return "https://res.cloudinary.com/" + s.CloudName + "/"
+}
+
+// Download fetches an asset of this cloud by its delivery URL; URLs of any other host or cloud are refused
+func (s *CloudinaryStorage) Download(url string, maxSize int64) ([]byte, error) {
+	if !strings.HasPrefix(url, s.deliveryPrefix()) {
+		return nil, ErrNotStored
+	}
+
+	resp, err := s.downloadClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from cloudinary: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloudinary download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from cloudinary: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage writes files to a directory that the API serves under BaseURL.
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Upload writes data to Dir/key and returns BaseURL/key
+func (s *LocalStorage) Upload(key, contentType string, data []byte) (string, error) {
+	cleanKey := filepath.Clean("/" + key)[1:]
+	path := filepath.Join(s.Dir, cleanKey)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write file to storage: %w", err)
+	}
+
+	return s.BaseURL + "/" + filepath.ToSlash(cleanKey), nil
+}
+
+// Download reads the file behind a BaseURL/key URL straight from Dir
+func (s *LocalStorage) Download(url string, maxSize int64) ([]byte, error) {
+	key, ok := strings.CutPrefix(url, s.BaseURL+"/")
+	if !ok || key == "" {
+		return nil, ErrNotStored
+	}
+	path := filepath.Join(s.Dir, filepath.Clean("/" + filepath.FromSlash(key))[1:])
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file from storage: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, ErrNotStored
+	}
+	if info.Size() > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file from storage: %w", err)
+	}
+	return data, nil
+}
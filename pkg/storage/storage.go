@@ -0,0 +1,17 @@
+// Package storage persists generated files and returns the URL they can be downloaded from.
+package storage
+
+import "errors"
+
+// ErrNotStored is returned by Download for a URL that does not point into the storage.
+var ErrNotStored = errors.New("url does not point to a stored file")
+
+// ErrTooLarge is returned by Download for a file larger than the requested limit.
+var ErrTooLarge = errors.New("stored file is larger than allowed")
+
+// Storage saves a file under key, overwriting any previous file with the same key, and returns its public URL.
+// Download reads back a file by the URL Upload returned, at most maxSize bytes; it never fetches URLs outside the storage.
+type Storage interface {
+	Upload(key, contentType string, data []byte) (string, error)
+	Download(url string, maxSize int64) ([]byte, error)
+}
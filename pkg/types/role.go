@@ -7,4 +7,5 @@ const (
 	RoleAdmin   Role = "admin"
 	RoleSeller  Role = "seller"
 	RoleBuyer   Role = "buyer"
+	RoleInspector Role = "inspector"
 )
\ No newline at end of file